@@ -78,6 +78,11 @@ var run = cli.Command{
 			Destination: &config.PaymentBaseURL,
 			EnvVar:      "BENCH_PAYMENT_URL",
 		},
+		cli.StringFlag{
+			Name:        "payment-merchant-key",
+			Destination: &config.PaymentMerchantKey,
+			EnvVar:      "BENCH_PAYMENT_MERCHANT_KEY",
+		},
 		cli.StringFlag{
 			Name:        "target",
 			Value:       "http://localhost",
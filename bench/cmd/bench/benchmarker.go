@@ -46,6 +46,8 @@ func (b *benchmarker) load(ctx context.Context) error {
 
 	scenario.AbnormalReserveWrongSeat(ctx)
 
+	scenario.AbnormalReserveWithCSRFTokenScenario(ctx)
+
 	if month > 3 {
 		scenario.NormalManyAmbigiousSearchScenario(ctx, month*3)
 	}
@@ -23,6 +23,11 @@ var pretest = cli.Command{
 			Destination: &config.PaymentBaseURL,
 			EnvVar:      "BENCH_PAYMENT_URL",
 		},
+		cli.StringFlag{
+			Name:        "payment-merchant-key",
+			Destination: &config.PaymentMerchantKey,
+			EnvVar:      "BENCH_PAYMENT_MERCHANT_KEY",
+		},
 		cli.StringFlag{
 			Name:        "target",
 			Value:       "http://localhost",
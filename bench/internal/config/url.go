@@ -4,3 +4,6 @@ var (
 	TargetBaseURL  = "http://localhost"
 	PaymentBaseURL = "http://localhost:5000"
 )
+
+// PaymentMerchantKey は課金APIに X-Merchant-Key ヘッダで渡す加盟店のAPIキーです (空なら付けない)
+var PaymentMerchantKey = ""
@@ -1,7 +1,8 @@
 package endpoint
 
 const (
-	PaymentInitializePath = "/initialize"
-	PaymentResultPath     = "/result"
-	PaymentRegistCardPath = "/card"
+	PaymentInitializePath   = "/initialize"
+	PaymentResultPath       = "/result"
+	PaymentResultStreamPath = "/result/stream"
+	PaymentRegistCardPath   = "/card"
 )
@@ -65,6 +65,17 @@ func (c *Client) ReplaceMockTransport() {
 	c.sess.httpClient.Transport = http.DefaultTransport
 }
 
+// CSRFToken は、ログイン時に払い出されたCSRFトークンを返します
+func (c *Client) CSRFToken() string {
+	return c.sess.csrfToken
+}
+
+// SetCSRFToken は、以降のリクエストで送るCSRFトークンを差し替えます
+// NOTE: 不正なトークンが弾かれるかのチェックに利用する
+func (c *Client) SetCSRFToken(token string) {
+	c.sess.csrfToken = token
+}
+
 func (c *Client) Initialize(ctx context.Context) {
 	var (
 		successCode  = http.StatusOK
@@ -227,6 +238,7 @@ func (c *Client) Login(ctx context.Context, email, password string, opt ...Clien
 
 	if resp.StatusCode == successCode {
 		c.loginUser = loginUser
+		c.sess.csrfToken = resp.Header.Get(csrfTokenHeader)
 	}
 
 	if err := bencherror.NewHTTPStatusCodeError(req, resp, opts.wantStatusCode); err != nil {
@@ -261,6 +273,8 @@ func (c *Client) Logout(ctx context.Context, opt ...ClientOption) error {
 		return bencherror.NewApplicationError(err, "POST %s: ステータスコードが不正です: got=%d, want=%d", endpointPath, resp.StatusCode, http.StatusOK)
 	}
 
+	c.sess.csrfToken = ""
+
 	endpoint.IncPathCounter(endpoint.Logout)
 
 	return nil
@@ -587,6 +601,13 @@ func (c *Client) CommitReservation(ctx context.Context, reservationID int, cardT
 }
 
 func (c *Client) ListReservations(ctx context.Context, opt ...ClientOption) (ListReservationsResponse, error) {
+	listReservationResp, _, err := c.ListReservationsPage(ctx, nil, opt...)
+	return listReservationResp, err
+}
+
+// ListReservationsPage は検索条件を指定して予約一覧を取得し、次ページのカーソルとあわせて返します
+// 次ページがない場合、カーソルは空文字列になります
+func (c *Client) ListReservationsPage(ctx context.Context, listReq *ListReservationsQuery, opt ...ClientOption) (ListReservationsResponse, string, error) {
 	var (
 		successCode  = http.StatusOK
 		opts         = newClientOptions(successCode, opt...)
@@ -597,29 +618,37 @@ func (c *Client) ListReservations(ctx context.Context, opt ...ClientOption) (Lis
 
 	req, err := c.sess.newRequest(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
-		return ListReservationsResponse{}, bencherror.NewApplicationError(err, "GET %s: リクエストに失敗しました", endpointPath)
+		return ListReservationsResponse{}, "", bencherror.NewApplicationError(err, "GET %s: リクエストに失敗しました", endpointPath)
+	}
+
+	if listReq != nil {
+		req.URL.RawQuery = listReq.values().Encode()
 	}
 
 	resp, err := c.sess.do(req)
 	if err != nil {
-		return ListReservationsResponse{}, bencherror.NewWrapError(err, "GET %s: リクエストに失敗しました", endpointPath)
+		return ListReservationsResponse{}, "", bencherror.NewWrapError(err, "GET %s: リクエストに失敗しました", endpointPath)
 	}
 	defer resp.Body.Close()
 
 	var listReservationResp ListReservationsResponse
 	if resp.StatusCode == successCode {
 		if err := json.NewDecoder(resp.Body).Decode(&listReservationResp); err != nil {
-			return ListReservationsResponse{}, bencherror.NewApplicationError(err, "GET %s: 予約のMarshalに失敗しました", endpointPath)
+			return ListReservationsResponse{}, "", bencherror.NewApplicationError(err, "GET %s: 予約のMarshalに失敗しました", endpointPath)
 		}
 	}
 
 	if err := bencherror.NewHTTPStatusCodeError(req, resp, opts.wantStatusCode); err != nil {
-		return ListReservationsResponse{}, bencherror.NewApplicationError(err, "GET %s: ステータスコードが不正です: got=%d, want=%d", endpointPath, resp.StatusCode, opts.wantStatusCode)
+		return ListReservationsResponse{}, "", bencherror.NewApplicationError(err, "GET %s: ステータスコードが不正です: got=%d, want=%d", endpointPath, resp.StatusCode, opts.wantStatusCode)
+	}
+
+	if listReq != nil && listReq.Limit > 0 && len(listReservationResp) > listReq.Limit {
+		return ListReservationsResponse{}, "", bencherror.NewSimpleApplicationError("GET %s: 予約一覧の件数がlimitを超えています: got=%d, limit=%d", endpointPath, len(listReservationResp), listReq.Limit)
 	}
 
 	endpoint.IncPathCounter(endpoint.ListReservations)
 
-	return listReservationResp, nil
+	return listReservationResp, resp.Header.Get("X-Next-Cursor"), nil
 }
 
 func (c *Client) ShowReservation(ctx context.Context, reservationID int, opt ...ClientOption) (ShowReservationResponse, error) {
@@ -2,6 +2,8 @@ package isutrain
 
 import (
 	"errors"
+	"net/url"
+	"strconv"
 )
 
 var (
@@ -34,6 +36,19 @@ type (
 
 // 予約詳細・列挙API
 type (
+	// ListReservationsQuery は予約一覧APIの検索条件です
+	// ゼロ値のフィールドはクエリに含めません
+	ListReservationsQuery struct {
+		Limit      int
+		Cursor     string
+		FromDate   string
+		ToDate     string
+		Status     string
+		TrainClass string
+		Sort       string
+		Order      string
+	}
+
 	ShowReservationResponse *Reservation
 
 	ListReservationsResponse []*Reservation
@@ -143,3 +158,24 @@ type (
 		IsOK bool `json:"is_ok"`
 	}
 )
+
+func (q *ListReservationsQuery) values() url.Values {
+	v := url.Values{}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	for key, value := range map[string]string{
+		"cursor":      q.Cursor,
+		"from_date":   q.FromDate,
+		"to_date":     q.ToDate,
+		"status":      q.Status,
+		"train_class": q.TrainClass,
+		"sort":        q.Sort,
+		"order":       q.Order,
+	} {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	return v
+}
@@ -18,8 +18,14 @@ var (
 	ErrRedirect = errors.New("redirectが検出されました")
 )
 
+// CSRFトークンを送るリクエストヘッダ
+const csrfTokenHeader = "X-CSRF-Token"
+
 type Session struct {
 	httpClient *http.Client
+
+	// ログイン時に払い出されたCSRFトークン
+	csrfToken string
 }
 
 func NewSession() (*Session, error) {
@@ -72,6 +78,9 @@ func (sess *Session) newRequest(ctx context.Context, method, uri string, body io
 
 	req = req.WithContext(ctx)
 	req.Header.Add("User-Agent", config.UserAgent)
+	if method != http.MethodGet && sess.csrfToken != "" {
+		req.Header.Set(csrfTokenHeader, sess.csrfToken)
+	}
 
 	return req, nil
 }
@@ -40,6 +40,7 @@ type (
 		Class         string `json:"class"`
 		IsSmokingSeat bool   `json:"is_smoking_seat,omitempty"`
 		IsOccupied    bool   `json:"is_occupied,omitempty"`
+		IsAccessible  bool   `json:"is_accessible,omitempty"`
 	}
 
 	TrainCars []*TrainCar
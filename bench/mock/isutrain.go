@@ -58,6 +58,20 @@ func (m *Mock) getSession(req *http.Request) (*sessions.Session, error) {
 	return session, nil
 }
 
+// checkCSRFToken は、リクエストヘッダのCSRFトークンがログイン時に払い出したものと一致するか検証します
+func (m *Mock) checkCSRFToken(req *http.Request) bool {
+	session, err := m.getSession(req)
+	if err != nil {
+		return false
+	}
+	token, ok := session.Values["csrf_token"].(string)
+	if !ok || token == "" {
+		return false
+	}
+
+	return req.Header.Get("X-CSRF-Token") == token
+}
+
 func (m *Mock) Inject(f func(path string) error) {
 	m.injectFunc = f
 }
@@ -121,15 +135,12 @@ func (m *Mock) Login(req *http.Request) (*httptest.ResponseRecorder, int) {
 		return wr, http.StatusInternalServerError
 	}
 
+	session.Values["csrf_token"] = csrfToken
 	if err := session.Save(req, wr); err != nil {
 		wr.Write([]byte(http.StatusText(http.StatusInternalServerError)))
 		return wr, http.StatusInternalServerError
 	}
-	http.SetCookie(wr, &http.Cookie{
-		Name:  "csrf_token",
-		Value: csrfToken,
-		Path:  "/",
-	})
+	wr.Header().Set("X-CSRF-Token", csrfToken)
 	http.SetCookie(wr, &http.Cookie{
 		Name:  "mock",
 		Value: "true",
@@ -316,6 +327,10 @@ func (m *Mock) SearchTrainSeats(req *http.Request) ([]byte, int) {
 func (m *Mock) Reserve(req *http.Request) ([]byte, int) {
 	<-time.After(m.ReserveDelay)
 
+	if !m.checkCSRFToken(req) {
+		return []byte(http.StatusText(http.StatusForbidden)), http.StatusForbidden
+	}
+
 	// 予約情報を受け取って、予約できたかを返す
 	b, err := ioutil.ReadAll(req.Body)
 	if err != nil {
@@ -349,6 +364,10 @@ func (m *Mock) Reserve(req *http.Request) ([]byte, int) {
 // CommitReservation は予約を確定します
 func (m *Mock) CommitReservation(req *http.Request) ([]byte, int) {
 	<-time.After(m.CommitReservationDelay)
+
+	if !m.checkCSRFToken(req) {
+		return []byte(http.StatusText(http.StatusForbidden)), http.StatusForbidden
+	}
 	// 予約IDを受け取って、確定するだけ
 
 	var reservation *isutrain.CommitReservationRequest
@@ -372,6 +391,10 @@ func (m *Mock) CommitReservation(req *http.Request) ([]byte, int) {
 // CancelReservation は予約をキャンセルします
 func (m *Mock) CancelReservation(req *http.Request) ([]byte, int) {
 	<-time.After(m.CancelReservationDelay)
+
+	if !m.checkCSRFToken(req) {
+		return []byte(http.StatusText(http.StatusForbidden)), http.StatusForbidden
+	}
 	// 予約IDを受け取って
 
 	_, err := httpmock.GetSubmatchAsUint(req, 1)
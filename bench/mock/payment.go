@@ -1,6 +1,7 @@
 package mock
 
 import (
+	"bytes"
 	"encoding/json"
 	"math/rand"
 	"net/http"
@@ -36,6 +37,11 @@ func (m *paymentMock) addPaymentInformation() {
 			Datetime:   time.Now(),
 			Amount:     int64(rand.Intn(200)),
 			IsCanceled: rand.Intn(10000)%2 == 0,
+			State:      payment.StateCaptured,
+		},
+		CardInfo: &payment.CardInformation{
+			MaskedCardNumber: "****" + payment.BenchCardNumber[len(payment.BenchCardNumber)-4:],
+			Brand:            "UNKNOWN",
 		},
 	})
 }
@@ -68,3 +74,25 @@ func (m *paymentMock) GetResult() ([]byte, int) {
 
 	return b, http.StatusOK
 }
+
+// StreamResults は /result/stream と同じく、1件ごとに {"result": ...} を改行区切りで返します
+func (m *paymentMock) StreamResults(canceled string) ([]byte, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	for _, rawData := range m.result.RawData {
+		if canceled == payment.CanceledFilterOnly && !rawData.PaymentInfo.IsCanceled {
+			continue
+		}
+		if canceled == payment.CanceledFilterNotCanceled && rawData.PaymentInfo.IsCanceled {
+			continue
+		}
+		if err := enc.Encode(&payment.ResultStreamChunk{Result: rawData}); err != nil {
+			return []byte(http.StatusText(http.StatusInternalServerError)), http.StatusInternalServerError
+		}
+	}
+
+	return buf.Bytes(), http.StatusOK
+}
@@ -165,6 +165,10 @@ func Register() (*Mock, error) {
 		body, status := paymentMock.GetResult()
 		return httpmock.NewBytesResponse(status, body), nil
 	})
+	httpmock.RegisterResponder("GET", fmt.Sprintf("%s%s", paymentBaseURL, endpoint.PaymentResultStreamPath), func(req *http.Request) (*http.Response, error) {
+		body, status := paymentMock.StreamResults(req.URL.Query().Get("canceled"))
+		return httpmock.NewBytesResponse(status, body), nil
+	})
 	httpmock.RegisterResponder("POST", fmt.Sprintf("%s%s", paymentBaseURL, endpoint.PaymentRegistCardPath), func(req *http.Request) (*http.Response, error) {
 		body, status := paymentMock.RegistCard()
 		return httpmock.NewBytesResponse(status, body), nil
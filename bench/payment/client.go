@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/url"
 	"path/filepath"
@@ -20,6 +21,13 @@ var (
 	ErrRegistCard        = errors.New("クレジットカードの登録及びトークン発行に失敗しました")
 )
 
+// ベンチマーカーが登録するカード
+const (
+	BenchCardNumber     = "11111119"
+	BenchCardCvv        = "222"
+	BenchCardExpiryDate = "10/50"
+)
+
 type Client struct {
 	BaseURL *url.URL
 }
@@ -35,11 +43,23 @@ func NewClient() (*Client, error) {
 	}, nil
 }
 
+// newRequest は加盟店のAPIキーを付けたリクエストを作ります
+func (c *Client) newRequest(method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if config.PaymentMerchantKey != "" {
+		req.Header.Set("X-Merchant-Key", config.PaymentMerchantKey)
+	}
+	return req, nil
+}
+
 func (c *Client) Initialize() error {
 	u := *c.BaseURL
 	u.Path = filepath.Join(u.Path, endpoint.PaymentInitializePath)
 
-	req, err := http.NewRequest(http.MethodPost, u.String(), nil)
+	req, err := c.newRequest(http.MethodPost, u.String(), nil)
 	if err != nil {
 		return bencherror.InitializeErrs.AddError(bencherror.NewCriticalError(err, "課金APIへのinitializeリクエストが失敗しました. 運営に確認をお願いいたします"))
 	}
@@ -77,7 +97,7 @@ func (c *Client) RegistCard(ctx context.Context, cardNumber, cvv, expiryDate str
 		return "", bencherror.NewCriticalError(ErrRegistCard, "課金APIへのRegistCard時、Marshal処理で失敗しました. 運営に確認をお願いいたします")
 	}
 
-	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewBuffer(b))
+	req, err := c.newRequest(http.MethodPost, u.String(), bytes.NewBuffer(b))
 	if err != nil {
 		return "", bencherror.NewCriticalError(ErrRegistCard, "課金APIにクレジットカードを登録できませんでした. 運営に確認をお願いいたします")
 	}
@@ -113,7 +133,7 @@ func (c *Client) Result(ctx context.Context) (*PaymentResult, error) {
 	u := *c.BaseURL
 	u.Path = filepath.Join(u.Path, endpoint.PaymentResultPath)
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	req, err := c.newRequest(http.MethodGet, u.String(), nil)
 	if err != nil {
 		return nil, bencherror.NewCriticalError(err, "課金APIから決済結果を取得できませんでした. 運営に確認をお願いいたします")
 	}
@@ -144,3 +164,55 @@ func (c *Client) Result(ctx context.Context) (*PaymentResult, error) {
 
 	return result, nil
 }
+
+// StreamResults は課金APIの決済結果を1件ずつ受け取り、fn に渡します
+// canceled で絞り込めます (空なら絞り込まない)
+func (c *Client) StreamResults(ctx context.Context, canceled string, fn func(*RawData) error) error {
+	u := *c.BaseURL
+	u.Path = filepath.Join(u.Path, endpoint.PaymentResultStreamPath)
+	if canceled != "" {
+		u.RawQuery = url.Values{"canceled": []string{canceled}}.Encode()
+	}
+
+	req, err := c.newRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return bencherror.NewCriticalError(err, "課金APIから決済結果を取得できませんでした. 運営に確認をお願いいたします")
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return bencherror.NewCriticalError(err, "課金APIへのリクエストに失敗しました. 運営に確認をお願いいたします")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return bencherror.NewCriticalError(
+			ErrPaymentResult,
+			"課金APIから決済結果取得時、不正なステータスコード(got=%d, want=%d)が返却されました. 運営に確認をお願いいたします",
+			resp.StatusCode,
+			http.StatusOK,
+		)
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		chunk := &ResultStreamChunk{}
+		err := dec.Decode(chunk)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return bencherror.NewCriticalError(err, "課金APIのレスポンスが不正です.運営に確認をお願いいたします")
+		}
+		if chunk.Error != nil {
+			return bencherror.NewCriticalError(ErrPaymentResult, "課金APIから決済結果取得中にエラーが返却されました(%s). 運営に確認をお願いいたします", chunk.Error.Message)
+		}
+		if chunk.Result == nil {
+			continue
+		}
+		if err := fn(chunk.Result); err != nil {
+			return err
+		}
+	}
+}
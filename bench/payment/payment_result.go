@@ -1,6 +1,18 @@
 package payment
 
-import "time"
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// 決済の状態
+const (
+	StateCaptured   = "CAPTURED"   // 売上確定
+	StateAuthorized = "AUTHORIZED" // 与信確保中
+	StateVoided     = "VOIDED"     // 与信取消 (有効期限切れを含む)
+	StateDeclined   = "DECLINED"   // 拒否 (拒否された決済・与信の記録)
+)
 
 type PaymentInformation struct {
 	CardToken     string    `json:"card_token"`
@@ -8,22 +20,80 @@ type PaymentInformation struct {
 	Datetime      time.Time `json:"datetime"`
 	Amount        int64     `json:"amount"`
 	IsCanceled    bool      `json:"is_canceled"`
+	State         string    `json:"state"`
+	Currency      string    `json:"currency"`
+	DeclineReason string    `json:"decline_reason"`
+}
+
+// IsCaptured は売上が確定しているかを返します
+// 状態を返さない課金APIの決済は、すべて売上確定として扱います
+func (p *PaymentInformation) IsCaptured() bool {
+	return p.State == "" || p.State == StateCaptured
 }
 
+var (
+	ErrUnmaskedCardInformation = errors.New("課金APIがマスクされていないカード情報を返しました")
+	ErrCardInformationMismatch = errors.New("課金APIのカード情報が登録したカードと一致しません")
+)
+
+// CardInformation はカード情報です
+// CardNumber・Cvv・ExpiryDate はカードの登録にだけ使い、結果取得では MaskedCardNumber と Brand だけが返ります
 type CardInformation struct {
-	CardNumber string `json:"card_number"`
-	Cvv        string `json:"cvv"`
-	ExpiryDate string `json:"expiry_date"`
+	CardNumber       string `json:"card_number"`
+	Cvv              string `json:"cvv"`
+	ExpiryDate       string `json:"expiry_date"`
+	MaskedCardNumber string `json:"masked_card_number,omitempty"`
+	Brand            string `json:"brand,omitempty"`
+}
+
+// ValidateMasked は結果取得のカード情報が、登録したカード番号を下4桁だけ残してマスクしたものかを確かめます
+// カード番号・CVV・有効期限が返った場合はエラーにします
+func (c *CardInformation) ValidateMasked(cardNumber string) error {
+	if c == nil || len(cardNumber) < 4 {
+		return ErrCardInformationMismatch
+	}
+	if c.CardNumber != "" || c.Cvv != "" || c.ExpiryDate != "" {
+		return ErrUnmaskedCardInformation
+	}
+	last4 := len(cardNumber) - 4
+	if len(c.MaskedCardNumber) != len(cardNumber) ||
+		c.MaskedCardNumber[last4:] != cardNumber[last4:] ||
+		strings.Trim(c.MaskedCardNumber[:last4], "*") != "" {
+		return ErrCardInformationMismatch
+	}
+	return nil
 }
 
 type RawData struct {
+	PaymentID   string              `json:"payment_id"`
 	PaymentInfo *PaymentInformation `json:"payment_information"`
 	CardInfo    *CardInformation    `json:"card_information"`
 }
 
 type PaymentResult struct {
-	RawData []*RawData `json:"raw_data"`
-	IsOK    bool       `json:"is_ok"`
+	RawData    []*RawData `json:"raw_data"`
+	NextCursor string     `json:"next_cursor"`
+	IsOK       bool       `json:"is_ok"`
+}
+
+// 結果取得のキャンセル状態での絞り込み
+const (
+	CanceledFilterAny         = "ANY_CANCELED"
+	CanceledFilterOnly        = "CANCELED_ONLY"
+	CanceledFilterNotCanceled = "NOT_CANCELED"
+)
+
+// ResultStreamChunk は /result/stream が1行ごとに返すJSON
+// 途中でエラーになった場合は Error が入ります
+type ResultStreamChunk struct {
+	Result *RawData           `json:"result"`
+	Error  *ResultStreamError `json:"error"`
+}
+
+type ResultStreamError struct {
+	GrpcCode int    `json:"grpc_code"`
+	HttpCode int    `json:"http_code"`
+	Message  string `json:"message"`
 }
 
 type RegistCardResponse struct {
@@ -0,0 +1,52 @@
+package payment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMasked(t *testing.T) {
+	tests := []struct {
+		name    string
+		card    *CardInformation
+		wantErr error
+	}{
+		{
+			name:    "masked",
+			card:    &CardInformation{MaskedCardNumber: "****1119", Brand: "UNKNOWN"},
+			wantErr: nil,
+		},
+		{
+			name:    "raw card number",
+			card:    &CardInformation{CardNumber: BenchCardNumber, MaskedCardNumber: "****1119"},
+			wantErr: ErrUnmaskedCardInformation,
+		},
+		{
+			name:    "cvv",
+			card:    &CardInformation{Cvv: BenchCardCvv, MaskedCardNumber: "****1119"},
+			wantErr: ErrUnmaskedCardInformation,
+		},
+		{
+			name:    "other card",
+			card:    &CardInformation{MaskedCardNumber: "****2228"},
+			wantErr: ErrCardInformationMismatch,
+		},
+		{
+			name:    "not masked",
+			card:    &CardInformation{MaskedCardNumber: "11111119"},
+			wantErr: ErrCardInformationMismatch,
+		},
+		{
+			name:    "no card information",
+			card:    nil,
+			wantErr: ErrCardInformationMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantErr, tt.card.ValidateMasked(BenchCardNumber))
+		})
+	}
+}
@@ -161,6 +161,73 @@ func AbnormalReserveWrongSeat(ctx context.Context) error {
 	return nil
 }
 
+// CSRFトークンを付けずに、または不正なトークンで予約しようとし、きちんと弾かれるかチェック
 func AbnormalReserveWithCSRFTokenScenario(ctx context.Context) error {
+
+	client, err := isutrain.NewClient()
+	if err != nil {
+		return bencherror.BenchmarkErrs.AddError(err)
+	}
+
+	if config.Debug {
+		client.ReplaceMockTransport()
+	}
+
+	user, err := xrandom.GetRandomUser()
+	if err != nil {
+		return bencherror.BenchmarkErrs.AddError(err)
+	}
+
+	err = registerUserAndLogin(ctx, client, user)
+	if err != nil {
+		return bencherror.BenchmarkErrs.AddError(err)
+	}
+
+	csrfToken := client.CSRFToken()
+	if csrfToken == "" {
+		return bencherror.BenchmarkErrs.AddError(bencherror.NewSimpleApplicationError("ログイン時にCSRFトークンが払い出されませんでした"))
+	}
+
+	invalidToken, err := util.SecureRandomStr(20)
+	if err != nil {
+		bencherror.SystemErrs.AddError(bencherror.NewCriticalError(err, "ランダム文字列生成でエラーが発生しました"))
+		return nil
+	}
+
+	useAt := xrandom.GetRandomUseAt()
+	departure, arrival := "東京", "大阪"
+	adult, child := xrandom.GetRandomNumberOfPeople()
+	trains, err := client.SearchTrains(ctx, useAt, departure, arrival, "最速", adult, child)
+	if err != nil {
+		return bencherror.BenchmarkErrs.AddError(err)
+	}
+
+	trainIdx := rand.Intn(len(trains))
+	train := trains[trainIdx]
+	carNum := xrandom.GetRandomCarNumber(train.Class, "reserved")
+	listTrainSeatsResp, err := client.SearchTrainSeats(ctx,
+		useAt,
+		train.Class, train.Name, carNum, departure, arrival)
+	if err != nil {
+		return bencherror.BenchmarkErrs.AddError(err)
+	}
+
+	availSeats := FilterTrainSeats(listTrainSeatsResp, 2)
+
+	// トークン無し、不正なトークンの順に試す
+	for _, token := range []string{"", invalidToken} {
+		client.SetCSRFToken(token)
+		_, err = client.Reserve(ctx,
+			train.Class, train.Name,
+			isutraindb.GetSeatClass(train.Class, carNum),
+			availSeats, departure, arrival, useAt,
+			carNum, 1, 1,
+			isutrain.StatusCodeOpt(http.StatusForbidden))
+		if err != nil {
+			return bencherror.BenchmarkErrs.AddError(err)
+		}
+	}
+	client.SetCSRFToken(csrfToken)
+
 	return nil
 }
@@ -9,7 +9,6 @@ import (
 	"github.com/chibiegg/isucon9-final/bench/isutrain"
 	"github.com/chibiegg/isucon9-final/bench/payment"
 	"go.uber.org/zap"
-	"golang.org/x/sync/errgroup"
 )
 
 var (
@@ -28,18 +27,52 @@ func FinalCheck(ctx context.Context, isutrainClient *isutrain.Client, paymentCli
 
 func finalcheckPayment(ctx context.Context, paymentClient *payment.Client) error {
 	lgr := zap.S()
-	//
-	paymentAPIResult, err := paymentClient.Result(ctx)
+
+	// 売上になっている(キャンセルされていない売上確定の)決済の金額を、予約IDごとにまとめる
+	// 決済結果は全件をメモリに載せず、ストリームで1件ずつ受け取って集計する
+	// カード情報はマスクされていて、ベンチマーカーが登録したカードのものであること
+	var (
+		total    int
+		payments = map[int][]int64{}
+		cardErr  error
+	)
+	err := paymentClient.StreamResults(ctx, payment.CanceledFilterNotCanceled, func(rawData *payment.RawData) error {
+		total++
+		if rawData.PaymentInfo == nil {
+			return nil
+		}
+		if rawData.PaymentInfo.IsCanceled {
+			// Commitしたものだけ見るので、Cancelされたものは無視する
+			return nil
+		}
+		if !rawData.PaymentInfo.IsCaptured() {
+			// 与信確保中・取消済み・拒否された決済は売上ではないので無視する
+			return nil
+		}
+		if err := rawData.CardInfo.ValidateMasked(payment.BenchCardNumber); err != nil && cardErr == nil {
+			lgr.Warnf("payment_id=%s: %s", rawData.PaymentID, err.Error())
+			cardErr = err
+		}
+		reservationID := rawData.PaymentInfo.ReservationID
+		payments[reservationID] = append(payments[reservationID], rawData.PaymentInfo.Amount)
+		return nil
+	})
 	if err != nil {
 		return bencherror.FinalCheckErrs.AddError(bencherror.NewCriticalError(err, "課金APIから決済結果を取得できませんでした"))
 	}
+	if cardErr == payment.ErrUnmaskedCardInformation {
+		return bencherror.FinalCheckErrs.AddError(bencherror.NewCriticalError(cardErr, "課金APIがマスクされていないカード情報を返しました. 運営に確認をお願いいたします"))
+	}
+	if cardErr != nil {
+		return bencherror.FinalCheckErrs.AddError(bencherror.NewCriticalError(cardErr, "登録したカード以外で決済されています"))
+	}
 
-	if isutrain.ReservationCache.CommitedLen() != 0 && len(paymentAPIResult.RawData) == 0 {
+	if isutrain.ReservationCache.CommitedLen() != 0 && total == 0 {
 		lgr.Warnf("ReservationCacheと課金APIのRawDataが不一致: 予約キャッシュ件数=%d に対し、 課金APIのデータ件数が0", isutrain.ReservationCache.Len())
 		return bencherror.FinalCheckErrs.AddError(bencherror.NewCriticalError(ErrInvalidReservationForPaymentAPI, "成功した予約が存在するはずですが、課金APIには予約が記録されていませんでした"))
 	}
 
-	eg := &errgroup.Group{}
+	var checkErr error
 
 	// commitされた予約について整合性チェック
 	isutrain.ReservationCache.RangeCommited(func(reservation *isutrain.ReservationCacheEntry) {
@@ -53,53 +86,35 @@ func finalcheckPayment(ctx context.Context, paymentClient *payment.Client) error
 			bencherror.FinalCheckErrs.AddError(bencherror.NewCriticalError(err, "予約の運賃取得に失敗しました"))
 			return
 		}
+		if checkErr != nil {
+			return
+		}
 
-		eg.Go(func() error {
-			for _, rawData := range paymentAPIResult.RawData {
-				if rawData.PaymentInfo == nil {
-					continue
-				}
-				if rawData.PaymentInfo.ReservationID != reservationID {
-					continue
-				}
-				if rawData.PaymentInfo.IsCanceled {
-					// Commitしたものだけ見るので、Cancelされたものは無視する
-					continue
-				}
-				if rawData.PaymentInfo.Amount != int64(amount) {
-					lgr.Warnf("reservation_id (payment=%d, cache=%d): not same amount %d != %d", rawData.PaymentInfo.ReservationID, reservationID, rawData.PaymentInfo.Amount, amount)
-					return ErrInvalidReservationForPaymentAPI
-				}
-				return nil
-			}
-
+		amounts, ok := payments[reservationID]
+		if !ok {
 			// 予約IDが見つからない場合は不正
-			return ErrInvalidReservationForPaymentAPI
-		})
+			checkErr = ErrInvalidReservationForPaymentAPI
+			return
+		}
+		if amounts[0] != int64(amount) {
+			lgr.Warnf("reservation_id=%d: not same amount %d != %d", reservationID, amounts[0], amount)
+			checkErr = ErrInvalidReservationForPaymentAPI
+		}
 	})
 
 	// cancelされた予約が存在しないことをチェック
 	isutrain.ReservationCache.RangeCanceled(func(reservation *isutrain.ReservationCacheEntry) {
-		var (
-			reservationID = reservation.ID
-		)
-		eg.Go(func() error {
-			for _, rawData := range paymentAPIResult.RawData {
-				if rawData.PaymentInfo == nil {
-					continue
-				}
-				if rawData.PaymentInfo.ReservationID == reservationID && !rawData.PaymentInfo.IsCanceled {
-					lgr.Warnf("キャンセルされた予約 %d が課金情報に含まれてる", reservationID)
-					return ErrCanceledReservationExistsPaymentInformations
-				}
-			}
-
-			return nil
-		})
+		if checkErr != nil {
+			return
+		}
+		if _, ok := payments[reservation.ID]; ok {
+			lgr.Warnf("キャンセルされた予約 %d が課金情報に含まれてる", reservation.ID)
+			checkErr = ErrCanceledReservationExistsPaymentInformations
+		}
 	})
 
-	if err := eg.Wait(); err != nil {
-		return bencherror.FinalCheckErrs.AddError(bencherror.NewCriticalError(err, err.Error()))
+	if checkErr != nil {
+		return bencherror.FinalCheckErrs.AddError(bencherror.NewCriticalError(checkErr, checkErr.Error()))
 	}
 
 	return nil
@@ -309,7 +309,7 @@ func pretestNormalReservation(ctx context.Context, client *isutrain.Client, paym
 		return bencherror.PreTestErrs.AddError(bencherror.NewSimpleCriticalError("GET %s: 予約一覧に、予約したはずの予約IDが含まれていません: want=%d", endpoint.GetPath(endpoint.ListReservations), reserveResp.ReservationID))
 	}
 
-	cardToken, err := paymentClient.RegistCard(ctx, "11111111", "222", "10/50")
+	cardToken, err := paymentClient.RegistCard(ctx, payment.BenchCardNumber, payment.BenchCardCvv, payment.BenchCardExpiryDate)
 	if err != nil {
 		return bencherror.PreTestErrs.AddError(err)
 	}
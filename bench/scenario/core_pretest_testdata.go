@@ -1252,8 +1252,8 @@ var pretestSearchTrainSeatsTests = []struct {
 		departure:  "雨稲ヶ丘",
 		arrival:    "油交",
 		wantSeats: isutrain.TrainSeats{
-			&isutrain.TrainSeat{Row: 1, Column: "A", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
-			&isutrain.TrainSeat{Row: 1, Column: "B", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
+			&isutrain.TrainSeat{Row: 1, Column: "A", Class: "reserved", IsSmokingSeat: false, IsOccupied: false, IsAccessible: true},
+			&isutrain.TrainSeat{Row: 1, Column: "B", Class: "reserved", IsSmokingSeat: false, IsOccupied: false, IsAccessible: true},
 			&isutrain.TrainSeat{Row: 1, Column: "C", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
 			&isutrain.TrainSeat{Row: 1, Column: "D", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
 			&isutrain.TrainSeat{Row: 1, Column: "E", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
@@ -1345,8 +1345,8 @@ var pretestSearchTrainSeatsTests = []struct {
 		departure:  "名古屋",
 		arrival:    "形顔",
 		wantSeats: isutrain.TrainSeats{
-			&isutrain.TrainSeat{Row: 1, Column: "A", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
-			&isutrain.TrainSeat{Row: 1, Column: "B", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
+			&isutrain.TrainSeat{Row: 1, Column: "A", Class: "reserved", IsSmokingSeat: false, IsOccupied: false, IsAccessible: true},
+			&isutrain.TrainSeat{Row: 1, Column: "B", Class: "reserved", IsSmokingSeat: false, IsOccupied: false, IsAccessible: true},
 			&isutrain.TrainSeat{Row: 1, Column: "C", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
 			&isutrain.TrainSeat{Row: 1, Column: "D", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
 			&isutrain.TrainSeat{Row: 1, Column: "E", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
@@ -1438,8 +1438,8 @@ var pretestSearchTrainSeatsTests = []struct {
 		departure:  "桐飛",
 		arrival:    "条川",
 		wantSeats: isutrain.TrainSeats{
-			&isutrain.TrainSeat{Row: 1, Column: "A", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
-			&isutrain.TrainSeat{Row: 1, Column: "B", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
+			&isutrain.TrainSeat{Row: 1, Column: "A", Class: "reserved", IsSmokingSeat: false, IsOccupied: false, IsAccessible: true},
+			&isutrain.TrainSeat{Row: 1, Column: "B", Class: "reserved", IsSmokingSeat: false, IsOccupied: false, IsAccessible: true},
 			&isutrain.TrainSeat{Row: 1, Column: "C", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
 			&isutrain.TrainSeat{Row: 1, Column: "D", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
 			&isutrain.TrainSeat{Row: 1, Column: "E", Class: "reserved", IsSmokingSeat: false, IsOccupied: false},
@@ -78,7 +78,7 @@ func NormalScenario(ctx context.Context) error {
 		return bencherror.BenchmarkErrs.AddError(err)
 	}
 
-	cardToken, err := paymentClient.RegistCard(ctx, "11111111", "222", "10/50")
+	cardToken, err := paymentClient.RegistCard(ctx, payment.BenchCardNumber, payment.BenchCardCvv, payment.BenchCardExpiryDate)
 	if err != nil {
 		return bencherror.BenchmarkErrs.AddError(err)
 	}
@@ -178,7 +178,7 @@ func NormalCancelScenario(ctx context.Context) error {
 		return bencherror.BenchmarkErrs.AddError(err)
 	}
 
-	cardToken, err := paymentClient.RegistCard(ctx, "11111111", "222", "10/50")
+	cardToken, err := paymentClient.RegistCard(ctx, payment.BenchCardNumber, payment.BenchCardCvv, payment.BenchCardExpiryDate)
 	if err != nil {
 		return bencherror.BenchmarkErrs.AddError(err)
 	}
@@ -67,7 +67,7 @@ func AwesomeScenario(ctx context.Context) error {
 		return bencherror.BenchmarkErrs.AddError(err)
 	}
 
-	cardToken, err := paymentClient.RegistCard(ctx, "11111111", "222", "10/50")
+	cardToken, err := paymentClient.RegistCard(ctx, payment.BenchCardNumber, payment.BenchCardCvv, payment.BenchCardExpiryDate)
 	if err != nil {
 		// `bencherror.BenchmarkErrs.AddError(err)` も忘れずに
 		return bencherror.BenchmarkErrs.AddError(err)
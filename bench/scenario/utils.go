@@ -66,7 +66,7 @@ func createSimpleReservation(ctx context.Context, client *isutrain.Client, user
 		return nil, err
 	}
 
-	cardToken, err := paymentClient.RegistCard(ctx, "11111111", "222", "10/50")
+	cardToken, err := paymentClient.RegistCard(ctx, payment.BenchCardNumber, payment.BenchCardCvv, payment.BenchCardExpiryDate)
 	if err != nil {
 		return nil, err
 	}
@@ -124,7 +124,7 @@ func createSpecifiedReservation(ctx context.Context, client *isutrain.Client, us
 		return nil, bencherror.BenchmarkErrs.AddError(err)
 	}
 
-	cardToken, err := paymentClient.RegistCard(ctx, "11111111", "222", "10/50")
+	cardToken, err := paymentClient.RegistCard(ctx, payment.BenchCardNumber, payment.BenchCardCvv, payment.BenchCardExpiryDate)
 	if err != nil {
 		return nil, bencherror.BenchmarkErrs.AddError(err)
 	}
@@ -143,7 +143,8 @@ func FilterTrainSeats(resp *isutrain.SearchTrainSeatsResponse, count int) isutra
 		if len(availSeats) == count {
 			break
 		}
-		if !seat.IsOccupied {
+		// 車いすスペースは介助が必要な予約向けに確保されていることがあるので選ばない
+		if !seat.IsOccupied && !seat.IsAccessible {
 			availSeats = append(availSeats, seat)
 		}
 	}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	pb "payment/pb"
+	"payment/server"
+	"payment/settlement"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// 精算レポートの取得と、webappの予約との突き合わせ
+//
+//	settlement report [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-format csv|json] [-payments] [-o FILE]
+//	settlement reconcile -reservations FILE [-report FILE] [-format csv|json] [-o FILE]
+//
+// 決済APIには gRPC で接続する。-merchant-key にカンマ区切りで複数のAPIキーを指定すると、加盟店ごとのレポートをまとめる
+
+const usage = `usage:
+  settlement report [flags]     精算レポートをCSV・JSONで書き出す
+  settlement reconcile [flags]  精算レポートとwebappの予約を突き合わせ、不一致を書き出す(不一致があれば終了コード1)`
+
+type options struct {
+	addr        string
+	merchantKey string
+	from        string
+	to          string
+	format      string
+	output      string
+	timeout     time.Duration
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	addr := os.Getenv("PAYMENT_GRPC_ADDR")
+	if addr == "" {
+		addr = "localhost:5001"
+	}
+	fs.StringVar(&o.addr, "addr", addr, "決済APIのgRPCのアドレス(PAYMENT_GRPC_ADDR)")
+	fs.StringVar(&o.merchantKey, "merchant-key", os.Getenv("PAYMENT_MERCHANT_KEY"), "加盟店のAPIキー。カンマ区切りで複数指定できる(PAYMENT_MERCHANT_KEY)")
+	fs.StringVar(&o.from, "from", "", "集計する最初の日(YYYY-MM-DD)")
+	fs.StringVar(&o.to, "to", "", "集計する最後の日(YYYY-MM-DD)")
+	fs.StringVar(&o.format, "format", "csv", "出力形式(csv/json)")
+	fs.StringVar(&o.output, "o", "", "出力先のファイル。空なら標準出力")
+	fs.DurationVar(&o.timeout, "timeout", 30*time.Second, "決済APIのタイムアウト")
+}
+
+func (o *options) validate() error {
+	if o.format != "csv" && o.format != "json" {
+		return fmt.Errorf("invalid format: %s", o.format)
+	}
+	return nil
+}
+
+// fetchReport は加盟店ごとに精算レポートを取得してまとめる
+func fetchReport(o *options, includePayments bool) (*pb.GetSettlementReportResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, o.addr, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect %s: %s", o.addr, err)
+	}
+	defer conn.Close()
+	client := pb.NewPaymentServiceClient(conn)
+
+	keys := []string{""}
+	if o.merchantKey != "" {
+		keys = strings.Split(o.merchantKey, ",")
+	}
+	reports := make([]*pb.GetSettlementReportResponse, 0, len(keys))
+	for _, key := range keys {
+		ctx := ctx
+		if key = strings.TrimSpace(key); key != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, server.MerchantKeyHeader, key)
+		}
+		r, err := client.GetSettlementReport(ctx, &pb.GetSettlementReportRequest{
+			From:            o.from,
+			To:              o.to,
+			IncludePayments: includePayments,
+		})
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	return settlement.Merge(reports...), nil
+}
+
+// create は出力先を開く
+func create(name string) (io.WriteCloser, error) {
+	if name == "" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(name)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func report(args []string) error {
+	o := &options{}
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	o.register(fs)
+	payments := fs.Bool("payments", false, "CSVで集計の代わりに明細を書き出す(JSONには常に明細を含める)")
+	fs.Parse(args)
+	if err := o.validate(); err != nil {
+		return err
+	}
+
+	r, err := fetchReport(o, o.format == "json" || *payments)
+	if err != nil {
+		return err
+	}
+	w, err := create(o.output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	switch {
+	case o.format == "json":
+		return settlement.WriteJSON(w, r)
+	case *payments:
+		return settlement.WritePaymentsCSV(w, r.Payments)
+	default:
+		return settlement.WriteSummariesCSV(w, r.Summaries)
+	}
+}
+
+// reconcile は不一致があれば true を返す
+func reconcile(args []string) (bool, error) {
+	o := &options{}
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	o.register(fs)
+	reservationsFile := fs.String("reservations", "", "webappの予約を書き出したCSV・TSV(必須)")
+	reportFile := fs.String("report", "", "report -format json で書き出したレポート。空なら決済APIから取得する")
+	fs.Parse(args)
+	if err := o.validate(); err != nil {
+		return false, err
+	}
+	if *reservationsFile == "" {
+		return false, fmt.Errorf("-reservations is required")
+	}
+
+	f, err := os.Open(*reservationsFile)
+	if err != nil {
+		return false, err
+	}
+	reservations, err := settlement.ReadReservations(f)
+	f.Close()
+	if err != nil {
+		return false, err
+	}
+
+	var r *pb.GetSettlementReportResponse
+	if *reportFile != "" {
+		f, err := os.Open(*reportFile)
+		if err != nil {
+			return false, err
+		}
+		r, err = settlement.ReadJSON(f)
+		f.Close()
+	} else {
+		r, err = fetchReport(o, true)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	mismatches := settlement.Reconcile(reservations, r.Payments)
+	log.Printf("Reservations %d, Payments %d, Mismatches %d\n", len(reservations), len(r.Payments), len(mismatches))
+
+	w, err := create(o.output)
+	if err != nil {
+		return false, err
+	}
+	defer w.Close()
+	if o.format == "json" {
+		err = settlement.WriteMismatchesJSON(w, mismatches)
+	} else {
+		err = settlement.WriteMismatchesCSV(w, mismatches)
+	}
+	return len(mismatches) > 0, err
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "report":
+		if err := report(os.Args[2:]); err != nil {
+			log.Fatalf("failed to write report: %s", err)
+		}
+	case "reconcile":
+		found, err := reconcile(os.Args[2:])
+		if err != nil {
+			log.Fatalf("failed to reconcile: %s", err)
+		}
+		if found {
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"io/ioutil"
+	"time"
 
 	"github.com/pkg/errors"
 	yaml "gopkg.in/yaml.v2"
@@ -33,4 +34,66 @@ func LoadFile(filename string) (*Config, error) {
 type Config struct {
 	HttpPort string `yaml:"http_port,omitempty"` // HTTP Port
 	GrpcPort string `yaml:"grpc_port,omitempty"` // gRPC Port
+
+	AuthorizationTTL time.Duration `yaml:"authorization_ttl,omitempty"` // 与信の有効期限
+	CardTokenTTL     time.Duration `yaml:"card_token_ttl,omitempty"`    // カードトークンの有効期限の上限
+	CancelLatency    time.Duration `yaml:"cancel_latency,omitempty"`    // キャンセルの処理にかかる時間
+
+	CardEncryptionKey string `yaml:"card_encryption_key,omitempty"` // カード番号を暗号化する鍵(base64)。空なら起動ごとに作る
+
+	WebhookMaxAttempts    int           `yaml:"webhook_max_attempts,omitempty"`    // Webhookの送信回数の上限
+	WebhookInitialBackoff time.Duration `yaml:"webhook_initial_backoff,omitempty"` // Webhookの再送までの最初の待ち時間
+	WebhookMaxBackoff     time.Duration `yaml:"webhook_max_backoff,omitempty"`     // Webhookの再送までの待ち時間の上限
+
+	Faults   FaultProfile `yaml:"faults,omitempty"`    // 障害注入
+	AdminKey string       `yaml:"admin_key,omitempty"` // 障害注入の設定の変更・取得に使う管理用のキー
+
+	Merchants          []Merchant `yaml:"merchants,omitempty"`            // 加盟店とAPIキー
+	RequireMerchantKey bool       `yaml:"require_merchant_key,omitempty"` // APIキーの無いリクエストを拒否する
+
+	Limits []SpendingLimit `yaml:"limits,omitempty"` // 全加盟店共通の利用額の上限
+}
+
+// Merchant は加盟店の設定
+type Merchant struct {
+	ID             string          `yaml:"id"`
+	APIKey         string          `yaml:"api_key"`                   // 全てのAPIに使える
+	PublishableKey string          `yaml:"publishable_key,omitempty"` // カードの登録にだけ使える
+	Limits         []SpendingLimit `yaml:"limits,omitempty"`          // 利用額の上限。空なら全加盟店共通の上限
+}
+
+// SpendingLimit は通貨ごとの1日の利用額の上限
+// 金額は "1000" や "99.99" のように通常の単位で書く。省略すると無制限
+type SpendingLimit struct {
+	Currency    string `yaml:"currency"`
+	PerCard     string `yaml:"per_card,omitempty"`     // カードごと
+	PerMerchant string `yaml:"per_merchant,omitempty"` // 加盟店ごと
+}
+
+// FaultProfile は障害注入の設定
+type FaultProfile struct {
+	Enabled              bool        `yaml:"enabled"`
+	Seed                 int64       `yaml:"seed,omitempty"`                   // 乱数のシード。0なら起動時刻から決める
+	Rules                []FaultRule `yaml:"rules,omitempty"`                  // RPCごとの障害
+	DeclinedCardPatterns []string    `yaml:"declined_card_patterns,omitempty"` // 拒否するカード番号の正規表現
+}
+
+// FaultRule はRPCごとの障害の設定
+type FaultRule struct {
+	Method                 string              `yaml:"method"` // RPC名。"*" は設定のないRPC全て
+	Latency                LatencyDistribution `yaml:"latency,omitempty"`
+	ErrorRate              float64             `yaml:"error_rate,omitempty"`
+	ErrorCode              string              `yaml:"error_code,omitempty"` // gRPCのコード名。デフォルトは UNAVAILABLE
+	TimeoutAfterCommitRate float64             `yaml:"timeout_after_commit_rate,omitempty"`
+	DuplicateRate          float64             `yaml:"duplicate_rate,omitempty"`
+}
+
+// LatencyDistribution は遅延の分布
+// Distribution: fixed / uniform / normal / exponential
+type LatencyDistribution struct {
+	Distribution string        `yaml:"distribution,omitempty"`
+	Min          time.Duration `yaml:"min,omitempty"`
+	Max          time.Duration `yaml:"max,omitempty"`
+	Mean         time.Duration `yaml:"mean,omitempty"`
+	Stddev       time.Duration `yaml:"stddev,omitempty"`
 }
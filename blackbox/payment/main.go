@@ -6,6 +6,8 @@ import (
 	"net"
 	_ "net/http/pprof"
 	"os"
+	"strconv"
+	"time"
 
 	"payment/config"
 	pb "payment/pb"
@@ -23,6 +25,45 @@ var (
 
 type PaymentService struct{}
 
+// durationEnv は環境変数から正の時間を読む。未設定ならデフォルト値
+func durationEnv(key string, defaultValue time.Duration) time.Duration {
+	s := os.Getenv(key)
+	if s == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		log.Fatalf("invalid %s: %s\n", key, s)
+	}
+	return d
+}
+
+// latencyEnv は環境変数から0以上の時間を読む。未設定ならデフォルト値
+func latencyEnv(key string, defaultValue time.Duration) time.Duration {
+	s := os.Getenv(key)
+	if s == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		log.Fatalf("invalid %s: %s\n", key, s)
+	}
+	return d
+}
+
+// intEnv は環境変数から正の整数を読む。未設定ならデフォルト値
+func intEnv(key string, defaultValue int) int {
+	s := os.Getenv(key)
+	if s == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		log.Fatalf("invalid %s: %s\n", key, s)
+	}
+	return n
+}
+
 func main() {
 	fmt.Println(banner)
 
@@ -37,22 +78,94 @@ func main() {
 
 	//setup config
 	c := config.Config{
-		HttpPort: httpPort,
-		GrpcPort: grpcPort,
+		HttpPort:         httpPort,
+		GrpcPort:         grpcPort,
+		AuthorizationTTL: durationEnv("PAYMENT_AUTHORIZATION_TTL", server.DefaultAuthorizationTTL),
+		CardTokenTTL:     durationEnv("PAYMENT_CARD_TOKEN_TTL", server.DefaultCardTokenTTL),
+		CancelLatency:    latencyEnv("PAYMENT_CANCEL_LATENCY", server.DefaultCancelLatency),
+
+		CardEncryptionKey: os.Getenv("PAYMENT_CARD_ENCRYPTION_KEY"),
+		AdminKey:          os.Getenv("PAYMENT_ADMIN_KEY"),
+
+		WebhookMaxAttempts:    intEnv("PAYMENT_WEBHOOK_MAX_ATTEMPTS", server.DefaultWebhookPolicy.MaxAttempts),
+		WebhookInitialBackoff: durationEnv("PAYMENT_WEBHOOK_INITIAL_BACKOFF", server.DefaultWebhookPolicy.InitialBackoff),
+		WebhookMaxBackoff:     durationEnv("PAYMENT_WEBHOOK_MAX_BACKOFF", server.DefaultWebhookPolicy.MaxBackoff),
+	}
+	//障害注入の設定は設定ファイルから読む
+	if filename := os.Getenv("PAYMENT_FAULT_CONFIG"); filename != "" {
+		fc, err := config.LoadFile(filename)
+		if err != nil {
+			log.Fatalf("failed to load fault config:%s", err)
+		}
+		c.Faults = fc.Faults
 	}
-	log.Printf("HTTP Port%s, gRPC Port%s\n", c.HttpPort, c.GrpcPort)
+	//加盟店のAPIキーも設定ファイルから読む
+	if filename := os.Getenv("PAYMENT_MERCHANT_CONFIG"); filename != "" {
+		mc, err := config.LoadFile(filename)
+		if err != nil {
+			log.Fatalf("failed to load merchant config:%s", err)
+		}
+		c.Merchants = mc.Merchants
+		c.RequireMerchantKey = mc.RequireMerchantKey
+		c.Limits = mc.Limits
+		if c.AdminKey == "" {
+			c.AdminKey = mc.AdminKey
+		}
+	}
+	if os.Getenv("PAYMENT_REQUIRE_MERCHANT_KEY") == "1" {
+		c.RequireMerchantKey = true
+	}
+	log.Printf("HTTP Port%s, gRPC Port%s, Authorization TTL %s, Card Token TTL %s, Cancel Latency %s\n", c.HttpPort, c.GrpcPort, c.AuthorizationTTL, c.CardTokenTTL, c.CancelLatency)
+	log.Printf("Merchants %d, Require Merchant Key %t, Admin Key %t\n", len(c.Merchants), c.RequireMerchantKey, c.AdminKey != "")
 
 	//setup grpc server
 	lis, err := net.Listen("tcp", c.GrpcPort)
 	if err != nil {
 		log.Fatalf("listen error: %s\n", err)
 	}
-	g := grpc.NewServer()
 
 	s, err := server.NewNetworkServer()
 	if err != nil {
 		log.Fatalf("failed to create new server:%s", err)
 	}
+	s.AuthorizationTTL = c.AuthorizationTTL
+	s.CardTokenTTL = c.CardTokenTTL
+	s.CancelLatency = c.CancelLatency
+	s.WebhookPolicy.MaxAttempts = c.WebhookMaxAttempts
+	s.WebhookPolicy.InitialBackoff = c.WebhookInitialBackoff
+	s.WebhookPolicy.MaxBackoff = c.WebhookMaxBackoff
+	s.RequireMerchantKey = c.RequireMerchantKey
+	s.AdminKey = c.AdminKey
+	if c.CardEncryptionKey != "" {
+		key, err := server.ParseCardEncryptionKey(c.CardEncryptionKey)
+		if err != nil {
+			log.Fatalf("invalid card encryption key:%s", err)
+		}
+		if err := s.SetCardEncryptionKey(key); err != nil {
+			log.Fatalf("invalid card encryption key:%s", err)
+		}
+	} else {
+		log.Println("PAYMENT_CARD_ENCRYPTION_KEY is not set. Card numbers are encrypted with a random key")
+	}
+	merchants, err := server.MerchantsFromConfig(c.Merchants)
+	if err != nil {
+		log.Fatalf("invalid merchant config:%s", err)
+	}
+	if err := s.SetMerchants(merchants); err != nil {
+		log.Fatalf("invalid merchant config:%s", err)
+	}
+	limits, err := server.SpendingLimitsFromConfig(c.Limits)
+	if err != nil {
+		log.Fatalf("invalid limits config:%s", err)
+	}
+	if err := s.SetDefaultSpendingLimits(limits); err != nil {
+		log.Fatalf("invalid limits config:%s", err)
+	}
+	if err := s.SetFaults(server.FaultProfileFromConfig(c.Faults)); err != nil {
+		log.Fatalf("invalid fault config:%s", err)
+	}
+
+	g := grpc.NewServer(grpc.UnaryInterceptor(s.FaultInterceptor))
 
 	pb.RegisterPaymentServiceServer(g, s)
 	done := make(chan struct{})
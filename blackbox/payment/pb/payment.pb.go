@@ -26,10 +26,118 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+// 決済の状態
+type PaymentState int32
+
+const (
+	PaymentState_CAPTURED   PaymentState = 0
+	PaymentState_AUTHORIZED PaymentState = 1
+	PaymentState_VOIDED     PaymentState = 2
+	PaymentState_DECLINED   PaymentState = 3
+)
+
+var PaymentState_name = map[int32]string{
+	0: "CAPTURED",
+	1: "AUTHORIZED",
+	2: "VOIDED",
+	3: "DECLINED",
+}
+
+var PaymentState_value = map[string]int32{
+	"CAPTURED":   0,
+	"AUTHORIZED": 1,
+	"VOIDED":     2,
+	"DECLINED":   3,
+}
+
+func (x PaymentState) String() string {
+	return proto.EnumName(PaymentState_name, int32(x))
+}
+
+func (PaymentState) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{0}
+}
+
+// 決済・与信を拒否した理由
+type DeclineReason int32
+
+const (
+	DeclineReason_NOT_DECLINED              DeclineReason = 0
+	DeclineReason_CARD_DECLINED             DeclineReason = 1
+	DeclineReason_CARD_TOKEN_REVOKED        DeclineReason = 2
+	DeclineReason_CARD_TOKEN_EXPIRED        DeclineReason = 3
+	DeclineReason_CARD_REFERENCE_MISMATCH   DeclineReason = 4
+	DeclineReason_CARD_TOKEN_USAGE_EXCEEDED DeclineReason = 5
+	DeclineReason_CARD_LIMIT_EXCEEDED       DeclineReason = 6
+	DeclineReason_MERCHANT_LIMIT_EXCEEDED   DeclineReason = 7
+)
+
+var DeclineReason_name = map[int32]string{
+	0: "NOT_DECLINED",
+	1: "CARD_DECLINED",
+	2: "CARD_TOKEN_REVOKED",
+	3: "CARD_TOKEN_EXPIRED",
+	4: "CARD_REFERENCE_MISMATCH",
+	5: "CARD_TOKEN_USAGE_EXCEEDED",
+	6: "CARD_LIMIT_EXCEEDED",
+	7: "MERCHANT_LIMIT_EXCEEDED",
+}
+
+var DeclineReason_value = map[string]int32{
+	"NOT_DECLINED":              0,
+	"CARD_DECLINED":             1,
+	"CARD_TOKEN_REVOKED":        2,
+	"CARD_TOKEN_EXPIRED":        3,
+	"CARD_REFERENCE_MISMATCH":   4,
+	"CARD_TOKEN_USAGE_EXCEEDED": 5,
+	"CARD_LIMIT_EXCEEDED":       6,
+	"MERCHANT_LIMIT_EXCEEDED":   7,
+}
+
+func (x DeclineReason) String() string {
+	return proto.EnumName(DeclineReason_name, int32(x))
+}
+
+func (DeclineReason) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{1}
+}
+
+// キャンセル状態での絞り込み
+type CanceledFilter int32
+
+const (
+	CanceledFilter_ANY_CANCELED  CanceledFilter = 0
+	CanceledFilter_CANCELED_ONLY CanceledFilter = 1
+	CanceledFilter_NOT_CANCELED  CanceledFilter = 2
+)
+
+var CanceledFilter_name = map[int32]string{
+	0: "ANY_CANCELED",
+	1: "CANCELED_ONLY",
+	2: "NOT_CANCELED",
+}
+
+var CanceledFilter_value = map[string]int32{
+	"ANY_CANCELED":  0,
+	"CANCELED_ONLY": 1,
+	"NOT_CANCELED":  2,
+}
+
+func (x CanceledFilter) String() string {
+	return proto.EnumName(CanceledFilter_name, int32(x))
+}
+
+func (CanceledFilter) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{2}
+}
+
+// カード情報。card_number・cvv・expiry_date は登録(RegistCard)にだけ使い、レスポンスでは返さない
 type CardInformation struct {
 	CardNumber           string   `protobuf:"bytes,1,opt,name=card_number,json=cardNumber,proto3" json:"card_number,omitempty"`
 	Cvv                  string   `protobuf:"bytes,2,opt,name=cvv,proto3" json:"cvv,omitempty"`
 	ExpiryDate           string   `protobuf:"bytes,3,opt,name=expiry_date,json=expiryDate,proto3" json:"expiry_date,omitempty"`
+	MaskedCardNumber     string   `protobuf:"bytes,4,opt,name=masked_card_number,json=maskedCardNumber,proto3" json:"masked_card_number,omitempty"`
+	Brand                string   `protobuf:"bytes,5,opt,name=brand,proto3" json:"brand,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -81,8 +189,25 @@ func (m *CardInformation) GetExpiryDate() string {
 	return ""
 }
 
+func (m *CardInformation) GetMaskedCardNumber() string {
+	if m != nil {
+		return m.MaskedCardNumber
+	}
+	return ""
+}
+
+func (m *CardInformation) GetBrand() string {
+	if m != nil {
+		return m.Brand
+	}
+	return ""
+}
+
 type RegistCardRequest struct {
 	CardInformation      *CardInformation `protobuf:"bytes,1,opt,name=card_information,json=cardInformation,proto3" json:"card_information,omitempty"`
+	MaxUses              int32            `protobuf:"varint,2,opt,name=max_uses,json=maxUses,proto3" json:"max_uses,omitempty"`
+	TtlSeconds           int64            `protobuf:"varint,3,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`
+	Reference            string           `protobuf:"bytes,4,opt,name=reference,proto3" json:"reference,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
 	XXX_unrecognized     []byte           `json:"-"`
 	XXX_sizecache        int32            `json:"-"`
@@ -120,12 +245,35 @@ func (m *RegistCardRequest) GetCardInformation() *CardInformation {
 	return nil
 }
 
+func (m *RegistCardRequest) GetMaxUses() int32 {
+	if m != nil {
+		return m.MaxUses
+	}
+	return 0
+}
+
+func (m *RegistCardRequest) GetTtlSeconds() int64 {
+	if m != nil {
+		return m.TtlSeconds
+	}
+	return 0
+}
+
+func (m *RegistCardRequest) GetReference() string {
+	if m != nil {
+		return m.Reference
+	}
+	return ""
+}
+
 type RegistCardResponse struct {
-	CardToken            string   `protobuf:"bytes,1,opt,name=card_token,json=cardToken,proto3" json:"card_token,omitempty"`
-	IsOk                 bool     `protobuf:"varint,2,opt,name=is_ok,json=isOk,proto3" json:"is_ok,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	CardToken            string               `protobuf:"bytes,1,opt,name=card_token,json=cardToken,proto3" json:"card_token,omitempty"`
+	IsOk                 bool                 `protobuf:"varint,2,opt,name=is_ok,json=isOk,proto3" json:"is_ok,omitempty"`
+	Brand                string               `protobuf:"bytes,3,opt,name=brand,proto3" json:"brand,omitempty"`
+	ExpiresAt            *timestamp.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
 }
 
 func (m *RegistCardResponse) Reset()         { *m = RegistCardResponse{} }
@@ -167,22 +315,120 @@ func (m *RegistCardResponse) GetIsOk() bool {
 	return false
 }
 
+func (m *RegistCardResponse) GetBrand() string {
+	if m != nil {
+		return m.Brand
+	}
+	return ""
+}
+
+func (m *RegistCardResponse) GetExpiresAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+type RevokeCardTokenRequest struct {
+	CardToken            string   `protobuf:"bytes,1,opt,name=card_token,json=cardToken,proto3" json:"card_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RevokeCardTokenRequest) Reset()         { *m = RevokeCardTokenRequest{} }
+func (m *RevokeCardTokenRequest) String() string { return proto.CompactTextString(m) }
+func (*RevokeCardTokenRequest) ProtoMessage()    {}
+func (*RevokeCardTokenRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{3}
+}
+
+func (m *RevokeCardTokenRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevokeCardTokenRequest.Unmarshal(m, b)
+}
+func (m *RevokeCardTokenRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevokeCardTokenRequest.Marshal(b, m, deterministic)
+}
+func (m *RevokeCardTokenRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevokeCardTokenRequest.Merge(m, src)
+}
+func (m *RevokeCardTokenRequest) XXX_Size() int {
+	return xxx_messageInfo_RevokeCardTokenRequest.Size(m)
+}
+func (m *RevokeCardTokenRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevokeCardTokenRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevokeCardTokenRequest proto.InternalMessageInfo
+
+func (m *RevokeCardTokenRequest) GetCardToken() string {
+	if m != nil {
+		return m.CardToken
+	}
+	return ""
+}
+
+type RevokeCardTokenResponse struct {
+	IsOk                 bool     `protobuf:"varint,1,opt,name=is_ok,json=isOk,proto3" json:"is_ok,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RevokeCardTokenResponse) Reset()         { *m = RevokeCardTokenResponse{} }
+func (m *RevokeCardTokenResponse) String() string { return proto.CompactTextString(m) }
+func (*RevokeCardTokenResponse) ProtoMessage()    {}
+func (*RevokeCardTokenResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{4}
+}
+
+func (m *RevokeCardTokenResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevokeCardTokenResponse.Unmarshal(m, b)
+}
+func (m *RevokeCardTokenResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevokeCardTokenResponse.Marshal(b, m, deterministic)
+}
+func (m *RevokeCardTokenResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevokeCardTokenResponse.Merge(m, src)
+}
+func (m *RevokeCardTokenResponse) XXX_Size() int {
+	return xxx_messageInfo_RevokeCardTokenResponse.Size(m)
+}
+func (m *RevokeCardTokenResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevokeCardTokenResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevokeCardTokenResponse proto.InternalMessageInfo
+
+func (m *RevokeCardTokenResponse) GetIsOk() bool {
+	if m != nil {
+		return m.IsOk
+	}
+	return false
+}
+
 type PaymentInformation struct {
-	CardToken            string               `protobuf:"bytes,1,opt,name=card_token,json=cardToken,proto3" json:"card_token,omitempty"`
-	ReservationId        int32                `protobuf:"varint,2,opt,name=reservation_id,json=reservationId,proto3" json:"reservation_id,omitempty"`
-	Datetime             *timestamp.Timestamp `protobuf:"bytes,3,opt,name=datetime,proto3" json:"datetime,omitempty"`
-	Amount               int32                `protobuf:"varint,4,opt,name=amount,proto3" json:"amount,omitempty"`
-	IsCanceled           bool                 `protobuf:"varint,5,opt,name=is_canceled,json=isCanceled,proto3" json:"is_canceled,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
-	XXX_unrecognized     []byte               `json:"-"`
-	XXX_sizecache        int32                `json:"-"`
+	CardToken              string               `protobuf:"bytes,1,opt,name=card_token,json=cardToken,proto3" json:"card_token,omitempty"`
+	ReservationId          int32                `protobuf:"varint,2,opt,name=reservation_id,json=reservationId,proto3" json:"reservation_id,omitempty"`
+	Datetime               *timestamp.Timestamp `protobuf:"bytes,3,opt,name=datetime,proto3" json:"datetime,omitempty"`
+	Amount                 int32                `protobuf:"varint,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	IsCanceled             bool                 `protobuf:"varint,5,opt,name=is_canceled,json=isCanceled,proto3" json:"is_canceled,omitempty"`
+	State                  PaymentState         `protobuf:"varint,6,opt,name=state,proto3,enum=paymentpb.PaymentState" json:"state,omitempty"`
+	AuthorizationExpiresAt *timestamp.Timestamp `protobuf:"bytes,7,opt,name=authorization_expires_at,json=authorizationExpiresAt,proto3" json:"authorization_expires_at,omitempty"`
+	CardReference          string               `protobuf:"bytes,8,opt,name=card_reference,json=cardReference,proto3" json:"card_reference,omitempty"`
+	Currency               string               `protobuf:"bytes,9,opt,name=currency,proto3" json:"currency,omitempty"`
+	DeclineReason          DeclineReason        `protobuf:"varint,10,opt,name=decline_reason,json=declineReason,proto3,enum=paymentpb.DeclineReason" json:"decline_reason,omitempty"`
+	CanceledAt             *timestamp.Timestamp `protobuf:"bytes,11,opt,name=canceled_at,json=canceledAt,proto3" json:"canceled_at,omitempty"`
+	XXX_NoUnkeyedLiteral   struct{}             `json:"-"`
+	XXX_unrecognized       []byte               `json:"-"`
+	XXX_sizecache          int32                `json:"-"`
 }
 
 func (m *PaymentInformation) Reset()         { *m = PaymentInformation{} }
 func (m *PaymentInformation) String() string { return proto.CompactTextString(m) }
 func (*PaymentInformation) ProtoMessage()    {}
 func (*PaymentInformation) Descriptor() ([]byte, []int) {
-	return fileDescriptor_595799929d632654, []int{3}
+	return fileDescriptor_595799929d632654, []int{5}
 }
 
 func (m *PaymentInformation) XXX_Unmarshal(b []byte) error {
@@ -238,6 +484,48 @@ func (m *PaymentInformation) GetIsCanceled() bool {
 	return false
 }
 
+func (m *PaymentInformation) GetState() PaymentState {
+	if m != nil {
+		return m.State
+	}
+	return PaymentState_CAPTURED
+}
+
+func (m *PaymentInformation) GetAuthorizationExpiresAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.AuthorizationExpiresAt
+	}
+	return nil
+}
+
+func (m *PaymentInformation) GetCardReference() string {
+	if m != nil {
+		return m.CardReference
+	}
+	return ""
+}
+
+func (m *PaymentInformation) GetCurrency() string {
+	if m != nil {
+		return m.Currency
+	}
+	return ""
+}
+
+func (m *PaymentInformation) GetDeclineReason() DeclineReason {
+	if m != nil {
+		return m.DeclineReason
+	}
+	return DeclineReason_NOT_DECLINED
+}
+
+func (m *PaymentInformation) GetCanceledAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.CanceledAt
+	}
+	return nil
+}
+
 type ExecutePaymentRequest struct {
 	PaymentInformation   *PaymentInformation `protobuf:"bytes,1,opt,name=payment_information,json=paymentInformation,proto3" json:"payment_information,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
@@ -249,7 +537,7 @@ func (m *ExecutePaymentRequest) Reset()         { *m = ExecutePaymentRequest{} }
 func (m *ExecutePaymentRequest) String() string { return proto.CompactTextString(m) }
 func (*ExecutePaymentRequest) ProtoMessage()    {}
 func (*ExecutePaymentRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_595799929d632654, []int{4}
+	return fileDescriptor_595799929d632654, []int{6}
 }
 
 func (m *ExecutePaymentRequest) XXX_Unmarshal(b []byte) error {
@@ -289,7 +577,7 @@ func (m *ExecutePaymentResponse) Reset()         { *m = ExecutePaymentResponse{}
 func (m *ExecutePaymentResponse) String() string { return proto.CompactTextString(m) }
 func (*ExecutePaymentResponse) ProtoMessage()    {}
 func (*ExecutePaymentResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_595799929d632654, []int{5}
+	return fileDescriptor_595799929d632654, []int{7}
 }
 
 func (m *ExecutePaymentResponse) XXX_Unmarshal(b []byte) error {
@@ -324,125 +612,375 @@ func (m *ExecutePaymentResponse) GetIsOk() bool {
 	return false
 }
 
-type CancelPaymentRequest struct {
-	PaymentId            string   `protobuf:"bytes,1,opt,name=payment_id,json=paymentId,proto3" json:"payment_id,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+type AuthorizePaymentRequest struct {
+	PaymentInformation   *PaymentInformation `protobuf:"bytes,1,opt,name=payment_information,json=paymentInformation,proto3" json:"payment_information,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
 }
 
-func (m *CancelPaymentRequest) Reset()         { *m = CancelPaymentRequest{} }
-func (m *CancelPaymentRequest) String() string { return proto.CompactTextString(m) }
-func (*CancelPaymentRequest) ProtoMessage()    {}
-func (*CancelPaymentRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_595799929d632654, []int{6}
+func (m *AuthorizePaymentRequest) Reset()         { *m = AuthorizePaymentRequest{} }
+func (m *AuthorizePaymentRequest) String() string { return proto.CompactTextString(m) }
+func (*AuthorizePaymentRequest) ProtoMessage()    {}
+func (*AuthorizePaymentRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{8}
 }
 
-func (m *CancelPaymentRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_CancelPaymentRequest.Unmarshal(m, b)
+func (m *AuthorizePaymentRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuthorizePaymentRequest.Unmarshal(m, b)
 }
-func (m *CancelPaymentRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_CancelPaymentRequest.Marshal(b, m, deterministic)
+func (m *AuthorizePaymentRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuthorizePaymentRequest.Marshal(b, m, deterministic)
 }
-func (m *CancelPaymentRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_CancelPaymentRequest.Merge(m, src)
+func (m *AuthorizePaymentRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuthorizePaymentRequest.Merge(m, src)
 }
-func (m *CancelPaymentRequest) XXX_Size() int {
-	return xxx_messageInfo_CancelPaymentRequest.Size(m)
+func (m *AuthorizePaymentRequest) XXX_Size() int {
+	return xxx_messageInfo_AuthorizePaymentRequest.Size(m)
 }
-func (m *CancelPaymentRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_CancelPaymentRequest.DiscardUnknown(m)
+func (m *AuthorizePaymentRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuthorizePaymentRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_CancelPaymentRequest proto.InternalMessageInfo
+var xxx_messageInfo_AuthorizePaymentRequest proto.InternalMessageInfo
 
-func (m *CancelPaymentRequest) GetPaymentId() string {
+func (m *AuthorizePaymentRequest) GetPaymentInformation() *PaymentInformation {
 	if m != nil {
-		return m.PaymentId
+		return m.PaymentInformation
 	}
-	return ""
+	return nil
 }
 
-type CancelPaymentResponse struct {
-	IsOk                 bool     `protobuf:"varint,1,opt,name=is_ok,json=isOk,proto3" json:"is_ok,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+type AuthorizePaymentResponse struct {
+	PaymentId            string               `protobuf:"bytes,1,opt,name=payment_id,json=paymentId,proto3" json:"payment_id,omitempty"`
+	ExpiresAt            *timestamp.Timestamp `protobuf:"bytes,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	IsOk                 bool                 `protobuf:"varint,3,opt,name=is_ok,json=isOk,proto3" json:"is_ok,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
 }
 
-func (m *CancelPaymentResponse) Reset()         { *m = CancelPaymentResponse{} }
-func (m *CancelPaymentResponse) String() string { return proto.CompactTextString(m) }
-func (*CancelPaymentResponse) ProtoMessage()    {}
-func (*CancelPaymentResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_595799929d632654, []int{7}
+func (m *AuthorizePaymentResponse) Reset()         { *m = AuthorizePaymentResponse{} }
+func (m *AuthorizePaymentResponse) String() string { return proto.CompactTextString(m) }
+func (*AuthorizePaymentResponse) ProtoMessage()    {}
+func (*AuthorizePaymentResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{9}
 }
 
-func (m *CancelPaymentResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_CancelPaymentResponse.Unmarshal(m, b)
+func (m *AuthorizePaymentResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AuthorizePaymentResponse.Unmarshal(m, b)
 }
-func (m *CancelPaymentResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_CancelPaymentResponse.Marshal(b, m, deterministic)
+func (m *AuthorizePaymentResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AuthorizePaymentResponse.Marshal(b, m, deterministic)
 }
-func (m *CancelPaymentResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_CancelPaymentResponse.Merge(m, src)
+func (m *AuthorizePaymentResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AuthorizePaymentResponse.Merge(m, src)
 }
-func (m *CancelPaymentResponse) XXX_Size() int {
-	return xxx_messageInfo_CancelPaymentResponse.Size(m)
+func (m *AuthorizePaymentResponse) XXX_Size() int {
+	return xxx_messageInfo_AuthorizePaymentResponse.Size(m)
 }
-func (m *CancelPaymentResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_CancelPaymentResponse.DiscardUnknown(m)
+func (m *AuthorizePaymentResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AuthorizePaymentResponse.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_CancelPaymentResponse proto.InternalMessageInfo
+var xxx_messageInfo_AuthorizePaymentResponse proto.InternalMessageInfo
 
-func (m *CancelPaymentResponse) GetIsOk() bool {
+func (m *AuthorizePaymentResponse) GetPaymentId() string {
+	if m != nil {
+		return m.PaymentId
+	}
+	return ""
+}
+
+func (m *AuthorizePaymentResponse) GetExpiresAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+func (m *AuthorizePaymentResponse) GetIsOk() bool {
 	if m != nil {
 		return m.IsOk
 	}
 	return false
 }
 
-type BulkCancelPaymentRequest struct {
-	PaymentId            []string `protobuf:"bytes,1,rep,name=payment_id,json=paymentId,proto3" json:"payment_id,omitempty"`
+type CapturePaymentRequest struct {
+	PaymentId            string   `protobuf:"bytes,1,opt,name=payment_id,json=paymentId,proto3" json:"payment_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *BulkCancelPaymentRequest) Reset()         { *m = BulkCancelPaymentRequest{} }
-func (m *BulkCancelPaymentRequest) String() string { return proto.CompactTextString(m) }
-func (*BulkCancelPaymentRequest) ProtoMessage()    {}
-func (*BulkCancelPaymentRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_595799929d632654, []int{8}
+func (m *CapturePaymentRequest) Reset()         { *m = CapturePaymentRequest{} }
+func (m *CapturePaymentRequest) String() string { return proto.CompactTextString(m) }
+func (*CapturePaymentRequest) ProtoMessage()    {}
+func (*CapturePaymentRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{10}
 }
 
-func (m *BulkCancelPaymentRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_BulkCancelPaymentRequest.Unmarshal(m, b)
+func (m *CapturePaymentRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CapturePaymentRequest.Unmarshal(m, b)
 }
-func (m *BulkCancelPaymentRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_BulkCancelPaymentRequest.Marshal(b, m, deterministic)
+func (m *CapturePaymentRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CapturePaymentRequest.Marshal(b, m, deterministic)
 }
-func (m *BulkCancelPaymentRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_BulkCancelPaymentRequest.Merge(m, src)
+func (m *CapturePaymentRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CapturePaymentRequest.Merge(m, src)
 }
-func (m *BulkCancelPaymentRequest) XXX_Size() int {
-	return xxx_messageInfo_BulkCancelPaymentRequest.Size(m)
+func (m *CapturePaymentRequest) XXX_Size() int {
+	return xxx_messageInfo_CapturePaymentRequest.Size(m)
 }
-func (m *BulkCancelPaymentRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_BulkCancelPaymentRequest.DiscardUnknown(m)
+func (m *CapturePaymentRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CapturePaymentRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_BulkCancelPaymentRequest proto.InternalMessageInfo
+var xxx_messageInfo_CapturePaymentRequest proto.InternalMessageInfo
 
-func (m *BulkCancelPaymentRequest) GetPaymentId() []string {
+func (m *CapturePaymentRequest) GetPaymentId() string {
 	if m != nil {
 		return m.PaymentId
 	}
-	return nil
+	return ""
 }
 
-type BulkCancelPaymentResponse struct {
-	Deleted              int32    `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"`
+type CapturePaymentResponse struct {
+	IsOk                 bool     `protobuf:"varint,1,opt,name=is_ok,json=isOk,proto3" json:"is_ok,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CapturePaymentResponse) Reset()         { *m = CapturePaymentResponse{} }
+func (m *CapturePaymentResponse) String() string { return proto.CompactTextString(m) }
+func (*CapturePaymentResponse) ProtoMessage()    {}
+func (*CapturePaymentResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{11}
+}
+
+func (m *CapturePaymentResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CapturePaymentResponse.Unmarshal(m, b)
+}
+func (m *CapturePaymentResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CapturePaymentResponse.Marshal(b, m, deterministic)
+}
+func (m *CapturePaymentResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CapturePaymentResponse.Merge(m, src)
+}
+func (m *CapturePaymentResponse) XXX_Size() int {
+	return xxx_messageInfo_CapturePaymentResponse.Size(m)
+}
+func (m *CapturePaymentResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CapturePaymentResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CapturePaymentResponse proto.InternalMessageInfo
+
+func (m *CapturePaymentResponse) GetIsOk() bool {
+	if m != nil {
+		return m.IsOk
+	}
+	return false
+}
+
+type VoidAuthorizationRequest struct {
+	PaymentId            string   `protobuf:"bytes,1,opt,name=payment_id,json=paymentId,proto3" json:"payment_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VoidAuthorizationRequest) Reset()         { *m = VoidAuthorizationRequest{} }
+func (m *VoidAuthorizationRequest) String() string { return proto.CompactTextString(m) }
+func (*VoidAuthorizationRequest) ProtoMessage()    {}
+func (*VoidAuthorizationRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{12}
+}
+
+func (m *VoidAuthorizationRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VoidAuthorizationRequest.Unmarshal(m, b)
+}
+func (m *VoidAuthorizationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VoidAuthorizationRequest.Marshal(b, m, deterministic)
+}
+func (m *VoidAuthorizationRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VoidAuthorizationRequest.Merge(m, src)
+}
+func (m *VoidAuthorizationRequest) XXX_Size() int {
+	return xxx_messageInfo_VoidAuthorizationRequest.Size(m)
+}
+func (m *VoidAuthorizationRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_VoidAuthorizationRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VoidAuthorizationRequest proto.InternalMessageInfo
+
+func (m *VoidAuthorizationRequest) GetPaymentId() string {
+	if m != nil {
+		return m.PaymentId
+	}
+	return ""
+}
+
+type VoidAuthorizationResponse struct {
+	IsOk                 bool     `protobuf:"varint,1,opt,name=is_ok,json=isOk,proto3" json:"is_ok,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VoidAuthorizationResponse) Reset()         { *m = VoidAuthorizationResponse{} }
+func (m *VoidAuthorizationResponse) String() string { return proto.CompactTextString(m) }
+func (*VoidAuthorizationResponse) ProtoMessage()    {}
+func (*VoidAuthorizationResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{13}
+}
+
+func (m *VoidAuthorizationResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VoidAuthorizationResponse.Unmarshal(m, b)
+}
+func (m *VoidAuthorizationResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VoidAuthorizationResponse.Marshal(b, m, deterministic)
+}
+func (m *VoidAuthorizationResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VoidAuthorizationResponse.Merge(m, src)
+}
+func (m *VoidAuthorizationResponse) XXX_Size() int {
+	return xxx_messageInfo_VoidAuthorizationResponse.Size(m)
+}
+func (m *VoidAuthorizationResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_VoidAuthorizationResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VoidAuthorizationResponse proto.InternalMessageInfo
+
+func (m *VoidAuthorizationResponse) GetIsOk() bool {
+	if m != nil {
+		return m.IsOk
+	}
+	return false
+}
+
+type CancelPaymentRequest struct {
+	PaymentId            string   `protobuf:"bytes,1,opt,name=payment_id,json=paymentId,proto3" json:"payment_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CancelPaymentRequest) Reset()         { *m = CancelPaymentRequest{} }
+func (m *CancelPaymentRequest) String() string { return proto.CompactTextString(m) }
+func (*CancelPaymentRequest) ProtoMessage()    {}
+func (*CancelPaymentRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{14}
+}
+
+func (m *CancelPaymentRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CancelPaymentRequest.Unmarshal(m, b)
+}
+func (m *CancelPaymentRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CancelPaymentRequest.Marshal(b, m, deterministic)
+}
+func (m *CancelPaymentRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CancelPaymentRequest.Merge(m, src)
+}
+func (m *CancelPaymentRequest) XXX_Size() int {
+	return xxx_messageInfo_CancelPaymentRequest.Size(m)
+}
+func (m *CancelPaymentRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CancelPaymentRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CancelPaymentRequest proto.InternalMessageInfo
+
+func (m *CancelPaymentRequest) GetPaymentId() string {
+	if m != nil {
+		return m.PaymentId
+	}
+	return ""
+}
+
+type CancelPaymentResponse struct {
+	IsOk                 bool     `protobuf:"varint,1,opt,name=is_ok,json=isOk,proto3" json:"is_ok,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CancelPaymentResponse) Reset()         { *m = CancelPaymentResponse{} }
+func (m *CancelPaymentResponse) String() string { return proto.CompactTextString(m) }
+func (*CancelPaymentResponse) ProtoMessage()    {}
+func (*CancelPaymentResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{15}
+}
+
+func (m *CancelPaymentResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CancelPaymentResponse.Unmarshal(m, b)
+}
+func (m *CancelPaymentResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CancelPaymentResponse.Marshal(b, m, deterministic)
+}
+func (m *CancelPaymentResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CancelPaymentResponse.Merge(m, src)
+}
+func (m *CancelPaymentResponse) XXX_Size() int {
+	return xxx_messageInfo_CancelPaymentResponse.Size(m)
+}
+func (m *CancelPaymentResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CancelPaymentResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CancelPaymentResponse proto.InternalMessageInfo
+
+func (m *CancelPaymentResponse) GetIsOk() bool {
+	if m != nil {
+		return m.IsOk
+	}
+	return false
+}
+
+type BulkCancelPaymentRequest struct {
+	PaymentId            []string `protobuf:"bytes,1,rep,name=payment_id,json=paymentId,proto3" json:"payment_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BulkCancelPaymentRequest) Reset()         { *m = BulkCancelPaymentRequest{} }
+func (m *BulkCancelPaymentRequest) String() string { return proto.CompactTextString(m) }
+func (*BulkCancelPaymentRequest) ProtoMessage()    {}
+func (*BulkCancelPaymentRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{16}
+}
+
+func (m *BulkCancelPaymentRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BulkCancelPaymentRequest.Unmarshal(m, b)
+}
+func (m *BulkCancelPaymentRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BulkCancelPaymentRequest.Marshal(b, m, deterministic)
+}
+func (m *BulkCancelPaymentRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BulkCancelPaymentRequest.Merge(m, src)
+}
+func (m *BulkCancelPaymentRequest) XXX_Size() int {
+	return xxx_messageInfo_BulkCancelPaymentRequest.Size(m)
+}
+func (m *BulkCancelPaymentRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BulkCancelPaymentRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BulkCancelPaymentRequest proto.InternalMessageInfo
+
+func (m *BulkCancelPaymentRequest) GetPaymentId() []string {
+	if m != nil {
+		return m.PaymentId
+	}
+	return nil
+}
+
+type BulkCancelPaymentResponse struct {
+	Deleted              int32    `protobuf:"varint,1,opt,name=deleted,proto3" json:"deleted,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -452,7 +990,7 @@ func (m *BulkCancelPaymentResponse) Reset()         { *m = BulkCancelPaymentResp
 func (m *BulkCancelPaymentResponse) String() string { return proto.CompactTextString(m) }
 func (*BulkCancelPaymentResponse) ProtoMessage()    {}
 func (*BulkCancelPaymentResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_595799929d632654, []int{9}
+	return fileDescriptor_595799929d632654, []int{17}
 }
 
 func (m *BulkCancelPaymentResponse) XXX_Unmarshal(b []byte) error {
@@ -491,7 +1029,7 @@ func (m *GetPaymentInformationRequest) Reset()         { *m = GetPaymentInformat
 func (m *GetPaymentInformationRequest) String() string { return proto.CompactTextString(m) }
 func (*GetPaymentInformationRequest) ProtoMessage()    {}
 func (*GetPaymentInformationRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_595799929d632654, []int{10}
+	return fileDescriptor_595799929d632654, []int{18}
 }
 
 func (m *GetPaymentInformationRequest) XXX_Unmarshal(b []byte) error {
@@ -531,7 +1069,7 @@ func (m *GetPaymentInformationResponse) Reset()         { *m = GetPaymentInforma
 func (m *GetPaymentInformationResponse) String() string { return proto.CompactTextString(m) }
 func (*GetPaymentInformationResponse) ProtoMessage()    {}
 func (*GetPaymentInformationResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_595799929d632654, []int{11}
+	return fileDescriptor_595799929d632654, []int{19}
 }
 
 func (m *GetPaymentInformationResponse) XXX_Unmarshal(b []byte) error {
@@ -576,7 +1114,7 @@ func (m *InitializeRequest) Reset()         { *m = InitializeRequest{} }
 func (m *InitializeRequest) String() string { return proto.CompactTextString(m) }
 func (*InitializeRequest) ProtoMessage()    {}
 func (*InitializeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_595799929d632654, []int{12}
+	return fileDescriptor_595799929d632654, []int{20}
 }
 
 func (m *InitializeRequest) XXX_Unmarshal(b []byte) error {
@@ -608,7 +1146,7 @@ func (m *InitializeResponse) Reset()         { *m = InitializeResponse{} }
 func (m *InitializeResponse) String() string { return proto.CompactTextString(m) }
 func (*InitializeResponse) ProtoMessage()    {}
 func (*InitializeResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_595799929d632654, []int{13}
+	return fileDescriptor_595799929d632654, []int{21}
 }
 
 func (m *InitializeResponse) XXX_Unmarshal(b []byte) error {
@@ -637,16 +1175,21 @@ func (m *InitializeResponse) GetIsOk() bool {
 }
 
 type GetResultRequest struct {
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Cursor               string               `protobuf:"bytes,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	PageSize             int32                `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	Since                *timestamp.Timestamp `protobuf:"bytes,3,opt,name=since,proto3" json:"since,omitempty"`
+	Until                *timestamp.Timestamp `protobuf:"bytes,4,opt,name=until,proto3" json:"until,omitempty"`
+	Canceled             CanceledFilter       `protobuf:"varint,5,opt,name=canceled,proto3,enum=paymentpb.CanceledFilter" json:"canceled,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
 }
 
 func (m *GetResultRequest) Reset()         { *m = GetResultRequest{} }
 func (m *GetResultRequest) String() string { return proto.CompactTextString(m) }
 func (*GetResultRequest) ProtoMessage()    {}
 func (*GetResultRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_595799929d632654, []int{14}
+	return fileDescriptor_595799929d632654, []int{22}
 }
 
 func (m *GetResultRequest) XXX_Unmarshal(b []byte) error {
@@ -667,9 +1210,45 @@ func (m *GetResultRequest) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_GetResultRequest proto.InternalMessageInfo
 
+func (m *GetResultRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+func (m *GetResultRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *GetResultRequest) GetSince() *timestamp.Timestamp {
+	if m != nil {
+		return m.Since
+	}
+	return nil
+}
+
+func (m *GetResultRequest) GetUntil() *timestamp.Timestamp {
+	if m != nil {
+		return m.Until
+	}
+	return nil
+}
+
+func (m *GetResultRequest) GetCanceled() CanceledFilter {
+	if m != nil {
+		return m.Canceled
+	}
+	return CanceledFilter_ANY_CANCELED
+}
+
 type RawData struct {
 	PaymentInformation   *PaymentInformation `protobuf:"bytes,1,opt,name=payment_information,json=paymentInformation,proto3" json:"payment_information,omitempty"`
 	CardInformation      *CardInformation    `protobuf:"bytes,2,opt,name=card_information,json=cardInformation,proto3" json:"card_information,omitempty"`
+	PaymentId            string              `protobuf:"bytes,3,opt,name=payment_id,json=paymentId,proto3" json:"payment_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
 	XXX_unrecognized     []byte              `json:"-"`
 	XXX_sizecache        int32               `json:"-"`
@@ -679,7 +1258,7 @@ func (m *RawData) Reset()         { *m = RawData{} }
 func (m *RawData) String() string { return proto.CompactTextString(m) }
 func (*RawData) ProtoMessage()    {}
 func (*RawData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_595799929d632654, []int{15}
+	return fileDescriptor_595799929d632654, []int{23}
 }
 
 func (m *RawData) XXX_Unmarshal(b []byte) error {
@@ -714,47 +1293,941 @@ func (m *RawData) GetCardInformation() *CardInformation {
 	return nil
 }
 
+func (m *RawData) GetPaymentId() string {
+	if m != nil {
+		return m.PaymentId
+	}
+	return ""
+}
+
 type GetResultResponse struct {
 	RawData              []*RawData `protobuf:"bytes,1,rep,name=raw_data,json=rawData,proto3" json:"raw_data,omitempty"`
 	IsOk                 bool       `protobuf:"varint,2,opt,name=is_ok,json=isOk,proto3" json:"is_ok,omitempty"`
+	NextCursor           string     `protobuf:"bytes,3,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
 	XXX_unrecognized     []byte     `json:"-"`
 	XXX_sizecache        int32      `json:"-"`
 }
 
-func (m *GetResultResponse) Reset()         { *m = GetResultResponse{} }
-func (m *GetResultResponse) String() string { return proto.CompactTextString(m) }
-func (*GetResultResponse) ProtoMessage()    {}
-func (*GetResultResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_595799929d632654, []int{16}
+func (m *GetResultResponse) Reset()         { *m = GetResultResponse{} }
+func (m *GetResultResponse) String() string { return proto.CompactTextString(m) }
+func (*GetResultResponse) ProtoMessage()    {}
+func (*GetResultResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{24}
+}
+
+func (m *GetResultResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetResultResponse.Unmarshal(m, b)
+}
+func (m *GetResultResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetResultResponse.Marshal(b, m, deterministic)
+}
+func (m *GetResultResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetResultResponse.Merge(m, src)
+}
+func (m *GetResultResponse) XXX_Size() int {
+	return xxx_messageInfo_GetResultResponse.Size(m)
+}
+func (m *GetResultResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetResultResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetResultResponse proto.InternalMessageInfo
+
+func (m *GetResultResponse) GetRawData() []*RawData {
+	if m != nil {
+		return m.RawData
+	}
+	return nil
+}
+
+func (m *GetResultResponse) GetIsOk() bool {
+	if m != nil {
+		return m.IsOk
+	}
+	return false
+}
+
+func (m *GetResultResponse) GetNextCursor() string {
+	if m != nil {
+		return m.NextCursor
+	}
+	return ""
+}
+
+// 遅延の分布
+// distribution: ""(遅延なし) / fixed(mean) / uniform(min〜max) / normal(mean, stddev) / exponential(mean)
+// min/max を指定すると、その範囲に収める
+type LatencyDistribution struct {
+	Distribution         string   `protobuf:"bytes,1,opt,name=distribution,proto3" json:"distribution,omitempty"`
+	MinMs                int64    `protobuf:"varint,2,opt,name=min_ms,json=minMs,proto3" json:"min_ms,omitempty"`
+	MaxMs                int64    `protobuf:"varint,3,opt,name=max_ms,json=maxMs,proto3" json:"max_ms,omitempty"`
+	MeanMs               int64    `protobuf:"varint,4,opt,name=mean_ms,json=meanMs,proto3" json:"mean_ms,omitempty"`
+	StddevMs             int64    `protobuf:"varint,5,opt,name=stddev_ms,json=stddevMs,proto3" json:"stddev_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LatencyDistribution) Reset()         { *m = LatencyDistribution{} }
+func (m *LatencyDistribution) String() string { return proto.CompactTextString(m) }
+func (*LatencyDistribution) ProtoMessage()    {}
+func (*LatencyDistribution) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{25}
+}
+
+func (m *LatencyDistribution) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LatencyDistribution.Unmarshal(m, b)
+}
+func (m *LatencyDistribution) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LatencyDistribution.Marshal(b, m, deterministic)
+}
+func (m *LatencyDistribution) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LatencyDistribution.Merge(m, src)
+}
+func (m *LatencyDistribution) XXX_Size() int {
+	return xxx_messageInfo_LatencyDistribution.Size(m)
+}
+func (m *LatencyDistribution) XXX_DiscardUnknown() {
+	xxx_messageInfo_LatencyDistribution.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LatencyDistribution proto.InternalMessageInfo
+
+func (m *LatencyDistribution) GetDistribution() string {
+	if m != nil {
+		return m.Distribution
+	}
+	return ""
+}
+
+func (m *LatencyDistribution) GetMinMs() int64 {
+	if m != nil {
+		return m.MinMs
+	}
+	return 0
+}
+
+func (m *LatencyDistribution) GetMaxMs() int64 {
+	if m != nil {
+		return m.MaxMs
+	}
+	return 0
+}
+
+func (m *LatencyDistribution) GetMeanMs() int64 {
+	if m != nil {
+		return m.MeanMs
+	}
+	return 0
+}
+
+func (m *LatencyDistribution) GetStddevMs() int64 {
+	if m != nil {
+		return m.StddevMs
+	}
+	return 0
+}
+
+// RPCごとの障害
+type FaultRule struct {
+	Method                 string               `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	Latency                *LatencyDistribution `protobuf:"bytes,2,opt,name=latency,proto3" json:"latency,omitempty"`
+	ErrorRate              float64              `protobuf:"fixed64,3,opt,name=error_rate,json=errorRate,proto3" json:"error_rate,omitempty"`
+	ErrorCode              string               `protobuf:"bytes,4,opt,name=error_code,json=errorCode,proto3" json:"error_code,omitempty"`
+	TimeoutAfterCommitRate float64              `protobuf:"fixed64,5,opt,name=timeout_after_commit_rate,json=timeoutAfterCommitRate,proto3" json:"timeout_after_commit_rate,omitempty"`
+	DuplicateRate          float64              `protobuf:"fixed64,6,opt,name=duplicate_rate,json=duplicateRate,proto3" json:"duplicate_rate,omitempty"`
+	XXX_NoUnkeyedLiteral   struct{}             `json:"-"`
+	XXX_unrecognized       []byte               `json:"-"`
+	XXX_sizecache          int32                `json:"-"`
+}
+
+func (m *FaultRule) Reset()         { *m = FaultRule{} }
+func (m *FaultRule) String() string { return proto.CompactTextString(m) }
+func (*FaultRule) ProtoMessage()    {}
+func (*FaultRule) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{26}
+}
+
+func (m *FaultRule) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FaultRule.Unmarshal(m, b)
+}
+func (m *FaultRule) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FaultRule.Marshal(b, m, deterministic)
+}
+func (m *FaultRule) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FaultRule.Merge(m, src)
+}
+func (m *FaultRule) XXX_Size() int {
+	return xxx_messageInfo_FaultRule.Size(m)
+}
+func (m *FaultRule) XXX_DiscardUnknown() {
+	xxx_messageInfo_FaultRule.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FaultRule proto.InternalMessageInfo
+
+func (m *FaultRule) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *FaultRule) GetLatency() *LatencyDistribution {
+	if m != nil {
+		return m.Latency
+	}
+	return nil
+}
+
+func (m *FaultRule) GetErrorRate() float64 {
+	if m != nil {
+		return m.ErrorRate
+	}
+	return 0
+}
+
+func (m *FaultRule) GetErrorCode() string {
+	if m != nil {
+		return m.ErrorCode
+	}
+	return ""
+}
+
+func (m *FaultRule) GetTimeoutAfterCommitRate() float64 {
+	if m != nil {
+		return m.TimeoutAfterCommitRate
+	}
+	return 0
+}
+
+func (m *FaultRule) GetDuplicateRate() float64 {
+	if m != nil {
+		return m.DuplicateRate
+	}
+	return 0
+}
+
+type FaultProfile struct {
+	Enabled              bool         `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Seed                 int64        `protobuf:"varint,2,opt,name=seed,proto3" json:"seed,omitempty"`
+	Rules                []*FaultRule `protobuf:"bytes,3,rep,name=rules,proto3" json:"rules,omitempty"`
+	DeclinedCardPatterns []string     `protobuf:"bytes,4,rep,name=declined_card_patterns,json=declinedCardPatterns,proto3" json:"declined_card_patterns,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *FaultProfile) Reset()         { *m = FaultProfile{} }
+func (m *FaultProfile) String() string { return proto.CompactTextString(m) }
+func (*FaultProfile) ProtoMessage()    {}
+func (*FaultProfile) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{27}
+}
+
+func (m *FaultProfile) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FaultProfile.Unmarshal(m, b)
+}
+func (m *FaultProfile) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FaultProfile.Marshal(b, m, deterministic)
+}
+func (m *FaultProfile) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FaultProfile.Merge(m, src)
+}
+func (m *FaultProfile) XXX_Size() int {
+	return xxx_messageInfo_FaultProfile.Size(m)
+}
+func (m *FaultProfile) XXX_DiscardUnknown() {
+	xxx_messageInfo_FaultProfile.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_FaultProfile proto.InternalMessageInfo
+
+func (m *FaultProfile) GetEnabled() bool {
+	if m != nil {
+		return m.Enabled
+	}
+	return false
+}
+
+func (m *FaultProfile) GetSeed() int64 {
+	if m != nil {
+		return m.Seed
+	}
+	return 0
+}
+
+func (m *FaultProfile) GetRules() []*FaultRule {
+	if m != nil {
+		return m.Rules
+	}
+	return nil
+}
+
+func (m *FaultProfile) GetDeclinedCardPatterns() []string {
+	if m != nil {
+		return m.DeclinedCardPatterns
+	}
+	return nil
+}
+
+type SetFaultProfileRequest struct {
+	FaultProfile         *FaultProfile `protobuf:"bytes,1,opt,name=fault_profile,json=faultProfile,proto3" json:"fault_profile,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *SetFaultProfileRequest) Reset()         { *m = SetFaultProfileRequest{} }
+func (m *SetFaultProfileRequest) String() string { return proto.CompactTextString(m) }
+func (*SetFaultProfileRequest) ProtoMessage()    {}
+func (*SetFaultProfileRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{28}
+}
+
+func (m *SetFaultProfileRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetFaultProfileRequest.Unmarshal(m, b)
+}
+func (m *SetFaultProfileRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetFaultProfileRequest.Marshal(b, m, deterministic)
+}
+func (m *SetFaultProfileRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetFaultProfileRequest.Merge(m, src)
+}
+func (m *SetFaultProfileRequest) XXX_Size() int {
+	return xxx_messageInfo_SetFaultProfileRequest.Size(m)
+}
+func (m *SetFaultProfileRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetFaultProfileRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetFaultProfileRequest proto.InternalMessageInfo
+
+func (m *SetFaultProfileRequest) GetFaultProfile() *FaultProfile {
+	if m != nil {
+		return m.FaultProfile
+	}
+	return nil
+}
+
+type SetFaultProfileResponse struct {
+	FaultProfile         *FaultProfile `protobuf:"bytes,1,opt,name=fault_profile,json=faultProfile,proto3" json:"fault_profile,omitempty"`
+	IsOk                 bool          `protobuf:"varint,2,opt,name=is_ok,json=isOk,proto3" json:"is_ok,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *SetFaultProfileResponse) Reset()         { *m = SetFaultProfileResponse{} }
+func (m *SetFaultProfileResponse) String() string { return proto.CompactTextString(m) }
+func (*SetFaultProfileResponse) ProtoMessage()    {}
+func (*SetFaultProfileResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{29}
+}
+
+func (m *SetFaultProfileResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetFaultProfileResponse.Unmarshal(m, b)
+}
+func (m *SetFaultProfileResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetFaultProfileResponse.Marshal(b, m, deterministic)
+}
+func (m *SetFaultProfileResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetFaultProfileResponse.Merge(m, src)
+}
+func (m *SetFaultProfileResponse) XXX_Size() int {
+	return xxx_messageInfo_SetFaultProfileResponse.Size(m)
+}
+func (m *SetFaultProfileResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetFaultProfileResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetFaultProfileResponse proto.InternalMessageInfo
+
+func (m *SetFaultProfileResponse) GetFaultProfile() *FaultProfile {
+	if m != nil {
+		return m.FaultProfile
+	}
+	return nil
+}
+
+func (m *SetFaultProfileResponse) GetIsOk() bool {
+	if m != nil {
+		return m.IsOk
+	}
+	return false
+}
+
+type GetFaultProfileRequest struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetFaultProfileRequest) Reset()         { *m = GetFaultProfileRequest{} }
+func (m *GetFaultProfileRequest) String() string { return proto.CompactTextString(m) }
+func (*GetFaultProfileRequest) ProtoMessage()    {}
+func (*GetFaultProfileRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{30}
+}
+
+func (m *GetFaultProfileRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetFaultProfileRequest.Unmarshal(m, b)
+}
+func (m *GetFaultProfileRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetFaultProfileRequest.Marshal(b, m, deterministic)
+}
+func (m *GetFaultProfileRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetFaultProfileRequest.Merge(m, src)
+}
+func (m *GetFaultProfileRequest) XXX_Size() int {
+	return xxx_messageInfo_GetFaultProfileRequest.Size(m)
+}
+func (m *GetFaultProfileRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetFaultProfileRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetFaultProfileRequest proto.InternalMessageInfo
+
+type GetFaultProfileResponse struct {
+	FaultProfile         *FaultProfile `protobuf:"bytes,1,opt,name=fault_profile,json=faultProfile,proto3" json:"fault_profile,omitempty"`
+	IsOk                 bool          `protobuf:"varint,2,opt,name=is_ok,json=isOk,proto3" json:"is_ok,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *GetFaultProfileResponse) Reset()         { *m = GetFaultProfileResponse{} }
+func (m *GetFaultProfileResponse) String() string { return proto.CompactTextString(m) }
+func (*GetFaultProfileResponse) ProtoMessage()    {}
+func (*GetFaultProfileResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{31}
+}
+
+func (m *GetFaultProfileResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetFaultProfileResponse.Unmarshal(m, b)
+}
+func (m *GetFaultProfileResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetFaultProfileResponse.Marshal(b, m, deterministic)
+}
+func (m *GetFaultProfileResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetFaultProfileResponse.Merge(m, src)
+}
+func (m *GetFaultProfileResponse) XXX_Size() int {
+	return xxx_messageInfo_GetFaultProfileResponse.Size(m)
+}
+func (m *GetFaultProfileResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetFaultProfileResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetFaultProfileResponse proto.InternalMessageInfo
+
+func (m *GetFaultProfileResponse) GetFaultProfile() *FaultProfile {
+	if m != nil {
+		return m.FaultProfile
+	}
+	return nil
+}
+
+func (m *GetFaultProfileResponse) GetIsOk() bool {
+	if m != nil {
+		return m.IsOk
+	}
+	return false
+}
+
+type RegisterWebhookRequest struct {
+	Url                  string   `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Secret               string   `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"`
+	Events               []string `protobuf:"bytes,3,rep,name=events,proto3" json:"events,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RegisterWebhookRequest) Reset()         { *m = RegisterWebhookRequest{} }
+func (m *RegisterWebhookRequest) String() string { return proto.CompactTextString(m) }
+func (*RegisterWebhookRequest) ProtoMessage()    {}
+func (*RegisterWebhookRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{32}
+}
+
+func (m *RegisterWebhookRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RegisterWebhookRequest.Unmarshal(m, b)
+}
+func (m *RegisterWebhookRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RegisterWebhookRequest.Marshal(b, m, deterministic)
+}
+func (m *RegisterWebhookRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RegisterWebhookRequest.Merge(m, src)
+}
+func (m *RegisterWebhookRequest) XXX_Size() int {
+	return xxx_messageInfo_RegisterWebhookRequest.Size(m)
+}
+func (m *RegisterWebhookRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RegisterWebhookRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RegisterWebhookRequest proto.InternalMessageInfo
+
+func (m *RegisterWebhookRequest) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *RegisterWebhookRequest) GetSecret() string {
+	if m != nil {
+		return m.Secret
+	}
+	return ""
+}
+
+func (m *RegisterWebhookRequest) GetEvents() []string {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+type RegisterWebhookResponse struct {
+	WebhookId            string   `protobuf:"bytes,1,opt,name=webhook_id,json=webhookId,proto3" json:"webhook_id,omitempty"`
+	Secret               string   `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"`
+	IsOk                 bool     `protobuf:"varint,3,opt,name=is_ok,json=isOk,proto3" json:"is_ok,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RegisterWebhookResponse) Reset()         { *m = RegisterWebhookResponse{} }
+func (m *RegisterWebhookResponse) String() string { return proto.CompactTextString(m) }
+func (*RegisterWebhookResponse) ProtoMessage()    {}
+func (*RegisterWebhookResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{33}
+}
+
+func (m *RegisterWebhookResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RegisterWebhookResponse.Unmarshal(m, b)
+}
+func (m *RegisterWebhookResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RegisterWebhookResponse.Marshal(b, m, deterministic)
+}
+func (m *RegisterWebhookResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RegisterWebhookResponse.Merge(m, src)
+}
+func (m *RegisterWebhookResponse) XXX_Size() int {
+	return xxx_messageInfo_RegisterWebhookResponse.Size(m)
+}
+func (m *RegisterWebhookResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RegisterWebhookResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RegisterWebhookResponse proto.InternalMessageInfo
+
+func (m *RegisterWebhookResponse) GetWebhookId() string {
+	if m != nil {
+		return m.WebhookId
+	}
+	return ""
+}
+
+func (m *RegisterWebhookResponse) GetSecret() string {
+	if m != nil {
+		return m.Secret
+	}
+	return ""
+}
+
+func (m *RegisterWebhookResponse) GetIsOk() bool {
+	if m != nil {
+		return m.IsOk
+	}
+	return false
+}
+
+type DeleteWebhookRequest struct {
+	WebhookId            string   `protobuf:"bytes,1,opt,name=webhook_id,json=webhookId,proto3" json:"webhook_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteWebhookRequest) Reset()         { *m = DeleteWebhookRequest{} }
+func (m *DeleteWebhookRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteWebhookRequest) ProtoMessage()    {}
+func (*DeleteWebhookRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{34}
+}
+
+func (m *DeleteWebhookRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteWebhookRequest.Unmarshal(m, b)
+}
+func (m *DeleteWebhookRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteWebhookRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteWebhookRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteWebhookRequest.Merge(m, src)
+}
+func (m *DeleteWebhookRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteWebhookRequest.Size(m)
+}
+func (m *DeleteWebhookRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteWebhookRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteWebhookRequest proto.InternalMessageInfo
+
+func (m *DeleteWebhookRequest) GetWebhookId() string {
+	if m != nil {
+		return m.WebhookId
+	}
+	return ""
+}
+
+type DeleteWebhookResponse struct {
+	IsOk                 bool     `protobuf:"varint,1,opt,name=is_ok,json=isOk,proto3" json:"is_ok,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteWebhookResponse) Reset()         { *m = DeleteWebhookResponse{} }
+func (m *DeleteWebhookResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteWebhookResponse) ProtoMessage()    {}
+func (*DeleteWebhookResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{35}
+}
+
+func (m *DeleteWebhookResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteWebhookResponse.Unmarshal(m, b)
+}
+func (m *DeleteWebhookResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteWebhookResponse.Marshal(b, m, deterministic)
+}
+func (m *DeleteWebhookResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteWebhookResponse.Merge(m, src)
+}
+func (m *DeleteWebhookResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteWebhookResponse.Size(m)
+}
+func (m *DeleteWebhookResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteWebhookResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteWebhookResponse proto.InternalMessageInfo
+
+func (m *DeleteWebhookResponse) GetIsOk() bool {
+	if m != nil {
+		return m.IsOk
+	}
+	return false
+}
+
+type GetSettlementReportRequest struct {
+	From                 string   `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To                   string   `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	IncludePayments      bool     `protobuf:"varint,3,opt,name=include_payments,json=includePayments,proto3" json:"include_payments,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetSettlementReportRequest) Reset()         { *m = GetSettlementReportRequest{} }
+func (m *GetSettlementReportRequest) String() string { return proto.CompactTextString(m) }
+func (*GetSettlementReportRequest) ProtoMessage()    {}
+func (*GetSettlementReportRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{36}
+}
+
+func (m *GetSettlementReportRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetSettlementReportRequest.Unmarshal(m, b)
+}
+func (m *GetSettlementReportRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetSettlementReportRequest.Marshal(b, m, deterministic)
+}
+func (m *GetSettlementReportRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetSettlementReportRequest.Merge(m, src)
+}
+func (m *GetSettlementReportRequest) XXX_Size() int {
+	return xxx_messageInfo_GetSettlementReportRequest.Size(m)
+}
+func (m *GetSettlementReportRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetSettlementReportRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetSettlementReportRequest proto.InternalMessageInfo
+
+func (m *GetSettlementReportRequest) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
+}
+
+func (m *GetSettlementReportRequest) GetTo() string {
+	if m != nil {
+		return m.To
+	}
+	return ""
+}
+
+func (m *GetSettlementReportRequest) GetIncludePayments() bool {
+	if m != nil {
+		return m.IncludePayments
+	}
+	return false
+}
+
+// 日・加盟店・通貨ごとの集計。金額は通貨の最小単位
+type SettlementSummary struct {
+	Date                 string   `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	MerchantId           string   `protobuf:"bytes,2,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id,omitempty"`
+	Currency             string   `protobuf:"bytes,3,opt,name=currency,proto3" json:"currency,omitempty"`
+	CapturedCount        int64    `protobuf:"varint,4,opt,name=captured_count,json=capturedCount,proto3" json:"captured_count,omitempty"`
+	CapturedAmount       int64    `protobuf:"varint,5,opt,name=captured_amount,json=capturedAmount,proto3" json:"captured_amount,omitempty"`
+	RefundedCount        int64    `protobuf:"varint,6,opt,name=refunded_count,json=refundedCount,proto3" json:"refunded_count,omitempty"`
+	RefundedAmount       int64    `protobuf:"varint,7,opt,name=refunded_amount,json=refundedAmount,proto3" json:"refunded_amount,omitempty"`
+	NetAmount            int64    `protobuf:"varint,8,opt,name=net_amount,json=netAmount,proto3" json:"net_amount,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SettlementSummary) Reset()         { *m = SettlementSummary{} }
+func (m *SettlementSummary) String() string { return proto.CompactTextString(m) }
+func (*SettlementSummary) ProtoMessage()    {}
+func (*SettlementSummary) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{37}
+}
+
+func (m *SettlementSummary) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SettlementSummary.Unmarshal(m, b)
+}
+func (m *SettlementSummary) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SettlementSummary.Marshal(b, m, deterministic)
+}
+func (m *SettlementSummary) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SettlementSummary.Merge(m, src)
+}
+func (m *SettlementSummary) XXX_Size() int {
+	return xxx_messageInfo_SettlementSummary.Size(m)
+}
+func (m *SettlementSummary) XXX_DiscardUnknown() {
+	xxx_messageInfo_SettlementSummary.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SettlementSummary proto.InternalMessageInfo
+
+func (m *SettlementSummary) GetDate() string {
+	if m != nil {
+		return m.Date
+	}
+	return ""
+}
+
+func (m *SettlementSummary) GetMerchantId() string {
+	if m != nil {
+		return m.MerchantId
+	}
+	return ""
+}
+
+func (m *SettlementSummary) GetCurrency() string {
+	if m != nil {
+		return m.Currency
+	}
+	return ""
+}
+
+func (m *SettlementSummary) GetCapturedCount() int64 {
+	if m != nil {
+		return m.CapturedCount
+	}
+	return 0
+}
+
+func (m *SettlementSummary) GetCapturedAmount() int64 {
+	if m != nil {
+		return m.CapturedAmount
+	}
+	return 0
+}
+
+func (m *SettlementSummary) GetRefundedCount() int64 {
+	if m != nil {
+		return m.RefundedCount
+	}
+	return 0
+}
+
+func (m *SettlementSummary) GetRefundedAmount() int64 {
+	if m != nil {
+		return m.RefundedAmount
+	}
+	return 0
+}
+
+func (m *SettlementSummary) GetNetAmount() int64 {
+	if m != nil {
+		return m.NetAmount
+	}
+	return 0
+}
+
+// 売上が確定した決済の明細。カード番号は下4桁以外をマスクする
+type SettlementPayment struct {
+	PaymentId            string               `protobuf:"bytes,1,opt,name=payment_id,json=paymentId,proto3" json:"payment_id,omitempty"`
+	ReservationId        int32                `protobuf:"varint,2,opt,name=reservation_id,json=reservationId,proto3" json:"reservation_id,omitempty"`
+	MerchantId           string               `protobuf:"bytes,3,opt,name=merchant_id,json=merchantId,proto3" json:"merchant_id,omitempty"`
+	Currency             string               `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"`
+	Amount               int64                `protobuf:"varint,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	CapturedAt           *timestamp.Timestamp `protobuf:"bytes,6,opt,name=captured_at,json=capturedAt,proto3" json:"captured_at,omitempty"`
+	RefundedAt           *timestamp.Timestamp `protobuf:"bytes,7,opt,name=refunded_at,json=refundedAt,proto3" json:"refunded_at,omitempty"`
+	MaskedCardNumber     string               `protobuf:"bytes,8,opt,name=masked_card_number,json=maskedCardNumber,proto3" json:"masked_card_number,omitempty"`
+	CardBrand            string               `protobuf:"bytes,9,opt,name=card_brand,json=cardBrand,proto3" json:"card_brand,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *SettlementPayment) Reset()         { *m = SettlementPayment{} }
+func (m *SettlementPayment) String() string { return proto.CompactTextString(m) }
+func (*SettlementPayment) ProtoMessage()    {}
+func (*SettlementPayment) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{38}
+}
+
+func (m *SettlementPayment) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SettlementPayment.Unmarshal(m, b)
+}
+func (m *SettlementPayment) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SettlementPayment.Marshal(b, m, deterministic)
+}
+func (m *SettlementPayment) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SettlementPayment.Merge(m, src)
+}
+func (m *SettlementPayment) XXX_Size() int {
+	return xxx_messageInfo_SettlementPayment.Size(m)
+}
+func (m *SettlementPayment) XXX_DiscardUnknown() {
+	xxx_messageInfo_SettlementPayment.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SettlementPayment proto.InternalMessageInfo
+
+func (m *SettlementPayment) GetPaymentId() string {
+	if m != nil {
+		return m.PaymentId
+	}
+	return ""
+}
+
+func (m *SettlementPayment) GetReservationId() int32 {
+	if m != nil {
+		return m.ReservationId
+	}
+	return 0
+}
+
+func (m *SettlementPayment) GetMerchantId() string {
+	if m != nil {
+		return m.MerchantId
+	}
+	return ""
+}
+
+func (m *SettlementPayment) GetCurrency() string {
+	if m != nil {
+		return m.Currency
+	}
+	return ""
+}
+
+func (m *SettlementPayment) GetAmount() int64 {
+	if m != nil {
+		return m.Amount
+	}
+	return 0
+}
+
+func (m *SettlementPayment) GetCapturedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.CapturedAt
+	}
+	return nil
+}
+
+func (m *SettlementPayment) GetRefundedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.RefundedAt
+	}
+	return nil
+}
+
+func (m *SettlementPayment) GetMaskedCardNumber() string {
+	if m != nil {
+		return m.MaskedCardNumber
+	}
+	return ""
+}
+
+func (m *SettlementPayment) GetCardBrand() string {
+	if m != nil {
+		return m.CardBrand
+	}
+	return ""
+}
+
+type GetSettlementReportResponse struct {
+	Summaries            []*SettlementSummary `protobuf:"bytes,1,rep,name=summaries,proto3" json:"summaries,omitempty"`
+	Payments             []*SettlementPayment `protobuf:"bytes,2,rep,name=payments,proto3" json:"payments,omitempty"`
+	IsOk                 bool                 `protobuf:"varint,3,opt,name=is_ok,json=isOk,proto3" json:"is_ok,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *GetSettlementReportResponse) Reset()         { *m = GetSettlementReportResponse{} }
+func (m *GetSettlementReportResponse) String() string { return proto.CompactTextString(m) }
+func (*GetSettlementReportResponse) ProtoMessage()    {}
+func (*GetSettlementReportResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_595799929d632654, []int{39}
 }
 
-func (m *GetResultResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GetResultResponse.Unmarshal(m, b)
+func (m *GetSettlementReportResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetSettlementReportResponse.Unmarshal(m, b)
 }
-func (m *GetResultResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GetResultResponse.Marshal(b, m, deterministic)
+func (m *GetSettlementReportResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetSettlementReportResponse.Marshal(b, m, deterministic)
 }
-func (m *GetResultResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GetResultResponse.Merge(m, src)
+func (m *GetSettlementReportResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetSettlementReportResponse.Merge(m, src)
 }
-func (m *GetResultResponse) XXX_Size() int {
-	return xxx_messageInfo_GetResultResponse.Size(m)
+func (m *GetSettlementReportResponse) XXX_Size() int {
+	return xxx_messageInfo_GetSettlementReportResponse.Size(m)
 }
-func (m *GetResultResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_GetResultResponse.DiscardUnknown(m)
+func (m *GetSettlementReportResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetSettlementReportResponse.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_GetResultResponse proto.InternalMessageInfo
+var xxx_messageInfo_GetSettlementReportResponse proto.InternalMessageInfo
 
-func (m *GetResultResponse) GetRawData() []*RawData {
+func (m *GetSettlementReportResponse) GetSummaries() []*SettlementSummary {
 	if m != nil {
-		return m.RawData
+		return m.Summaries
 	}
 	return nil
 }
 
-func (m *GetResultResponse) GetIsOk() bool {
+func (m *GetSettlementReportResponse) GetPayments() []*SettlementPayment {
+	if m != nil {
+		return m.Payments
+	}
+	return nil
+}
+
+func (m *GetSettlementReportResponse) GetIsOk() bool {
 	if m != nil {
 		return m.IsOk
 	}
@@ -762,12 +2235,23 @@ func (m *GetResultResponse) GetIsOk() bool {
 }
 
 func init() {
+	proto.RegisterEnum("paymentpb.PaymentState", PaymentState_name, PaymentState_value)
+	proto.RegisterEnum("paymentpb.DeclineReason", DeclineReason_name, DeclineReason_value)
+	proto.RegisterEnum("paymentpb.CanceledFilter", CanceledFilter_name, CanceledFilter_value)
 	proto.RegisterType((*CardInformation)(nil), "paymentpb.CardInformation")
 	proto.RegisterType((*RegistCardRequest)(nil), "paymentpb.RegistCardRequest")
 	proto.RegisterType((*RegistCardResponse)(nil), "paymentpb.RegistCardResponse")
+	proto.RegisterType((*RevokeCardTokenRequest)(nil), "paymentpb.RevokeCardTokenRequest")
+	proto.RegisterType((*RevokeCardTokenResponse)(nil), "paymentpb.RevokeCardTokenResponse")
 	proto.RegisterType((*PaymentInformation)(nil), "paymentpb.PaymentInformation")
 	proto.RegisterType((*ExecutePaymentRequest)(nil), "paymentpb.ExecutePaymentRequest")
 	proto.RegisterType((*ExecutePaymentResponse)(nil), "paymentpb.ExecutePaymentResponse")
+	proto.RegisterType((*AuthorizePaymentRequest)(nil), "paymentpb.AuthorizePaymentRequest")
+	proto.RegisterType((*AuthorizePaymentResponse)(nil), "paymentpb.AuthorizePaymentResponse")
+	proto.RegisterType((*CapturePaymentRequest)(nil), "paymentpb.CapturePaymentRequest")
+	proto.RegisterType((*CapturePaymentResponse)(nil), "paymentpb.CapturePaymentResponse")
+	proto.RegisterType((*VoidAuthorizationRequest)(nil), "paymentpb.VoidAuthorizationRequest")
+	proto.RegisterType((*VoidAuthorizationResponse)(nil), "paymentpb.VoidAuthorizationResponse")
 	proto.RegisterType((*CancelPaymentRequest)(nil), "paymentpb.CancelPaymentRequest")
 	proto.RegisterType((*CancelPaymentResponse)(nil), "paymentpb.CancelPaymentResponse")
 	proto.RegisterType((*BulkCancelPaymentRequest)(nil), "paymentpb.BulkCancelPaymentRequest")
@@ -779,61 +2263,175 @@ func init() {
 	proto.RegisterType((*GetResultRequest)(nil), "paymentpb.GetResultRequest")
 	proto.RegisterType((*RawData)(nil), "paymentpb.RawData")
 	proto.RegisterType((*GetResultResponse)(nil), "paymentpb.GetResultResponse")
+	proto.RegisterType((*LatencyDistribution)(nil), "paymentpb.LatencyDistribution")
+	proto.RegisterType((*FaultRule)(nil), "paymentpb.FaultRule")
+	proto.RegisterType((*FaultProfile)(nil), "paymentpb.FaultProfile")
+	proto.RegisterType((*SetFaultProfileRequest)(nil), "paymentpb.SetFaultProfileRequest")
+	proto.RegisterType((*SetFaultProfileResponse)(nil), "paymentpb.SetFaultProfileResponse")
+	proto.RegisterType((*GetFaultProfileRequest)(nil), "paymentpb.GetFaultProfileRequest")
+	proto.RegisterType((*GetFaultProfileResponse)(nil), "paymentpb.GetFaultProfileResponse")
+	proto.RegisterType((*RegisterWebhookRequest)(nil), "paymentpb.RegisterWebhookRequest")
+	proto.RegisterType((*RegisterWebhookResponse)(nil), "paymentpb.RegisterWebhookResponse")
+	proto.RegisterType((*DeleteWebhookRequest)(nil), "paymentpb.DeleteWebhookRequest")
+	proto.RegisterType((*DeleteWebhookResponse)(nil), "paymentpb.DeleteWebhookResponse")
+	proto.RegisterType((*GetSettlementReportRequest)(nil), "paymentpb.GetSettlementReportRequest")
+	proto.RegisterType((*SettlementSummary)(nil), "paymentpb.SettlementSummary")
+	proto.RegisterType((*SettlementPayment)(nil), "paymentpb.SettlementPayment")
+	proto.RegisterType((*GetSettlementReportResponse)(nil), "paymentpb.GetSettlementReportResponse")
 }
 
 func init() { proto.RegisterFile("pb/payment.proto", fileDescriptor_595799929d632654) }
 
 var fileDescriptor_595799929d632654 = []byte{
-	// 774 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x55, 0x51, 0x6b, 0xd3, 0x50,
-	0x14, 0x26, 0xdb, 0xba, 0xb6, 0xa7, 0xac, 0x6b, 0x6f, 0xed, 0xec, 0x62, 0xcb, 0x66, 0x54, 0x9c,
-	0x43, 0x1b, 0x98, 0x4c, 0x50, 0xf0, 0xc5, 0x6d, 0xcc, 0x81, 0x4c, 0x89, 0x83, 0x81, 0x82, 0xe5,
-	0xb6, 0xb9, 0x2b, 0xd7, 0xb6, 0x49, 0x96, 0xdc, 0x74, 0x9b, 0xe2, 0x8b, 0xf8, 0x22, 0xf8, 0xe6,
-	0x1f, 0xf0, 0xff, 0xf8, 0xe8, 0x5f, 0xf0, 0x87, 0xc8, 0xbd, 0xb9, 0x49, 0x93, 0x36, 0xed, 0x26,
-	0xec, 0xad, 0xf7, 0xdc, 0x73, 0xbf, 0xef, 0x7c, 0xdf, 0xc9, 0x39, 0x85, 0x92, 0xd3, 0xd6, 0x1d,
-	0x7c, 0x31, 0x20, 0x16, 0x6b, 0x3a, 0xae, 0xcd, 0x6c, 0x94, 0x97, 0x47, 0xa7, 0xad, 0xd6, 0xbb,
-	0xb6, 0xdd, 0xed, 0x13, 0x1d, 0x3b, 0x54, 0xc7, 0x96, 0x65, 0x33, 0xcc, 0xa8, 0x6d, 0x79, 0x41,
-	0xa2, 0xba, 0x26, 0x6f, 0xc5, 0xa9, 0xed, 0x9f, 0xe8, 0x8c, 0x0e, 0x88, 0xc7, 0xf0, 0xc0, 0x09,
-	0x12, 0x34, 0x02, 0xcb, 0x3b, 0xd8, 0x35, 0x0f, 0xac, 0x13, 0xdb, 0x1d, 0x88, 0xa7, 0x68, 0x0d,
-	0x0a, 0x1d, 0xec, 0x9a, 0x2d, 0xcb, 0x1f, 0xb4, 0x89, 0x5b, 0x53, 0xd6, 0x95, 0x8d, 0xbc, 0x01,
-	0x3c, 0x74, 0x28, 0x22, 0xa8, 0x04, 0xf3, 0x9d, 0xe1, 0xb0, 0x36, 0x27, 0x2e, 0xf8, 0x4f, 0xfe,
-	0x84, 0x9c, 0x3b, 0xd4, 0xbd, 0x68, 0x99, 0x98, 0x91, 0xda, 0x7c, 0xf0, 0x24, 0x08, 0xed, 0x62,
-	0x46, 0xb4, 0x77, 0x50, 0x36, 0x48, 0x97, 0x7a, 0x8c, 0x93, 0x19, 0xe4, 0xd4, 0x27, 0x1e, 0x43,
-	0x7b, 0x50, 0x12, 0x44, 0x74, 0x44, 0x2e, 0xd8, 0x0a, 0x5b, 0x6a, 0x33, 0x12, 0xd8, 0x1c, 0x2b,
-	0xcf, 0x58, 0xee, 0x24, 0x03, 0xda, 0x4b, 0x40, 0x71, 0x6c, 0xcf, 0xb1, 0x2d, 0x8f, 0xa0, 0x06,
-	0x88, 0x92, 0x5b, 0xcc, 0xee, 0x11, 0x4b, 0x8a, 0xc8, 0xf3, 0xc8, 0x11, 0x0f, 0xa0, 0x0a, 0x64,
-	0xa8, 0xd7, 0xb2, 0x7b, 0x42, 0x45, 0xce, 0x58, 0xa0, 0xde, 0xeb, 0x9e, 0xf6, 0x5b, 0x01, 0xf4,
-	0x26, 0x20, 0x8e, 0x1b, 0x72, 0x09, 0xd4, 0x3d, 0x28, 0xba, 0xc4, 0x23, 0xee, 0x50, 0x64, 0xb7,
-	0xa8, 0x29, 0x30, 0x33, 0xc6, 0x52, 0x2c, 0x7a, 0x60, 0xa2, 0x27, 0x90, 0xe3, 0xe6, 0xf0, 0x06,
-	0x08, 0x83, 0xb8, 0xca, 0xa0, 0x3b, 0xcd, 0xb0, 0x3b, 0xcd, 0xa3, 0xb0, 0x3b, 0x46, 0x94, 0x8b,
-	0x56, 0x60, 0x11, 0x0f, 0x6c, 0xdf, 0x62, 0xb5, 0x05, 0x01, 0x2b, 0x4f, 0xdc, 0x73, 0xea, 0xb5,
-	0x3a, 0xd8, 0xea, 0x90, 0x3e, 0x31, 0x6b, 0x19, 0xa1, 0x03, 0xa8, 0xb7, 0x23, 0x23, 0x5a, 0x17,
-	0xaa, 0x7b, 0xe7, 0xa4, 0xe3, 0x33, 0x22, 0x35, 0x85, 0xbe, 0x1f, 0x42, 0x45, 0xda, 0x9b, 0x62,
-	0x7d, 0x23, 0x66, 0xfd, 0xa4, 0x17, 0x06, 0x72, 0x26, 0x62, 0xda, 0x2b, 0x58, 0x19, 0x27, 0x1a,
-	0x35, 0x21, 0x62, 0x32, 0x43, 0xe7, 0x42, 0x04, 0x33, 0xbd, 0x09, 0xdb, 0x70, 0x23, 0x90, 0x30,
-	0x56, 0xf5, 0x6c, 0x2c, 0xed, 0x21, 0x54, 0xc7, 0x9e, 0xc9, 0x1a, 0x22, 0x12, 0x25, 0x46, 0xf2,
-	0x14, 0x6a, 0x2f, 0xfc, 0x7e, 0xef, 0x4a, 0x44, 0xf3, 0x49, 0xa2, 0x6d, 0x58, 0x4d, 0x79, 0x2a,
-	0xc9, 0x6a, 0x90, 0x35, 0x49, 0x9f, 0x30, 0x12, 0x54, 0x98, 0x31, 0xc2, 0xa3, 0xf6, 0x1c, 0xea,
-	0xfb, 0x84, 0xa5, 0x38, 0x7a, 0x35, 0x79, 0xdf, 0x14, 0x68, 0x4c, 0x79, 0x2f, 0xa9, 0xaf, 0xb9,
-	0xab, 0xe9, 0xcd, 0xa9, 0x40, 0xf9, 0xc0, 0xa2, 0x8c, 0xe2, 0x3e, 0xfd, 0x44, 0x64, 0xe9, 0xda,
-	0x03, 0x40, 0xf1, 0xe0, 0x2c, 0xdf, 0x11, 0x94, 0xf6, 0x09, 0xb7, 0xcb, 0xef, 0x87, 0x7e, 0x6b,
-	0xbf, 0x14, 0xc8, 0x1a, 0xf8, 0x6c, 0x17, 0x33, 0x7c, 0xed, 0x22, 0xd2, 0x56, 0xcc, 0xdc, 0xff,
-	0xaf, 0x98, 0x63, 0x28, 0xc7, 0xca, 0x96, 0x02, 0x1f, 0x41, 0xce, 0xc5, 0x67, 0x7c, 0xe3, 0x61,
-	0xf1, 0x95, 0x14, 0xb6, 0x50, 0x0c, 0x53, 0x2a, 0x32, 0xb2, 0xae, 0x94, 0x96, 0xe6, 0xe7, 0xd6,
-	0x8f, 0x45, 0x28, 0x4a, 0x29, 0x6f, 0x89, 0x3b, 0xa4, 0x1d, 0x82, 0xde, 0x03, 0x8c, 0xd6, 0x19,
-	0xaa, 0xc7, 0x21, 0xc7, 0x37, 0xa8, 0xda, 0x98, 0x72, 0x1b, 0x54, 0xa8, 0x95, 0xbe, 0xfe, 0xf9,
-	0xfb, 0x73, 0x0e, 0xb4, 0x8c, 0xce, 0x05, 0x3d, 0x53, 0x36, 0xd1, 0x47, 0x28, 0x26, 0x47, 0x15,
-	0xad, 0xc7, 0x20, 0x52, 0xd7, 0x85, 0x7a, 0x7b, 0x46, 0x86, 0x24, 0xaa, 0x08, 0xa2, 0x25, 0x2d,
-	0x17, 0xfe, 0x4f, 0x71, 0xae, 0x53, 0x58, 0x4a, 0x0c, 0x09, 0x5a, 0x4b, 0x58, 0x3e, 0x39, 0x79,
-	0xea, 0xfa, 0xf4, 0x04, 0x49, 0xd4, 0x10, 0x44, 0x37, 0x37, 0xab, 0x21, 0x91, 0xfe, 0x79, 0x34,
-	0x35, 0x5f, 0xd0, 0x05, 0x94, 0x27, 0x66, 0x13, 0xdd, 0x89, 0xa1, 0x4e, 0x1b, 0x7a, 0xf5, 0xee,
-	0xec, 0x24, 0x49, 0xbf, 0x2a, 0xe8, 0x2b, 0x5a, 0x31, 0xa2, 0x6f, 0xb5, 0xfd, 0x7e, 0x8f, 0xab,
-	0xfd, 0xae, 0x40, 0x35, 0x75, 0x40, 0xd1, 0xfd, 0x18, 0xf4, 0xac, 0x15, 0xa0, 0x6e, 0x5c, 0x9e,
-	0x98, 0xb4, 0x01, 0x4d, 0xb1, 0xe1, 0x03, 0xc0, 0x68, 0x20, 0x13, 0x9f, 0xd0, 0xc4, 0xf0, 0x26,
-	0x3e, 0xa1, 0xc9, 0x29, 0x8e, 0x3a, 0x5b, 0xd0, 0xe9, 0x08, 0xf1, 0x18, 0xf2, 0xd1, 0x38, 0xa0,
-	0x5b, 0xc9, 0xaa, 0x13, 0xb3, 0xad, 0xd6, 0xd3, 0x2f, 0x25, 0xf8, 0xb2, 0x00, 0xcf, 0xa3, 0xac,
-	0xee, 0x8a, 0x8b, 0xf6, 0xa2, 0xf8, 0x27, 0x7c, 0xfc, 0x2f, 0x00, 0x00, 0xff, 0xff, 0xaf, 0xe9,
-	0x76, 0xa4, 0xf2, 0x08, 0x00, 0x00,
+	// 2367 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x58, 0xdd, 0x6f, 0x1b, 0xc7,
+	0x11, 0xcf, 0x89, 0xa2, 0x44, 0x8e, 0xc4, 0x0f, 0xad, 0x24, 0x8a, 0xa2, 0xa5, 0xd8, 0xb9, 0xc6,
+	0x4d, 0x22, 0x24, 0xa2, 0xe1, 0xd6, 0x69, 0xec, 0xb6, 0x28, 0x18, 0xf2, 0x2c, 0x13, 0xd1, 0x87,
+	0x71, 0x92, 0x9d, 0xc4, 0x05, 0x7a, 0x38, 0xf1, 0x56, 0xf6, 0x55, 0xf7, 0xc1, 0xec, 0xed, 0xc9,
+	0xb2, 0x5d, 0x03, 0x45, 0xd0, 0x97, 0x3c, 0x16, 0x7d, 0xea, 0x43, 0x0b, 0x14, 0x7d, 0x69, 0xdf,
+	0xfb, 0xde, 0x3f, 0xa2, 0x40, 0xff, 0x80, 0xa2, 0xff, 0x42, 0x1f, 0x0b, 0x14, 0xfb, 0x71, 0x5f,
+	0xe4, 0x91, 0x94, 0xd1, 0xb4, 0x6f, 0xb7, 0xb3, 0xb3, 0xf3, 0x9b, 0x99, 0x9d, 0x9d, 0x9b, 0x19,
+	0xa8, 0x0f, 0x4f, 0xdb, 0x43, 0xf3, 0x85, 0x8b, 0x3d, 0xba, 0x3b, 0x24, 0x3e, 0xf5, 0x51, 0x59,
+	0x2e, 0x87, 0xa7, 0xad, 0xad, 0xa7, 0xbe, 0xff, 0xd4, 0xc1, 0x6d, 0x73, 0x68, 0xb7, 0x4d, 0xcf,
+	0xf3, 0xa9, 0x49, 0x6d, 0xdf, 0x0b, 0x04, 0x63, 0xeb, 0xba, 0xdc, 0xe5, 0xab, 0xd3, 0xf0, 0xac,
+	0x4d, 0x6d, 0x17, 0x07, 0xd4, 0x74, 0x87, 0x82, 0x41, 0xfd, 0xb3, 0x02, 0xb5, 0xae, 0x49, 0xac,
+	0xbe, 0x77, 0xe6, 0x13, 0x97, 0x9f, 0x45, 0xd7, 0x61, 0x69, 0x60, 0x12, 0xcb, 0xf0, 0x42, 0xf7,
+	0x14, 0x93, 0xa6, 0x72, 0x43, 0x79, 0xbf, 0xac, 0x03, 0x23, 0x1d, 0x72, 0x0a, 0xaa, 0x43, 0x61,
+	0x70, 0x71, 0xd1, 0x9c, 0xe3, 0x1b, 0xec, 0x93, 0x1d, 0xc1, 0x97, 0x43, 0x9b, 0xbc, 0x30, 0x2c,
+	0x93, 0xe2, 0x66, 0x41, 0x1c, 0x11, 0xa4, 0x9e, 0x49, 0x31, 0xfa, 0x10, 0x90, 0x6b, 0x06, 0xe7,
+	0xd8, 0x32, 0xd2, 0xa2, 0xe7, 0x39, 0x5f, 0x5d, 0xec, 0x74, 0x13, 0x80, 0x35, 0x28, 0x9e, 0x12,
+	0xd3, 0xb3, 0x9a, 0x45, 0xce, 0x20, 0x16, 0xea, 0x5f, 0x14, 0x58, 0xd1, 0xf1, 0x53, 0x3b, 0xa0,
+	0x8c, 0x55, 0xc7, 0x5f, 0x85, 0x38, 0xa0, 0x48, 0x83, 0x3a, 0x17, 0x69, 0x27, 0x16, 0x70, 0x95,
+	0x97, 0x6e, 0xb7, 0x76, 0x63, 0x37, 0xed, 0x8e, 0xd8, 0xa8, 0xd7, 0x06, 0x23, 0x46, 0x6f, 0x42,
+	0xc9, 0x35, 0x2f, 0x8d, 0x30, 0xc0, 0x01, 0x37, 0xac, 0xa8, 0x2f, 0xba, 0xe6, 0xe5, 0xa3, 0x00,
+	0x07, 0xcc, 0x38, 0x4a, 0x1d, 0x23, 0xc0, 0x03, 0xdf, 0xb3, 0x02, 0x6e, 0x5c, 0x41, 0x07, 0x4a,
+	0x9d, 0x63, 0x41, 0x41, 0x5b, 0x50, 0x26, 0xf8, 0x0c, 0x13, 0xec, 0x0d, 0xb0, 0xb4, 0x29, 0x21,
+	0xa8, 0xbf, 0x55, 0x00, 0xa5, 0xd5, 0x0e, 0x86, 0xbe, 0x17, 0x60, 0xb4, 0x0d, 0xdc, 0xa5, 0x06,
+	0xf5, 0xcf, 0xb1, 0x27, 0x9d, 0x5c, 0x66, 0x94, 0x13, 0x46, 0x40, 0xab, 0x50, 0xb4, 0x03, 0xc3,
+	0x3f, 0xe7, 0xca, 0x94, 0xf4, 0x79, 0x3b, 0x38, 0x3a, 0x4f, 0xfc, 0x52, 0x48, 0xf9, 0x05, 0xdd,
+	0x05, 0xe1, 0x69, 0x1c, 0x18, 0x26, 0x6d, 0xce, 0x4b, 0xdb, 0xc5, 0xcd, 0xef, 0x46, 0x37, 0xbf,
+	0x7b, 0x12, 0xdd, 0xbc, 0x5e, 0x96, 0xdc, 0x1d, 0xaa, 0xfe, 0x00, 0x1a, 0x3a, 0xbe, 0xf0, 0xcf,
+	0x71, 0x37, 0x02, 0x8e, 0xdc, 0x3a, 0x5d, 0x3d, 0x75, 0x17, 0x36, 0xc6, 0x0e, 0x4a, 0xc3, 0x62,
+	0xcd, 0x95, 0x44, 0x73, 0xf5, 0xd7, 0xf3, 0x80, 0x1e, 0x8a, 0xdb, 0x48, 0x7b, 0x7d, 0x86, 0x13,
+	0x6e, 0x42, 0x95, 0xe0, 0x00, 0x93, 0x0b, 0xce, 0x6d, 0xd8, 0x96, 0xbc, 0x9a, 0x4a, 0x8a, 0xda,
+	0xb7, 0xd0, 0xc7, 0x50, 0x62, 0x61, 0xc7, 0x62, 0xbb, 0x59, 0x98, 0x69, 0x7e, 0xcc, 0x8b, 0x1a,
+	0xb0, 0x60, 0xba, 0x7e, 0xe8, 0x09, 0xa7, 0x15, 0x75, 0xb9, 0x62, 0x17, 0x6e, 0x07, 0xc6, 0xc0,
+	0xf4, 0x06, 0xd8, 0xc1, 0x22, 0x08, 0x4b, 0x3a, 0xd8, 0x41, 0x57, 0x52, 0xd0, 0x47, 0x50, 0x0c,
+	0x28, 0x0b, 0xf4, 0x85, 0x1b, 0xca, 0xfb, 0xd5, 0xdb, 0x1b, 0xa9, 0x40, 0x93, 0x46, 0x1e, 0xb3,
+	0x6d, 0x5d, 0x70, 0xa1, 0x13, 0x68, 0x9a, 0x21, 0x7d, 0xe6, 0x13, 0xfb, 0xa5, 0x30, 0x24, 0x75,
+	0x5d, 0x8b, 0x33, 0xf5, 0x6d, 0x64, 0xce, 0x6a, 0xd1, 0xdd, 0x31, 0xe7, 0x70, 0xdf, 0x25, 0xa1,
+	0x57, 0xe2, 0xfe, 0xab, 0x0c, 0x78, 0x98, 0x49, 0x22, 0x6a, 0x41, 0x69, 0x10, 0x12, 0xf6, 0xfd,
+	0xa2, 0x59, 0xe6, 0x0c, 0xf1, 0x1a, 0xfd, 0x04, 0xaa, 0x16, 0x1e, 0x38, 0xb6, 0x87, 0x0d, 0x82,
+	0xcd, 0xc0, 0xf7, 0x9a, 0xc0, 0x0d, 0x6a, 0xa6, 0x0c, 0xea, 0x09, 0x06, 0x9d, 0xef, 0xeb, 0x15,
+	0x2b, 0xbd, 0x44, 0x3f, 0x64, 0xa9, 0x42, 0x38, 0x85, 0x19, 0xb3, 0x34, 0xd3, 0x18, 0x88, 0xd8,
+	0x3b, 0x54, 0x7d, 0x0a, 0xeb, 0xda, 0x25, 0x1e, 0x84, 0x14, 0x4b, 0xa7, 0x45, 0xb1, 0x77, 0x08,
+	0xab, 0x12, 0x3f, 0xe7, 0x55, 0x6f, 0x8f, 0x3b, 0x3b, 0xfd, 0xb0, 0xd1, 0x70, 0x8c, 0xa6, 0xee,
+	0x43, 0x63, 0x14, 0x28, 0x79, 0x84, 0x31, 0x92, 0x15, 0xc5, 0x5f, 0x24, 0xc1, 0xca, 0x7d, 0x84,
+	0xaa, 0x0d, 0x1b, 0x1d, 0x79, 0x23, 0xff, 0x6b, 0xc5, 0xbf, 0x51, 0xa0, 0x39, 0x8e, 0x75, 0x35,
+	0xdd, 0xb3, 0x59, 0x61, 0xee, 0x0d, 0xb2, 0x42, 0x62, 0x76, 0x21, 0x65, 0xf6, 0xc7, 0xb0, 0xde,
+	0x35, 0x87, 0x34, 0x24, 0xa3, 0x46, 0x4f, 0xd7, 0x43, 0xfd, 0x08, 0x1a, 0xa3, 0xe7, 0xa6, 0x25,
+	0x8a, 0xbb, 0xd0, 0x7c, 0xec, 0xdb, 0x56, 0x27, 0x1d, 0xf3, 0x57, 0x44, 0xba, 0x05, 0x9b, 0x39,
+	0x47, 0xa7, 0x81, 0xdd, 0x81, 0x35, 0xf1, 0xa6, 0xdf, 0xcc, 0xa4, 0x0f, 0x61, 0x7d, 0xe4, 0xd8,
+	0x0c, 0x8b, 0x3e, 0x0d, 0x9d, 0xf3, 0x2b, 0x01, 0x15, 0xb2, 0x40, 0x77, 0x60, 0x33, 0xe7, 0xa8,
+	0x04, 0x6b, 0xc2, 0xa2, 0x85, 0x1d, 0x4c, 0xb1, 0xd0, 0xb0, 0xa8, 0x47, 0x4b, 0xf5, 0xc7, 0xb0,
+	0xb5, 0x87, 0x69, 0x4e, 0x8c, 0x5d, 0xcd, 0xbc, 0x5f, 0x29, 0xb0, 0x3d, 0xe1, 0xbc, 0x84, 0xfe,
+	0x96, 0xe3, 0x3c, 0xff, 0x9d, 0xad, 0xc2, 0x4a, 0xdf, 0xb3, 0xa9, 0x6d, 0x3a, 0xf6, 0x4b, 0x2c,
+	0x55, 0x57, 0x3f, 0x00, 0x94, 0x26, 0x4e, 0xf3, 0xfb, 0x3f, 0x14, 0xa8, 0xef, 0x61, 0xe6, 0xaf,
+	0xd0, 0x89, 0x1d, 0xde, 0x80, 0x85, 0x41, 0x48, 0x02, 0x3f, 0x2a, 0x6b, 0xe4, 0x0a, 0x5d, 0x83,
+	0xf2, 0xd0, 0x7c, 0x8a, 0x8d, 0xc0, 0x7e, 0x89, 0xe5, 0x4f, 0xa6, 0xc4, 0x08, 0xc7, 0xf6, 0x4b,
+	0x8c, 0x6e, 0x41, 0x31, 0xb0, 0x59, 0x82, 0x9d, 0xfd, 0x73, 0x11, 0x8c, 0xec, 0x44, 0xe8, 0x51,
+	0xdb, 0xb9, 0xc2, 0xdf, 0x58, 0x30, 0xa2, 0x3b, 0x50, 0xca, 0xfc, 0x70, 0xaa, 0xb7, 0x37, 0x33,
+	0xe5, 0x8b, 0xd8, 0xba, 0x6f, 0x3b, 0x14, 0x13, 0x3d, 0x66, 0x55, 0xff, 0xaa, 0xc0, 0xa2, 0x6e,
+	0x3e, 0xef, 0x99, 0xd4, 0xfc, 0xd6, 0x6f, 0x25, 0xaf, 0xb2, 0x9a, 0x7b, 0xf3, 0xca, 0x2a, 0x1b,
+	0x6d, 0x85, 0xd1, 0x68, 0xbb, 0x84, 0x95, 0xd4, 0x2d, 0xc9, 0x0b, 0xfd, 0x08, 0x4a, 0xc4, 0x7c,
+	0xce, 0x8a, 0x49, 0x93, 0xbf, 0x8a, 0xa5, 0xdb, 0x28, 0x05, 0x29, 0x0d, 0xd6, 0x17, 0x89, 0xb4,
+	0x3c, 0xb7, 0x58, 0xba, 0x0e, 0x4b, 0x1e, 0xbe, 0xa4, 0x86, 0xbc, 0x6f, 0x01, 0x0c, 0x8c, 0xd4,
+	0xe5, 0x14, 0xf5, 0x77, 0x0a, 0xac, 0xee, 0x9b, 0x94, 0xfd, 0x09, 0x7b, 0x76, 0x40, 0x89, 0x7d,
+	0x1a, 0x72, 0x85, 0x55, 0x58, 0xb6, 0x52, 0x6b, 0x19, 0x29, 0x19, 0x1a, 0x5a, 0x87, 0x05, 0xd7,
+	0xf6, 0x0c, 0x57, 0x14, 0x8b, 0x05, 0xbd, 0xe8, 0xda, 0xde, 0x41, 0xc0, 0xc9, 0xe6, 0x25, 0x23,
+	0x17, 0x24, 0xd9, 0xbc, 0x3c, 0x08, 0xd0, 0x06, 0x2c, 0xba, 0xd8, 0xe4, 0xec, 0xf3, 0x9c, 0xbe,
+	0xc0, 0x96, 0x07, 0x01, 0x0b, 0xbb, 0x80, 0x5a, 0x16, 0xbe, 0x60, 0x5b, 0x45, 0xbe, 0x55, 0x12,
+	0x84, 0x83, 0x40, 0xfd, 0xb7, 0x02, 0xe5, 0xfb, 0x26, 0x73, 0x4b, 0xe8, 0xf0, 0x62, 0xc5, 0xc5,
+	0xf4, 0x99, 0x1f, 0x3d, 0x58, 0xb9, 0x42, 0x9f, 0xc0, 0xa2, 0x23, 0x8c, 0x90, 0x97, 0xf3, 0x76,
+	0xca, 0x53, 0x39, 0xe6, 0xe9, 0x11, 0x3b, 0xbb, 0x18, 0x4c, 0x88, 0x4f, 0x0c, 0x12, 0xd5, 0xec,
+	0x8a, 0x5e, 0xe6, 0x14, 0xdd, 0xa4, 0x38, 0xd9, 0x1e, 0xf8, 0x56, 0x5c, 0xd6, 0x72, 0x4a, 0xd7,
+	0xb7, 0x30, 0xba, 0x0b, 0x9b, 0xd4, 0x76, 0xb1, 0x1f, 0x52, 0xc3, 0x3c, 0xa3, 0x98, 0xb1, 0xb9,
+	0xae, 0x4d, 0x85, 0xb0, 0x22, 0x17, 0xd6, 0x90, 0x0c, 0x1d, 0xb6, 0xdf, 0xe5, 0xdb, 0x5c, 0xf2,
+	0x4d, 0xa8, 0x5a, 0xe1, 0xd0, 0xb1, 0x07, 0x26, 0xc5, 0x06, 0x89, 0xea, 0x28, 0x45, 0xaf, 0xc4,
+	0x54, 0xc6, 0xa6, 0xfe, 0x5e, 0x81, 0x65, 0x6e, 0xff, 0x43, 0xe2, 0x9f, 0xd9, 0x0e, 0xcf, 0x78,
+	0xd8, 0x33, 0x4f, 0x1d, 0x99, 0xf1, 0x4a, 0x7a, 0xb4, 0x44, 0x08, 0xe6, 0x03, 0x8c, 0x2d, 0x79,
+	0x19, 0xfc, 0x1b, 0xed, 0x40, 0x91, 0x84, 0x0e, 0x66, 0x57, 0xc1, 0x02, 0x68, 0x2d, 0xe5, 0x96,
+	0xd8, 0xab, 0xba, 0x60, 0x41, 0xdf, 0x87, 0x86, 0x2c, 0x6c, 0x64, 0x83, 0x32, 0x34, 0x29, 0xc5,
+	0xc4, 0x63, 0xf7, 0xc5, 0x72, 0xf2, 0x5a, 0xb4, 0xcb, 0xa2, 0xfd, 0xa1, 0xdc, 0x53, 0x1f, 0x43,
+	0xe3, 0x18, 0xd3, 0xb4, 0x8a, 0x51, 0x9a, 0xf9, 0x11, 0x54, 0xce, 0x18, 0xd9, 0x18, 0x0a, 0xba,
+	0x7c, 0x84, 0x1b, 0xa3, 0x3a, 0x44, 0xc7, 0x96, 0xcf, 0x52, 0x2b, 0xd5, 0x81, 0x8d, 0x31, 0xb9,
+	0xf2, 0x61, 0xfc, 0x57, 0x82, 0xf3, 0xf3, 0x6c, 0x13, 0x1a, 0x7b, 0xb9, 0x56, 0x30, 0x3d, 0xf6,
+	0xfe, 0x7f, 0x7a, 0x3c, 0x81, 0x86, 0x68, 0x93, 0x30, 0xf9, 0x1c, 0x9f, 0x3e, 0xf3, 0xfd, 0xf3,
+	0xc8, 0x9b, 0x75, 0x28, 0x84, 0xc4, 0x91, 0x71, 0xcf, 0x3e, 0xd9, 0x63, 0x08, 0xf0, 0x80, 0x60,
+	0x2a, 0x9b, 0x50, 0xb9, 0x62, 0x74, 0x7c, 0x81, 0x3d, 0x2a, 0x2e, 0xbd, 0xac, 0xcb, 0x95, 0x8a,
+	0x61, 0x63, 0x4c, 0x76, 0x52, 0x46, 0x3d, 0x17, 0xa4, 0xd4, 0xcf, 0x50, 0x52, 0xfa, 0xd6, 0x44,
+	0xa4, 0xdc, 0x1a, 0xe9, 0x0e, 0xac, 0xf5, 0xf8, 0x3f, 0x78, 0xc4, 0x80, 0xe9, 0x18, 0xac, 0x9e,
+	0x18, 0x39, 0x36, 0xed, 0xbf, 0x76, 0x0e, 0xad, 0x3d, 0x4c, 0x8f, 0x31, 0xa5, 0x0e, 0x16, 0x05,
+	0xc1, 0xd0, 0x27, 0xf1, 0x0f, 0x0e, 0xc1, 0xfc, 0x19, 0xf1, 0x5d, 0x09, 0xc2, 0xbf, 0x51, 0x15,
+	0xe6, 0xa8, 0x2f, 0xf5, 0x9f, 0xa3, 0x3e, 0xfa, 0x00, 0xea, 0xb6, 0x37, 0x70, 0x42, 0x0b, 0x1b,
+	0xf2, 0xba, 0x02, 0x69, 0x46, 0x4d, 0xd2, 0xe5, 0x1f, 0x22, 0x50, 0xff, 0x38, 0x07, 0x2b, 0x09,
+	0xd4, 0x71, 0xe8, 0xba, 0x26, 0x79, 0xc1, 0x40, 0x78, 0x9f, 0x2f, 0x41, 0xd8, 0x37, 0x4b, 0xb7,
+	0x2e, 0x26, 0x83, 0x67, 0xa6, 0xc8, 0xf3, 0x02, 0x0d, 0x22, 0x52, 0xdf, 0xca, 0x34, 0x22, 0x85,
+	0x91, 0x46, 0x84, 0xf7, 0x32, 0xbc, 0x48, 0xb4, 0x8c, 0x41, 0xdc, 0x91, 0x15, 0xf4, 0x4a, 0x44,
+	0xed, 0x32, 0x22, 0x7a, 0x0f, 0x6a, 0x31, 0x9b, 0xec, 0xdc, 0x44, 0xd2, 0x8c, 0x4f, 0x77, 0x38,
+	0x55, 0x34, 0x8e, 0x67, 0xa1, 0x67, 0xc5, 0xf2, 0x16, 0x84, 0xbc, 0x88, 0x1a, 0xcb, 0x8b, 0xd9,
+	0xa4, 0xbc, 0x45, 0x21, 0x2f, 0x22, 0x4b, 0x79, 0xdb, 0x00, 0x1e, 0xa6, 0x11, 0x4f, 0x89, 0xf3,
+	0x94, 0x3d, 0x4c, 0xc5, 0xb6, 0xfa, 0xaf, 0x8c, 0x97, 0xa4, 0xf3, 0x66, 0x15, 0xe8, 0x57, 0x6c,
+	0x6e, 0x47, 0xfc, 0x5a, 0x98, 0xea, 0xd7, 0xf9, 0x11, 0xbf, 0x26, 0x1d, 0xae, 0xf0, 0x93, 0x5c,
+	0x89, 0xbe, 0x2d, 0x72, 0xa4, 0x70, 0xce, 0xcc, 0xbe, 0x4d, 0x3a, 0x98, 0x1f, 0x4e, 0xbc, 0x76,
+	0x95, 0x0e, 0x16, 0x62, 0x6f, 0xd2, 0x09, 0x83, 0xa0, 0xd2, 0x84, 0x41, 0x50, 0x34, 0x1f, 0x10,
+	0x53, 0x8f, 0x72, 0x32, 0x1f, 0xf8, 0x94, 0x11, 0xd4, 0x3f, 0x29, 0x70, 0x2d, 0xf7, 0x2d, 0xc8,
+	0xf7, 0x73, 0x0f, 0xca, 0x01, 0x0f, 0x59, 0x1b, 0x07, 0xb2, 0x8e, 0xd8, 0x4a, 0x65, 0xa8, 0xb1,
+	0xc0, 0xd6, 0x13, 0x76, 0xf4, 0x09, 0x94, 0xe2, 0xc7, 0x31, 0x37, 0xe5, 0x68, 0x54, 0x96, 0xc7,
+	0xdc, 0xb9, 0xa9, 0x61, 0xe7, 0x3e, 0x2c, 0xa7, 0x47, 0x03, 0x68, 0x19, 0x4a, 0xdd, 0xce, 0xc3,
+	0x93, 0x47, 0xba, 0xd6, 0xab, 0xbf, 0x85, 0xaa, 0x00, 0x9d, 0x47, 0x27, 0x0f, 0x8e, 0xf4, 0xfe,
+	0x13, 0xad, 0x57, 0x57, 0x10, 0xc0, 0xc2, 0xe3, 0xa3, 0x7e, 0x4f, 0xeb, 0xd5, 0xe7, 0x18, 0x67,
+	0x4f, 0xeb, 0xee, 0xf7, 0x0f, 0xb5, 0x5e, 0xbd, 0xb0, 0xf3, 0x77, 0x05, 0x2a, 0x99, 0x96, 0x1c,
+	0xd5, 0x61, 0xf9, 0xf0, 0xe8, 0xc4, 0x88, 0x79, 0xde, 0x42, 0x2b, 0x50, 0xe9, 0x76, 0xf4, 0x5e,
+	0x42, 0x52, 0x50, 0x03, 0x10, 0x27, 0x9d, 0x1c, 0x7d, 0xa6, 0x1d, 0x1a, 0xba, 0xf6, 0xf8, 0xe8,
+	0x33, 0x2e, 0x3c, 0x4b, 0xd7, 0xbe, 0x78, 0xd8, 0x67, 0x0a, 0x15, 0xd0, 0x35, 0xd8, 0xe0, 0x74,
+	0x5d, 0xbb, 0xaf, 0xe9, 0xda, 0x61, 0x57, 0x33, 0x0e, 0xfa, 0xc7, 0x07, 0x9d, 0x93, 0xee, 0x83,
+	0xfa, 0x3c, 0xda, 0x86, 0xcd, 0xd4, 0xa1, 0x47, 0xc7, 0x9d, 0x3d, 0xcd, 0xd0, 0xbe, 0xe8, 0x6a,
+	0x1a, 0x53, 0xb8, 0x88, 0x36, 0x60, 0x95, 0x6f, 0xef, 0xf7, 0x0f, 0xfa, 0x27, 0xc9, 0xc6, 0x02,
+	0x13, 0x7a, 0xa0, 0xe9, 0xdd, 0x07, 0x9d, 0xc3, 0x93, 0xd1, 0xcd, 0xc5, 0x9d, 0x3d, 0xa8, 0x66,
+	0xab, 0x5c, 0x66, 0x58, 0xe7, 0xf0, 0x4b, 0xa3, 0xdb, 0x39, 0xec, 0x6a, 0xfb, 0x89, 0x61, 0x62,
+	0x65, 0x1c, 0x1d, 0xee, 0x7f, 0x59, 0x57, 0x22, 0xeb, 0x63, 0xa6, 0xb9, 0xdb, 0x7f, 0xa8, 0x41,
+	0x35, 0x72, 0x35, 0x26, 0x17, 0xf6, 0x00, 0xa3, 0x9f, 0x02, 0x24, 0x13, 0x38, 0x94, 0xbe, 0xc7,
+	0xb1, 0x79, 0x62, 0x6b, 0x7b, 0xc2, 0xae, 0x08, 0x29, 0xb5, 0xfe, 0xf5, 0xdf, 0xfe, 0xf9, 0x9b,
+	0x39, 0xb8, 0xa7, 0xec, 0xa8, 0xc5, 0x36, 0x8b, 0x44, 0x44, 0xa1, 0x36, 0x32, 0x0a, 0x43, 0xef,
+	0x64, 0x64, 0xe4, 0xcd, 0xd7, 0x5a, 0xea, 0x34, 0x16, 0x89, 0xd5, 0xe2, 0x58, 0x6b, 0x3b, 0x88,
+	0x03, 0xb5, 0x5f, 0x25, 0xa3, 0xb2, 0xd7, 0xe8, 0xe7, 0x50, 0xcd, 0xce, 0x34, 0xd0, 0x8d, 0x94,
+	0xc4, 0xdc, 0xb9, 0x4a, 0xeb, 0x9d, 0x29, 0x1c, 0x12, 0x72, 0x95, 0x43, 0x56, 0x98, 0x79, 0xa5,
+	0x68, 0xe8, 0x8c, 0x08, 0xd4, 0x47, 0xa7, 0x10, 0x28, 0xad, 0xff, 0x84, 0x71, 0x48, 0xeb, 0x3b,
+	0x53, 0x79, 0x24, 0xe2, 0x3a, 0x47, 0xac, 0xa9, 0xd0, 0x8e, 0xe6, 0x5c, 0xf8, 0x9e, 0xb2, 0x83,
+	0x7e, 0xc1, 0xc2, 0x21, 0x3d, 0x36, 0xc8, 0xd8, 0x97, 0x3b, 0x89, 0x68, 0xbd, 0x33, 0x85, 0x43,
+	0xa2, 0xdd, 0xe4, 0x68, 0xd7, 0xd5, 0xed, 0xc8, 0xb8, 0xf6, 0xab, 0x24, 0x47, 0xbf, 0x6e, 0xcb,
+	0x2c, 0x87, 0xbe, 0x56, 0x60, 0x65, 0x6c, 0x96, 0x80, 0xd2, 0xf6, 0x4c, 0x1a, 0x52, 0xb4, 0xde,
+	0x9d, 0xce, 0x24, 0xf5, 0x50, 0xb9, 0x1e, 0x5b, 0x6a, 0x2b, 0x5f, 0x8f, 0x0b, 0xdf, 0xb6, 0xd0,
+	0x57, 0x50, 0xc9, 0x74, 0xfe, 0xe8, 0xfa, 0x58, 0x47, 0x38, 0xe2, 0x80, 0x1b, 0x93, 0x19, 0x24,
+	0xee, 0x36, 0xc7, 0xdd, 0xd8, 0x59, 0xcf, 0xc5, 0x45, 0x2f, 0x60, 0x65, 0x6c, 0xe0, 0x90, 0x31,
+	0x7b, 0xd2, 0x24, 0xa3, 0xf5, 0xee, 0x74, 0x26, 0x09, 0xbf, 0xc9, 0xe1, 0x57, 0x59, 0x78, 0x55,
+	0x63, 0x0d, 0x8c, 0xd3, 0xd0, 0x39, 0x47, 0xdf, 0x28, 0xb0, 0x9e, 0x3b, 0x75, 0x40, 0xef, 0xa5,
+	0x44, 0x4f, 0x9b, 0x6b, 0xb4, 0xde, 0x9f, 0xcd, 0x98, 0x75, 0x03, 0x9a, 0xe0, 0x86, 0x9f, 0x01,
+	0x24, 0x53, 0x86, 0x4c, 0xbe, 0x18, 0x9b, 0x48, 0xb4, 0xb6, 0x27, 0xec, 0x66, 0x1f, 0x94, 0xba,
+	0xd4, 0xb6, 0x13, 0x89, 0x9f, 0x43, 0x39, 0xee, 0x79, 0xd1, 0xb5, 0xac, 0xd6, 0x99, 0x79, 0x45,
+	0x6b, 0x2b, 0x7f, 0x53, 0x0a, 0xaf, 0x71, 0xe1, 0x65, 0xb4, 0xd8, 0x26, 0x42, 0xd6, 0x13, 0xa8,
+	0x1c, 0x53, 0x82, 0x4d, 0x57, 0x30, 0x06, 0xd3, 0x85, 0xe7, 0xf4, 0xd4, 0x6a, 0x83, 0x8b, 0xac,
+	0xa3, 0xaa, 0x14, 0xd9, 0x0e, 0xb8, 0xbc, 0x5b, 0x0a, 0xfa, 0xa5, 0x02, 0xab, 0x39, 0x3f, 0x5b,
+	0x74, 0x33, 0x0b, 0x31, 0xa1, 0x30, 0x6d, 0x7d, 0x77, 0x16, 0x5b, 0x36, 0xe9, 0x21, 0xd4, 0x26,
+	0x7c, 0xa3, 0x1d, 0xc4, 0x9c, 0xc8, 0x83, 0x9a, 0x48, 0xc9, 0x71, 0x19, 0x3f, 0x92, 0x6a, 0xf3,
+	0xda, 0x87, 0x96, 0x3a, 0x8d, 0x45, 0xa2, 0xae, 0x71, 0xd4, 0xaa, 0x5a, 0x6e, 0xcb, 0xb2, 0x3c,
+	0x60, 0x49, 0x88, 0x40, 0x25, 0x53, 0x98, 0x67, 0x5e, 0x60, 0x5e, 0xa5, 0xdf, 0xba, 0x31, 0x99,
+	0x41, 0x22, 0xbd, 0xcd, 0x91, 0x9a, 0x3b, 0x8d, 0x18, 0xa9, 0xfd, 0x2a, 0x69, 0x0e, 0x5e, 0x23,
+	0x02, 0xb5, 0x91, 0xe6, 0x2f, 0x63, 0x63, 0x7e, 0xc3, 0xd9, 0x52, 0xa7, 0xb1, 0x48, 0xe4, 0x26,
+	0x47, 0x46, 0xec, 0xf1, 0x55, 0xda, 0xa6, 0xe5, 0xda, 0x5e, 0x9b, 0x77, 0x65, 0x01, 0xf2, 0xa1,
+	0xb6, 0x37, 0x05, 0x73, 0x6f, 0x36, 0xe6, 0x84, 0x3e, 0x31, 0xca, 0xee, 0x28, 0x0b, 0x78, 0xba,
+	0xc0, 0xab, 0xc4, 0xef, 0xfd, 0x67, 0x00, 0x86, 0xa2, 0xb4, 0x3f, 0xdb, 0x1c, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -850,8 +2448,16 @@ const _ = grpc.SupportPackageIsVersion4
 type PaymentServiceClient interface {
 	//クレジットカードのトークン発行(非保持化対応)
 	RegistCard(ctx context.Context, in *RegistCardRequest, opts ...grpc.CallOption) (*RegistCardResponse, error)
+	//クレジットカードのトークンを失効させる
+	RevokeCardToken(ctx context.Context, in *RevokeCardTokenRequest, opts ...grpc.CallOption) (*RevokeCardTokenResponse, error)
 	//決済を行う
 	ExecutePayment(ctx context.Context, in *ExecutePaymentRequest, opts ...grpc.CallOption) (*ExecutePaymentResponse, error)
+	//与信を確保する(オーソリ)
+	AuthorizePayment(ctx context.Context, in *AuthorizePaymentRequest, opts ...grpc.CallOption) (*AuthorizePaymentResponse, error)
+	//確保した与信で売上を確定する(キャプチャ)
+	CapturePayment(ctx context.Context, in *CapturePaymentRequest, opts ...grpc.CallOption) (*CapturePaymentResponse, error)
+	//確保した与信を取り消す
+	VoidAuthorization(ctx context.Context, in *VoidAuthorizationRequest, opts ...grpc.CallOption) (*VoidAuthorizationResponse, error)
 	//決済をキャンセルする
 	CancelPayment(ctx context.Context, in *CancelPaymentRequest, opts ...grpc.CallOption) (*CancelPaymentResponse, error)
 	//決済をバルクでキャンセルする
@@ -861,7 +2467,20 @@ type PaymentServiceClient interface {
 	//メモリ初期化
 	Initialize(ctx context.Context, in *InitializeRequest, opts ...grpc.CallOption) (*InitializeResponse, error)
 	//ベンチマーカー用結果取得API
+	//page_size を指定するとページに分けて返す。続きは next_cursor を cursor に指定して取得する
 	GetResult(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (*GetResultResponse, error)
+	//ベンチマーカー用結果取得API(ストリーミング)
+	StreamResults(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (PaymentService_StreamResultsClient, error)
+	//精算レポート(日ごとの売上・返金の集計と、マスクしたカード番号の明細)を取得する
+	GetSettlementReport(ctx context.Context, in *GetSettlementReportRequest, opts ...grpc.CallOption) (*GetSettlementReportResponse, error)
+	//加盟店のWebhookを登録する(同じURLなら更新する)
+	RegisterWebhook(ctx context.Context, in *RegisterWebhookRequest, opts ...grpc.CallOption) (*RegisterWebhookResponse, error)
+	//加盟店のWebhookを削除する
+	DeleteWebhook(ctx context.Context, in *DeleteWebhookRequest, opts ...grpc.CallOption) (*DeleteWebhookResponse, error)
+	//障害注入の設定を変更する
+	SetFaultProfile(ctx context.Context, in *SetFaultProfileRequest, opts ...grpc.CallOption) (*SetFaultProfileResponse, error)
+	//障害注入の設定を取得する
+	GetFaultProfile(ctx context.Context, in *GetFaultProfileRequest, opts ...grpc.CallOption) (*GetFaultProfileResponse, error)
 }
 
 type paymentServiceClient struct {
@@ -881,6 +2500,15 @@ func (c *paymentServiceClient) RegistCard(ctx context.Context, in *RegistCardReq
 	return out, nil
 }
 
+func (c *paymentServiceClient) RevokeCardToken(ctx context.Context, in *RevokeCardTokenRequest, opts ...grpc.CallOption) (*RevokeCardTokenResponse, error) {
+	out := new(RevokeCardTokenResponse)
+	err := c.cc.Invoke(ctx, "/paymentpb.PaymentService/RevokeCardToken", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *paymentServiceClient) ExecutePayment(ctx context.Context, in *ExecutePaymentRequest, opts ...grpc.CallOption) (*ExecutePaymentResponse, error) {
 	out := new(ExecutePaymentResponse)
 	err := c.cc.Invoke(ctx, "/paymentpb.PaymentService/ExecutePayment", in, out, opts...)
@@ -890,6 +2518,33 @@ func (c *paymentServiceClient) ExecutePayment(ctx context.Context, in *ExecutePa
 	return out, nil
 }
 
+func (c *paymentServiceClient) AuthorizePayment(ctx context.Context, in *AuthorizePaymentRequest, opts ...grpc.CallOption) (*AuthorizePaymentResponse, error) {
+	out := new(AuthorizePaymentResponse)
+	err := c.cc.Invoke(ctx, "/paymentpb.PaymentService/AuthorizePayment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) CapturePayment(ctx context.Context, in *CapturePaymentRequest, opts ...grpc.CallOption) (*CapturePaymentResponse, error) {
+	out := new(CapturePaymentResponse)
+	err := c.cc.Invoke(ctx, "/paymentpb.PaymentService/CapturePayment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) VoidAuthorization(ctx context.Context, in *VoidAuthorizationRequest, opts ...grpc.CallOption) (*VoidAuthorizationResponse, error) {
+	out := new(VoidAuthorizationResponse)
+	err := c.cc.Invoke(ctx, "/paymentpb.PaymentService/VoidAuthorization", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *paymentServiceClient) CancelPayment(ctx context.Context, in *CancelPaymentRequest, opts ...grpc.CallOption) (*CancelPaymentResponse, error) {
 	out := new(CancelPaymentResponse)
 	err := c.cc.Invoke(ctx, "/paymentpb.PaymentService/CancelPayment", in, out, opts...)
@@ -935,12 +2590,97 @@ func (c *paymentServiceClient) GetResult(ctx context.Context, in *GetResultReque
 	return out, nil
 }
 
+func (c *paymentServiceClient) StreamResults(ctx context.Context, in *GetResultRequest, opts ...grpc.CallOption) (PaymentService_StreamResultsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_PaymentService_serviceDesc.Streams[0], "/paymentpb.PaymentService/StreamResults", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &paymentServiceStreamResultsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type PaymentService_StreamResultsClient interface {
+	Recv() (*RawData, error)
+	grpc.ClientStream
+}
+
+type paymentServiceStreamResultsClient struct {
+	grpc.ClientStream
+}
+
+func (x *paymentServiceStreamResultsClient) Recv() (*RawData, error) {
+	m := new(RawData)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *paymentServiceClient) GetSettlementReport(ctx context.Context, in *GetSettlementReportRequest, opts ...grpc.CallOption) (*GetSettlementReportResponse, error) {
+	out := new(GetSettlementReportResponse)
+	err := c.cc.Invoke(ctx, "/paymentpb.PaymentService/GetSettlementReport", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) RegisterWebhook(ctx context.Context, in *RegisterWebhookRequest, opts ...grpc.CallOption) (*RegisterWebhookResponse, error) {
+	out := new(RegisterWebhookResponse)
+	err := c.cc.Invoke(ctx, "/paymentpb.PaymentService/RegisterWebhook", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) DeleteWebhook(ctx context.Context, in *DeleteWebhookRequest, opts ...grpc.CallOption) (*DeleteWebhookResponse, error) {
+	out := new(DeleteWebhookResponse)
+	err := c.cc.Invoke(ctx, "/paymentpb.PaymentService/DeleteWebhook", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) SetFaultProfile(ctx context.Context, in *SetFaultProfileRequest, opts ...grpc.CallOption) (*SetFaultProfileResponse, error) {
+	out := new(SetFaultProfileResponse)
+	err := c.cc.Invoke(ctx, "/paymentpb.PaymentService/SetFaultProfile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *paymentServiceClient) GetFaultProfile(ctx context.Context, in *GetFaultProfileRequest, opts ...grpc.CallOption) (*GetFaultProfileResponse, error) {
+	out := new(GetFaultProfileResponse)
+	err := c.cc.Invoke(ctx, "/paymentpb.PaymentService/GetFaultProfile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // PaymentServiceServer is the server API for PaymentService service.
 type PaymentServiceServer interface {
 	//クレジットカードのトークン発行(非保持化対応)
 	RegistCard(context.Context, *RegistCardRequest) (*RegistCardResponse, error)
+	//クレジットカードのトークンを失効させる
+	RevokeCardToken(context.Context, *RevokeCardTokenRequest) (*RevokeCardTokenResponse, error)
 	//決済を行う
 	ExecutePayment(context.Context, *ExecutePaymentRequest) (*ExecutePaymentResponse, error)
+	//与信を確保する(オーソリ)
+	AuthorizePayment(context.Context, *AuthorizePaymentRequest) (*AuthorizePaymentResponse, error)
+	//確保した与信で売上を確定する(キャプチャ)
+	CapturePayment(context.Context, *CapturePaymentRequest) (*CapturePaymentResponse, error)
+	//確保した与信を取り消す
+	VoidAuthorization(context.Context, *VoidAuthorizationRequest) (*VoidAuthorizationResponse, error)
 	//決済をキャンセルする
 	CancelPayment(context.Context, *CancelPaymentRequest) (*CancelPaymentResponse, error)
 	//決済をバルクでキャンセルする
@@ -950,7 +2690,20 @@ type PaymentServiceServer interface {
 	//メモリ初期化
 	Initialize(context.Context, *InitializeRequest) (*InitializeResponse, error)
 	//ベンチマーカー用結果取得API
+	//page_size を指定するとページに分けて返す。続きは next_cursor を cursor に指定して取得する
 	GetResult(context.Context, *GetResultRequest) (*GetResultResponse, error)
+	//ベンチマーカー用結果取得API(ストリーミング)
+	StreamResults(*GetResultRequest, PaymentService_StreamResultsServer) error
+	//精算レポート(日ごとの売上・返金の集計と、マスクしたカード番号の明細)を取得する
+	GetSettlementReport(context.Context, *GetSettlementReportRequest) (*GetSettlementReportResponse, error)
+	//加盟店のWebhookを登録する(同じURLなら更新する)
+	RegisterWebhook(context.Context, *RegisterWebhookRequest) (*RegisterWebhookResponse, error)
+	//加盟店のWebhookを削除する
+	DeleteWebhook(context.Context, *DeleteWebhookRequest) (*DeleteWebhookResponse, error)
+	//障害注入の設定を変更する
+	SetFaultProfile(context.Context, *SetFaultProfileRequest) (*SetFaultProfileResponse, error)
+	//障害注入の設定を取得する
+	GetFaultProfile(context.Context, *GetFaultProfileRequest) (*GetFaultProfileResponse, error)
 }
 
 // UnimplementedPaymentServiceServer can be embedded to have forward compatible implementations.
@@ -960,9 +2713,21 @@ type UnimplementedPaymentServiceServer struct {
 func (*UnimplementedPaymentServiceServer) RegistCard(ctx context.Context, req *RegistCardRequest) (*RegistCardResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RegistCard not implemented")
 }
+func (*UnimplementedPaymentServiceServer) RevokeCardToken(ctx context.Context, req *RevokeCardTokenRequest) (*RevokeCardTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeCardToken not implemented")
+}
 func (*UnimplementedPaymentServiceServer) ExecutePayment(ctx context.Context, req *ExecutePaymentRequest) (*ExecutePaymentResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ExecutePayment not implemented")
 }
+func (*UnimplementedPaymentServiceServer) AuthorizePayment(ctx context.Context, req *AuthorizePaymentRequest) (*AuthorizePaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AuthorizePayment not implemented")
+}
+func (*UnimplementedPaymentServiceServer) CapturePayment(ctx context.Context, req *CapturePaymentRequest) (*CapturePaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CapturePayment not implemented")
+}
+func (*UnimplementedPaymentServiceServer) VoidAuthorization(ctx context.Context, req *VoidAuthorizationRequest) (*VoidAuthorizationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VoidAuthorization not implemented")
+}
 func (*UnimplementedPaymentServiceServer) CancelPayment(ctx context.Context, req *CancelPaymentRequest) (*CancelPaymentResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CancelPayment not implemented")
 }
@@ -978,6 +2743,24 @@ func (*UnimplementedPaymentServiceServer) Initialize(ctx context.Context, req *I
 func (*UnimplementedPaymentServiceServer) GetResult(ctx context.Context, req *GetResultRequest) (*GetResultResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetResult not implemented")
 }
+func (*UnimplementedPaymentServiceServer) StreamResults(req *GetResultRequest, srv PaymentService_StreamResultsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamResults not implemented")
+}
+func (*UnimplementedPaymentServiceServer) GetSettlementReport(ctx context.Context, req *GetSettlementReportRequest) (*GetSettlementReportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSettlementReport not implemented")
+}
+func (*UnimplementedPaymentServiceServer) RegisterWebhook(ctx context.Context, req *RegisterWebhookRequest) (*RegisterWebhookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterWebhook not implemented")
+}
+func (*UnimplementedPaymentServiceServer) DeleteWebhook(ctx context.Context, req *DeleteWebhookRequest) (*DeleteWebhookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWebhook not implemented")
+}
+func (*UnimplementedPaymentServiceServer) SetFaultProfile(ctx context.Context, req *SetFaultProfileRequest) (*SetFaultProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFaultProfile not implemented")
+}
+func (*UnimplementedPaymentServiceServer) GetFaultProfile(ctx context.Context, req *GetFaultProfileRequest) (*GetFaultProfileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFaultProfile not implemented")
+}
 
 func RegisterPaymentServiceServer(s *grpc.Server, srv PaymentServiceServer) {
 	s.RegisterService(&_PaymentService_serviceDesc, srv)
@@ -1001,6 +2784,24 @@ func _PaymentService_RegistCard_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PaymentService_RevokeCardToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeCardTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).RevokeCardToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/paymentpb.PaymentService/RevokeCardToken",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).RevokeCardToken(ctx, req.(*RevokeCardTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _PaymentService_ExecutePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ExecutePaymentRequest)
 	if err := dec(in); err != nil {
@@ -1019,6 +2820,60 @@ func _PaymentService_ExecutePayment_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PaymentService_AuthorizePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizePaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).AuthorizePayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/paymentpb.PaymentService/AuthorizePayment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).AuthorizePayment(ctx, req.(*AuthorizePaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_CapturePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CapturePaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).CapturePayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/paymentpb.PaymentService/CapturePayment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).CapturePayment(ctx, req.(*CapturePaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_VoidAuthorization_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VoidAuthorizationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).VoidAuthorization(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/paymentpb.PaymentService/VoidAuthorization",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).VoidAuthorization(ctx, req.(*VoidAuthorizationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _PaymentService_CancelPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CancelPaymentRequest)
 	if err := dec(in); err != nil {
@@ -1109,6 +2964,117 @@ func _PaymentService_GetResult_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _PaymentService_StreamResults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetResultRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PaymentServiceServer).StreamResults(m, &paymentServiceStreamResultsServer{stream})
+}
+
+type PaymentService_StreamResultsServer interface {
+	Send(*RawData) error
+	grpc.ServerStream
+}
+
+type paymentServiceStreamResultsServer struct {
+	grpc.ServerStream
+}
+
+func (x *paymentServiceStreamResultsServer) Send(m *RawData) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _PaymentService_GetSettlementReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSettlementReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetSettlementReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/paymentpb.PaymentService/GetSettlementReport",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetSettlementReport(ctx, req.(*GetSettlementReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_RegisterWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).RegisterWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/paymentpb.PaymentService/RegisterWebhook",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).RegisterWebhook(ctx, req.(*RegisterWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_DeleteWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteWebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).DeleteWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/paymentpb.PaymentService/DeleteWebhook",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).DeleteWebhook(ctx, req.(*DeleteWebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_SetFaultProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetFaultProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).SetFaultProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/paymentpb.PaymentService/SetFaultProfile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).SetFaultProfile(ctx, req.(*SetFaultProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PaymentService_GetFaultProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFaultProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PaymentServiceServer).GetFaultProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/paymentpb.PaymentService/GetFaultProfile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PaymentServiceServer).GetFaultProfile(ctx, req.(*GetFaultProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _PaymentService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "paymentpb.PaymentService",
 	HandlerType: (*PaymentServiceServer)(nil),
@@ -1117,10 +3083,26 @@ var _PaymentService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "RegistCard",
 			Handler:    _PaymentService_RegistCard_Handler,
 		},
+		{
+			MethodName: "RevokeCardToken",
+			Handler:    _PaymentService_RevokeCardToken_Handler,
+		},
 		{
 			MethodName: "ExecutePayment",
 			Handler:    _PaymentService_ExecutePayment_Handler,
 		},
+		{
+			MethodName: "AuthorizePayment",
+			Handler:    _PaymentService_AuthorizePayment_Handler,
+		},
+		{
+			MethodName: "CapturePayment",
+			Handler:    _PaymentService_CapturePayment_Handler,
+		},
+		{
+			MethodName: "VoidAuthorization",
+			Handler:    _PaymentService_VoidAuthorization_Handler,
+		},
 		{
 			MethodName: "CancelPayment",
 			Handler:    _PaymentService_CancelPayment_Handler,
@@ -1141,7 +3123,33 @@ var _PaymentService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetResult",
 			Handler:    _PaymentService_GetResult_Handler,
 		},
+		{
+			MethodName: "GetSettlementReport",
+			Handler:    _PaymentService_GetSettlementReport_Handler,
+		},
+		{
+			MethodName: "RegisterWebhook",
+			Handler:    _PaymentService_RegisterWebhook_Handler,
+		},
+		{
+			MethodName: "DeleteWebhook",
+			Handler:    _PaymentService_DeleteWebhook_Handler,
+		},
+		{
+			MethodName: "SetFaultProfile",
+			Handler:    _PaymentService_SetFaultProfile_Handler,
+		},
+		{
+			MethodName: "GetFaultProfile",
+			Handler:    _PaymentService_GetFaultProfile_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamResults",
+			Handler:       _PaymentService_StreamResults_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "pb/payment.proto",
 }
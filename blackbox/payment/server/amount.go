@@ -0,0 +1,112 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	pb "payment/pb"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 通貨と金額
+//
+// 金額は通貨の最小単位の整数で扱う(JPYなら円、USDならセント)。
+// 通貨コードを省略した決済は DefaultCurrency として扱う
+const DefaultCurrency = "JPY"
+
+// Currency は扱える通貨
+type Currency struct {
+	Code      string
+	Exponent  int   // 最小単位の桁数(JPYは0、USDは2)
+	MaxAmount int64 // 1回の決済の上限(最小単位)
+}
+
+var currencies = map[string]Currency{
+	"JPY": {Code: "JPY", Exponent: 0, MaxAmount: 9999999},
+	"KRW": {Code: "KRW", Exponent: 0, MaxAmount: 99999999},
+	"USD": {Code: "USD", Exponent: 2, MaxAmount: 9999999},
+	"EUR": {Code: "EUR", Exponent: 2, MaxAmount: 9999999},
+	"GBP": {Code: "GBP", Exponent: 2, MaxAmount: 9999999},
+	"CNY": {Code: "CNY", Exponent: 2, MaxAmount: 49999999},
+}
+
+// LookupCurrency は通貨コードの通貨を返す。空なら DefaultCurrency
+func LookupCurrency(code string) (Currency, bool) {
+	if code == "" {
+		code = DefaultCurrency
+	}
+	c, ok := currencies[strings.ToUpper(code)]
+	return c, ok
+}
+
+// ParseAmount は "1234.56" のような通常の単位の金額を最小単位の整数にする
+// 通貨の最小単位より細かい金額はエラーにする
+func ParseAmount(s string, c Currency) (int64, error) {
+	s = strings.TrimSpace(s)
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if len(frac) > c.Exponent {
+		return 0, fmt.Errorf("invalid amount %q: %s has %d decimal places", s, c.Code, c.Exponent)
+	}
+	frac += strings.Repeat("0", c.Exponent-len(frac))
+	n, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil || strings.HasPrefix(whole, "+") {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return n, nil
+}
+
+// FormatAmount は最小単位の金額を "1234.56 USD" のように表示する
+func FormatAmount(amount int64, c Currency) string {
+	if c.Exponent == 0 {
+		return fmt.Sprintf("%d %s", amount, c.Code)
+	}
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	unit := int64(1)
+	for i := 0; i < c.Exponent; i++ {
+		unit *= 10
+	}
+	return fmt.Sprintf("%s%d.%0*d %s", sign, amount/unit, c.Exponent, amount%unit, c.Code)
+}
+
+// badRequest は項目ごとの不正をBadRequestの詳細に付けたエラーを返す
+func badRequest(code codes.Code, field, description string) error {
+	st, err := status.New(code, description).WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: description}},
+	})
+	if err != nil {
+		return status.Errorf(code, description)
+	}
+	return st.Err()
+}
+
+// validatePaymentAmount は決済・与信の通貨と金額を確かめ、通貨を返す
+//   - 通貨が不正・扱えない: InvalidArgument
+//   - 金額が0以下: InvalidArgument
+//   - 1回の決済の上限を超える: OutOfRange
+func validatePaymentAmount(info *pb.PaymentInformation) (Currency, error) {
+	c, ok := LookupCurrency(info.Currency)
+	if !ok {
+		log.Printf("Unsupported Currency: %s\n", info.Currency)
+		return c, badRequest(codes.InvalidArgument, "payment_information.currency", "Unsupported Currency")
+	}
+	if info.Amount <= 0 {
+		log.Printf("Invalid Amount: %d\n", info.Amount)
+		return c, badRequest(codes.InvalidArgument, "payment_information.amount", "Amount Must Be Positive")
+	}
+	if int64(info.Amount) > c.MaxAmount {
+		log.Printf("Amount Too Large: %s\n", FormatAmount(int64(info.Amount), c))
+		return c, badRequest(codes.OutOfRange, "payment_information.amount", "Amount Exceeds Maximum")
+	}
+	return c, nil
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	pb "payment/pb"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+	テスト内容
+	・通常の単位の金額を最小単位に変換・表示できる(最小単位より細かい金額はエラー)
+	・通貨を省略した決済はJPYになる
+	・不正な通貨・0以下の金額・上限を超える金額は、理由ごとのコードと不正な項目を返し、記録されない
+*/
+func TestAmount(t *testing.T) {
+	t.Run("Minor units", func(t *testing.T) {
+		jpy, _ := LookupCurrency("JPY")
+		usd, _ := LookupCurrency("usd")
+		for _, c := range []struct {
+			s    string
+			cur  Currency
+			want int64
+			str  string
+		}{
+			{"1000", jpy, 1000, "1000 JPY"},
+			{"12.34", usd, 1234, "12.34 USD"},
+			{"12.3", usd, 1230, "12.30 USD"},
+			{"12", usd, 1200, "12.00 USD"},
+			{"0.05", usd, 5, "0.05 USD"},
+		} {
+			got, err := ParseAmount(c.s, c.cur)
+			if err != nil || got != c.want {
+				t.Fatalf("Failed. %s Expected:%d but %d %v\n", c.s, c.want, got, err)
+			}
+			if str := FormatAmount(got, c.cur); str != c.str {
+				t.Fatalf("Failed. Expected:%s but %s\n", c.str, str)
+			}
+		}
+		for _, c := range []struct {
+			s   string
+			cur Currency
+		}{{"10.5", jpy}, {"1.234", usd}, {"abc", jpy}, {"", jpy}, {"+1", jpy}} {
+			if _, err := ParseAmount(c.s, c.cur); err == nil {
+				t.Fatalf("Failed. %q should be invalid for %s\n", c.s, c.cur.Code)
+			}
+		}
+	})
+
+	s, err := NewNetworkServer()
+	if err != nil {
+		t.Fatalf("failed to create new server:%s", err)
+	}
+	ctx := context.Background()
+
+	card, err := s.RegistCard(ctx, &pb.RegistCardRequest{CardInformation: &pb.CardInformation{
+		CardNumber: "12345674",
+		Cvv:        "123",
+		ExpiryDate: "11/99",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Default currency", func(t *testing.T) {
+		r, err := s.ExecutePayment(ctx, &pb.ExecutePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card.CardToken, Amount: 100}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := s.GetPaymentInformation(ctx, &pb.GetPaymentInformationRequest{PaymentId: r.PaymentId})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.PaymentInformation.Currency != "JPY" {
+			t.Fatalf("Failed. Expected:JPY but %s\n", info.PaymentInformation.Currency)
+		}
+
+		a, err := s.AuthorizePayment(ctx, &pb.AuthorizePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card.CardToken, Amount: 1234, Currency: "usd"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err = s.GetPaymentInformation(ctx, &pb.GetPaymentInformationRequest{PaymentId: a.PaymentId})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.PaymentInformation.Currency != "USD" {
+			t.Fatalf("Failed. Expected:USD but %s\n", info.PaymentInformation.Currency)
+		}
+	})
+
+	t.Run("Invalid amount", func(t *testing.T) {
+		before, err := s.GetResult(ctx, &pb.GetResultRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range []struct {
+			info  *pb.PaymentInformation
+			code  codes.Code
+			field string
+		}{
+			{&pb.PaymentInformation{CardToken: card.CardToken, Amount: 0}, codes.InvalidArgument, "payment_information.amount"},
+			{&pb.PaymentInformation{CardToken: card.CardToken, Amount: -100}, codes.InvalidArgument, "payment_information.amount"},
+			{&pb.PaymentInformation{CardToken: card.CardToken, Amount: 100, Currency: "XXX"}, codes.InvalidArgument, "payment_information.currency"},
+			{&pb.PaymentInformation{CardToken: card.CardToken, Amount: 10000000}, codes.OutOfRange, "payment_information.amount"},
+			{&pb.PaymentInformation{CardToken: card.CardToken, Amount: 10000000, Currency: "USD"}, codes.OutOfRange, "payment_information.amount"},
+		} {
+			for _, call := range []func() error{
+				func() error {
+					_, err := s.ExecutePayment(ctx, &pb.ExecutePaymentRequest{PaymentInformation: c.info})
+					return err
+				},
+				func() error {
+					_, err := s.AuthorizePayment(ctx, &pb.AuthorizePaymentRequest{PaymentInformation: c.info})
+					return err
+				},
+			} {
+				err := call()
+				st := status.Convert(err)
+				if st.Code() != c.code {
+					t.Fatalf("Failed. %v Expected:%v but %v\n", c.info, c.code, err)
+				}
+				field := ""
+				for _, d := range st.Details() {
+					if br, ok := d.(*errdetails.BadRequest); ok && len(br.FieldViolations) > 0 {
+						field = br.FieldViolations[0].Field
+					}
+				}
+				if field != c.field {
+					t.Fatalf("Failed. Expected:%s but %q\n", c.field, field)
+				}
+			}
+		}
+
+		after, err := s.GetResult(ctx, &pb.GetResultRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(after.RawData) != len(before.RawData) {
+			t.Fatalf("Failed. Invalid payments should not be recorded: %d -> %d\n", len(before.RawData), len(after.RawData))
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "payment/pb"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/rs/xid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 与信の有効期限のデフォルト
+const DefaultAuthorizationTTL = 30 * time.Minute
+
+// expireAuthorization は有効期限が切れた与信を取消状態にする
+// 状態を変えた場合は true を返す
+func expireAuthorization(paydata *pb.PaymentInformation, now time.Time) bool {
+	if paydata.State != pb.PaymentState_AUTHORIZED || paydata.AuthorizationExpiresAt == nil {
+		return false
+	}
+	expiresAt, err := ptypes.Timestamp(paydata.AuthorizationExpiresAt)
+	if err != nil {
+		log.Println(err.Error())
+		return false
+	}
+	if now.Before(expiresAt) {
+		return false
+	}
+	paydata.State = pb.PaymentState_VOIDED
+	return true
+}
+
+//与信を確保する(オーソリ)
+func (s *Server) AuthorizePayment(ctx context.Context, req *pb.AuthorizePaymentRequest) (*pb.AuthorizePaymentResponse, error) {
+	st, err := s.merchant(ctx, false)
+	if err != nil {
+		return &pb.AuthorizePaymentResponse{IsOk: false}, err
+	}
+	if req.PaymentInformation == nil {
+		log.Println("Invalid POST Data. PaymentInformation is nil.")
+		return &pb.AuthorizePaymentResponse{IsOk: false}, status.Errorf(codes.InvalidArgument, "Invalid POST data")
+	}
+
+	currency, err := validatePaymentAmount(req.PaymentInformation)
+	if err != nil {
+		return &pb.AuthorizePaymentResponse{IsOk: false}, err
+	}
+
+	now := time.Now()
+
+	date, err := ptypes.TimestampProto(now)
+	if err != nil {
+		log.Println(err.Error())
+		return &pb.AuthorizePaymentResponse{IsOk: false}, status.Errorf(codes.Internal, err.Error())
+	}
+	expiresAt, err := ptypes.TimestampProto(now.Add(s.AuthorizationTTL))
+	if err != nil {
+		log.Println(err.Error())
+		return &pb.AuthorizePaymentResponse{IsOk: false}, status.Errorf(codes.Internal, err.Error())
+	}
+	guid := xid.New()
+
+	paydata := pb.PaymentInformation{
+		CardToken:              req.PaymentInformation.CardToken,
+		ReservationId:          req.PaymentInformation.ReservationId,
+		Datetime:               date,
+		Amount:                 req.PaymentInformation.Amount,
+		IsCanceled:             false,
+		State:                  pb.PaymentState_AUTHORIZED,
+		AuthorizationExpiresAt: expiresAt,
+		CardReference:          req.PaymentInformation.CardReference,
+		Currency:               currency.Code,
+	}
+	err = s.recordPayment(ctx, st, guid.String(), paydata, currency, now)
+	if err != nil {
+		log.Println(err.Error())
+		return &pb.AuthorizePaymentResponse{IsOk: false}, err
+	}
+
+	return &pb.AuthorizePaymentResponse{PaymentId: guid.String(), ExpiresAt: expiresAt, IsOk: true}, nil
+}
+
+//確保した与信で売上を確定する(キャプチャ)
+//既に確定済みの決済はそのまま成功を返す。キャンセルした決済はキャプチャできない
+func (s *Server) CapturePayment(ctx context.Context, req *pb.CapturePaymentRequest) (*pb.CapturePaymentResponse, error) {
+	st, err := s.merchant(ctx, false)
+	if err != nil {
+		return &pb.CapturePaymentResponse{IsOk: false}, err
+	}
+
+	//処理中のキャンセルと直列にする
+	unlock := s.lockPayments(req.PaymentId)
+	defer unlock()
+
+	now := time.Now()
+	date, err := ptypes.TimestampProto(now)
+	if err != nil {
+		log.Println(err.Error())
+		return &pb.CapturePaymentResponse{IsOk: false}, status.Errorf(codes.Internal, err.Error())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paydata, ok := st.PayInfoMap[req.PaymentId]
+	if !ok {
+		log.Println("PaymentID Not Found")
+		return &pb.CapturePaymentResponse{IsOk: false}, status.Errorf(codes.NotFound, "PaymentID Not Found")
+	}
+	if expireAuthorization(&paydata, now) {
+		st.PayInfoMap[req.PaymentId] = paydata
+		st.releaseSpend(req.PaymentId)
+	}
+	if paydata.IsCanceled {
+		log.Println("Payment Canceled")
+		return &pb.CapturePaymentResponse{IsOk: false}, status.Errorf(codes.FailedPrecondition, "Payment Canceled")
+	}
+
+	switch paydata.State {
+	case pb.PaymentState_CAPTURED:
+		return &pb.CapturePaymentResponse{IsOk: true}, nil
+	case pb.PaymentState_VOIDED:
+		log.Println("Authorization Voided or Expired")
+		return &pb.CapturePaymentResponse{IsOk: false}, status.Errorf(codes.FailedPrecondition, "Authorization Voided or Expired")
+	case pb.PaymentState_DECLINED:
+		log.Println("Payment Declined")
+		return &pb.CapturePaymentResponse{IsOk: false}, status.Errorf(codes.FailedPrecondition, "Payment Declined")
+	}
+
+	// 売上確定日時をキャプチャした時刻にする
+	paydata.State = pb.PaymentState_CAPTURED
+	paydata.Datetime = date
+	st.PayInfoMap[req.PaymentId] = paydata
+	delete(st.authorizationExpiry, req.PaymentId)
+	s.publishWebhook(st.merchantID, WebhookEventPaymentSucceeded, req.PaymentId, paydata)
+
+	return &pb.CapturePaymentResponse{IsOk: true}, nil
+}
+
+//確保した与信を取り消す
+//既に取り消し済み(有効期限切れを含む)の与信はそのまま成功を返す。キャンセルした決済は取り消せない
+func (s *Server) VoidAuthorization(ctx context.Context, req *pb.VoidAuthorizationRequest) (*pb.VoidAuthorizationResponse, error) {
+	st, err := s.merchant(ctx, false)
+	if err != nil {
+		return &pb.VoidAuthorizationResponse{IsOk: false}, err
+	}
+
+	//処理中のキャンセルと直列にする
+	unlock := s.lockPayments(req.PaymentId)
+	defer unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paydata, ok := st.PayInfoMap[req.PaymentId]
+	if !ok {
+		log.Println("PaymentID Not Found")
+		return &pb.VoidAuthorizationResponse{IsOk: false}, status.Errorf(codes.NotFound, "PaymentID Not Found")
+	}
+	if paydata.IsCanceled {
+		log.Println("Payment Canceled")
+		return &pb.VoidAuthorizationResponse{IsOk: false}, status.Errorf(codes.FailedPrecondition, "Payment Canceled")
+	}
+
+	switch paydata.State {
+	case pb.PaymentState_CAPTURED:
+		log.Println("Payment Already Captured")
+		return &pb.VoidAuthorizationResponse{IsOk: false}, status.Errorf(codes.FailedPrecondition, "Payment Already Captured")
+	case pb.PaymentState_VOIDED:
+		return &pb.VoidAuthorizationResponse{IsOk: true}, nil
+	case pb.PaymentState_DECLINED:
+		log.Println("Payment Declined")
+		return &pb.VoidAuthorizationResponse{IsOk: false}, status.Errorf(codes.FailedPrecondition, "Payment Declined")
+	}
+
+	paydata.State = pb.PaymentState_VOIDED
+	st.PayInfoMap[req.PaymentId] = paydata
+	st.releaseSpend(req.PaymentId)
+	s.publishWebhook(st.merchantID, WebhookEventPaymentCanceled, req.PaymentId, paydata)
+
+	return &pb.VoidAuthorizationResponse{IsOk: true}, nil
+}
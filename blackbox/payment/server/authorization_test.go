@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "payment/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+	テスト内容
+	・与信確保 → キャプチャ(売上確定になる/再キャプチャも成功)
+	・与信確保 → 取消(取消後のキャプチャは失敗)
+	・売上確定済みの決済は取り消せない
+	・有効期限切れの与信は取消扱いになり、キャプチャできない
+	・キャンセルした与信はキャプチャ・取消できない(売上確定にならない)
+	・ベンチマーカー用生データで状態が区別できる
+*/
+func TestAuthorization(t *testing.T) {
+	s, err := NewNetworkServer()
+	if err != nil {
+		t.Fatalf("failed to create new server:%s", err)
+	}
+	ctx := context.Background()
+
+	card, err := s.RegistCard(ctx, &pb.RegistCardRequest{CardInformation: &pb.CardInformation{
+		CardNumber: "12345674",
+		Cvv:        "123",
+		ExpiryDate: "11/99",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorize := func(amount int32) string {
+		r, err := s.AuthorizePayment(ctx, &pb.AuthorizePaymentRequest{PaymentInformation: &pb.PaymentInformation{
+			CardToken:     card.CardToken,
+			ReservationId: 1,
+			Amount:        amount,
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.ExpiresAt == nil {
+			t.Fatal("expires_at is nil")
+		}
+		return r.PaymentId
+	}
+	state := func(id string) pb.PaymentState {
+		r, err := s.GetPaymentInformation(ctx, &pb.GetPaymentInformationRequest{PaymentId: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.PaymentInformation.State
+	}
+
+	t.Run("AuthorizePayment with invalid card token", func(t *testing.T) {
+		_, err := s.AuthorizePayment(ctx, &pb.AuthorizePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: "hoge", Amount: 100}})
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.NotFound, err)
+		}
+	})
+
+	var captured string
+	t.Run("Authorize and capture", func(t *testing.T) {
+		captured = authorize(9800)
+		if got := state(captured); got != pb.PaymentState_AUTHORIZED {
+			t.Fatalf("Failed. Expected:%v but %v\n", pb.PaymentState_AUTHORIZED, got)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := s.CapturePayment(ctx, &pb.CapturePaymentRequest{PaymentId: captured}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := state(captured); got != pb.PaymentState_CAPTURED {
+			t.Fatalf("Failed. Expected:%v but %v\n", pb.PaymentState_CAPTURED, got)
+		}
+		_, err := s.VoidAuthorization(ctx, &pb.VoidAuthorizationRequest{PaymentId: captured})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.FailedPrecondition, err)
+		}
+	})
+
+	var voided string
+	t.Run("Authorize and void", func(t *testing.T) {
+		voided = authorize(1200)
+		if _, err := s.VoidAuthorization(ctx, &pb.VoidAuthorizationRequest{PaymentId: voided}); err != nil {
+			t.Fatal(err)
+		}
+		if got := state(voided); got != pb.PaymentState_VOIDED {
+			t.Fatalf("Failed. Expected:%v but %v\n", pb.PaymentState_VOIDED, got)
+		}
+		_, err := s.CapturePayment(ctx, &pb.CapturePaymentRequest{PaymentId: voided})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.FailedPrecondition, err)
+		}
+	})
+
+	t.Run("Expired authorization", func(t *testing.T) {
+		s.AuthorizationTTL = time.Millisecond
+		defer func() { s.AuthorizationTTL = DefaultAuthorizationTTL }()
+
+		id := authorize(500)
+		time.Sleep(10 * time.Millisecond)
+		if got := state(id); got != pb.PaymentState_VOIDED {
+			t.Fatalf("Failed. Expected:%v but %v\n", pb.PaymentState_VOIDED, got)
+		}
+		_, err := s.CapturePayment(ctx, &pb.CapturePaymentRequest{PaymentId: id})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.FailedPrecondition, err)
+		}
+	})
+
+	t.Run("GetResult", func(t *testing.T) {
+		authorized := authorize(300)
+		r, err := s.GetResult(ctx, &pb.GetResultRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.RawData) != 4 {
+			t.Fatalf("Failed. Expected:4 but %d\n", len(r.RawData))
+		}
+		count := map[pb.PaymentState]int{}
+		for _, v := range r.RawData {
+			count[v.PaymentInformation.State]++
+			if v.PaymentInformation.Amount == 300 && v.PaymentInformation.State != pb.PaymentState_AUTHORIZED {
+				t.Fatalf("Failed. %s Expected:%v but %v\n", authorized, pb.PaymentState_AUTHORIZED, v.PaymentInformation.State)
+			}
+		}
+		if count[pb.PaymentState_CAPTURED] != 1 || count[pb.PaymentState_AUTHORIZED] != 1 || count[pb.PaymentState_VOIDED] != 2 {
+			t.Fatalf("Failed. Wrong states: %v\n", count)
+		}
+	})
+	t.Run("Canceled authorization", func(t *testing.T) {
+		s.CancelLatency = 0
+		defer func() { s.CancelLatency = DefaultCancelLatency }()
+
+		id := authorize(700)
+		if _, err := s.CancelPayment(ctx, &pb.CancelPaymentRequest{PaymentId: id}); err != nil {
+			t.Fatal(err)
+		}
+		_, err := s.CapturePayment(ctx, &pb.CapturePaymentRequest{PaymentId: id})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.FailedPrecondition, err)
+		}
+		_, err = s.VoidAuthorization(ctx, &pb.VoidAuthorizationRequest{PaymentId: id})
+		if status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.FailedPrecondition, err)
+		}
+		if got := state(id); got != pb.PaymentState_AUTHORIZED {
+			t.Fatalf("Failed. Expected:%v but %v\n", pb.PaymentState_AUTHORIZED, got)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "payment/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// カードトークンの有効期限のデフォルト
+const DefaultCardTokenTTL = 24 * time.Hour
+
+// CardToken はカードトークンの発行情報
+type CardToken struct {
+	Brand     string
+	Reference string // 空でなければ、決済時に同じ参照を指定した場合のみ使える
+	ExpiresAt time.Time
+	MaxUses   int32 // 0なら無制限
+	Uses      int32
+	Revoked   bool
+}
+
+// newCardToken はリクエストの指定からカードトークンの発行情報を作る
+// 有効期限はサーバーの設定値より長くはできない
+func (s *Server) newCardToken(req *pb.RegistCardRequest, now time.Time) (CardToken, error) {
+	if req.MaxUses < 0 {
+		return CardToken{}, status.Errorf(codes.InvalidArgument, "Invalid MaxUses")
+	}
+	if req.TtlSeconds < 0 {
+		return CardToken{}, status.Errorf(codes.InvalidArgument, "Invalid TtlSeconds")
+	}
+
+	ttl := s.CardTokenTTL
+	if req.TtlSeconds > 0 && time.Duration(req.TtlSeconds)*time.Second < ttl {
+		ttl = time.Duration(req.TtlSeconds) * time.Second
+	}
+
+	return CardToken{
+		Brand:     CardBrand(req.CardInformation.CardNumber),
+		Reference: req.Reference,
+		ExpiresAt: now.Add(ttl),
+		MaxUses:   req.MaxUses,
+	}, nil
+}
+
+// checkCardToken はカードトークンが決済に使えるかを確かめる(s.mu を取ってから呼ぶ)
+// 使えない場合は拒否の理由と理由ごとのgRPCステータスを返す。存在しないトークンは拒否として記録しないので理由は NOT_DECLINED
+func checkCardToken(st *merchantStore, token, reference string, now time.Time) (CardToken, pb.DeclineReason, error) {
+	t, ok := st.CardTokenMap[token]
+	if !ok {
+		return t, pb.DeclineReason_NOT_DECLINED, status.Errorf(codes.NotFound, "Card_Token Not Found")
+	}
+	if t.Revoked {
+		return t, pb.DeclineReason_CARD_TOKEN_REVOKED, status.Errorf(codes.PermissionDenied, "Card_Token Revoked")
+	}
+	if !now.Before(t.ExpiresAt) {
+		return t, pb.DeclineReason_CARD_TOKEN_EXPIRED, status.Errorf(codes.FailedPrecondition, "Card_Token Expired")
+	}
+	if t.Reference != "" && t.Reference != reference {
+		return t, pb.DeclineReason_CARD_REFERENCE_MISMATCH, status.Errorf(codes.PermissionDenied, "Card_Token Reference Mismatch")
+	}
+	if t.MaxUses > 0 && t.Uses >= t.MaxUses {
+		return t, pb.DeclineReason_CARD_TOKEN_USAGE_EXCEEDED, status.Errorf(codes.ResourceExhausted, "Card_Token Usage Limit Exceeded")
+	}
+	return t, pb.DeclineReason_NOT_DECLINED, nil
+}
+
+//クレジットカードのトークンを失効させる
+//既に失効しているトークンはそのまま成功を返す
+func (s *Server) RevokeCardToken(ctx context.Context, req *pb.RevokeCardTokenRequest) (*pb.RevokeCardTokenResponse, error) {
+	st, err := s.merchant(ctx, false)
+	if err != nil {
+		return &pb.RevokeCardTokenResponse{IsOk: false}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := st.CardTokenMap[req.CardToken]
+	if !ok {
+		log.Println("Card_Token Not Found")
+		return &pb.RevokeCardTokenResponse{IsOk: false}, status.Errorf(codes.NotFound, "Card_Token Not Found")
+	}
+	t.Revoked = true
+	st.CardTokenMap[req.CardToken] = t
+
+	return &pb.RevokeCardTokenResponse{IsOk: true}, nil
+}
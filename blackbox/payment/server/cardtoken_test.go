@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "payment/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+	テスト内容
+	・ブランドと有効期限が返る
+	・使用回数の上限を超えると失敗する
+	・参照を紐づけたトークンは同じ参照でのみ使える
+	・失効したトークンは使えない
+	・有効期限切れのトークンは使えない(設定値より長い有効期限は指定できない)
+*/
+func TestCardToken(t *testing.T) {
+	s, err := NewNetworkServer()
+	if err != nil {
+		t.Fatalf("failed to create new server:%s", err)
+	}
+	ctx := context.Background()
+
+	regist := func(req *pb.RegistCardRequest) *pb.RegistCardResponse {
+		req.CardInformation = &pb.CardInformation{
+			CardNumber: "41111113",
+			Cvv:        "123",
+			ExpiryDate: "11/99",
+		}
+		r, err := s.RegistCard(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	pay := func(token, reference string) error {
+		_, err := s.ExecutePayment(ctx, &pb.ExecutePaymentRequest{PaymentInformation: &pb.PaymentInformation{
+			CardToken:     token,
+			Amount:        100,
+			CardReference: reference,
+		}})
+		return err
+	}
+
+	t.Run("RegistCard returns brand and expiry", func(t *testing.T) {
+		r := regist(&pb.RegistCardRequest{})
+		if r.Brand != "VISA" {
+			t.Fatalf("Failed. Expected:VISA but %s\n", r.Brand)
+		}
+		if r.ExpiresAt == nil {
+			t.Fatal("expires_at is nil")
+		}
+	})
+
+	t.Run("MaxUses", func(t *testing.T) {
+		r := regist(&pb.RegistCardRequest{MaxUses: 2})
+		for i := 0; i < 2; i++ {
+			if err := pay(r.CardToken, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := pay(r.CardToken, ""); status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.ResourceExhausted, err)
+		}
+	})
+
+	t.Run("Reference", func(t *testing.T) {
+		r := regist(&pb.RegistCardRequest{Reference: "user-1"})
+		if err := pay(r.CardToken, "user-2"); status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.PermissionDenied, err)
+		}
+		if err := pay(r.CardToken, "user-1"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("RevokeCardToken", func(t *testing.T) {
+		r := regist(&pb.RegistCardRequest{})
+		if _, err := s.RevokeCardToken(ctx, &pb.RevokeCardTokenRequest{CardToken: r.CardToken}); err != nil {
+			t.Fatal(err)
+		}
+		if err := pay(r.CardToken, ""); status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.PermissionDenied, err)
+		}
+		_, err := s.RevokeCardToken(ctx, &pb.RevokeCardTokenRequest{CardToken: "hoge"})
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.NotFound, err)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		s.CardTokenTTL = time.Millisecond
+		defer func() { s.CardTokenTTL = DefaultCardTokenTTL }()
+
+		r := regist(&pb.RegistCardRequest{TtlSeconds: 3600})
+		time.Sleep(10 * time.Millisecond)
+		if err := pay(r.CardToken, ""); status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.FailedPrecondition, err)
+		}
+	})
+}
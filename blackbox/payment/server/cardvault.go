@@ -0,0 +1,133 @@
+package server
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	pb "payment/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// カード情報の保持
+//
+// CVVはカードの検証に使うだけで保持しない。カード番号は鍵で暗号化(AES-GCM)して保持し、
+// レスポンスにはマスクしたカード番号(下4桁)とブランドだけを返す。
+// 利用額の上限はカード番号の代わりに、鍵付きハッシュ(fingerprint)でカードを区別する
+
+// storedCard は登録したカード
+type storedCard struct {
+	EncryptedNumber []byte
+	Fingerprint     string
+	MaskedNumber    string
+	Brand           string
+	ExpiryDate      string
+}
+
+// Info はレスポンスに返すカード情報(マスクしたカード番号とブランドだけ)
+func (c storedCard) Info() *pb.CardInformation {
+	return &pb.CardInformation{MaskedCardNumber: c.MaskedNumber, Brand: c.Brand}
+}
+
+// cardVault はカード番号の暗号化・復号と fingerprint の計算をする
+type cardVault struct {
+	aead           cipher.AEAD
+	fingerprintKey []byte
+}
+
+// ParseCardEncryptionKey は base64 の鍵を読む。AES-128/192/256 の 16/24/32 バイト
+func ParseCardEncryptionKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("card encryption key must be base64: %s", err)
+	}
+	if n := len(key); n != 16 && n != 24 && n != 32 {
+		return nil, fmt.Errorf("card encryption key must be 16, 24 or 32 bytes: %d bytes", n)
+	}
+	return key, nil
+}
+
+func newCardVault(key []byte) (*cardVault, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("card fingerprint"))
+	return &cardVault{aead: aead, fingerprintKey: mac.Sum(nil)}, nil
+}
+
+// newRandomCardVault は起動ごとに作る鍵で暗号化する
+// カード情報はメモリにしか無いので、鍵を設定しなくても使える
+func newRandomCardVault() (*cardVault, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return newCardVault(key)
+}
+
+// seal はカード番号を暗号化し、カードとして保持する情報を作る
+func (v *cardVault) seal(card *pb.CardInformation) (storedCard, error) {
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return storedCard{}, err
+	}
+	return storedCard{
+		EncryptedNumber: v.aead.Seal(nonce, nonce, []byte(card.CardNumber), nil),
+		Fingerprint:     v.fingerprint(card.CardNumber),
+		MaskedNumber:    MaskCardNumber(card.CardNumber),
+		Brand:           CardBrand(card.CardNumber),
+		ExpiryDate:      card.ExpiryDate,
+	}, nil
+}
+
+// open はカード番号を復号する
+func (v *cardVault) open(c storedCard) (string, error) {
+	n := v.aead.NonceSize()
+	if len(c.EncryptedNumber) < n {
+		return "", fmt.Errorf("invalid encrypted card number")
+	}
+	number, err := v.aead.Open(nil, c.EncryptedNumber[:n], c.EncryptedNumber[n:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(number), nil
+}
+
+func (v *cardVault) fingerprint(number string) string {
+	mac := hmac.New(sha256.New, v.fingerprintKey)
+	mac.Write([]byte(number))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SetCardEncryptionKey はカード番号を暗号化する鍵を設定する
+// 登録済みのカードが復号できなくなるので、カードを登録する前(起動時)に設定する
+func (s *Server) SetCardEncryptionKey(key []byte) error {
+	v, err := newCardVault(key)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid Card Encryption Key: %s", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, st := range s.merchants {
+		if len(st.CardInfoMap) > 0 {
+			return status.Errorf(codes.FailedPrecondition, "Cards Already Registered")
+		}
+	}
+	s.cards = v
+	return nil
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"testing"
+
+	pb "payment/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+	テスト内容
+	・CVVを保持せず、カード番号は暗号化して保持する(設定した鍵で復号できる)
+	・結果取得・精算レポートはマスクしたカード番号とブランドだけを返す
+	・同じカード番号は別のトークンでも同じカードとして利用額の上限を数える
+	・鍵の長さが不正・カードの登録後の鍵の変更はエラー
+*/
+func TestCardVault(t *testing.T) {
+	s, err := NewNetworkServer()
+	if err != nil {
+		t.Fatalf("failed to create new server:%s", err)
+	}
+	key, err := ParseCardEncryptionKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetCardEncryptionKey(key); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetDefaultSpendingLimits([]SpendingLimit{{Currency: "JPY", PerCard: 1000}}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	regist := func() string {
+		r, err := s.RegistCard(ctx, &pb.RegistCardRequest{CardInformation: &pb.CardInformation{
+			CardNumber: "12345674",
+			Cvv:        "123",
+			ExpiryDate: "11/99",
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.CardToken
+	}
+	token1, token2 := regist(), regist()
+
+	t.Run("Stored card", func(t *testing.T) {
+		s.mu.RLock()
+		card := s.merchants[DefaultMerchantID].CardInfoMap[token1]
+		other := s.merchants[DefaultMerchantID].CardInfoMap[token2]
+		s.mu.RUnlock()
+
+		if bytes.Contains(card.EncryptedNumber, []byte("12345674")) {
+			t.Fatal("Failed. Card number stored in plain text")
+		}
+		if bytes.Equal(card.EncryptedNumber, other.EncryptedNumber) {
+			t.Fatal("Failed. Same card number should be encrypted differently")
+		}
+		if card.MaskedNumber != "****5674" || card.Fingerprint != other.Fingerprint {
+			t.Fatalf("Failed. Unexpected card: %v %v\n", card, other)
+		}
+
+		v, err := newCardVault(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if number, err := v.open(card); err != nil || number != "12345674" {
+			t.Fatalf("Failed. Expected:12345674 but %q %v\n", number, err)
+		}
+		wrong, _ := newRandomCardVault()
+		if _, err := wrong.open(card); err == nil {
+			t.Fatal("Failed. Card number should not be decrypted with wrong key")
+		}
+	})
+
+	t.Run("Masked result", func(t *testing.T) {
+		if _, err := s.ExecutePayment(ctx, &pb.ExecutePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: token1, Amount: 600}}); err != nil {
+			t.Fatal(err)
+		}
+		r, err := s.GetResult(ctx, &pb.GetResultRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.RawData) != 1 {
+			t.Fatalf("Failed. Expected:1 but %d\n", len(r.RawData))
+		}
+		card := r.RawData[0].CardInformation
+		if card.MaskedCardNumber != "****5674" || card.Brand != CardBrand("12345674") {
+			t.Fatalf("Failed. Expected:****5674 but %v\n", card)
+		}
+		if card.CardNumber != "" || card.Cvv != "" || card.ExpiryDate != "" {
+			t.Fatalf("Failed. Raw card information returned: %v\n", card)
+		}
+	})
+
+	t.Run("Same card number", func(t *testing.T) {
+		_, err := s.ExecutePayment(ctx, &pb.ExecutePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: token2, Amount: 600}})
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.ResourceExhausted, err)
+		}
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		for _, k := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+			if _, err := ParseCardEncryptionKey(k); err == nil {
+				t.Fatalf("Failed. %q should be invalid\n", k)
+			}
+		}
+		if err := s.SetCardEncryptionKey([]byte("short")); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.InvalidArgument, err)
+		}
+		if err := s.SetCardEncryptionKey(key); status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.FailedPrecondition, err)
+		}
+	})
+}
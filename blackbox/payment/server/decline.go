@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "payment/pb"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 決済・与信の承認と拒否
+//
+// 拒否した決済・与信も DECLINED の決済として拒否の理由(decline_reason)を付けて記録する。
+// 拒否した決済の決済IDは、エラーの詳細(ResourceInfo)で返す
+
+type cardDeclinedKey struct{}
+
+// withCardDeclined は障害注入でカードを拒否することを context に記録する
+func withCardDeclined(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cardDeclinedKey{}, true)
+}
+
+func cardDeclined(ctx context.Context) bool {
+	declined, _ := ctx.Value(cardDeclinedKey{}).(bool)
+	return declined
+}
+
+// withDeclinedPayment はエラーに拒否した決済の決済IDと理由を付ける
+func withDeclinedPayment(err error, paymentID string, reason pb.DeclineReason) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	withDetails, derr := st.WithDetails(&errdetails.ResourceInfo{
+		ResourceType: "payment",
+		ResourceName: paymentID,
+		Description:  reason.String(),
+	})
+	if derr != nil {
+		log.Println(derr.Error())
+		return err
+	}
+	return withDetails.Err()
+}
+
+// approvePayment は決済・与信を承認するかを決める(s.mu を取ってから呼ぶ)
+// 承認したらカードトークンの使用回数と利用額を数える
+func (s *Server) approvePayment(ctx context.Context, st *merchantStore, paymentID string, info *pb.PaymentInformation, c Currency, now time.Time) (pb.DeclineReason, error) {
+	t, reason, err := checkCardToken(st, info.CardToken, info.CardReference, now)
+	if err != nil {
+		return reason, err
+	}
+	if cardDeclined(ctx) {
+		return pb.DeclineReason_CARD_DECLINED, status.Errorf(codes.FailedPrecondition, "Card Declined")
+	}
+	card := st.CardInfoMap[info.CardToken].Fingerprint
+	st.releaseExpiredAuthorizations(now)
+	reason, err = s.checkSpendingLimits(st, info.CardToken, card, int64(info.Amount), c, now)
+	if err != nil {
+		return reason, err
+	}
+
+	t.Uses++
+	st.CardTokenMap[info.CardToken] = t
+	st.addSpend(paymentID, card, int64(info.Amount), c, now)
+	if info.State == pb.PaymentState_AUTHORIZED && info.AuthorizationExpiresAt != nil {
+		if expiresAt, err := ptypes.Timestamp(info.AuthorizationExpiresAt); err == nil {
+			st.authorizationExpiry[paymentID] = expiresAt
+		}
+	}
+	return pb.DeclineReason_NOT_DECLINED, nil
+}
+
+// recordPayment は決済・与信を承認して記録する
+// 拒否した場合は DECLINED の決済として記録し、決済IDを付けたエラーを返す
+func (s *Server) recordPayment(ctx context.Context, st *merchantStore, paymentID string, paydata pb.PaymentInformation, c Currency, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reason, err := s.approvePayment(ctx, st, paymentID, &paydata, c, now)
+	if err != nil {
+		if reason == pb.DeclineReason_NOT_DECLINED {
+			return err
+		}
+		log.Printf("Payment Declined: %s %s\n", paymentID, reason)
+		paydata.State = pb.PaymentState_DECLINED
+		paydata.DeclineReason = reason
+		paydata.AuthorizationExpiresAt = nil
+		err = withDeclinedPayment(err, paymentID, reason)
+	}
+	st.PayInfoMap[paymentID] = paydata
+	st.paymentOrder = append(st.paymentOrder, paymentID)
+	return err
+}
@@ -0,0 +1,379 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"math"
+	"math/rand"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"payment/config"
+	pb "payment/pb"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 障害注入
+//
+// 遅延・エラー・カードの拒否・決済を記録したうえでのタイムアウト・二重処理を
+// 設定ファイル(config.FaultProfile)や SetFaultProfile で再現する。
+// 乱数はシードで固定できるので、同じ順序でリクエストすれば同じ障害が起きる。
+// 障害注入の設定変更・取得のRPCには障害を入れない
+//
+// 障害注入の設定は全ての加盟店に共通なので、変更・取得には管理用のキー
+// (gRPCのメタデータ x-admin-key、HTTPでは X-Admin-Key ヘッダ)が必要。
+// 管理用のキーを設定していない場合は、加盟店を設定していなければ(加盟店 default だけなら)キー無しで使える
+
+const faultRuleDefault = "*"
+
+// AdminKeyHeader は管理用のキーのメタデータ名
+const AdminKeyHeader = "x-admin-key"
+
+// faultPlan は1リクエストで起こす障害
+type faultPlan struct {
+	Latency            time.Duration
+	Err                error
+	Declined           bool
+	Duplicate          bool
+	TimeoutAfterCommit bool
+}
+
+type faultInjector struct {
+	mu       sync.Mutex
+	profile  *pb.FaultProfile
+	rules    map[string]*pb.FaultRule
+	codes    map[string]codes.Code
+	declined []*regexp.Regexp
+	rng      *rand.Rand
+}
+
+func newFaultInjector() *faultInjector {
+	f := &faultInjector{}
+	if err := f.set(&pb.FaultProfile{}); err != nil {
+		log.Fatal(err)
+	}
+	return f
+}
+
+// FaultProfileFromConfig は設定ファイルの障害注入の設定を変換する
+func FaultProfileFromConfig(c config.FaultProfile) *pb.FaultProfile {
+	ms := func(d time.Duration) int64 {
+		return int64(d / time.Millisecond)
+	}
+	profile := &pb.FaultProfile{
+		Enabled:              c.Enabled,
+		Seed:                 c.Seed,
+		DeclinedCardPatterns: c.DeclinedCardPatterns,
+	}
+	for _, r := range c.Rules {
+		profile.Rules = append(profile.Rules, &pb.FaultRule{
+			Method: r.Method,
+			Latency: &pb.LatencyDistribution{
+				Distribution: r.Latency.Distribution,
+				MinMs:        ms(r.Latency.Min),
+				MaxMs:        ms(r.Latency.Max),
+				MeanMs:       ms(r.Latency.Mean),
+				StddevMs:     ms(r.Latency.Stddev),
+			},
+			ErrorRate:              r.ErrorRate,
+			ErrorCode:              r.ErrorCode,
+			TimeoutAfterCommitRate: r.TimeoutAfterCommitRate,
+			DuplicateRate:          r.DuplicateRate,
+		})
+	}
+	return profile
+}
+
+// parseErrorCode はgRPCのコード名(UNAVAILABLE, DEADLINE_EXCEEDED など)をコードにする
+func parseErrorCode(name string) (codes.Code, bool) {
+	if name == "" {
+		return codes.Unavailable, true
+	}
+	key := strings.ToUpper(strings.Replace(name, "_", "", -1))
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if strings.ToUpper(c.String()) == key {
+			return c, true
+		}
+	}
+	return codes.Unknown, false
+}
+
+// set は障害注入の設定を差し替え、乱数をシードで初期化しなおす
+func (f *faultInjector) set(profile *pb.FaultProfile) error {
+	profile = proto.Clone(profile).(*pb.FaultProfile)
+
+	rules := make(map[string]*pb.FaultRule, len(profile.Rules))
+	errorCodes := make(map[string]codes.Code, len(profile.Rules))
+	for _, r := range profile.Rules {
+		if r.Method == "" {
+			return status.Errorf(codes.InvalidArgument, "Invalid FaultRule. Method is empty")
+		}
+		for _, rate := range []float64{r.ErrorRate, r.TimeoutAfterCommitRate, r.DuplicateRate} {
+			if rate < 0 || 1 < rate {
+				return status.Errorf(codes.InvalidArgument, "Invalid FaultRule. Rate must be between 0 and 1: %s", r.Method)
+			}
+		}
+		switch r.GetLatency().GetDistribution() {
+		case "", "fixed", "uniform", "normal", "exponential":
+		default:
+			return status.Errorf(codes.InvalidArgument, "Invalid FaultRule. Unknown distribution: %s", r.Latency.Distribution)
+		}
+		code, ok := parseErrorCode(r.ErrorCode)
+		if !ok {
+			return status.Errorf(codes.InvalidArgument, "Invalid FaultRule. Unknown error code: %s", r.ErrorCode)
+		}
+		rules[r.Method] = r
+		errorCodes[r.Method] = code
+	}
+
+	declined := make([]*regexp.Regexp, 0, len(profile.DeclinedCardPatterns))
+	for _, p := range profile.DeclinedCardPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "Invalid DeclinedCardPattern: %s", p)
+		}
+		declined = append(declined, re)
+	}
+
+	if profile.Seed == 0 {
+		profile.Seed = time.Now().UnixNano()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.profile = profile
+	f.rules = rules
+	f.codes = errorCodes
+	f.declined = declined
+	f.rng = rand.New(rand.NewSource(profile.Seed))
+	if profile.Enabled {
+		log.Printf("Fault injection enabled. seed=%d\n", profile.Seed)
+	}
+	return nil
+}
+
+func (f *faultInjector) get() *pb.FaultProfile {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return proto.Clone(f.profile).(*pb.FaultProfile)
+}
+
+// sampleLatency は分布から遅延を決める。u は[0,1)の一様乱数、n は標準正規乱数
+func sampleLatency(l *pb.LatencyDistribution, u, n float64) time.Duration {
+	var ms float64
+	switch l.GetDistribution() {
+	case "fixed":
+		ms = float64(l.MeanMs)
+	case "uniform":
+		ms = float64(l.MinMs) + u*float64(l.MaxMs-l.MinMs)
+	case "normal":
+		ms = float64(l.MeanMs) + n*float64(l.StddevMs)
+	case "exponential":
+		ms = -math.Log(1-u) * float64(l.MeanMs)
+	default:
+		return 0
+	}
+
+	if ms < float64(l.GetMinMs()) {
+		ms = float64(l.GetMinMs())
+	}
+	if l.GetMaxMs() > 0 && ms > float64(l.GetMaxMs()) {
+		ms = float64(l.GetMaxMs())
+	}
+	if ms < 0 {
+		ms = 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// declinesCards は拒否するカード番号の設定が有効かどうか
+// 有効でなければカード番号を調べる(復号する)必要はない
+func (f *faultInjector) declinesCards() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.profile.Enabled && len(f.declined) > 0
+}
+
+// plan はRPCで起こす障害を決める
+// 設定に関わらず1リクエストごとに同じ数だけ乱数を引くので、シードが同じなら同じ順序で障害が起きる
+func (f *faultInjector) plan(method, cardNumber string) faultPlan {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	u, n := f.rng.Float64(), f.rng.NormFloat64()
+	errDraw, timeoutDraw, duplicateDraw := f.rng.Float64(), f.rng.Float64(), f.rng.Float64()
+
+	plan := faultPlan{}
+	if !f.profile.Enabled {
+		return plan
+	}
+
+	if cardNumber != "" {
+		for _, re := range f.declined {
+			if re.MatchString(cardNumber) {
+				plan.Declined = true
+				break
+			}
+		}
+	}
+
+	rule, ok := f.rules[method]
+	if !ok {
+		rule, ok = f.rules[faultRuleDefault]
+		method = faultRuleDefault
+	}
+	if !ok {
+		return plan
+	}
+	plan.Latency = sampleLatency(rule.Latency, u, n)
+	if errDraw < rule.ErrorRate {
+		plan.Err = status.Errorf(f.codes[method], "Injected Fault")
+	}
+	plan.TimeoutAfterCommit = timeoutDraw < rule.TimeoutAfterCommitRate
+	plan.Duplicate = duplicateDraw < rule.DuplicateRate
+	return plan
+}
+
+// paymentCardNumber は決済・与信のリクエストに使われたカード番号を返す
+func (s *Server) paymentCardNumber(ctx context.Context, req interface{}) string {
+	var info *pb.PaymentInformation
+	switch r := req.(type) {
+	case *pb.ExecutePaymentRequest:
+		info = r.PaymentInformation
+	case *pb.AuthorizePaymentRequest:
+		info = r.PaymentInformation
+	}
+	if info == nil {
+		return ""
+	}
+
+	merchantID, err := s.merchantID(ctx, false)
+	if err != nil {
+		return ""
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st, ok := s.merchants[merchantID]
+	if !ok {
+		return ""
+	}
+	card, ok := st.CardInfoMap[info.CardToken]
+	if !ok {
+		return ""
+	}
+	number, err := s.cards.open(card)
+	if err != nil {
+		log.Println(err.Error())
+		return ""
+	}
+	return number
+}
+
+// FaultInterceptor は設定に従ってRPCに障害を注入する
+func (s *Server) FaultInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	method := path.Base(info.FullMethod)
+	if method == "SetFaultProfile" || method == "GetFaultProfile" {
+		return handler(ctx, req)
+	}
+
+	cardNumber := ""
+	if s.faults.declinesCards() {
+		cardNumber = s.paymentCardNumber(ctx, req)
+	}
+	plan := s.faults.plan(method, cardNumber)
+	if plan.Latency > 0 {
+		select {
+		case <-time.After(plan.Latency):
+		case <-ctx.Done():
+			return nil, status.Errorf(codes.Canceled, ctx.Err().Error())
+		}
+	}
+	if plan.Declined {
+		//拒否した決済として記録する
+		log.Println("Card Declined")
+		return handler(withCardDeclined(ctx), req)
+	}
+	if plan.Err != nil {
+		log.Printf("Injected Fault: %s\n", method)
+		return nil, plan.Err
+	}
+
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	if plan.Duplicate {
+		log.Printf("Injected Duplicate: %s\n", method)
+		handler(ctx, req)
+	}
+	if plan.TimeoutAfterCommit {
+		log.Printf("Injected Timeout After Commit: %s\n", method)
+		return nil, status.Errorf(codes.DeadlineExceeded, "Deadline Exceeded")
+	}
+	return resp, nil
+}
+
+// authorizeAdmin は管理用のキーを確かめる
+func (s *Server) authorizeAdmin(ctx context.Context) error {
+	var key string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(AdminKeyHeader); len(v) > 0 {
+			key = v[0]
+		}
+	}
+
+	if s.AdminKey == "" {
+		s.mu.RLock()
+		multiMerchant := len(s.merchantKeys) > 0
+		s.mu.RUnlock()
+		if multiMerchant {
+			return status.Errorf(codes.PermissionDenied, "Admin Key Required")
+		}
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(key), []byte(s.AdminKey)) != 1 {
+		return status.Errorf(codes.PermissionDenied, "Invalid Admin Key")
+	}
+	return nil
+}
+
+// SetFaults は起動時に設定ファイルの障害注入の設定を反映する(管理用のキーは要らない)
+func (s *Server) SetFaults(profile *pb.FaultProfile) error {
+	return s.faults.set(profile)
+}
+
+//障害注入の設定を変更する
+func (s *Server) SetFaultProfile(ctx context.Context, req *pb.SetFaultProfileRequest) (*pb.SetFaultProfileResponse, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		log.Println(err.Error())
+		return &pb.SetFaultProfileResponse{IsOk: false}, err
+	}
+	if req.FaultProfile == nil {
+		log.Println("Invalid POST data. FaultProfile is nil.")
+		return &pb.SetFaultProfileResponse{IsOk: false}, status.Errorf(codes.InvalidArgument, "Invalid POST data")
+	}
+	if err := s.faults.set(req.FaultProfile); err != nil {
+		log.Println(err.Error())
+		return &pb.SetFaultProfileResponse{IsOk: false}, err
+	}
+	return &pb.SetFaultProfileResponse{FaultProfile: s.faults.get(), IsOk: true}, nil
+}
+
+//障害注入の設定を取得する
+func (s *Server) GetFaultProfile(ctx context.Context, req *pb.GetFaultProfileRequest) (*pb.GetFaultProfileResponse, error) {
+	if err := s.authorizeAdmin(ctx); err != nil {
+		log.Println(err.Error())
+		return &pb.GetFaultProfileResponse{IsOk: false}, err
+	}
+	return &pb.GetFaultProfileResponse{FaultProfile: s.faults.get(), IsOk: true}, nil
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "payment/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+/*
+	テスト内容
+	・同じシードなら同じ順序で障害が起きる
+	・エラー率1ならエラーを返し、決済は記録されない
+	・拒否するカード番号は決済できない
+	・決済を記録したうえでタイムアウトを返す
+	・二重処理で決済が2件記録される
+	・遅延の分布
+*/
+func TestFaultInjection(t *testing.T) {
+	s, err := NewNetworkServer()
+	if err != nil {
+		t.Fatalf("failed to create new server:%s", err)
+	}
+	ctx := context.Background()
+
+	card, err := s.RegistCard(ctx, &pb.RegistCardRequest{CardInformation: &pb.CardInformation{
+		CardNumber: "41111113",
+		Cvv:        "123",
+		ExpiryDate: "11/99",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/paymentpb.PaymentService/ExecutePayment"}
+	execute := func() error {
+		req := &pb.ExecutePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card.CardToken, Amount: 100}}
+		_, err := s.FaultInterceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.ExecutePayment(ctx, req.(*pb.ExecutePaymentRequest))
+		})
+		return err
+	}
+	setProfile := func(profile *pb.FaultProfile) {
+		if _, err := s.SetFaultProfile(ctx, &pb.SetFaultProfileRequest{FaultProfile: profile}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	payments := func() int {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return len(s.merchants[DefaultMerchantID].PayInfoMap)
+	}
+
+	t.Run("Same seed, same faults", func(t *testing.T) {
+		profile := &pb.FaultProfile{
+			Enabled: true,
+			Seed:    42,
+			Rules:   []*pb.FaultRule{{Method: "*", ErrorRate: 0.5}},
+		}
+		run := func() []codes.Code {
+			setProfile(profile)
+			got := []codes.Code{}
+			for i := 0; i < 20; i++ {
+				got = append(got, status.Code(execute()))
+			}
+			return got
+		}
+		first, second := run(), run()
+		failed := 0
+		for i := range first {
+			if first[i] != second[i] {
+				t.Fatalf("Failed. Not reproducible: %v != %v\n", first, second)
+			}
+			if first[i] == codes.Unavailable {
+				failed++
+			}
+		}
+		if failed == 0 || failed == len(first) {
+			t.Fatalf("Failed. Wrong error rate: %v\n", first)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		setProfile(&pb.FaultProfile{
+			Enabled: true,
+			Rules:   []*pb.FaultRule{{Method: "ExecutePayment", ErrorRate: 1, ErrorCode: "RESOURCE_EXHAUSTED"}},
+		})
+		before := payments()
+		if err := execute(); status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.ResourceExhausted, err)
+		}
+		if payments() != before {
+			t.Fatal("Failed. Payment should not be recorded")
+		}
+	})
+
+	t.Run("Declined card", func(t *testing.T) {
+		setProfile(&pb.FaultProfile{Enabled: true, DeclinedCardPatterns: []string{"^4"}})
+		if !s.faults.declinesCards() {
+			t.Fatal("Failed. Card number should be checked")
+		}
+		before := payments()
+		if err := execute(); status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.FailedPrecondition, err)
+		}
+		// 拒否した決済として記録される
+		if payments() != before+1 {
+			t.Fatal("Failed. Declined payment should be recorded")
+		}
+	})
+
+	t.Run("Timeout after commit", func(t *testing.T) {
+		setProfile(&pb.FaultProfile{
+			Enabled: true,
+			Rules:   []*pb.FaultRule{{Method: "ExecutePayment", TimeoutAfterCommitRate: 1}},
+		})
+		before := payments()
+		if err := execute(); status.Code(err) != codes.DeadlineExceeded {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.DeadlineExceeded, err)
+		}
+		if payments() != before+1 {
+			t.Fatal("Failed. Payment should be recorded")
+		}
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		setProfile(&pb.FaultProfile{
+			Enabled: true,
+			Rules:   []*pb.FaultRule{{Method: "ExecutePayment", DuplicateRate: 1}},
+		})
+		before := payments()
+		if err := execute(); err != nil {
+			t.Fatal(err)
+		}
+		if payments() != before+2 {
+			t.Fatalf("Failed. Expected:%d but %d\n", before+2, payments())
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		setProfile(&pb.FaultProfile{
+			Rules:                []*pb.FaultRule{{Method: "*", ErrorRate: 1}},
+			DeclinedCardPatterns: []string{"^4"},
+		})
+		// 無効な間はカード番号を調べない
+		if s.faults.declinesCards() {
+			t.Fatal("Failed. Card number should not be checked")
+		}
+		if err := execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Invalid profile", func(t *testing.T) {
+		_, err := s.SetFaultProfile(ctx, &pb.SetFaultProfileRequest{FaultProfile: &pb.FaultProfile{
+			Rules: []*pb.FaultRule{{Method: "*", ErrorRate: 2}},
+		}})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.InvalidArgument, err)
+		}
+	})
+}
+
+/*
+	テスト内容
+	・加盟店のAPIキーでは障害注入の設定を変更・取得できない(他の加盟店の決済に障害を入れられない)
+	・管理用のキーを設定していれば、そのキーで変更・取得できる
+*/
+func TestFaultProfileAdmin(t *testing.T) {
+	s, err := NewNetworkServer()
+	if err != nil {
+		t.Fatalf("failed to create new server:%s", err)
+	}
+	err = s.SetMerchants([]Merchant{
+		{ID: "shop_a", APIKey: "sk_a"},
+		{ID: "shop_b", APIKey: "sk_b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withKey := func(header, key string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(header, key))
+	}
+	shopA, shopB := withKey(MerchantKeyHeader, "sk_a"), withKey(MerchantKeyHeader, "sk_b")
+
+	card, err := s.RegistCard(shopA, &pb.RegistCardRequest{CardInformation: &pb.CardInformation{
+		CardNumber: "41111113",
+		Cvv:        "123",
+		ExpiryDate: "11/99",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/paymentpb.PaymentService/ExecutePayment"}
+	executeA := func() error {
+		req := &pb.ExecutePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card.CardToken, Amount: 100}}
+		_, err := s.FaultInterceptor(shopA, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return s.ExecutePayment(ctx, req.(*pb.ExecutePaymentRequest))
+		})
+		return err
+	}
+	attack := &pb.SetFaultProfileRequest{FaultProfile: &pb.FaultProfile{
+		Enabled:              true,
+		DeclinedCardPatterns: []string{"^4"},
+		Rules:                []*pb.FaultRule{{Method: "*", ErrorRate: 1}},
+	}}
+
+	t.Run("Merchant key without admin key", func(t *testing.T) {
+		for _, ctx := range []context.Context{shopB, context.Background()} {
+			if _, err := s.SetFaultProfile(ctx, attack); status.Code(err) != codes.PermissionDenied {
+				t.Fatalf("Failed. Expected:%v but %v\n", codes.PermissionDenied, err)
+			}
+			if _, err := s.GetFaultProfile(ctx, &pb.GetFaultProfileRequest{}); status.Code(err) != codes.PermissionDenied {
+				t.Fatalf("Failed. Expected:%v but %v\n", codes.PermissionDenied, err)
+			}
+		}
+		if err := executeA(); err != nil {
+			t.Fatalf("Failed. Payment of shop_a should not be affected: %v\n", err)
+		}
+	})
+
+	s.AdminKey = "admin_secret"
+
+	t.Run("Merchant key with admin key configured", func(t *testing.T) {
+		for _, ctx := range []context.Context{shopB, withKey(AdminKeyHeader, "sk_b")} {
+			if _, err := s.SetFaultProfile(ctx, attack); status.Code(err) != codes.PermissionDenied {
+				t.Fatalf("Failed. Expected:%v but %v\n", codes.PermissionDenied, err)
+			}
+		}
+		if err := executeA(); err != nil {
+			t.Fatalf("Failed. Payment of shop_a should not be affected: %v\n", err)
+		}
+	})
+
+	t.Run("Admin key", func(t *testing.T) {
+		admin := withKey(AdminKeyHeader, "admin_secret")
+		if _, err := s.SetFaultProfile(admin, attack); err != nil {
+			t.Fatal(err)
+		}
+		r, err := s.GetFaultProfile(admin, &pb.GetFaultProfileRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !r.FaultProfile.Enabled {
+			t.Fatal("Failed. Fault profile should be enabled")
+		}
+	})
+}
+
+func TestSampleLatency(t *testing.T) {
+	tests := []struct {
+		latency *pb.LatencyDistribution
+		u       float64
+		n       float64
+		want    time.Duration
+	}{
+		{nil, 0.5, 0, 0},
+		{&pb.LatencyDistribution{Distribution: "fixed", MeanMs: 100}, 0.5, 1, 100 * time.Millisecond},
+		{&pb.LatencyDistribution{Distribution: "uniform", MinMs: 100, MaxMs: 300}, 0.5, 0, 200 * time.Millisecond},
+		{&pb.LatencyDistribution{Distribution: "normal", MeanMs: 100, StddevMs: 20}, 0, 1.5, 130 * time.Millisecond},
+		{&pb.LatencyDistribution{Distribution: "normal", MeanMs: 100, StddevMs: 20, MaxMs: 110}, 0, 1.5, 110 * time.Millisecond},
+		{&pb.LatencyDistribution{Distribution: "normal", MeanMs: 10, StddevMs: 20}, 0, -3, 0},
+		{&pb.LatencyDistribution{Distribution: "exponential", MeanMs: 100}, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := sampleLatency(tt.latency, tt.u, tt.n); got != tt.want {
+			t.Fatalf("failed test %#v: got %s", tt, got)
+		}
+	}
+}
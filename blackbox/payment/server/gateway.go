@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	_ "net/http/pprof"
+	"net/textproto"
 
 	"payment/config"
 	pb "payment/pb"
@@ -12,9 +13,20 @@ import (
 	"google.golang.org/grpc"
 )
 
+// merchantHeaderMatcher は X-Merchant-Key・X-Admin-Key ヘッダを gRPC のメタデータとして渡す
+func merchantHeaderMatcher(key string) (string, bool) {
+	for _, h := range []string{MerchantKeyHeader, AdminKeyHeader} {
+		if textproto.CanonicalMIMEHeaderKey(key) == textproto.CanonicalMIMEHeaderKey(h) {
+			return h, true
+		}
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
 func newGateway(c config.Config, ctx context.Context, opts ...runtime.ServeMuxOption) (http.Handler, error) {
 	opts = []runtime.ServeMuxOption{
 		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{OrigName: true, EmitDefaults: true}),
+		runtime.WithIncomingHeaderMatcher(merchantHeaderMatcher),
 	}
 	mux := runtime.NewServeMux(opts...)
 	dialOpts := []grpc.DialOption{grpc.WithInsecure()}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"payment/config"
+	pb "payment/pb"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 利用額の上限
+//
+// カード(カード番号)ごと・加盟店ごとに、通貨ごとの1日(サーバーのタイムゾーンの日付)の決済額の上限を設定できる。
+// 決済・与信の金額を数え、キャンセル・与信の取消(有効期限切れを含む)をすると戻す。
+// 上限を超える決済・与信は拒否し、DECLINED の決済として記録する
+
+// SpendingLimit は通貨ごとの1日の利用額の上限(最小単位)。0なら無制限
+type SpendingLimit struct {
+	Currency    string
+	PerCard     int64
+	PerMerchant int64
+}
+
+type spendKey struct {
+	day      string
+	currency string
+	card     string // カードの fingerprint。空なら加盟店の合計
+}
+
+// spendEntry は決済ごとに数えた利用額(キャンセルで戻すのに使う)
+type spendEntry struct {
+	day      string
+	currency string
+	card     string
+	amount   int64
+}
+
+// localDate はサーバーのタイムゾーンの日付を返す(利用額の上限・精算レポートの日付)
+func localDate(t time.Time) string {
+	return t.In(time.Local).Format("2006-01-02")
+}
+
+// SpendingLimitsFromConfig は設定ファイルの利用額の上限を変換する
+func SpendingLimitsFromConfig(c []config.SpendingLimit) ([]SpendingLimit, error) {
+	limits := make([]SpendingLimit, 0, len(c))
+	for _, l := range c {
+		cur, ok := LookupCurrency(l.Currency)
+		if !ok {
+			return nil, fmt.Errorf("unsupported currency: %s", l.Currency)
+		}
+		limit := SpendingLimit{Currency: cur.Code}
+		for _, v := range []struct {
+			s   string
+			dst *int64
+		}{{l.PerCard, &limit.PerCard}, {l.PerMerchant, &limit.PerMerchant}} {
+			if v.s == "" {
+				continue
+			}
+			n, err := ParseAmount(v.s, cur)
+			if err != nil {
+				return nil, err
+			}
+			*v.dst = n
+		}
+		limits = append(limits, limit)
+	}
+	return limits, nil
+}
+
+func validateSpendingLimits(limits []SpendingLimit) error {
+	seen := make(map[string]bool, len(limits))
+	for _, l := range limits {
+		c, ok := LookupCurrency(l.Currency)
+		if !ok || l.Currency == "" {
+			return status.Errorf(codes.InvalidArgument, "Invalid Spending Limit. Unsupported Currency: %s", l.Currency)
+		}
+		if seen[c.Code] {
+			return status.Errorf(codes.InvalidArgument, "Invalid Spending Limit. Duplicate Currency: %s", l.Currency)
+		}
+		seen[c.Code] = true
+		if l.PerCard < 0 || l.PerMerchant < 0 {
+			return status.Errorf(codes.InvalidArgument, "Invalid Spending Limit. Limit must not be negative: %s", l.Currency)
+		}
+	}
+	return nil
+}
+
+// SetDefaultSpendingLimits は加盟店ごとの設定が無い加盟店の利用額の上限を設定する
+func (s *Server) SetDefaultSpendingLimits(limits []SpendingLimit) error {
+	if err := validateSpendingLimits(limits); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultLimits = limits
+	return nil
+}
+
+// spendingLimit は加盟店の通貨の利用額の上限を返す(s.mu を取ってから呼ぶ)
+func (s *Server) spendingLimit(merchantID, currency string) SpendingLimit {
+	limits, ok := s.merchantLimits[merchantID]
+	if !ok {
+		limits = s.defaultLimits
+	}
+	for _, l := range limits {
+		if c, _ := LookupCurrency(l.Currency); c.Code == currency {
+			return l
+		}
+	}
+	return SpendingLimit{Currency: currency}
+}
+
+// limitExceeded は上限を超えたことをQuotaFailureの詳細に付けたエラーを返す
+func limitExceeded(message, subject string, limit int64, c Currency) error {
+	st, err := status.New(codes.ResourceExhausted, message).WithDetails(&errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{{
+			Subject:     subject,
+			Description: fmt.Sprintf("daily limit %s", FormatAmount(limit, c)),
+		}},
+	})
+	if err != nil {
+		return status.Errorf(codes.ResourceExhausted, message)
+	}
+	return st.Err()
+}
+
+// checkSpendingLimits は決済・与信で1日の利用額の上限を超えないかを確かめる(s.mu を取ってから呼ぶ)
+func (s *Server) checkSpendingLimits(st *merchantStore, token, card string, amount int64, c Currency, now time.Time) (pb.DeclineReason, error) {
+	limit := s.spendingLimit(st.merchantID, c.Code)
+	day := localDate(now)
+
+	if limit.PerCard > 0 && st.spend[spendKey{day, c.Code, card}]+amount > limit.PerCard {
+		log.Printf("Card Limit Exceeded: %s\n", FormatAmount(limit.PerCard, c))
+		return pb.DeclineReason_CARD_LIMIT_EXCEEDED, limitExceeded("Card Limit Exceeded", "card_token:"+token, limit.PerCard, c)
+	}
+	if limit.PerMerchant > 0 && st.spend[spendKey{day, c.Code, ""}]+amount > limit.PerMerchant {
+		log.Printf("Merchant Limit Exceeded: %s %s\n", st.merchantID, FormatAmount(limit.PerMerchant, c))
+		return pb.DeclineReason_MERCHANT_LIMIT_EXCEEDED, limitExceeded("Merchant Limit Exceeded", "merchant:"+st.merchantID, limit.PerMerchant, c)
+	}
+	return pb.DeclineReason_NOT_DECLINED, nil
+}
+
+// addSpend は決済・与信の金額を利用額に数える(s.mu を取ってから呼ぶ)
+func (st *merchantStore) addSpend(paymentID, card string, amount int64, c Currency, now time.Time) {
+	e := spendEntry{day: localDate(now), currency: c.Code, card: card, amount: amount}
+	st.spend[spendKey{e.day, e.currency, e.card}] += amount
+	st.spend[spendKey{e.day, e.currency, ""}] += amount
+	st.spentOn[paymentID] = e
+}
+
+// releaseSpend はキャンセル・与信の取消をした決済の金額を利用額から戻す(s.mu を取ってから呼ぶ)
+// 同じ決済を2回戻すことはない
+func (st *merchantStore) releaseSpend(paymentID string) {
+	delete(st.authorizationExpiry, paymentID)
+	e, ok := st.spentOn[paymentID]
+	if !ok {
+		return
+	}
+	delete(st.spentOn, paymentID)
+	st.spend[spendKey{e.day, e.currency, e.card}] -= e.amount
+	st.spend[spendKey{e.day, e.currency, ""}] -= e.amount
+}
+
+// releaseExpiredAuthorizations は有効期限が切れた与信を取消状態にし、金額を利用額から戻す(s.mu を取ってから呼ぶ)
+// 有効期限切れは結果の取得などでも判定するが、状態を書き換えられるのは s.mu を取ったときだけなので、利用額を確かめる前に呼ぶ
+func (st *merchantStore) releaseExpiredAuthorizations(now time.Time) {
+	for id, expiresAt := range st.authorizationExpiry {
+		if now.Before(expiresAt) {
+			continue
+		}
+		paydata := st.PayInfoMap[id]
+		if expireAuthorization(&paydata, now) {
+			st.PayInfoMap[id] = paydata
+		}
+		st.releaseSpend(id)
+	}
+}
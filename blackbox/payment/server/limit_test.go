@@ -0,0 +1,233 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "payment/pb"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+/*
+	テスト内容
+	・カードごとの1日の利用額の上限を超える決済・与信は拒否される(通貨ごと)
+	・キャンセル・与信の取消をすると利用額が戻る(有効期限が切れた与信も戻る)
+	・加盟店ごとの上限は、全加盟店共通の上限より優先される
+	・拒否した決済は理由を付けて記録され、決済IDがエラーの詳細で返る
+	・拒否した決済はキャンセル・キャプチャ・取消できない
+	・失効したトークンでの決済も拒否として記録される(存在しないトークンは記録されない)
+*/
+func TestSpendingLimit(t *testing.T) {
+	s, err := NewNetworkServer()
+	if err != nil {
+		t.Fatalf("failed to create new server:%s", err)
+	}
+	s.CancelLatency = 0
+	if err := s.SetDefaultSpendingLimits([]SpendingLimit{{Currency: "JPY", PerCard: 1000, PerMerchant: 1500}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetMerchants([]Merchant{{ID: "shop", APIKey: "sk_shop", Limits: []SpendingLimit{{Currency: "JPY", PerCard: 5000}}}}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	regist := func(ctx context.Context, number string) string {
+		r, err := s.RegistCard(ctx, &pb.RegistCardRequest{CardInformation: &pb.CardInformation{
+			CardNumber: number,
+			Cvv:        "123",
+			ExpiryDate: "11/99",
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.CardToken
+	}
+	pay := func(ctx context.Context, token string, amount int32, currency string) (string, error) {
+		r, err := s.ExecutePayment(ctx, &pb.ExecutePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: token, Amount: amount, Currency: currency}})
+		if err != nil {
+			return "", err
+		}
+		return r.PaymentId, nil
+	}
+	// declined はエラーの詳細から拒否した決済の決済IDを取り出す
+	declined := func(err error) string {
+		for _, d := range status.Convert(err).Details() {
+			if r, ok := d.(*errdetails.ResourceInfo); ok && r.ResourceType == "payment" {
+				return r.ResourceName
+			}
+		}
+		return ""
+	}
+	state := func(ctx context.Context, id string) *pb.PaymentInformation {
+		r, err := s.GetPaymentInformation(ctx, &pb.GetPaymentInformationRequest{PaymentId: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.PaymentInformation
+	}
+
+	card1 := regist(ctx, "12345674")
+	card2 := regist(ctx, "22345672")
+
+	var declinedID string
+	t.Run("Per card", func(t *testing.T) {
+		id, err := pay(ctx, card1, 800, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = pay(ctx, card1, 300, "")
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.ResourceExhausted, err)
+		}
+		declinedID = declined(err)
+		if declinedID == "" {
+			t.Fatal("Failed. Declined payment id not found")
+		}
+		if info := state(ctx, declinedID); info.State != pb.PaymentState_DECLINED || info.DeclineReason != pb.DeclineReason_CARD_LIMIT_EXCEEDED {
+			t.Fatalf("Failed. Expected:DECLINED CARD_LIMIT_EXCEEDED but %v %v\n", info.State, info.DeclineReason)
+		}
+		_, err = s.AuthorizePayment(ctx, &pb.AuthorizePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card1, Amount: 300}})
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.ResourceExhausted, err)
+		}
+		// 上限の無い通貨は決済できる
+		if _, err := pay(ctx, card1, 100000, "USD"); err != nil {
+			t.Fatal(err)
+		}
+
+		// キャンセルすると戻る
+		if _, err := s.CancelPayment(ctx, &pb.CancelPaymentRequest{PaymentId: id}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pay(ctx, card1, 1000, ""); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Per merchant", func(t *testing.T) {
+		a, err := s.AuthorizePayment(ctx, &pb.AuthorizePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card2, Amount: 500}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = pay(ctx, card2, 1, "")
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.ResourceExhausted, err)
+		}
+		if info := state(ctx, declined(err)); info.DeclineReason != pb.DeclineReason_MERCHANT_LIMIT_EXCEEDED {
+			t.Fatalf("Failed. Expected:MERCHANT_LIMIT_EXCEEDED but %v\n", info.DeclineReason)
+		}
+
+		// 与信を取り消すと戻る
+		if _, err := s.VoidAuthorization(ctx, &pb.VoidAuthorizationRequest{PaymentId: a.PaymentId}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pay(ctx, card2, 500, ""); err != nil {
+			t.Fatal(err)
+		}
+
+		// 加盟店ごとの上限
+		shop := metadata.NewIncomingContext(ctx, metadata.Pairs(MerchantKeyHeader, "sk_shop"))
+		token := regist(shop, "12345674")
+		if _, err := pay(shop, token, 4000, ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pay(shop, token, 1001, ""); status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.ResourceExhausted, err)
+		}
+	})
+
+	t.Run("Declined payment", func(t *testing.T) {
+		if _, err := s.CancelPayment(ctx, &pb.CancelPaymentRequest{PaymentId: declinedID}); status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.FailedPrecondition, err)
+		}
+		if _, err := s.CapturePayment(ctx, &pb.CapturePaymentRequest{PaymentId: declinedID}); status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.FailedPrecondition, err)
+		}
+		if _, err := s.VoidAuthorization(ctx, &pb.VoidAuthorizationRequest{PaymentId: declinedID}); status.Code(err) != codes.FailedPrecondition {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.FailedPrecondition, err)
+		}
+		r, err := s.BulkCancelPayment(ctx, &pb.BulkCancelPaymentRequest{PaymentId: []string{declinedID}})
+		if err != nil || r.Deleted != 0 {
+			t.Fatalf("Failed. Expected:0 but %v %v\n", r, err)
+		}
+		if info := state(ctx, declinedID); info.IsCanceled {
+			t.Fatal("Failed. Declined payment canceled")
+		}
+	})
+
+	t.Run("Revoked token", func(t *testing.T) {
+		token := regist(ctx, "12345674")
+		if _, err := s.RevokeCardToken(ctx, &pb.RevokeCardTokenRequest{CardToken: token}); err != nil {
+			t.Fatal(err)
+		}
+		_, err := pay(ctx, token, 1, "")
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.PermissionDenied, err)
+		}
+		if info := state(ctx, declined(err)); info.DeclineReason != pb.DeclineReason_CARD_TOKEN_REVOKED {
+			t.Fatalf("Failed. Expected:CARD_TOKEN_REVOKED but %v\n", info.DeclineReason)
+		}
+
+		_, err = pay(ctx, "unknown", 1, "")
+		if status.Code(err) != codes.NotFound || declined(err) != "" {
+			t.Fatalf("Failed. Expected:%v without payment but %v\n", codes.NotFound, err)
+		}
+	})
+
+	t.Run("Invalid limits", func(t *testing.T) {
+		for _, limits := range [][]SpendingLimit{
+			{{Currency: "XXX", PerCard: 1}},
+			{{Currency: "", PerCard: 1}},
+			{{Currency: "JPY", PerCard: -1}},
+			{{Currency: "JPY"}, {Currency: "jpy"}},
+		} {
+			if err := s.SetDefaultSpendingLimits(limits); status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("Failed. %v Expected:%v but %v\n", limits, codes.InvalidArgument, err)
+			}
+		}
+	})
+}
+
+func TestSpendingLimitExpiredAuthorization(t *testing.T) {
+	s, err := NewNetworkServer()
+	if err != nil {
+		t.Fatalf("failed to create new server:%s", err)
+	}
+	if err := s.SetDefaultSpendingLimits([]SpendingLimit{{Currency: "JPY", PerCard: 1000}}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	card, err := s.RegistCard(ctx, &pb.RegistCardRequest{CardInformation: &pb.CardInformation{
+		CardNumber: "12345674",
+		Cvv:        "123",
+		ExpiryDate: "11/99",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorize := func(amount int32) error {
+		_, err := s.AuthorizePayment(ctx, &pb.AuthorizePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card.CardToken, Amount: amount}})
+		return err
+	}
+
+	s.AuthorizationTTL = time.Millisecond
+	if err := authorize(800); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// 有効期限が切れた与信の分は利用額から戻る
+	s.AuthorizationTTL = DefaultAuthorizationTTL
+	if err := authorize(1000); err != nil {
+		t.Fatalf("Failed. Expired authorization should not count: %v\n", err)
+	}
+	if err := authorize(1); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Failed. Expected:%v but %v\n", codes.ResourceExhausted, err)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultCancelLatency はキャンセルの処理にかかる時間のデフォルト値
+const DefaultCancelLatency = time.Second
+
+// 決済ごとのロック
+//
+// 決済IDのハッシュで分けたロックを使う。同じ決済のキャンセルは直列になり、
+// 別の決済のキャンセルは(たまたま同じロックにならない限り)並行して処理できる
+const paymentLockShards = 256
+
+type paymentLocks [paymentLockShards]sync.Mutex
+
+func paymentLockShard(paymentID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(paymentID))
+	return int(h.Sum32() % paymentLockShards)
+}
+
+// lockPayments は決済IDのロックを取り、解放する関数を返す
+// 複数の決済をロックするときは、デッドロックしないように番号の小さいロックから取る
+func (s *Server) lockPayments(paymentIDs ...string) func() {
+	seen := make(map[int]bool, len(paymentIDs))
+	shards := make([]int, 0, len(paymentIDs))
+	for _, id := range paymentIDs {
+		shard := paymentLockShard(id)
+		if !seen[shard] {
+			seen[shard] = true
+			shards = append(shards, shard)
+		}
+	}
+	sort.Ints(shards)
+
+	for _, shard := range shards {
+		s.paymentLocks[shard].Lock()
+	}
+	return func() {
+		for i := len(shards) - 1; i >= 0; i-- {
+			s.paymentLocks[shards[i]].Unlock()
+		}
+	}
+}
+
+// waitCancelLatency はキャンセルの処理時間だけ待つ
+func (s *Server) waitCancelLatency(ctx context.Context) error {
+	if s.CancelLatency <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(s.CancelLatency):
+		return nil
+	case <-ctx.Done():
+		return status.Errorf(codes.Canceled, ctx.Err().Error())
+	}
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "payment/pb"
+)
+
+/*
+	テスト内容
+	・別の決済のキャンセルは並行して処理される
+	・決済・与信・キャプチャ・キャンセル・バルクキャンセル・結果取得を同時に実行してもデッドロックしない(-race で実行する)
+	・同じ決済を同時にキャンセルしてもWebhookは1回だけ
+*/
+func TestConcurrentCancel(t *testing.T) {
+	s, err := NewNetworkServer()
+	if err != nil {
+		t.Fatalf("failed to create new server:%s", err)
+	}
+	ctx := context.Background()
+
+	card, err := s.RegistCard(ctx, &pb.RegistCardRequest{CardInformation: &pb.CardInformation{
+		CardNumber: "12345674",
+		Cvv:        "123",
+		ExpiryDate: "11/99",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pay := func() (string, error) {
+		r, err := s.ExecutePayment(ctx, &pb.ExecutePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card.CardToken, Amount: 100}})
+		if err != nil {
+			return "", err
+		}
+		return r.PaymentId, nil
+	}
+	// deadline までに終わらなければデッドロックとみなす
+	run := func(deadline time.Duration, f func()) {
+		done := make(chan struct{})
+		go func() {
+			f()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(deadline):
+			t.Fatal("Failed. Deadlock")
+		}
+	}
+
+	t.Run("Cancels run concurrently", func(t *testing.T) {
+		s.CancelLatency = 200 * time.Millisecond
+		defer func() { s.CancelLatency = DefaultCancelLatency }()
+
+		ids := []string{}
+		for i := 0; i < 10; i++ {
+			id, err := pay()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+
+		start := time.Now()
+		run(5*time.Second, func() {
+			wg := sync.WaitGroup{}
+			for _, id := range ids {
+				wg.Add(1)
+				go func(id string) {
+					defer wg.Done()
+					if _, err := s.CancelPayment(ctx, &pb.CancelPaymentRequest{PaymentId: id}); err != nil {
+						t.Error(err)
+					}
+				}(id)
+			}
+			wg.Wait()
+		})
+		// 直列なら2秒かかる
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("Failed. Cancels are serialized: %s\n", elapsed)
+		}
+	})
+
+	t.Run("Mixed load", func(t *testing.T) {
+		s.CancelLatency = time.Millisecond
+		defer func() { s.CancelLatency = DefaultCancelLatency }()
+
+		var mu sync.Mutex
+		ids := []string{}
+		pick := func(r *rand.Rand, n int) []string {
+			mu.Lock()
+			defer mu.Unlock()
+			picked := []string{}
+			for i := 0; i < n && len(ids) > 0; i++ {
+				picked = append(picked, ids[r.Intn(len(ids))])
+			}
+			return picked
+		}
+
+		run(30*time.Second, func() {
+			wg := sync.WaitGroup{}
+			for w := 0; w < 16; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					r := rand.New(rand.NewSource(int64(w)))
+					for i := 0; i < 100; i++ {
+						switch r.Intn(6) {
+						case 0, 1:
+							id, err := pay()
+							if err != nil {
+								t.Error(err)
+								return
+							}
+							mu.Lock()
+							ids = append(ids, id)
+							mu.Unlock()
+						case 2:
+							a, err := s.AuthorizePayment(ctx, &pb.AuthorizePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card.CardToken, Amount: 100}})
+							if err != nil {
+								t.Error(err)
+								return
+							}
+							s.CapturePayment(ctx, &pb.CapturePaymentRequest{PaymentId: a.PaymentId})
+							mu.Lock()
+							ids = append(ids, a.PaymentId)
+							mu.Unlock()
+						case 3:
+							for _, id := range pick(r, 1) {
+								s.CancelPayment(ctx, &pb.CancelPaymentRequest{PaymentId: id})
+							}
+						case 4:
+							s.BulkCancelPayment(ctx, &pb.BulkCancelPaymentRequest{PaymentId: pick(r, 5)})
+						case 5:
+							if _, err := s.GetResult(ctx, &pb.GetResultRequest{PageSize: 50}); err != nil {
+								t.Error(err)
+								return
+							}
+						}
+					}
+				}(w)
+			}
+			wg.Wait()
+		})
+	})
+
+	t.Run("Same payment", func(t *testing.T) {
+		s.CancelLatency = 10 * time.Millisecond
+		defer func() { s.CancelLatency = DefaultCancelLatency }()
+
+		var events int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&events, 1)
+		}))
+		defer ts.Close()
+		hook, err := s.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{Url: ts.URL, Events: []string{WebhookEventRefundCreated}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.DeleteWebhook(ctx, &pb.DeleteWebhookRequest{WebhookId: hook.WebhookId})
+
+		id, err := pay()
+		if err != nil {
+			t.Fatal(err)
+		}
+		canceled := 0
+		var mu sync.Mutex
+		run(5*time.Second, func() {
+			wg := sync.WaitGroup{}
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					var err error
+					if i%2 == 0 {
+						_, err = s.CancelPayment(ctx, &pb.CancelPaymentRequest{PaymentId: id})
+					} else {
+						_, err = s.BulkCancelPayment(ctx, &pb.BulkCancelPaymentRequest{PaymentId: []string{id, fmt.Sprint("unknown", i)}})
+					}
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					mu.Lock()
+					canceled++
+					mu.Unlock()
+				}(i)
+			}
+			wg.Wait()
+		})
+		if canceled != 10 {
+			t.Fatalf("Failed. Expected:10 but %d\n", canceled)
+		}
+		s.webhooks.wg.Wait()
+		if n := atomic.LoadInt32(&events); n != 1 {
+			t.Fatalf("Failed. Expected:1 webhook but %d\n", n)
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"payment/config"
+	pb "payment/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 加盟店(マルチテナント)
+//
+// 加盟店は gRPC のメタデータ x-merchant-key (HTTPでは X-Merchant-Key ヘッダ) のAPIキーで識別する。
+// カード・決済・Webhookは加盟店ごとに分かれていて、他の加盟店のものは存在しないものとして扱う。
+// APIキーが無いリクエストは、RequireMerchantKey が false なら DefaultMerchantID の加盟店として扱う
+const (
+	MerchantKeyHeader = "x-merchant-key"
+	DefaultMerchantID = "default"
+)
+
+// Merchant は加盟店とAPIキー
+type Merchant struct {
+	ID             string
+	APIKey         string          // 全てのAPIに使える
+	PublishableKey string          // カードの登録(RegistCard)にだけ使える。ブラウザに渡してよい
+	Limits         []SpendingLimit // 空なら全加盟店共通の上限
+}
+
+// MerchantsFromConfig は設定ファイルの加盟店の設定を変換する
+func MerchantsFromConfig(c []config.Merchant) ([]Merchant, error) {
+	merchants := make([]Merchant, 0, len(c))
+	for _, m := range c {
+		limits, err := SpendingLimitsFromConfig(m.Limits)
+		if err != nil {
+			return nil, err
+		}
+		merchants = append(merchants, Merchant{ID: m.ID, APIKey: m.APIKey, PublishableKey: m.PublishableKey, Limits: limits})
+	}
+	return merchants, nil
+}
+
+type merchantKey struct {
+	merchantID  string
+	publishable bool
+}
+
+// merchantStore は加盟店ごとのカード・決済の情報。s.mu で守る
+type merchantStore struct {
+	merchantID string
+	PayInfoMap map[string]pb.PaymentInformation
+	// 決済IDを記録した順に並べたもの(GetResultのページ分割に使う)
+	paymentOrder []string
+	CardInfoMap  map[string]storedCard
+	// カードトークンの有効期限・使用回数・失効など
+	CardTokenMap map[string]CardToken
+	// 日付・通貨・カードごとの利用額と、決済ごとに数えた利用額
+	spend   map[spendKey]int64
+	spentOn map[string]spendEntry
+	// 利用額に数えている与信(キャプチャ前)の有効期限
+	authorizationExpiry map[string]time.Time
+}
+
+func newMerchantStore(merchantID string) *merchantStore {
+	return &merchantStore{
+		merchantID:   merchantID,
+		PayInfoMap:   make(map[string]pb.PaymentInformation),
+		CardInfoMap:  make(map[string]storedCard),
+		CardTokenMap: make(map[string]CardToken),
+		spend:        make(map[spendKey]int64),
+		spentOn:      make(map[string]spendEntry),
+
+		authorizationExpiry: make(map[string]time.Time),
+	}
+}
+
+// SetMerchants は加盟店のAPIキーを設定する
+func (s *Server) SetMerchants(merchants []Merchant) error {
+	keys := make(map[string]merchantKey, len(merchants)*2)
+	ids := make(map[string]bool, len(merchants))
+	limits := make(map[string][]SpendingLimit, len(merchants))
+	for _, m := range merchants {
+		if m.ID == "" || m.APIKey == "" {
+			return status.Errorf(codes.InvalidArgument, "Invalid Merchant. ID and APIKey are required")
+		}
+		if ids[m.ID] {
+			return status.Errorf(codes.InvalidArgument, "Duplicate Merchant ID: %s", m.ID)
+		}
+		ids[m.ID] = true
+		if err := validateSpendingLimits(m.Limits); err != nil {
+			return err
+		}
+		if len(m.Limits) > 0 {
+			limits[m.ID] = m.Limits
+		}
+		for _, k := range []merchantKey{{m.ID, false}, {m.ID, true}} {
+			key := m.APIKey
+			if k.publishable {
+				key = m.PublishableKey
+				if key == "" {
+					continue
+				}
+			}
+			if _, ok := keys[key]; ok {
+				return status.Errorf(codes.InvalidArgument, "Duplicate Merchant Key: %s", m.ID)
+			}
+			keys[key] = k
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.merchantKeys = keys
+	s.merchantLimits = limits
+	return nil
+}
+
+// merchantID はリクエストのAPIキーから加盟店を決める
+// allowPublishable が false のAPIには、公開用のキーは使えない
+func (s *Server) merchantID(ctx context.Context, allowPublishable bool) (string, error) {
+	var key string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(MerchantKeyHeader); len(v) > 0 {
+			key = v[0]
+		}
+	}
+	if key == "" {
+		if s.RequireMerchantKey {
+			return "", status.Errorf(codes.Unauthenticated, "Merchant Key Required")
+		}
+		return DefaultMerchantID, nil
+	}
+
+	s.mu.RLock()
+	k, ok := s.merchantKeys[key]
+	s.mu.RUnlock()
+	if !ok {
+		return "", status.Errorf(codes.Unauthenticated, "Invalid Merchant Key")
+	}
+	if k.publishable && !allowPublishable {
+		return "", status.Errorf(codes.PermissionDenied, "Publishable Key Not Allowed")
+	}
+	return k.merchantID, nil
+}
+
+// merchant はリクエストの加盟店の情報を返す(無ければ作る)
+func (s *Server) merchant(ctx context.Context, allowPublishable bool) (*merchantStore, error) {
+	id, err := s.merchantID(ctx, allowPublishable)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	s.mu.RLock()
+	st, ok := s.merchants[id]
+	s.mu.RUnlock()
+	if ok {
+		return st, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.merchants[id]; ok {
+		return st, nil
+	}
+	st = newMerchantStore(id)
+	s.merchants[id] = st
+	return st, nil
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	pb "payment/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+/*
+	テスト内容
+	・他の加盟店のカード・決済は存在しないものとして扱われる
+	・初期化・結果取得は加盟店ごと
+	・不正なAPIキーは拒否される(APIキー必須の設定ではAPIキー無しも拒否される)
+	・公開用のキーはカードの登録にだけ使える
+	・Webhookは登録した加盟店のイベントだけが届き、他の加盟店からは削除できない
+	・X-Merchant-Key ヘッダがメタデータとして渡る
+*/
+func TestMerchant(t *testing.T) {
+	s, err := NewNetworkServer()
+	if err != nil {
+		t.Fatalf("failed to create new server:%s", err)
+	}
+	s.CancelLatency = 0
+	err = s.SetMerchants([]Merchant{
+		{ID: "shop-a", APIKey: "sk_a", PublishableKey: "pk_a"},
+		{ID: "shop-b", APIKey: "sk_b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withKey := func(key string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(MerchantKeyHeader, key))
+	}
+	ctxA, ctxB := withKey("sk_a"), withKey("sk_b")
+
+	regist := func(ctx context.Context) (*pb.RegistCardResponse, error) {
+		return s.RegistCard(ctx, &pb.RegistCardRequest{CardInformation: &pb.CardInformation{
+			CardNumber: "12345674",
+			Cvv:        "123",
+			ExpiryDate: "11/99",
+		}})
+	}
+	pay := func(ctx context.Context, token string) (string, error) {
+		r, err := s.ExecutePayment(ctx, &pb.ExecutePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: token, Amount: 100}})
+		if err != nil {
+			return "", err
+		}
+		return r.PaymentId, nil
+	}
+	results := func(ctx context.Context) int {
+		r, err := s.GetResult(ctx, &pb.GetResultRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(r.RawData)
+	}
+
+	cardA, err := regist(ctxA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paymentA, err := pay(ctxA, cardA.CardToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Isolation", func(t *testing.T) {
+		if _, err := pay(ctxB, cardA.CardToken); status.Code(err) != codes.NotFound {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.NotFound, err)
+		}
+		if _, err := s.GetPaymentInformation(ctxB, &pb.GetPaymentInformationRequest{PaymentId: paymentA}); status.Code(err) != codes.NotFound {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.NotFound, err)
+		}
+		if _, err := s.CancelPayment(ctxB, &pb.CancelPaymentRequest{PaymentId: paymentA}); status.Code(err) != codes.NotFound {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.NotFound, err)
+		}
+		if _, err := s.RevokeCardToken(ctxB, &pb.RevokeCardTokenRequest{CardToken: cardA.CardToken}); status.Code(err) != codes.NotFound {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.NotFound, err)
+		}
+		r, err := s.BulkCancelPayment(ctxB, &pb.BulkCancelPaymentRequest{PaymentId: []string{paymentA}})
+		if err != nil || r.Deleted != 0 {
+			t.Fatalf("Failed. Expected:0 but %v %v\n", r, err)
+		}
+		// APIキーが無ければデフォルトの加盟店
+		if _, err := pay(context.Background(), cardA.CardToken); status.Code(err) != codes.NotFound {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.NotFound, err)
+		}
+
+		info, err := s.GetPaymentInformation(ctxA, &pb.GetPaymentInformationRequest{PaymentId: paymentA})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.PaymentInformation.IsCanceled {
+			t.Fatal("Failed. Canceled by another merchant")
+		}
+	})
+
+	t.Run("Initialize and GetResult", func(t *testing.T) {
+		cardB, err := regist(ctxB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pay(ctxB, cardB.CardToken); err != nil {
+			t.Fatal(err)
+		}
+		if n := results(ctxA); n != 1 {
+			t.Fatalf("Failed. Expected:1 but %d\n", n)
+		}
+		if n := results(ctxB); n != 1 {
+			t.Fatalf("Failed. Expected:1 but %d\n", n)
+		}
+
+		if _, err := s.Initialize(ctxB, &pb.InitializeRequest{}); err != nil {
+			t.Fatal(err)
+		}
+		if n := results(ctxB); n != 0 {
+			t.Fatalf("Failed. Expected:0 but %d\n", n)
+		}
+		if n := results(ctxA); n != 1 {
+			t.Fatalf("Failed. Expected:1 but %d\n", n)
+		}
+	})
+
+	t.Run("Merchant key", func(t *testing.T) {
+		if _, err := regist(withKey("sk_unknown")); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.Unauthenticated, err)
+		}
+
+		// 公開用のキーで登録したカードは、APIキーで決済できる
+		card, err := regist(withKey("pk_a"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pay(withKey("pk_a"), card.CardToken); status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.PermissionDenied, err)
+		}
+		if _, err := s.GetResult(withKey("pk_a"), &pb.GetResultRequest{}); status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.PermissionDenied, err)
+		}
+		if _, err := pay(ctxA, card.CardToken); err != nil {
+			t.Fatal(err)
+		}
+
+		s.RequireMerchantKey = true
+		defer func() { s.RequireMerchantKey = false }()
+		if _, err := regist(context.Background()); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.Unauthenticated, err)
+		}
+		if _, err := regist(ctxA); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("Invalid merchants", func(t *testing.T) {
+		for _, merchants := range [][]Merchant{
+			{{ID: "", APIKey: "sk"}},
+			{{ID: "shop", APIKey: ""}},
+			{{ID: "shop", APIKey: "sk"}, {ID: "shop", APIKey: "sk2"}},
+			{{ID: "shop", APIKey: "sk"}, {ID: "shop2", APIKey: "sk"}},
+			{{ID: "shop", APIKey: "sk", PublishableKey: "sk"}},
+		} {
+			if err := s.SetMerchants(merchants); status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("Failed. %v Expected:%v but %v\n", merchants, codes.InvalidArgument, err)
+			}
+		}
+	})
+
+	t.Run("Webhook", func(t *testing.T) {
+		var eventsA, eventsB int32
+		tsA := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&eventsA, 1)
+		}))
+		defer tsA.Close()
+		tsB := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&eventsB, 1)
+		}))
+		defer tsB.Close()
+
+		hookA, err := s.RegisterWebhook(ctxA, &pb.RegisterWebhookRequest{Url: tsA.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.DeleteWebhook(ctxA, &pb.DeleteWebhookRequest{WebhookId: hookA.WebhookId})
+		hookB, err := s.RegisterWebhook(ctxB, &pb.RegisterWebhookRequest{Url: tsB.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.DeleteWebhook(ctxB, &pb.DeleteWebhookRequest{WebhookId: hookB.WebhookId})
+
+		// 同じURLでも加盟店が違えば別のWebhook
+		hookB2, err := s.RegisterWebhook(ctxB, &pb.RegisterWebhookRequest{Url: tsA.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hookB2.WebhookId == hookA.WebhookId {
+			t.Fatal("Failed. Webhook shared between merchants")
+		}
+		if _, err := s.DeleteWebhook(ctxA, &pb.DeleteWebhookRequest{WebhookId: hookB2.WebhookId}); status.Code(err) != codes.NotFound {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.NotFound, err)
+		}
+		if _, err := s.DeleteWebhook(ctxB, &pb.DeleteWebhookRequest{WebhookId: hookB2.WebhookId}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := pay(ctxA, cardA.CardToken); err != nil {
+			t.Fatal(err)
+		}
+		s.webhooks.wg.Wait()
+		if a, b := atomic.LoadInt32(&eventsA), atomic.LoadInt32(&eventsB); a != 1 || b != 0 {
+			t.Fatalf("Failed. Expected:1 0 but %d %d\n", a, b)
+		}
+	})
+
+	t.Run("Header matcher", func(t *testing.T) {
+		for _, key := range []string{"X-Merchant-Key", "x-merchant-key"} {
+			if got, ok := merchantHeaderMatcher(key); !ok || got != MerchantKeyHeader {
+				t.Fatalf("Failed. %s Expected:%s but %s\n", key, MerchantKeyHeader, got)
+			}
+		}
+		if _, ok := merchantHeaderMatcher("X-Unknown"); ok {
+			t.Fatal("Failed. X-Unknown should not be passed")
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	pb "payment/pb"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ベンチマーカー用結果取得
+//
+// リクエストした加盟店の決済を記録した順(paymentOrder)に返す。カーソルはその位置なので、
+// ページを取得する間に決済が増えても、取得済みの決済が重複したり漏れたりしない。
+// 一度に読む件数を resultScanSize に抑え、読むたびにロックを外す
+
+const resultScanSize = 1000
+
+type resultFilter struct {
+	since    time.Time
+	until    time.Time
+	canceled pb.CanceledFilter
+}
+
+func newResultFilter(req *pb.GetResultRequest) (resultFilter, error) {
+	f := resultFilter{canceled: req.Canceled}
+	if req.Since != nil {
+		t, err := ptypes.Timestamp(req.Since)
+		if err != nil {
+			return f, status.Errorf(codes.InvalidArgument, "Invalid Since")
+		}
+		f.since = t
+	}
+	if req.Until != nil {
+		t, err := ptypes.Timestamp(req.Until)
+		if err != nil {
+			return f, status.Errorf(codes.InvalidArgument, "Invalid Until")
+		}
+		f.until = t
+	}
+	if !f.since.IsZero() && !f.until.IsZero() && !f.since.Before(f.until) {
+		return f, status.Errorf(codes.InvalidArgument, "Since must be before Until")
+	}
+	if _, ok := pb.CanceledFilter_name[int32(f.canceled)]; !ok {
+		return f, status.Errorf(codes.InvalidArgument, "Invalid Canceled Filter")
+	}
+	return f, nil
+}
+
+func (f resultFilter) match(v *pb.PaymentInformation) bool {
+	switch f.canceled {
+	case pb.CanceledFilter_CANCELED_ONLY:
+		if !v.IsCanceled {
+			return false
+		}
+	case pb.CanceledFilter_NOT_CANCELED:
+		if v.IsCanceled {
+			return false
+		}
+	}
+	if f.since.IsZero() && f.until.IsZero() {
+		return true
+	}
+	t, err := ptypes.Timestamp(v.Datetime)
+	if err != nil {
+		return false
+	}
+	if !f.since.IsZero() && t.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && !t.Before(f.until) {
+		return false
+	}
+	return true
+}
+
+func parseResultCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(cursor)
+	if err != nil || offset < 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "Invalid Cursor")
+	}
+	return offset, nil
+}
+
+// scanResults は offset から最大 resultScanSize 件の決済を読み、条件に合うものを最大 limit 件(0なら制限なし)返す
+// next は次に読む位置、end は最後まで読んだかどうか
+func (s *Server) scanResults(st *merchantStore, offset, limit int, f resultFilter, now time.Time) (raw []*pb.RawData, next int, end bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	last := offset + resultScanSize
+	if last > len(st.paymentOrder) {
+		last = len(st.paymentOrder)
+	}
+	for next = offset; next < last && (limit == 0 || len(raw) < limit); next++ {
+		id := st.paymentOrder[next]
+		v := st.PayInfoMap[id]
+		expireAuthorization(&v, now)
+		if !f.match(&v) {
+			continue
+		}
+
+		card := st.CardInfoMap[v.CardToken]
+		//カード番号はマスクして、CVV・有効期限は返さない
+		raw = append(raw, &pb.RawData{
+			PaymentId: id,
+			PaymentInformation: &pb.PaymentInformation{
+				CardToken:              v.CardToken,
+				ReservationId:          v.ReservationId,
+				Datetime:               v.Datetime,
+				Amount:                 v.Amount,
+				IsCanceled:             v.IsCanceled,
+				State:                  v.State,
+				AuthorizationExpiresAt: v.AuthorizationExpiresAt,
+				CardReference:          v.CardReference,
+				Currency:               v.Currency,
+				DeclineReason:          v.DeclineReason,
+				CanceledAt:             v.CanceledAt,
+			},
+			CardInformation: card.Info(),
+		})
+	}
+	return raw, next, next >= len(st.paymentOrder)
+}
+
+// rangeResults はリクエストの条件に合う決済を順に fn に渡し、続きのカーソルを返す
+func (s *Server) rangeResults(ctx context.Context, req *pb.GetResultRequest, fn func([]*pb.RawData) error) (string, error) {
+	st, err := s.merchant(ctx, false)
+	if err != nil {
+		return "", err
+	}
+	s.mu.RLock()
+	log.Printf("Merchant: %s\n", st.merchantID)
+	log.Printf("Card count: %d\n", len(st.CardInfoMap))
+	log.Printf("Payment count: %d\n", len(st.PayInfoMap))
+	s.mu.RUnlock()
+
+	f, err := newResultFilter(req)
+	if err != nil {
+		return "", err
+	}
+	offset, err := parseResultCursor(req.Cursor)
+	if err != nil {
+		return "", err
+	}
+	if req.PageSize < 0 {
+		return "", status.Errorf(codes.InvalidArgument, "Invalid Page Size")
+	}
+
+	now := time.Now()
+	limit := int(req.PageSize)
+	count := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", status.Errorf(codes.Canceled, err.Error())
+		}
+
+		rest := 0
+		if limit > 0 {
+			rest = limit - count
+		}
+		raw, next, end := s.scanResults(st, offset, rest, f, now)
+		offset = next
+		count += len(raw)
+		if len(raw) > 0 {
+			if err := fn(raw); err != nil {
+				return "", err
+			}
+		}
+		if end {
+			return "", nil
+		}
+		if limit > 0 && count >= limit {
+			return strconv.Itoa(offset), nil
+		}
+	}
+}
+
+//ベンチマーカー用結果取得API
+func (s *Server) GetResult(ctx context.Context, req *pb.GetResultRequest) (*pb.GetResultResponse, error) {
+	raw := []*pb.RawData{}
+	cursor, err := s.rangeResults(ctx, req, func(r []*pb.RawData) error {
+		raw = append(raw, r...)
+		return nil
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return &pb.GetResultResponse{IsOk: false}, err
+	}
+
+	return &pb.GetResultResponse{RawData: raw, NextCursor: cursor, IsOk: true}, nil
+}
+
+//ベンチマーカー用結果取得API(ストリーミング)
+func (s *Server) StreamResults(req *pb.GetResultRequest, stream pb.PaymentService_StreamResultsServer) error {
+	_, err := s.rangeResults(stream.Context(), req, func(r []*pb.RawData) error {
+		for _, rawData := range r {
+			if err := stream.Send(rawData); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println(err.Error())
+	}
+	return err
+}
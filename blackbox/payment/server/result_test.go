@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "payment/pb"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeResultStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*pb.RawData
+}
+
+func (f *fakeResultStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeResultStream) Send(r *pb.RawData) error {
+	f.sent = append(f.sent, r)
+	return nil
+}
+
+/*
+	テスト内容
+	・ページに分けて全件を記録した順に取得できる(ページの間に増えた決済も漏れない)
+	・キャンセル状態・決済日時で絞り込める
+	・ストリーミングで全件を取得できる
+	・不正なカーソル・期間はエラーになる
+*/
+func TestResult(t *testing.T) {
+	s, err := NewNetworkServer()
+	if err != nil {
+		t.Fatalf("failed to create new server:%s", err)
+	}
+	ctx := context.Background()
+
+	card, err := s.RegistCard(ctx, &pb.RegistCardRequest{CardInformation: &pb.CardInformation{
+		CardNumber: "12345674",
+		Cvv:        "123",
+		ExpiryDate: "11/99",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pay := func(reservationID int32) string {
+		r, err := s.ExecutePayment(ctx, &pb.ExecutePaymentRequest{PaymentInformation: &pb.PaymentInformation{
+			CardToken:     card.CardToken,
+			ReservationId: reservationID,
+			Amount:        100,
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.PaymentId
+	}
+
+	n := resultScanSize + 500
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		ids = append(ids, pay(int32(i)))
+	}
+	// 偶数番目をキャンセルする
+	cancel := []string{}
+	for i := 0; i < n; i += 2 {
+		cancel = append(cancel, ids[i])
+	}
+	if _, err := s.BulkCancelPayment(ctx, &pb.BulkCancelPaymentRequest{PaymentId: cancel}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Pagination", func(t *testing.T) {
+		got := []string{}
+		cursor := ""
+		for page := 0; ; page++ {
+			r, err := s.GetResult(ctx, &pb.GetResultRequest{Cursor: cursor, PageSize: 700})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range r.RawData {
+				got = append(got, v.PaymentId)
+			}
+			if page == 0 {
+				// ページの間に増えた決済も最後のページで取得できる
+				ids = append(ids, pay(int32(n)))
+			}
+			if r.NextCursor == "" {
+				break
+			}
+			cursor = r.NextCursor
+		}
+		if len(got) != len(ids) {
+			t.Fatalf("Failed. Expected:%d but %d\n", len(ids), len(got))
+		}
+		for i := range ids {
+			if got[i] != ids[i] {
+				t.Fatalf("Failed. %d Expected:%s but %s\n", i, ids[i], got[i])
+			}
+		}
+	})
+
+	t.Run("Canceled filter", func(t *testing.T) {
+		r, err := s.GetResult(ctx, &pb.GetResultRequest{Canceled: pb.CanceledFilter_CANCELED_ONLY})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.RawData) != len(cancel) {
+			t.Fatalf("Failed. Expected:%d but %d\n", len(cancel), len(r.RawData))
+		}
+		r, err = s.GetResult(ctx, &pb.GetResultRequest{Canceled: pb.CanceledFilter_NOT_CANCELED, PageSize: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range r.RawData {
+			if v.PaymentInformation.IsCanceled {
+				t.Fatalf("Failed. %s is canceled\n", v.PaymentId)
+			}
+		}
+		if len(r.RawData) != 10 || r.NextCursor == "" {
+			t.Fatalf("Failed. Expected:10 with cursor but %d %q\n", len(r.RawData), r.NextCursor)
+		}
+	})
+
+	t.Run("Time range", func(t *testing.T) {
+		time.Sleep(10 * time.Millisecond)
+		since, _ := ptypes.TimestampProto(time.Now())
+		id := pay(-1)
+
+		r, err := s.GetResult(ctx, &pb.GetResultRequest{Since: since})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.RawData) != 1 || r.RawData[0].PaymentId != id {
+			t.Fatalf("Failed. Expected:%s but %v\n", id, r.RawData)
+		}
+		r, err = s.GetResult(ctx, &pb.GetResultRequest{Until: since})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.RawData) != len(ids) {
+			t.Fatalf("Failed. Expected:%d but %d\n", len(ids), len(r.RawData))
+		}
+		ids = append(ids, id)
+	})
+
+	t.Run("StreamResults", func(t *testing.T) {
+		stream := &fakeResultStream{ctx: ctx}
+		if err := s.StreamResults(&pb.GetResultRequest{}, stream); err != nil {
+			t.Fatal(err)
+		}
+		if len(stream.sent) != len(ids) {
+			t.Fatalf("Failed. Expected:%d but %d\n", len(ids), len(stream.sent))
+		}
+
+		canceled, cancelFunc := context.WithCancel(ctx)
+		cancelFunc()
+		err := s.StreamResults(&pb.GetResultRequest{}, &fakeResultStream{ctx: canceled})
+		if status.Code(err) != codes.Canceled {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.Canceled, err)
+		}
+	})
+
+	t.Run("Invalid request", func(t *testing.T) {
+		since, _ := ptypes.TimestampProto(time.Now())
+		until, _ := ptypes.TimestampProto(time.Now().Add(-time.Hour))
+		for _, req := range []*pb.GetResultRequest{
+			{Cursor: "hoge"},
+			{Cursor: "-1"},
+			{PageSize: -1},
+			{Since: since, Until: until},
+			{Canceled: 100},
+		} {
+			_, err := s.GetResult(ctx, req)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("Failed. %v Expected:%v but %v\n", req, codes.InvalidArgument, err)
+			}
+		}
+	})
+}
@@ -16,26 +16,55 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-var rawDataPool = sync.Pool{
-	New: func() interface{} {
-		return &pb.RawData{
-			PaymentInformation: &pb.PaymentInformation{},
-			CardInformation:    &pb.CardInformation{},
-		}
-	},
-}
-
 type Server struct {
-	PayInfoMap  map[string]pb.PaymentInformation
-	CardInfoMap map[string]pb.CardInformation
-	mu          sync.RWMutex
-	cancelLock  sync.RWMutex
+	// 加盟店ごとのカード・決済の情報
+	merchants map[string]*merchantStore
+	// APIキーと加盟店の対応
+	merchantKeys map[string]merchantKey
+	// APIキーの無いリクエストを拒否する(false なら DefaultMerchantID として扱う)
+	RequireMerchantKey bool
+	// 利用額の上限(加盟店ごとの設定と、設定の無い加盟店の上限)
+	merchantLimits map[string][]SpendingLimit
+	defaultLimits  []SpendingLimit
+	mu             sync.RWMutex
+	// 決済ごとのロック(キャンセルの直列化に使う)
+	paymentLocks paymentLocks
+	// キャンセルの処理にかかる時間(処理時間の模擬)
+	CancelLatency time.Duration
+	// 与信(AuthorizePayment)の有効期限。過ぎると自動で取り消される
+	AuthorizationTTL time.Duration
+	// カードトークンの有効期限の上限
+	CardTokenTTL time.Duration
+	// カード番号の暗号化の鍵
+	cards *cardVault
+	// 障害注入
+	faults *faultInjector
+	// 障害注入の設定の変更・取得に使う管理用のキー
+	AdminKey string
+	// Webhookの再送の設定
+	WebhookPolicy WebhookPolicy
+	// 登録されたWebhook(Initializeでは消えない)
+	webhooks *webhookRegistry
 }
 
 func NewNetworkServer() (*Server, error) {
+	cards, err := newRandomCardVault()
+	if err != nil {
+		return nil, err
+	}
 	ns := &Server{
-		PayInfoMap:  make(map[string]pb.PaymentInformation, 1000000),
-		CardInfoMap: make(map[string]pb.CardInformation, 1000000),
+		merchants:    map[string]*merchantStore{},
+		merchantKeys: map[string]merchantKey{},
+
+		AuthorizationTTL: DefaultAuthorizationTTL,
+		CardTokenTTL:     DefaultCardTokenTTL,
+		CancelLatency:    DefaultCancelLatency,
+
+		cards:  cards,
+		faults: newFaultInjector(),
+
+		WebhookPolicy: DefaultWebhookPolicy,
+		webhooks:      &webhookRegistry{hooks: map[string]Webhook{}},
 	}
 	return ns, nil
 }
@@ -45,18 +74,36 @@ func (s *Server) RegistCard(ctx context.Context, req *pb.RegistCardRequest) (*pb
 	done := make(chan *pb.RegistCardResponse, 1)
 	ec := make(chan error, 1)
 	go func() {
+		st, err := s.merchant(ctx, true)
+		if err != nil {
+			ec <- err
+			return
+		}
 		if req.CardInformation == nil {
 			log.Println("Invalid POST data. CardInformation is nil.")
 			ec <- status.Errorf(codes.InvalidArgument, "Invalid POST data")
 			return
 		}
-		err := s.ValidateCardInformation(req)
+		err = s.ValidateCardInformation(req)
 		if err != nil {
 			log.Println(err.Error())
 			ec <- status.Errorf(codes.InvalidArgument, err.Error())
 			return
 		}
 
+		cardToken, err := s.newCardToken(req, time.Now())
+		if err != nil {
+			log.Println(err.Error())
+			ec <- err
+			return
+		}
+		expiresAt, err := ptypes.TimestampProto(cardToken.ExpiresAt)
+		if err != nil {
+			log.Println(err.Error())
+			ec <- status.Errorf(codes.Internal, err.Error())
+			return
+		}
+
 		id, err := uuid.NewV4()
 		if err != nil {
 			log.Println(err.Error())
@@ -64,15 +111,20 @@ func (s *Server) RegistCard(ctx context.Context, req *pb.RegistCardRequest) (*pb
 			return
 		}
 
+		//CVVは保持せず、カード番号は暗号化して保持する
 		s.mu.Lock()
-		s.CardInfoMap[id.String()] = pb.CardInformation{
-			CardNumber: req.CardInformation.CardNumber,
-			Cvv:        req.CardInformation.Cvv,
-			ExpiryDate: req.CardInformation.ExpiryDate,
+		card, err := s.cards.seal(req.CardInformation)
+		if err != nil {
+			s.mu.Unlock()
+			log.Println(err.Error())
+			ec <- status.Errorf(codes.Internal, "Internal Error, Encrypt Card")
+			return
 		}
+		st.CardInfoMap[id.String()] = card
+		st.CardTokenMap[id.String()] = cardToken
 		s.mu.Unlock()
 
-		done <- &pb.RegistCardResponse{CardToken: id.String(), IsOk: true}
+		done <- &pb.RegistCardResponse{CardToken: id.String(), IsOk: true, Brand: cardToken.Brand, ExpiresAt: expiresAt}
 	}()
 	select {
 	case r := <-done:
@@ -87,39 +139,50 @@ func (s *Server) ExecutePayment(ctx context.Context, req *pb.ExecutePaymentReque
 	done := make(chan *pb.ExecutePaymentResponse, 1)
 	ec := make(chan error, 1)
 	go func() {
+		st, err := s.merchant(ctx, false)
+		if err != nil {
+			ec <- err
+			return
+		}
 		if req.PaymentInformation == nil {
 			log.Println("Invalid POST Data. PaymentInformation is nil.")
 			ec <- status.Errorf(codes.InvalidArgument, "Invalid POST data")
 			return
 		}
 
-		s.mu.RLock()
-		_, ok := s.CardInfoMap[req.PaymentInformation.CardToken]
-		s.mu.RUnlock()
-		if ok {
-			date, err := ptypes.TimestampProto(time.Now())
-			if err != nil {
-				log.Println(err.Error())
-				ec <- err
-				return
-			}
-			guid := xid.New()
-
-			s.mu.Lock()
-			s.PayInfoMap[guid.String()] = pb.PaymentInformation{
-				CardToken:     req.PaymentInformation.CardToken,
-				ReservationId: req.PaymentInformation.ReservationId,
-				Datetime:      date,
-				Amount:        req.PaymentInformation.Amount,
-				IsCanceled:    false,
-			}
-			s.mu.Unlock()
+		currency, err := validatePaymentAmount(req.PaymentInformation)
+		if err != nil {
+			ec <- err
+			return
+		}
+
+		now := time.Now()
+		date, err := ptypes.TimestampProto(now)
+		if err != nil {
+			log.Println(err.Error())
+			ec <- err
+			return
+		}
+		guid := xid.New()
 
-			done <- &pb.ExecutePaymentResponse{PaymentId: guid.String(), IsOk: true}
+		paydata := pb.PaymentInformation{
+			CardToken:     req.PaymentInformation.CardToken,
+			ReservationId: req.PaymentInformation.ReservationId,
+			Datetime:      date,
+			Amount:        req.PaymentInformation.Amount,
+			IsCanceled:    false,
+			CardReference: req.PaymentInformation.CardReference,
+			Currency:      currency.Code,
+		}
+		err = s.recordPayment(ctx, st, guid.String(), paydata, currency, now)
+		if err != nil {
+			log.Println(err.Error())
+			ec <- err
 			return
 		}
-		log.Println("Card_Token Not Found")
-		ec <- status.Errorf(codes.NotFound, "Card_Token Not Found")
+		s.publishWebhook(st.merchantID, WebhookEventPaymentSucceeded, guid.String(), paydata)
+
+		done <- &pb.ExecutePaymentResponse{PaymentId: guid.String(), IsOk: true}
 	}()
 	select {
 	case r := <-done:
@@ -130,73 +193,99 @@ func (s *Server) ExecutePayment(ctx context.Context, req *pb.ExecutePaymentReque
 }
 
 //決済をキャンセルする
+//同じ決済のキャンセルだけを直列にし、別の決済のキャンセルは並行して処理する
 func (s *Server) CancelPayment(ctx context.Context, req *pb.CancelPaymentRequest) (*pb.CancelPaymentResponse, error) {
-	done := make(chan struct{}, 1)
-	ec := make(chan error, 1)
-	s.cancelLock.Lock()
-	defer s.cancelLock.Unlock()
-	go func() {
-		s.mu.RLock()
-		paydata, ok := s.PayInfoMap[req.PaymentId]
-		s.mu.RUnlock()
-		time.Sleep(1 * time.Second)
-		if ok {
-			s.mu.Lock()
-			paydata.IsCanceled = true
-			s.PayInfoMap[req.PaymentId] = paydata
-			s.mu.Unlock()
-			done <- struct{}{}
-			return
-		}
+	st, err := s.merchant(ctx, false)
+	if err != nil {
+		return &pb.CancelPaymentResponse{IsOk: false}, err
+	}
 
+	unlock := s.lockPayments(req.PaymentId)
+	defer unlock()
+
+	s.mu.RLock()
+	paydata, ok := st.PayInfoMap[req.PaymentId]
+	s.mu.RUnlock()
+	if !ok {
 		log.Println("PaymentID Not Found")
-		ec <- status.Errorf(codes.NotFound, "PaymentID Not Found")
-	}()
-	select {
-	case <-done:
-		return &pb.CancelPaymentResponse{IsOk: true}, nil
-	case err := <-ec:
+		return &pb.CancelPaymentResponse{IsOk: false}, status.Errorf(codes.NotFound, "PaymentID Not Found")
+	}
+	if paydata.State == pb.PaymentState_DECLINED {
+		log.Println("Payment Declined")
+		return &pb.CancelPaymentResponse{IsOk: false}, status.Errorf(codes.FailedPrecondition, "Payment Declined")
+	}
+
+	if err := s.waitCancelLatency(ctx); err != nil {
+		log.Println(err.Error())
 		return &pb.CancelPaymentResponse{IsOk: false}, err
 	}
+
+	// 待っている間にキャプチャなどで変わっているかもしれないので読み直す
+	s.mu.Lock()
+	paydata = st.PayInfoMap[req.PaymentId]
+	canceled := paydata.IsCanceled
+	paydata.IsCanceled = true
+	if !canceled {
+		paydata.CanceledAt = ptypes.TimestampNow()
+	}
+	st.PayInfoMap[req.PaymentId] = paydata
+	st.releaseSpend(req.PaymentId)
+	s.mu.Unlock()
+	if !canceled {
+		s.publishCancelWebhook(st.merchantID, req.PaymentId, paydata)
+	}
+
+	return &pb.CancelPaymentResponse{IsOk: true}, nil
 }
 
 //バルクで決済をキャンセルする
 func (s *Server) BulkCancelPayment(ctx context.Context, req *pb.BulkCancelPaymentRequest) (*pb.BulkCancelPaymentResponse, error) {
-	done := make(chan int32, 1)
-	ec := make(chan int32, 1)
-	s.cancelLock.Lock()
-	defer s.cancelLock.Unlock()
-	go func() {
-		s.mu.Lock()
-		if len(req.PaymentId) < 1 {
-			ec <- 0
-			return
-		}
+	st, err := s.merchant(ctx, false)
+	if err != nil {
+		return &pb.BulkCancelPaymentResponse{Deleted: 0}, err
+	}
+	if len(req.PaymentId) < 1 {
+		return &pb.BulkCancelPaymentResponse{Deleted: 0}, nil
+	}
 
-		var i int32
-		for _, v := range req.PaymentId {
-			paydata, ok := s.PayInfoMap[v]
-			if ok {
-				paydata.IsCanceled = true
-				s.PayInfoMap[v] = paydata
-			} else {
-				i--
-			}
-			i++
+	unlock := s.lockPayments(req.PaymentId...)
+	defer unlock()
+
+	if err := s.waitCancelLatency(ctx); err != nil {
+		log.Println(err.Error())
+		return &pb.BulkCancelPaymentResponse{Deleted: 0}, err
+	}
+
+	type canceledPayment struct {
+		id      string
+		paydata pb.PaymentInformation
+	}
+	var i int32
+	newlyCanceled := []canceledPayment{}
+	canceledAt := ptypes.TimestampNow()
+	s.mu.Lock()
+	for _, v := range req.PaymentId {
+		paydata, ok := st.PayInfoMap[v]
+		if !ok || paydata.State == pb.PaymentState_DECLINED {
+			continue
 		}
-		done <- i
-	}()
-	select {
-	case num := <-done:
-		s.mu.Unlock()
-		time.Sleep(time.Second * 1)
-		return &pb.BulkCancelPaymentResponse{Deleted: num}, nil
-	case num := <-ec:
-		s.mu.Unlock()
-		time.Sleep(time.Second * 1)
-		return &pb.BulkCancelPaymentResponse{Deleted: num}, nil
+		i++
+		if paydata.IsCanceled {
+			continue
+		}
+		paydata.IsCanceled = true
+		paydata.CanceledAt = canceledAt
+		st.PayInfoMap[v] = paydata
+		st.releaseSpend(v)
+		newlyCanceled = append(newlyCanceled, canceledPayment{v, paydata})
 	}
-	return nil, nil
+	s.mu.Unlock()
+
+	for _, c := range newlyCanceled {
+		s.publishCancelWebhook(st.merchantID, c.id, c.paydata)
+	}
+
+	return &pb.BulkCancelPaymentResponse{Deleted: i}, nil
 }
 
 //決済情報を取得する
@@ -204,10 +293,16 @@ func (s *Server) GetPaymentInformation(ctx context.Context, req *pb.GetPaymentIn
 	done := make(chan *pb.GetPaymentInformationResponse, 1)
 	ec := make(chan error, 1)
 	go func() {
+		st, err := s.merchant(ctx, false)
+		if err != nil {
+			ec <- err
+			return
+		}
 		s.mu.RLock()
-		id, ok := s.PayInfoMap[req.PaymentId]
+		id, ok := st.PayInfoMap[req.PaymentId]
 		s.mu.RUnlock()
 		if ok {
+			expireAuthorization(&id, time.Now())
 			done <- &pb.GetPaymentInformationResponse{PaymentInformation: &id, IsOk: true}
 			return
 		}
@@ -223,16 +318,18 @@ func (s *Server) GetPaymentInformation(ctx context.Context, req *pb.GetPaymentIn
 	}
 }
 
-//メモリ初期化
+//メモリ初期化(リクエストした加盟店の分だけ)
 func (s *Server) Initialize(ctx context.Context, req *pb.InitializeRequest) (*pb.InitializeResponse, error) {
 	done := make(chan struct{}, 1)
 	ec := make(chan error, 1)
 	go func() {
+		st, err := s.merchant(ctx, false)
+		if err != nil {
+			ec <- err
+			return
+		}
 		s.mu.Lock()
-		s.PayInfoMap = nil
-		s.CardInfoMap = nil
-		s.PayInfoMap = make(map[string]pb.PaymentInformation, 1000000)
-		s.CardInfoMap = make(map[string]pb.CardInformation, 1000000)
+		*st = *newMerchantStore(st.merchantID)
 		s.mu.Unlock()
 		done <- struct{}{}
 	}()
@@ -243,55 +340,3 @@ func (s *Server) Initialize(ctx context.Context, req *pb.InitializeRequest) (*pb
 		return &pb.InitializeResponse{IsOk: false}, err
 	}
 }
-
-//ベンチマーカー用結果取得API
-func (s *Server) GetResult(ctx context.Context, req *pb.GetResultRequest) (*pb.GetResultResponse, error) {
-	done := make(chan *pb.GetResultResponse, 1)
-	ec := make(chan error, 1)
-	go func() {
-		log.Printf("Card count: %d\n", len(s.CardInfoMap))
-		log.Printf("Payment count: %d\n", len(s.PayInfoMap))
-
-		raw := []*pb.RawData{}
-		s.mu.RLock()
-		for _, v := range s.PayInfoMap {
-			rawData := getRawData()
-			defer putRawData(rawData)
-
-			t := v.CardToken
-			rawData.PaymentInformation.CardToken = t
-			rawData.PaymentInformation.ReservationId = v.ReservationId
-			rawData.PaymentInformation.Datetime = v.Datetime
-			rawData.PaymentInformation.Amount = v.Amount
-			rawData.PaymentInformation.IsCanceled = v.IsCanceled
-
-			rawData.CardInformation.CardNumber = s.CardInfoMap[t].CardNumber
-			rawData.CardInformation.Cvv = s.CardInfoMap[t].Cvv
-			rawData.CardInformation.ExpiryDate = s.CardInfoMap[t].ExpiryDate
-			raw = append(raw, rawData)
-		}
-		s.mu.RUnlock()
-
-		done <- &pb.GetResultResponse{RawData: raw, IsOk: true}
-	}()
-	select {
-	case r := <-done:
-		return r, nil
-	case err := <-ec:
-		return &pb.GetResultResponse{IsOk: false}, err
-	}
-}
-
-func init() {
-	for i := 0; i < 1000000; i++ {
-		putRawData(getRawData())
-	}
-}
-
-func getRawData() *pb.RawData {
-	return rawDataPool.Get().(*pb.RawData)
-}
-
-func putRawData(rawData *pb.RawData) {
-	rawDataPool.Put(rawData)
-}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	pb "payment/pb"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 精算レポート
+//
+// リクエストした加盟店の売上が確定した(CAPTURED)決済を、日(サーバーのタイムゾーンの日付)・通貨ごとに集計する。
+//   - 売上: 売上が確定した日(決済日時)に数える
+//   - 返金: 売上が確定した決済をキャンセルした日に数える
+//   - 差引: 売上 - 返金
+// 与信中・与信取消・拒否した決済と、キャプチャ前にキャンセルした与信は含めない。
+// 明細のカード番号は下4桁以外をマスクし、CVVは返さない
+
+type settlementKey struct {
+	date     string
+	currency string
+}
+
+func parseSettlementDate(s, field string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if _, err := time.Parse("2006-01-02", s); err != nil {
+		return "", badRequest(codes.InvalidArgument, field, "Invalid Date")
+	}
+	return s, nil
+}
+
+// timestampDate は決済の日時をサーバーのタイムゾーンの日付にする
+func timestampDate(ts *timestamp.Timestamp) string {
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return ""
+	}
+	return localDate(t)
+}
+
+//精算レポートを取得する
+func (s *Server) GetSettlementReport(ctx context.Context, req *pb.GetSettlementReportRequest) (*pb.GetSettlementReportResponse, error) {
+	st, err := s.merchant(ctx, false)
+	if err != nil {
+		return &pb.GetSettlementReportResponse{IsOk: false}, err
+	}
+	from, err := parseSettlementDate(req.From, "from")
+	if err != nil {
+		return &pb.GetSettlementReportResponse{IsOk: false}, err
+	}
+	to, err := parseSettlementDate(req.To, "to")
+	if err != nil {
+		return &pb.GetSettlementReportResponse{IsOk: false}, err
+	}
+	if from != "" && to != "" && from > to {
+		return &pb.GetSettlementReportResponse{IsOk: false}, status.Errorf(codes.InvalidArgument, "From must not be after To")
+	}
+	inRange := func(date string) bool {
+		return (from == "" || from <= date) && (to == "" || date <= to)
+	}
+
+	summaries := map[settlementKey]*pb.SettlementSummary{}
+	summary := func(date, currency string) *pb.SettlementSummary {
+		k := settlementKey{date, currency}
+		if _, ok := summaries[k]; !ok {
+			summaries[k] = &pb.SettlementSummary{Date: date, MerchantId: st.merchantID, Currency: currency}
+		}
+		return summaries[k]
+	}
+	payments := []*pb.SettlementPayment{}
+
+	s.mu.RLock()
+	for _, id := range st.paymentOrder {
+		v := st.PayInfoMap[id]
+		if v.State != pb.PaymentState_CAPTURED || v.Datetime == nil {
+			continue
+		}
+		currency := v.Currency
+		if currency == "" {
+			currency = DefaultCurrency
+		}
+		amount := int64(v.Amount)
+		capturedOn := timestampDate(v.Datetime)
+		// CanceledAt が無い(記録する前にキャンセルした)決済は売上の日に返金したものとする
+		refundedAt := v.CanceledAt
+		if v.IsCanceled && refundedAt == nil {
+			refundedAt = v.Datetime
+		}
+
+		inReport := false
+		if inRange(capturedOn) {
+			sum := summary(capturedOn, currency)
+			sum.CapturedCount++
+			sum.CapturedAmount += amount
+			sum.NetAmount += amount
+			inReport = true
+		}
+		if v.IsCanceled && inRange(timestampDate(refundedAt)) {
+			sum := summary(timestampDate(refundedAt), currency)
+			sum.RefundedCount++
+			sum.RefundedAmount += amount
+			sum.NetAmount -= amount
+			inReport = true
+		}
+		if !inReport || !req.IncludePayments {
+			continue
+		}
+
+		p := &pb.SettlementPayment{
+			PaymentId:     id,
+			ReservationId: v.ReservationId,
+			MerchantId:    st.merchantID,
+			Currency:      currency,
+			Amount:        amount,
+			CapturedAt:    v.Datetime,
+		}
+		if v.IsCanceled {
+			p.RefundedAt = refundedAt
+		}
+		if card, ok := st.CardInfoMap[v.CardToken]; ok {
+			p.MaskedCardNumber = card.MaskedNumber
+			p.CardBrand = card.Brand
+		}
+		payments = append(payments, p)
+	}
+	s.mu.RUnlock()
+
+	res := &pb.GetSettlementReportResponse{
+		Summaries: make([]*pb.SettlementSummary, 0, len(summaries)),
+		Payments:  payments,
+		IsOk:      true,
+	}
+	for _, sum := range summaries {
+		res.Summaries = append(res.Summaries, sum)
+	}
+	sort.Slice(res.Summaries, func(i, j int) bool {
+		a, b := res.Summaries[i], res.Summaries[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		return a.Currency < b.Currency
+	})
+	log.Printf("Settlement Report: %s %s〜%s %d days %d payments\n", st.merchantID, from, to, len(res.Summaries), len(payments))
+
+	return res, nil
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pb "payment/pb"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+/*
+	テスト内容
+	・売上が確定した決済を日・通貨ごとに集計する(与信中・与信取消・キャプチャ前のキャンセルは含めない)
+	・返金はキャンセルした日に数え、差引は売上 - 返金になる
+	・from/to で日付を絞り込める。不正な日付はエラー
+	・明細のカード番号は下4桁以外をマスクする
+	・リクエストした加盟店の決済だけを集計する
+*/
+func TestSettlementReport(t *testing.T) {
+	s, err := NewNetworkServer()
+	if err != nil {
+		t.Fatalf("failed to create new server:%s", err)
+	}
+	s.CancelLatency = 0
+	if err := s.SetMerchants([]Merchant{{ID: "shop", APIKey: "sk_shop"}}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	card, err := s.RegistCard(ctx, &pb.RegistCardRequest{CardInformation: &pb.CardInformation{
+		CardNumber: "12345674",
+		Cvv:        "123",
+		ExpiryDate: "11/99",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pay := func(amount int32, currency string) string {
+		r, err := s.ExecutePayment(ctx, &pb.ExecutePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card.CardToken, ReservationId: 1, Amount: amount, Currency: currency}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.PaymentId
+	}
+	authorize := func(amount int32) string {
+		r, err := s.AuthorizePayment(ctx, &pb.AuthorizePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card.CardToken, Amount: amount}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r.PaymentId
+	}
+	cancel := func(id string) {
+		if _, err := s.CancelPayment(ctx, &pb.CancelPaymentRequest{PaymentId: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pay(1000, "")
+	refunded := pay(500, "")
+	authorize(300)
+	captured := authorize(200)
+	if _, err := s.CapturePayment(ctx, &pb.CapturePaymentRequest{PaymentId: captured}); err != nil {
+		t.Fatal(err)
+	}
+	cancel(authorize(700))
+	pay(1234, "USD")
+
+	// 返金する決済は前日に売上が確定したことにする
+	now := time.Now()
+	today, yesterday := localDate(now), localDate(now.AddDate(0, 0, -1))
+	s.mu.Lock()
+	st := s.merchants[DefaultMerchantID]
+	v := st.PayInfoMap[refunded]
+	v.Datetime, _ = ptypes.TimestampProto(now.AddDate(0, 0, -1))
+	st.PayInfoMap[refunded] = v
+	s.mu.Unlock()
+	cancel(refunded)
+
+	report := func(ctx context.Context, req *pb.GetSettlementReportRequest) *pb.GetSettlementReportResponse {
+		r, err := s.GetSettlementReport(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	t.Run("Summaries", func(t *testing.T) {
+		r := report(ctx, &pb.GetSettlementReportRequest{})
+		want := []pb.SettlementSummary{
+			{Date: yesterday, MerchantId: DefaultMerchantID, Currency: "JPY", CapturedCount: 1, CapturedAmount: 500, NetAmount: 500},
+			{Date: today, MerchantId: DefaultMerchantID, Currency: "JPY", CapturedCount: 2, CapturedAmount: 1200, RefundedCount: 1, RefundedAmount: 500, NetAmount: 700},
+			{Date: today, MerchantId: DefaultMerchantID, Currency: "USD", CapturedCount: 1, CapturedAmount: 1234, NetAmount: 1234},
+		}
+		if len(r.Summaries) != len(want) {
+			t.Fatalf("Failed. Expected:%d summaries but %v\n", len(want), r.Summaries)
+		}
+		for i, w := range want {
+			got := r.Summaries[i]
+			if got.Date != w.Date || got.MerchantId != w.MerchantId || got.Currency != w.Currency ||
+				got.CapturedCount != w.CapturedCount || got.CapturedAmount != w.CapturedAmount ||
+				got.RefundedCount != w.RefundedCount || got.RefundedAmount != w.RefundedAmount || got.NetAmount != w.NetAmount {
+				t.Fatalf("Failed. Expected:%v but %v\n", &w, got)
+			}
+		}
+		if len(r.Payments) != 0 {
+			t.Fatalf("Failed. Payments should not be included: %v\n", r.Payments)
+		}
+	})
+
+	t.Run("Date range", func(t *testing.T) {
+		r := report(ctx, &pb.GetSettlementReportRequest{From: today, To: today})
+		if len(r.Summaries) != 2 || r.Summaries[0].Date != today {
+			t.Fatalf("Failed. Expected:%s only but %v\n", today, r.Summaries)
+		}
+		r = report(ctx, &pb.GetSettlementReportRequest{To: yesterday})
+		if len(r.Summaries) != 1 || r.Summaries[0].RefundedCount != 0 {
+			t.Fatalf("Failed. Expected:%s only but %v\n", yesterday, r.Summaries)
+		}
+
+		for _, req := range []*pb.GetSettlementReportRequest{
+			{From: "2020/01/01"},
+			{To: "2020-13-01"},
+			{From: today, To: yesterday},
+		} {
+			if _, err := s.GetSettlementReport(ctx, req); status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("Failed. %v Expected:%v but %v\n", req, codes.InvalidArgument, err)
+			}
+		}
+	})
+
+	t.Run("Payments", func(t *testing.T) {
+		r := report(ctx, &pb.GetSettlementReportRequest{IncludePayments: true})
+		if len(r.Payments) != 4 {
+			t.Fatalf("Failed. Expected:4 payments but %v\n", r.Payments)
+		}
+		for _, p := range r.Payments {
+			if p.MaskedCardNumber != "****5674" || p.CardBrand == "" {
+				t.Fatalf("Failed. Expected:****5674 but %q %q\n", p.MaskedCardNumber, p.CardBrand)
+			}
+			if strings.Contains(p.String(), "12345674") {
+				t.Fatalf("Failed. Card number in report: %v\n", p)
+			}
+			if (p.PaymentId == refunded) != (p.RefundedAt != nil) {
+				t.Fatalf("Failed. Unexpected refunded_at: %v\n", p)
+			}
+		}
+
+		r = report(ctx, &pb.GetSettlementReportRequest{From: today, IncludePayments: true})
+		if len(r.Payments) != 4 {
+			t.Fatalf("Failed. Payment refunded today should be included: %v\n", r.Payments)
+		}
+	})
+
+	t.Run("Merchant", func(t *testing.T) {
+		shop := metadata.NewIncomingContext(ctx, metadata.Pairs(MerchantKeyHeader, "sk_shop"))
+		r := report(shop, &pb.GetSettlementReportRequest{IncludePayments: true})
+		if len(r.Summaries) != 0 || len(r.Payments) != 0 {
+			t.Fatalf("Failed. Expected empty report but %v\n", r)
+		}
+	})
+
+	t.Run("Mask card number", func(t *testing.T) {
+		for number, want := range map[string]string{
+			"4111111111111111": "************1111",
+			"12345674":         "****5674",
+			"1234":             "****",
+			"":                 "",
+		} {
+			if got := MaskCardNumber(number); got != want {
+				t.Fatalf("Failed. Expected:%s but %s\n", want, got)
+			}
+		}
+	})
+}
@@ -30,6 +30,9 @@ func (s *Server) ValidateCardInformation(req *pb.RegistCardRequest) error {
 	if !cardnum.MatchString(card.CardNumber) {
 		return errors.New("Invalid CardNumber")
 	}
+	if !luhnValid(card.CardNumber) {
+		return errors.New("Invalid CardNumber Checksum")
+	}
 	cvvnum := regexp.MustCompile("^[0-9]{3}$")
 	if !cvvnum.MatchString(card.Cvv) {
 		return errors.New("Invalid Cvv")
@@ -64,3 +67,68 @@ func (s *Server) ValidateCardInformation(req *pb.RegistCardRequest) error {
 	}
 	return nil
 }
+
+// luhnValid はカード番号のチェックディジット(Luhnアルゴリズム)を確かめる
+func luhnValid(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// カード番号の先頭桁(IIN)の範囲とブランド。先に一致したものを使う
+var cardBrandRanges = []struct {
+	Brand  string
+	Digits int
+	From   int
+	To     int
+}{
+	{"AMEX", 2, 34, 34},
+	{"AMEX", 2, 37, 37},
+	{"JCB", 4, 3528, 3589},
+	{"DINERS", 3, 300, 305},
+	{"DINERS", 2, 36, 36},
+	{"DINERS", 2, 38, 39},
+	{"VISA", 1, 4, 4},
+	{"MASTERCARD", 2, 51, 55},
+	{"MASTERCARD", 4, 2221, 2720},
+	{"DISCOVER", 4, 6011, 6011},
+	{"DISCOVER", 3, 644, 649},
+	{"DISCOVER", 2, 65, 65},
+}
+
+// CardBrand はカード番号の先頭桁からブランドを判定する
+// どのブランドにも当てはまらなければ UNKNOWN を返す
+func CardBrand(number string) string {
+	for _, r := range cardBrandRanges {
+		if len(number) < r.Digits {
+			continue
+		}
+		prefix, err := strconv.Atoi(number[:r.Digits])
+		if err != nil {
+			continue
+		}
+		if r.From <= prefix && prefix <= r.To {
+			return r.Brand
+		}
+	}
+	return "UNKNOWN"
+}
+
+// MaskCardNumber はカード番号の下4桁以外を * にする
+func MaskCardNumber(number string) string {
+	if len(number) <= 4 {
+		return strings.Repeat("*", len(number))
+	}
+	return strings.Repeat("*", len(number)-4) + number[len(number)-4:]
+}
@@ -14,9 +14,9 @@ func TestValidator(t *testing.T) {
 
 	t.Run("ValidateCard with correct parameters", func(t *testing.T) {
 		card := &pb.CardInformation{
-			CardNumber: "12345678",
+			CardNumber: "12345674",
 			Cvv:        "123",
-			ExpiryDate: "11/22",
+			ExpiryDate: "10/50",
 		}
 		err := s.ValidateCardInformation(&pb.RegistCardRequest{CardInformation: card})
 		if err != nil {
@@ -29,7 +29,7 @@ func TestValidator(t *testing.T) {
 		card := &pb.CardInformation{
 			CardNumber: "1", //less
 			Cvv:        "123",
-			ExpiryDate: "11/22",
+			ExpiryDate: "10/50",
 		}
 		err := s.ValidateCardInformation(&pb.RegistCardRequest{CardInformation: card})
 		if err == nil {
@@ -50,13 +50,20 @@ func TestValidator(t *testing.T) {
 			t.Fatal("should fail")
 		}
 		t.Logf("%#v", err)
+
+		card.CardNumber = "12345678" //wrong check digit
+		err = s.ValidateCardInformation(&pb.RegistCardRequest{CardInformation: card})
+		if err == nil {
+			t.Fatal("should fail")
+		}
+		t.Logf("%#v", err)
 	})
 
 	t.Run("ValidateCard with invalid cvv", func(t *testing.T) {
 		card := &pb.CardInformation{
-			CardNumber: "12345678",
+			CardNumber: "12345674",
 			Cvv:        "1", //less
-			ExpiryDate: "11/22",
+			ExpiryDate: "10/50",
 		}
 		err := s.ValidateCardInformation(&pb.RegistCardRequest{CardInformation: card})
 		if err == nil {
@@ -81,7 +88,7 @@ func TestValidator(t *testing.T) {
 
 	t.Run("ValidateCard with invalid ExpiryDate", func(t *testing.T) {
 		card := &pb.CardInformation{
-			CardNumber: "12345678",
+			CardNumber: "12345674",
 			Cvv:        "123",
 			ExpiryDate: "01/15", //past
 		}
@@ -107,3 +114,24 @@ func TestValidator(t *testing.T) {
 	})
 
 }
+
+func TestCardBrand(t *testing.T) {
+	tests := []struct {
+		number string
+		brand  string
+	}{
+		{"41111113", "VISA"},
+		{"51111112", "MASTERCARD"},
+		{"22211113", "MASTERCARD"},
+		{"34111119", "AMEX"},
+		{"35281110", "JCB"},
+		{"60111117", "DISCOVER"},
+		{"36111112", "DINERS"},
+		{"11111119", "UNKNOWN"},
+	}
+	for _, tt := range tests {
+		if got := CardBrand(tt.number); got != tt.brand {
+			t.Fatalf("failed test %#v: got %s", tt, got)
+		}
+	}
+}
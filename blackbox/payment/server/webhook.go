@@ -0,0 +1,299 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	pb "payment/pb"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/rs/xid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Webhook
+//
+// 決済の結果を加盟店に通知する。
+//   - payment.succeeded: 決済(ExecutePayment)・売上確定(CapturePayment)
+//   - payment.canceled: 与信の取消(VoidAuthorization)、与信確保中の決済のキャンセル
+//   - refund.created: 売上確定済みの決済のキャンセル(CancelPayment/BulkCancelPayment)
+//
+// 署名は X-Payment-Signature: t=<unixtime>,v1=<hex(HMAC-SHA256(secret, "<unixtime>.<body>"))>
+// 2xx 以外の応答や通信エラーは指数バックオフで再送する(at-least-once)。
+// 同じイベントは同じ X-Payment-Event-Id で届くので、受け取る側で重複を除くこと
+const (
+	WebhookEventPaymentSucceeded = "payment.succeeded"
+	WebhookEventPaymentCanceled  = "payment.canceled"
+	WebhookEventRefundCreated    = "refund.created"
+
+	WebhookSignatureHeader = "X-Payment-Signature"
+	WebhookEventIDHeader   = "X-Payment-Event-Id"
+	WebhookAttemptHeader   = "X-Payment-Delivery-Attempt"
+)
+
+var webhookEvents = map[string]bool{
+	WebhookEventPaymentSucceeded: true,
+	WebhookEventPaymentCanceled:  true,
+	WebhookEventRefundCreated:    true,
+}
+
+// WebhookPolicy は再送の設定
+type WebhookPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+}
+
+var DefaultWebhookPolicy = WebhookPolicy{
+	MaxAttempts:    8,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+	Timeout:        5 * time.Second,
+}
+
+// Webhook は加盟店が登録した通知先
+type Webhook struct {
+	ID         string
+	MerchantID string
+	URL        string
+	Secret     string
+	Events     map[string]bool // 空なら全て
+}
+
+type webhookRegistry struct {
+	mu    sync.RWMutex
+	hooks map[string]Webhook
+	wg    sync.WaitGroup
+}
+
+// WebhookEvent は通知するイベント
+type WebhookEvent struct {
+	ID        string           `json:"id"`
+	Type      string           `json:"type"`
+	CreatedAt time.Time        `json:"created_at"`
+	Data      WebhookEventData `json:"data"`
+}
+
+type WebhookEventData struct {
+	PaymentID     string     `json:"payment_id"`
+	ReservationID int32      `json:"reservation_id"`
+	Amount        int32      `json:"amount"`
+	Currency      string     `json:"currency"`
+	State         string     `json:"state"`
+	IsCanceled    bool       `json:"is_canceled"`
+	Datetime      *time.Time `json:"datetime"`
+}
+
+// SignWebhook は X-Payment-Signature の値を作る
+func SignWebhook(secret string, t time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", t.Unix())
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", t.Unix(), hex.EncodeToString(mac.Sum(nil)))
+}
+
+// backoff は attempt 回目(1始まり)の送信に失敗したあとの待ち時間
+func (p WebhookPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return d
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+//加盟店のWebhookを登録する(同じ加盟店の同じURLなら更新する)
+func (s *Server) RegisterWebhook(ctx context.Context, req *pb.RegisterWebhookRequest) (*pb.RegisterWebhookResponse, error) {
+	merchantID, err := s.merchantID(ctx, false)
+	if err != nil {
+		log.Println(err.Error())
+		return &pb.RegisterWebhookResponse{IsOk: false}, err
+	}
+	u, err := url.Parse(req.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		log.Println("Invalid Webhook URL")
+		return &pb.RegisterWebhookResponse{IsOk: false}, status.Errorf(codes.InvalidArgument, "Invalid Webhook URL")
+	}
+	events := map[string]bool{}
+	for _, e := range req.Events {
+		if !webhookEvents[e] {
+			log.Printf("Invalid Webhook Event: %s\n", e)
+			return &pb.RegisterWebhookResponse{IsOk: false}, status.Errorf(codes.InvalidArgument, "Invalid Webhook Event: %s", e)
+		}
+		events[e] = true
+	}
+	secret := req.Secret
+	if secret == "" {
+		secret, err = newWebhookSecret()
+		if err != nil {
+			log.Println(err.Error())
+			return &pb.RegisterWebhookResponse{IsOk: false}, status.Errorf(codes.Internal, "Internal Error, Generate Secret")
+		}
+	}
+
+	s.webhooks.mu.Lock()
+	defer s.webhooks.mu.Unlock()
+
+	id := ""
+	for _, hook := range s.webhooks.hooks {
+		if hook.MerchantID == merchantID && hook.URL == req.Url {
+			id = hook.ID
+			break
+		}
+	}
+	if id == "" {
+		id = "wh_" + xid.New().String()
+	}
+	s.webhooks.hooks[id] = Webhook{ID: id, MerchantID: merchantID, URL: req.Url, Secret: secret, Events: events}
+
+	return &pb.RegisterWebhookResponse{WebhookId: id, Secret: secret, IsOk: true}, nil
+}
+
+//加盟店のWebhookを削除する(他の加盟店のWebhookは削除できない)
+func (s *Server) DeleteWebhook(ctx context.Context, req *pb.DeleteWebhookRequest) (*pb.DeleteWebhookResponse, error) {
+	merchantID, err := s.merchantID(ctx, false)
+	if err != nil {
+		log.Println(err.Error())
+		return &pb.DeleteWebhookResponse{IsOk: false}, err
+	}
+
+	s.webhooks.mu.Lock()
+	defer s.webhooks.mu.Unlock()
+
+	if hook, ok := s.webhooks.hooks[req.WebhookId]; !ok || hook.MerchantID != merchantID {
+		log.Println("WebhookID Not Found")
+		return &pb.DeleteWebhookResponse{IsOk: false}, status.Errorf(codes.NotFound, "WebhookID Not Found")
+	}
+	delete(s.webhooks.hooks, req.WebhookId)
+
+	return &pb.DeleteWebhookResponse{IsOk: true}, nil
+}
+
+// publishWebhook は加盟店が登録したWebhookにイベントを送る(送信はバックグラウンドで行う)
+func (s *Server) publishWebhook(merchantID, eventType, paymentID string, paydata pb.PaymentInformation) {
+	s.webhooks.mu.RLock()
+	hooks := make([]Webhook, 0, len(s.webhooks.hooks))
+	for _, hook := range s.webhooks.hooks {
+		if hook.MerchantID != merchantID {
+			continue
+		}
+		if len(hook.Events) == 0 || hook.Events[eventType] {
+			hooks = append(hooks, hook)
+		}
+	}
+	s.webhooks.mu.RUnlock()
+	if len(hooks) == 0 {
+		return
+	}
+
+	event := WebhookEvent{
+		ID:        "evt_" + xid.New().String(),
+		Type:      eventType,
+		CreatedAt: time.Now(),
+		Data: WebhookEventData{
+			PaymentID:     paymentID,
+			ReservationID: paydata.ReservationId,
+			Amount:        paydata.Amount,
+			Currency:      paydata.Currency,
+			State:         paydata.State.String(),
+			IsCanceled:    paydata.IsCanceled,
+		},
+	}
+	if paydata.Datetime != nil {
+		if t, err := ptypes.Timestamp(paydata.Datetime); err == nil {
+			event.Data.Datetime = &t
+		}
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	for _, hook := range hooks {
+		s.webhooks.wg.Add(1)
+		go func(hook Webhook) {
+			defer s.webhooks.wg.Done()
+			s.deliverWebhook(hook, event.ID, body)
+		}(hook)
+	}
+}
+
+// publishCancelWebhook はキャンセルされた決済のイベントを送る
+// 与信確保中なら与信の取消、売上確定済みなら返金として通知する
+func (s *Server) publishCancelWebhook(merchantID, paymentID string, paydata pb.PaymentInformation) {
+	switch paydata.State {
+	case pb.PaymentState_AUTHORIZED:
+		s.publishWebhook(merchantID, WebhookEventPaymentCanceled, paymentID, paydata)
+	case pb.PaymentState_CAPTURED:
+		s.publishWebhook(merchantID, WebhookEventRefundCreated, paymentID, paydata)
+	}
+}
+
+// deliverWebhook は2xxが返るか試行回数の上限まで送信する
+func (s *Server) deliverWebhook(hook Webhook, eventID string, body []byte) bool {
+	policy := s.WebhookPolicy
+	client := &http.Client{Timeout: policy.Timeout}
+
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		err := postWebhook(client, hook, eventID, body, attempt)
+		if err == nil {
+			return true
+		}
+		log.Printf("Webhook delivery failed (attempt %d/%d, %s): %s\n", attempt, policy.MaxAttempts, hook.URL, err.Error())
+		if attempt < policy.MaxAttempts {
+			time.Sleep(policy.backoff(attempt))
+		}
+	}
+	log.Printf("Webhook delivery gave up: %s %s\n", hook.URL, eventID)
+	return false
+}
+
+func postWebhook(client *http.Client, hook Webhook, eventID string, body []byte, attempt int) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventIDHeader, eventID)
+	req.Header.Set(WebhookAttemptHeader, strconv.Itoa(attempt))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(hook.Secret, time.Now(), body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || 300 <= resp.StatusCode {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "payment/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+/*
+	テスト内容
+	・不正なURL・イベントは登録できない
+	・決済・キャンセルでイベントが届く(署名が正しい)
+	・失敗した送信は同じイベントIDで再送される
+	・購読していないイベントは届かない
+	・削除したWebhookには届かない
+*/
+func TestWebhook(t *testing.T) {
+	s, err := NewNetworkServer()
+	if err != nil {
+		t.Fatalf("failed to create new server:%s", err)
+	}
+	s.WebhookPolicy = WebhookPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond, Timeout: time.Second}
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var received []WebhookEvent
+	attempts := map[string]int{}
+	fail := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+
+		id := r.Header.Get(WebhookEventIDHeader)
+		attempts[id]++
+		if fail && attempts[id] == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		sig := r.Header.Get(WebhookSignatureHeader)
+		parts := strings.SplitN(strings.TrimPrefix(sig, "t="), ",", 2)
+		if len(parts) != 2 {
+			t.Errorf("invalid signature header: %s", sig)
+			return
+		}
+		unix, _ := strconv.ParseInt(parts[0], 10, 64)
+		if SignWebhook("secret", time.Unix(unix, 0), body) != sig {
+			t.Errorf("invalid signature: %s", sig)
+		}
+		var event WebhookEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Error(err)
+			return
+		}
+		if event.ID != id {
+			t.Errorf("event id mismatch: %s != %s", event.ID, id)
+		}
+		received = append(received, event)
+	}))
+	defer ts.Close()
+
+	wait := func(n int) []WebhookEvent {
+		s.webhooks.wg.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		if len(received) != n {
+			t.Fatalf("Failed. Expected:%d events but %d\n", n, len(received))
+		}
+		events := received
+		received = nil
+		return events
+	}
+
+	t.Run("Invalid webhook", func(t *testing.T) {
+		_, err := s.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{Url: "ftp://example.com"})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.InvalidArgument, err)
+		}
+		_, err = s.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{Url: ts.URL, Events: []string{"hoge"}})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.InvalidArgument, err)
+		}
+	})
+
+	hook, err := s.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{Url: ts.URL, Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := s.RegistCard(ctx, &pb.RegistCardRequest{CardInformation: &pb.CardInformation{
+		CardNumber: "12345674",
+		Cvv:        "123",
+		ExpiryDate: "11/99",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Payment and refund with retry", func(t *testing.T) {
+		r, err := s.ExecutePayment(ctx, &pb.ExecutePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card.CardToken, ReservationId: 7, Amount: 100}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		events := wait(1)
+		if events[0].Type != WebhookEventPaymentSucceeded || events[0].Data.PaymentID != r.PaymentId || events[0].Data.ReservationID != 7 {
+			t.Fatalf("Failed. Wrong event: %#v\n", events[0])
+		}
+		if attempts[events[0].ID] != 2 {
+			t.Fatalf("Failed. Expected:2 attempts but %d\n", attempts[events[0].ID])
+		}
+
+		if _, err := s.CancelPayment(ctx, &pb.CancelPaymentRequest{PaymentId: r.PaymentId}); err != nil {
+			t.Fatal(err)
+		}
+		events = wait(1)
+		if events[0].Type != WebhookEventRefundCreated || !events[0].Data.IsCanceled {
+			t.Fatalf("Failed. Wrong event: %#v\n", events[0])
+		}
+	})
+
+	t.Run("Subscribed events only", func(t *testing.T) {
+		mu.Lock()
+		fail = false
+		mu.Unlock()
+		_, err := s.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{Url: ts.URL, Secret: "secret", Events: []string{WebhookEventPaymentCanceled}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := s.AuthorizePayment(ctx, &pb.AuthorizePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card.CardToken, Amount: 100}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.CapturePayment(ctx, &pb.CapturePaymentRequest{PaymentId: r.PaymentId}); err != nil {
+			t.Fatal(err)
+		}
+		wait(0)
+
+		r, err = s.AuthorizePayment(ctx, &pb.AuthorizePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card.CardToken, Amount: 100}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.VoidAuthorization(ctx, &pb.VoidAuthorizationRequest{PaymentId: r.PaymentId}); err != nil {
+			t.Fatal(err)
+		}
+		if events := wait(1); events[0].Type != WebhookEventPaymentCanceled {
+			t.Fatalf("Failed. Wrong event: %#v\n", events[0])
+		}
+	})
+
+	t.Run("DeleteWebhook", func(t *testing.T) {
+		if _, err := s.DeleteWebhook(ctx, &pb.DeleteWebhookRequest{WebhookId: hook.WebhookId}); err != nil {
+			t.Fatal(err)
+		}
+		_, err := s.DeleteWebhook(ctx, &pb.DeleteWebhookRequest{WebhookId: hook.WebhookId})
+		if status.Code(err) != codes.NotFound {
+			t.Fatalf("Failed. Expected:%v but %v\n", codes.NotFound, err)
+		}
+		if _, err := s.ExecutePayment(ctx, &pb.ExecutePaymentRequest{PaymentInformation: &pb.PaymentInformation{CardToken: card.CardToken, Amount: 100}}); err != nil {
+			t.Fatal(err)
+		}
+		wait(0)
+	})
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	p := WebhookPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w {
+			t.Fatalf("Failed. attempt %d Expected:%s but %s\n", i+1, w, got)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package settlement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	pb "payment/pb"
+
+	"github.com/pkg/errors"
+)
+
+// 精算レポートとwebappの予約の突き合わせ
+//
+// 予約は webapp の reservations テーブルを見出し付きのCSVかTSV(mysql -B の出力)で書き出したものを読む。
+// reservation_id, status, payment_id, amount の列が必要(payment_status があれば使う)。
+// status が done・no_show で payment_id のある予約を支払い済みとし、精算レポートの明細と突き合わせる
+
+// ReservationCurrency は webapp の予約の金額の通貨
+const ReservationCurrency = "JPY"
+
+// 突き合わせで見つかった不一致の種類
+const (
+	MissingPayment      = "missing_payment"      // 支払い済みの予約の決済が明細に無い
+	AmountMismatch      = "amount_mismatch"      // 予約と決済の金額・通貨が違う
+	ReservationMismatch = "reservation_mismatch" // 決済の予約IDが予約と違う
+	RefundedReservation = "refunded_reservation" // 返金した決済の予約が支払い済みのまま
+	DuplicatePayment    = "duplicate_payment"    // 同じ決済IDの予約が複数ある
+	UnmatchedPayment    = "unmatched_payment"    // 返金していない決済に支払い済みの予約が無い
+)
+
+// Reservation は webapp の予約
+type Reservation struct {
+	ReservationID int64
+	Status        string
+	PaymentID     string
+	PaymentStatus string
+	Amount        int64
+}
+
+// Paid は予約が支払い済みかどうか
+func (r Reservation) Paid() bool {
+	return (r.Status == "done" || r.Status == "no_show") && r.PaymentID != "" && r.PaymentStatus != "authorized"
+}
+
+// Mismatch は突き合わせで見つかった不一致
+type Mismatch struct {
+	Kind          string `json:"kind"`
+	ReservationID int64  `json:"reservation_id"`
+	PaymentID     string `json:"payment_id"`
+	Detail        string `json:"detail"`
+}
+
+// MismatchHeader は不一致のCSVの見出し
+var MismatchHeader = []string{"kind", "reservation_id", "payment_id", "detail"}
+
+// ReadReservations は予約のCSV・TSVを読む。見出しの行にタブがあればTSVとして読む
+func ReadReservations(r io.Reader) ([]Reservation, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read reservations")
+	}
+	header := b
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		header = b[:i]
+	}
+	cr := csv.NewReader(bytes.NewReader(b))
+	if bytes.IndexByte(header, '\t') >= 0 {
+		cr.Comma = '\t'
+		cr.LazyQuotes = true
+	}
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read reservations")
+	}
+	if len(records) == 0 {
+		return nil, errors.New("reservations header not found")
+	}
+
+	column := map[string]int{}
+	for i, name := range records[0] {
+		column[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"reservation_id", "status", "payment_id", "amount"} {
+		if _, ok := column[name]; !ok {
+			return nil, errors.Errorf("reservations column not found: %s", name)
+		}
+	}
+	get := func(record []string, name string) string {
+		i, ok := column[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	reservations := make([]Reservation, 0, len(records)-1)
+	for n, record := range records[1:] {
+		id, err := strconv.ParseInt(get(record, "reservation_id"), 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid reservation_id at line %d: %s", n+2, get(record, "reservation_id"))
+		}
+		amount, err := strconv.ParseInt(get(record, "amount"), 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid amount at line %d: %s", n+2, get(record, "amount"))
+		}
+		reservations = append(reservations, Reservation{
+			ReservationID: id,
+			Status:        get(record, "status"),
+			PaymentID:     get(record, "payment_id"),
+			PaymentStatus: get(record, "payment_status"),
+			Amount:        amount,
+		})
+	}
+	return reservations, nil
+}
+
+// Reconcile は予約と精算レポートの明細を突き合わせ、不一致を予約の順、決済の順に返す
+func Reconcile(reservations []Reservation, payments []*pb.SettlementPayment) []Mismatch {
+	byID := make(map[string]*pb.SettlementPayment, len(payments))
+	for _, p := range payments {
+		byID[p.PaymentId] = p
+	}
+
+	mismatches := []Mismatch{}
+	paidBy := map[string]int64{}
+	for _, r := range reservations {
+		if !r.Paid() {
+			continue
+		}
+		if first, ok := paidBy[r.PaymentID]; ok {
+			mismatches = append(mismatches, Mismatch{DuplicatePayment, r.ReservationID, r.PaymentID,
+				fmt.Sprintf("payment is also used by reservation %d", first)})
+			continue
+		}
+		paidBy[r.PaymentID] = r.ReservationID
+
+		p, ok := byID[r.PaymentID]
+		if !ok {
+			mismatches = append(mismatches, Mismatch{MissingPayment, r.ReservationID, r.PaymentID,
+				fmt.Sprintf("reservation is %s but payment is not in report", r.Status)})
+			continue
+		}
+		if p.RefundedAt != nil {
+			mismatches = append(mismatches, Mismatch{RefundedReservation, r.ReservationID, r.PaymentID,
+				fmt.Sprintf("payment is refunded but reservation is %s", r.Status)})
+		}
+		if p.Amount != r.Amount || p.Currency != ReservationCurrency {
+			mismatches = append(mismatches, Mismatch{AmountMismatch, r.ReservationID, r.PaymentID,
+				fmt.Sprintf("reservation %d %s, payment %d %s", r.Amount, ReservationCurrency, p.Amount, p.Currency)})
+		}
+		if p.ReservationId != 0 && int64(p.ReservationId) != r.ReservationID {
+			mismatches = append(mismatches, Mismatch{ReservationMismatch, r.ReservationID, r.PaymentID,
+				fmt.Sprintf("payment is for reservation %d", p.ReservationId)})
+		}
+	}
+
+	for _, p := range payments {
+		if p.RefundedAt != nil {
+			continue
+		}
+		if _, ok := paidBy[p.PaymentId]; !ok {
+			mismatches = append(mismatches, Mismatch{UnmatchedPayment, int64(p.ReservationId), p.PaymentId,
+				fmt.Sprintf("captured %d %s without paid reservation", p.Amount, p.Currency)})
+		}
+	}
+	return mismatches
+}
+
+// WriteMismatchesCSV は不一致をCSVで書き出す
+func WriteMismatchesCSV(w io.Writer, mismatches []Mismatch) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(MismatchHeader); err != nil {
+		return err
+	}
+	for _, m := range mismatches {
+		if err := cw.Write([]string{m.Kind, strconv.FormatInt(m.ReservationID, 10), m.PaymentID, m.Detail}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteMismatchesJSON は不一致をJSONの配列で書き出す
+func WriteMismatchesJSON(w io.Writer, mismatches []Mismatch) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(mismatches)
+}
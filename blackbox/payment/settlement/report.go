@@ -0,0 +1,134 @@
+package settlement
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	pb "payment/pb"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/pkg/errors"
+)
+
+// 精算レポートの書き出し
+//
+// JSONは GET /report/settlement のレスポンスと同じ形式(項目名はsnake_case)。
+// CSVは集計(summaries)か明細(payments)のどちらかを書き出す。金額は通貨の最小単位
+
+// SummaryHeader は集計のCSVの見出し
+var SummaryHeader = []string{"date", "merchant_id", "currency", "captured_count", "captured_amount", "refunded_count", "refunded_amount", "net_amount"}
+
+// PaymentHeader は明細のCSVの見出し
+var PaymentHeader = []string{"payment_id", "reservation_id", "merchant_id", "currency", "amount", "captured_at", "refunded_at", "masked_card_number", "card_brand"}
+
+// Merge は加盟店ごとに取得したレポートをまとめ、日・加盟店・通貨の順に並べる
+func Merge(reports ...*pb.GetSettlementReportResponse) *pb.GetSettlementReportResponse {
+	merged := &pb.GetSettlementReportResponse{
+		Summaries: []*pb.SettlementSummary{},
+		Payments:  []*pb.SettlementPayment{},
+		IsOk:      true,
+	}
+	for _, r := range reports {
+		merged.Summaries = append(merged.Summaries, r.Summaries...)
+		merged.Payments = append(merged.Payments, r.Payments...)
+		merged.IsOk = merged.IsOk && r.IsOk
+	}
+	sort.SliceStable(merged.Summaries, func(i, j int) bool {
+		a, b := merged.Summaries[i], merged.Summaries[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.MerchantId != b.MerchantId {
+			return a.MerchantId < b.MerchantId
+		}
+		return a.Currency < b.Currency
+	})
+	return merged
+}
+
+// WriteJSON はレポートをJSONで書き出す
+func WriteJSON(w io.Writer, r *pb.GetSettlementReportResponse) error {
+	m := jsonpb.Marshaler{OrigName: true, EmitDefaults: true, Indent: "  "}
+	if err := m.Marshal(w, r); err != nil {
+		return errors.Wrap(err, "failed to write report")
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ReadJSON は WriteJSON で書き出したレポートを読む
+func ReadJSON(r io.Reader) (*pb.GetSettlementReportResponse, error) {
+	report := &pb.GetSettlementReportResponse{}
+	u := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err := u.Unmarshal(r, report); err != nil {
+		return nil, errors.Wrap(err, "failed to read report")
+	}
+	return report, nil
+}
+
+// WriteSummariesCSV は集計をCSVで書き出す
+func WriteSummariesCSV(w io.Writer, summaries []*pb.SettlementSummary) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(SummaryHeader); err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		err := cw.Write([]string{
+			s.Date,
+			s.MerchantId,
+			s.Currency,
+			strconv.FormatInt(s.CapturedCount, 10),
+			strconv.FormatInt(s.CapturedAmount, 10),
+			strconv.FormatInt(s.RefundedCount, 10),
+			strconv.FormatInt(s.RefundedAmount, 10),
+			strconv.FormatInt(s.NetAmount, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WritePaymentsCSV は明細をCSVで書き出す。日時はRFC3339、返金していなければ refunded_at は空
+func WritePaymentsCSV(w io.Writer, payments []*pb.SettlementPayment) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(PaymentHeader); err != nil {
+		return err
+	}
+	for _, p := range payments {
+		err := cw.Write([]string{
+			p.PaymentId,
+			strconv.FormatInt(int64(p.ReservationId), 10),
+			p.MerchantId,
+			p.Currency,
+			strconv.FormatInt(p.Amount, 10),
+			formatTimestamp(p.CapturedAt),
+			formatTimestamp(p.RefundedAt),
+			p.MaskedCardNumber,
+			p.CardBrand,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatTimestamp(ts *timestamp.Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+	t, err := ptypes.Timestamp(ts)
+	if err != nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,174 @@
+package settlement
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	pb "payment/pb"
+
+	"github.com/golang/protobuf/ptypes"
+)
+
+/*
+	テスト内容
+	・加盟店ごとのレポートをまとめて日・加盟店・通貨の順に並べる
+	・集計・明細をCSVで、レポートをJSONで書き出し、JSONは読み直せる
+	・予約のCSV・TSVを読める(必要な列が無ければエラー)
+	・予約と明細の不一致を種類ごとに見つける
+*/
+func TestReport(t *testing.T) {
+	a := &pb.GetSettlementReportResponse{IsOk: true, Summaries: []*pb.SettlementSummary{
+		{Date: "2020-01-02", MerchantId: "a", Currency: "JPY", CapturedCount: 1, CapturedAmount: 100, NetAmount: 100},
+	}}
+	b := &pb.GetSettlementReportResponse{IsOk: true, Summaries: []*pb.SettlementSummary{
+		{Date: "2020-01-02", MerchantId: "b", Currency: "JPY", CapturedCount: 2, CapturedAmount: 300, RefundedCount: 1, RefundedAmount: 100, NetAmount: 200},
+		{Date: "2020-01-01", MerchantId: "b", Currency: "USD", CapturedCount: 1, CapturedAmount: 1234, NetAmount: 1234},
+	}}
+	refundedAt, _ := ptypes.TimestampProto(mustTime(t, "2020-01-02T10:00:00Z"))
+	capturedAt, _ := ptypes.TimestampProto(mustTime(t, "2020-01-01T09:00:00Z"))
+	b.Payments = []*pb.SettlementPayment{{
+		PaymentId: "p1", ReservationId: 1, MerchantId: "b", Currency: "JPY", Amount: 100,
+		CapturedAt: capturedAt, RefundedAt: refundedAt, MaskedCardNumber: "****5674", CardBrand: "UNKNOWN",
+	}}
+
+	r := Merge(a, b)
+	var order []string
+	for _, s := range r.Summaries {
+		order = append(order, s.Date+"/"+s.MerchantId)
+	}
+	if want := []string{"2020-01-01/b", "2020-01-02/a", "2020-01-02/b"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("Failed. Expected:%v but %v\n", want, order)
+	}
+
+	t.Run("CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteSummariesCSV(&buf, r.Summaries); err != nil {
+			t.Fatal(err)
+		}
+		want := "date,merchant_id,currency,captured_count,captured_amount,refunded_count,refunded_amount,net_amount\n" +
+			"2020-01-01,b,USD,1,1234,0,0,1234\n" +
+			"2020-01-02,a,JPY,1,100,0,0,100\n" +
+			"2020-01-02,b,JPY,2,300,1,100,200\n"
+		if buf.String() != want {
+			t.Fatalf("Failed. Expected:\n%s but\n%s", want, buf.String())
+		}
+
+		buf.Reset()
+		if err := WritePaymentsCSV(&buf, r.Payments); err != nil {
+			t.Fatal(err)
+		}
+		want = "payment_id,reservation_id,merchant_id,currency,amount,captured_at,refunded_at,masked_card_number,card_brand\n" +
+			"p1,1,b,JPY,100,2020-01-01T09:00:00Z,2020-01-02T10:00:00Z,****5674,UNKNOWN\n"
+		if buf.String() != want {
+			t.Fatalf("Failed. Expected:\n%s but\n%s", want, buf.String())
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteJSON(&buf, r); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), `"masked_card_number": "****5674"`) {
+			t.Fatalf("Failed. Expected snake_case fields but %s\n", buf.String())
+		}
+		got, err := ReadJSON(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Summaries) != 3 || len(got.Payments) != 1 || got.Payments[0].RefundedAt.Seconds != refundedAt.Seconds {
+			t.Fatalf("Failed. Expected:%v but %v\n", r, got)
+		}
+	})
+}
+
+func TestReconcile(t *testing.T) {
+	t.Run("Read reservations", func(t *testing.T) {
+		tsv := "reservation_id\tuser_id\tstatus\tpayment_id\tpayment_status\tamount\n" +
+			"1\t10\tdone\tp1\tcaptured\t1000\n" +
+			"2\t10\trequesting\tp2\tauthorized\t500\n"
+		csv := "reservation_id,status,payment_id,amount\n1,done,p1,1000\n2,requesting,p2,500\n"
+		for _, s := range []string{tsv, csv} {
+			got, err := ReadReservations(strings.NewReader(s))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 2 || got[0] != (Reservation{1, "done", "p1", got[0].PaymentStatus, 1000}) || !got[0].Paid() || got[1].Paid() {
+				t.Fatalf("Failed. Unexpected reservations: %v\n", got)
+			}
+		}
+
+		for _, s := range []string{
+			"",
+			"reservation_id,status,amount\n1,done,1000\n",
+			"reservation_id,status,payment_id,amount\nx,done,p1,1000\n",
+			"reservation_id,status,payment_id,amount\n1,done,p1,abc\n",
+		} {
+			if _, err := ReadReservations(strings.NewReader(s)); err == nil {
+				t.Fatalf("Failed. %q should be invalid\n", s)
+			}
+		}
+	})
+
+	t.Run("Mismatches", func(t *testing.T) {
+		refundedAt := ptypes.TimestampNow()
+		payments := []*pb.SettlementPayment{
+			{PaymentId: "ok", ReservationId: 1, Currency: "JPY", Amount: 1000},
+			{PaymentId: "amount", ReservationId: 2, Currency: "JPY", Amount: 900},
+			{PaymentId: "refunded", ReservationId: 3, Currency: "JPY", Amount: 1000, RefundedAt: refundedAt},
+			{PaymentId: "other", ReservationId: 99, Currency: "JPY", Amount: 1000},
+			{PaymentId: "orphan", ReservationId: 6, Currency: "JPY", Amount: 1000},
+			{PaymentId: "canceled", ReservationId: 7, Currency: "JPY", Amount: 1000, RefundedAt: refundedAt},
+			{PaymentId: "usd", ReservationId: 8, Currency: "USD", Amount: 1000},
+		}
+		reservations := []Reservation{
+			{1, "done", "ok", "captured", 1000},
+			{2, "done", "amount", "captured", 1000},
+			{3, "no_show", "refunded", "", 1000},
+			{4, "done", "other", "captured", 1000},
+			{5, "done", "missing", "captured", 1000},
+			{9, "done", "ok", "captured", 1000},
+			{10, "requesting", "", "", 1000},
+			{8, "done", "usd", "captured", 1000},
+		}
+		var got []string
+		for _, m := range Reconcile(reservations, payments) {
+			got = append(got, m.Kind+"/"+m.PaymentID)
+		}
+		want := []string{
+			AmountMismatch + "/amount",
+			RefundedReservation + "/refunded",
+			ReservationMismatch + "/other",
+			MissingPayment + "/missing",
+			DuplicatePayment + "/ok",
+			AmountMismatch + "/usd",
+			UnmatchedPayment + "/orphan",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Failed. Expected:%v but %v\n", want, got)
+		}
+
+		if m := Reconcile(reservations[:1], payments[:1]); len(m) != 0 {
+			t.Fatalf("Failed. Expected no mismatch but %v\n", m)
+		}
+
+		var buf bytes.Buffer
+		if err := WriteMismatchesCSV(&buf, Reconcile(reservations[4:5], nil)); err != nil {
+			t.Fatal(err)
+		}
+		if want := "kind,reservation_id,payment_id,detail\nmissing_payment,5,missing,reservation is done but payment is not in report\n"; buf.String() != want {
+			t.Fatalf("Failed. Expected:\n%s but\n%s", want, buf.String())
+		}
+	})
+}
+
+func mustTime(t *testing.T, s string) time.Time {
+	v, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -3,6 +3,8 @@ import Router from 'vue-router'
 import Home from './views/Home.vue'
 import Register from './views/Register.vue'
 import Login from './views/Login.vue'
+import VerifyEmail from './views/VerifyEmail.vue'
+import ResetPassword from './views/ResetPassword.vue'
 import Search from './views/Search.vue'
 import Trains from './views/Trains.vue'
 import Seats from './views/Seats.vue'
@@ -35,6 +37,16 @@ export default new Router({
       name: 'login',
       component: Login
     },
+    {
+      path: '/verify-email',
+      name: 'verify-email',
+      component: VerifyEmail
+    },
+    {
+      path: '/reset-password',
+      name: 'reset-password',
+      component: ResetPassword
+    },
     {
       path: '/reservation',
       name: 'search',
@@ -82,6 +82,21 @@ class ApiService {
       });
     }
 
+    // メールアドレスの確認
+    async verifyEmail(token) {
+      return await this.httpService.post('/api/auth/verify', {token: token})
+    }
+
+    // パスワード再設定メールの送信
+    async requestPasswordReset(email) {
+      return await this.httpService.post('/api/auth/password/reset', {email: email})
+    }
+
+    // パスワードの再設定
+    async confirmPasswordReset(token, password) {
+      return await this.httpService.post('/api/auth/password/reset/confirm', {token: token, password: password})
+    }
+
     async reserve(condition) {
       var date = new Date(condition.year, condition.month - 1, condition.day)
       var request = {
@@ -96,6 +111,7 @@ class ApiService {
         adult: condition.adult,
         column: condition.column,
         seats: condition.seats,
+        needs_assistance: condition.needs_assistance || false,
       }
 
       return await this.httpService.post('/api/train/reserve', request).then(function(resp){
@@ -129,7 +145,7 @@ class ApiService {
       /*
       data =
       {
-          card_number: "12345678",
+          card_number: "12345674",
           cvv: "123",
           expiry_date: "12/22"
       }
@@ -137,7 +153,12 @@ class ApiService {
 
       return await this.httpService.get('/api/settings').then(function(res){
         var paymentService = new HttpService(res.payment_api)
-        return paymentService.post('/card', data).then(function(res){
+        // 加盟店の公開用キー(カードの登録にだけ使える)
+        var config = {}
+        if (res.payment_publishable_key) {
+          config.headers = { 'X-Merchant-Key': res.payment_publishable_key }
+        }
+        return paymentService.post('/card', data, config).then(function(res){
           return res
         })
       });
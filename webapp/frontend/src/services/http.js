@@ -2,6 +2,7 @@ import axios from 'axios'
 import Router from '../router.js'
 
 const API_BASE = "";
+const CSRF_HEADER = 'X-CSRF-Token';
 
 class ErrorHandler {
     handle (error) {
@@ -39,6 +40,8 @@ export class HttpService {
             timeout: 600*1000
         })
         this.svc = svc
+        // ログイン時・GET /api/auth で配布されるCSRFトークン
+        this.csrfToken = null
     }
 
     /**
@@ -47,9 +50,16 @@ export class HttpService {
      * @param {object} config axios設定
      */
     request (url, config = {}) {
-        // FIXME: トークン判定処理
+        // 状態を変更するリクエストにはCSRFトークンを付ける
+        if (this.csrfToken && config.method && config.method !== 'get') {
+            config.headers = Object.assign({}, config.headers, { [CSRF_HEADER]: this.csrfToken })
+        }
         return this.svc.request(url, config).then((response) => {
             // 正常レスポンスハンドリング
+            const token = response.headers[CSRF_HEADER.toLowerCase()]
+            if (token) {
+                this.csrfToken = token
+            }
             const resp = response.data
             resp.status = response.status
             return resp
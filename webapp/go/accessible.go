@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 車いすスペース (seat_master.is_accessible)
+//
+//   - 介助が必要なお客様 (needs_assistance) 向けに、乗車駅の発車時刻の
+//     ACCESSIBLE_SEAT_RELEASE_BEFORE 前 (デフォルト1時間前) まで確保しておく
+//   - それを過ぎると、誰でも予約できる通常の座席として扱う
+//   - あいまい予約では、介助が必要な予約に車いすスペースを優先して割り当てる
+const defaultAccessibleSeatRelease = time.Hour
+
+var accessibleSeatRelease = newAccessibleSeatReleaseFromEnv()
+
+func newAccessibleSeatReleaseFromEnv() time.Duration {
+	s := os.Getenv("ACCESSIBLE_SEAT_RELEASE_BEFORE")
+	if s == "" {
+		return defaultAccessibleSeatRelease
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		log.Fatalf("invalid ACCESSIBLE_SEAT_RELEASE_BEFORE: %s.", s)
+	}
+	return d
+}
+
+// trainDepartureAt は列車が station を発車する日時を返す
+func trainDepartureAt(q sqlx.Queryer, date time.Time, trainClass, trainName, station string) (time.Time, error) {
+	var departure string
+	query := "SELECT departure FROM train_timetable_master WHERE date=? AND train_class=? AND train_name=? AND station=?"
+	err := sqlx.Get(q, &departure, query, date.Format("2006/01/02"), trainClass, trainName, station)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse("2006/01/02 15:04:05 -07:00 MST", fmt.Sprintf("%s %s +09:00 JST", date.Format("2006/01/02"), departure))
+}
+
+// accessibleSeatHoldUntil は車いすスペースを介助が必要なお客様向けに確保しておく期限を返す
+func accessibleSeatHoldUntil(departureAt time.Time) time.Time {
+	return departureAt.Add(-accessibleSeatRelease)
+}
+
+// accessibleSeatsForRequest はあいまい予約の候補席を並べ替える
+//   - 介助が必要な予約: 車いすスペースを先頭にする。空いている車いすスペースがなければ ok=false
+//   - それ以外: 確保期間中 (held) なら車いすスペースを候補から外す
+func accessibleSeatsForRequest(seatList []SeatInformation, needsAssistance, held bool) ([]SeatInformation, bool) {
+	accessible := []SeatInformation{}
+	others := []SeatInformation{}
+	for _, seat := range seatList {
+		if seat.IsAccessible {
+			accessible = append(accessible, seat)
+		} else {
+			others = append(others, seat)
+		}
+	}
+
+	if !needsAssistance {
+		if held {
+			return others, true
+		}
+		return seatList, true
+	}
+
+	for _, seat := range accessible {
+		if !seat.IsOccupied {
+			return append(accessible, others...), true
+		}
+	}
+	return nil, false
+}
+
+// availableSeatsForRequest は検索の空席数に数える座席を返す
+// 介助が不要な検索では、確保期間中 (held) の車いすスペースは予約できないので外す
+func availableSeatsForRequest(seatList []Seat, needsAssistance, held bool) []Seat {
+	if needsAssistance || !held {
+		return seatList
+	}
+	ret := []Seat{}
+	for _, seat := range seatList {
+		if !seat.IsAccessible {
+			ret = append(ret, seat)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAccessibleSeatsForRequest(t *testing.T) {
+	seatList := []SeatInformation{
+		{Row: 1, Column: "A", IsAccessible: true},
+		{Row: 1, Column: "B", IsAccessible: true, IsOccupied: true},
+		{Row: 1, Column: "C"},
+		{Row: 2, Column: "A"},
+	}
+	columns := func(seats []SeatInformation) []string {
+		result := []string{}
+		for _, seat := range seats {
+			result = append(result, seat.Column+string(rune('0'+seat.Row)))
+		}
+		return result
+	}
+
+	tests := []struct {
+		needsAssistance bool
+		held            bool
+		want            []string
+		ok              bool
+	}{
+		{false, true, []string{"C1", "A2"}, true},
+		{false, false, []string{"A1", "B1", "C1", "A2"}, true},
+		{true, true, []string{"A1", "B1", "C1", "A2"}, true},
+		{true, false, []string{"A1", "B1", "C1", "A2"}, true},
+	}
+	for _, tt := range tests {
+		got, ok := accessibleSeatsForRequest(seatList, tt.needsAssistance, tt.held)
+		if ok != tt.ok || fmt.Sprint(columns(got)) != fmt.Sprint(tt.want) {
+			t.Fatalf("failed test %#v: got=%#v %#v", tt, columns(got), ok)
+		}
+	}
+
+	// 車いすスペースが埋まっている号車には、介助が必要な予約は入れない
+	occupied := []SeatInformation{
+		{Row: 1, Column: "A", IsAccessible: true, IsOccupied: true},
+		{Row: 1, Column: "C"},
+	}
+	if _, ok := accessibleSeatsForRequest(occupied, true, true); ok {
+		t.Fatalf("failed test %#v", occupied)
+	}
+	if _, ok := accessibleSeatsForRequest([]SeatInformation{{Row: 1, Column: "C"}}, true, false); ok {
+		t.Fatal("failed test: no accessible seats")
+	}
+}
+
+func TestAccessibleSeatHoldUntil(t *testing.T) {
+	jst := time.FixedZone("Asia/Tokyo", 9*60*60)
+	departureAt := time.Date(2020, 1, 1, 6, 0, 0, 0, jst)
+	want := departureAt.Add(-defaultAccessibleSeatRelease)
+	if got := accessibleSeatHoldUntil(departureAt); !got.Equal(want) {
+		t.Fatalf("failed test %#v", got)
+	}
+}
+
+func TestAvailableSeatsForRequest(t *testing.T) {
+	seatList := []Seat{
+		{SeatRow: 1, SeatColumn: "A", IsAccessible: true},
+		{SeatRow: 1, SeatColumn: "C"},
+	}
+
+	tests := []struct {
+		needsAssistance bool
+		held            bool
+		want            int
+	}{
+		// 確保期間中は介助が不要な検索では車いすスペースを数えない
+		{false, true, 1},
+		{false, false, 2},
+		{true, true, 2},
+		{true, false, 2},
+	}
+	for _, tt := range tests {
+		if got := availableSeatsForRequest(seatList, tt.needsAssistance, tt.held); len(got) != tt.want {
+			t.Fatalf("failed test %#v: got=%#v", tt, got)
+		}
+	}
+
+	// 空いているのが確保中の車いすスペースだけなら空席なし
+	if got := availableSeatsForRequest(seatList[:1], false, true); len(got) != 0 {
+		t.Fatalf("failed test %#v", got)
+	}
+}
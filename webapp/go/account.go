@@ -0,0 +1,370 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// user_tokens.purpose
+const (
+	userTokenVerifyEmail   = "verify_email"
+	userTokenResetPassword = "reset_password"
+)
+
+const (
+	verifyEmailTokenTTL   = 24 * time.Hour
+	resetPasswordTokenTTL = time.Hour
+	userTokenBytes        = 32
+)
+
+var (
+	mailer Mailer = newMailerFromEnv()
+
+	errInvalidUserToken = errors.New("invalid or expired token")
+)
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func hashUserToken(token string) string {
+	// DBにはトークンそのものではなくハッシュを保存する
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueUserToken はトークンを発行する。同じ用途の未使用トークンは無効にする
+func issueUserToken(tx *sqlx.Tx, userID int64, purpose string, ttl time.Duration) (string, error) {
+	_, err := tx.Exec(
+		"UPDATE user_tokens SET used_at=NOW() WHERE user_id=? AND purpose=? AND used_at IS NULL",
+		userID, purpose,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	token := secureRandomStr(userTokenBytes)
+	_, err = tx.Exec(
+		"INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at, created_at) VALUES (?, ?, ?, DATE_ADD(NOW(), INTERVAL ? SECOND), NOW())",
+		userID, purpose, hashUserToken(token), int64(ttl/time.Second),
+	)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeUserToken は有効なトークンを使用済みにして、ユーザーIDを返す
+func consumeUserToken(tx *sqlx.Tx, token, purpose string) (int64, error) {
+	var userToken struct {
+		ID     int64 `db:"id"`
+		UserID int64 `db:"user_id"`
+	}
+	err := tx.Get(
+		&userToken,
+		"SELECT id, user_id FROM user_tokens WHERE token_hash=? AND purpose=? AND used_at IS NULL AND expires_at > NOW() FOR UPDATE",
+		hashUserToken(token), purpose,
+	)
+	if err == sql.ErrNoRows {
+		return 0, errInvalidUserToken
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("UPDATE user_tokens SET used_at=NOW() WHERE id=?", userToken.ID)
+	if err != nil {
+		return 0, err
+	}
+	return userToken.UserID, nil
+}
+
+// appBaseURL はメール内のURLの起点。Hostヘッダは偽装できるので、環境変数から組み立てる
+func appBaseURL() string {
+	base := os.Getenv("APP_BASE_URL")
+	if base == "" {
+		base = "http://localhost"
+	}
+	return strings.TrimRight(base, "/")
+}
+
+func accountURL(path, token string) string {
+	return appBaseURL() + path + "?token=" + url.QueryEscape(token)
+}
+
+func sendVerifyEmailMail(email, token string) error {
+	body := fmt.Sprintf(`ISUTRAINへのご登録ありがとうございます。
+
+次のURLを開いて、メールアドレスの確認を完了してください。
+%s
+
+このURLの有効期限は%d時間です。
+お心当たりのない場合は、このメールを破棄してください。
+`, accountURL("/verify-email", token), int(verifyEmailTokenTTL/time.Hour))
+
+	return mailer.Send(MailMessage{
+		To:      email,
+		Subject: "【ISUTRAIN】メールアドレスの確認",
+		Body:    body,
+	})
+}
+
+func sendPasswordResetMail(email, token string) error {
+	body := fmt.Sprintf(`パスワードの再設定を受け付けました。
+
+次のURLを開いて、新しいパスワードを設定してください。
+%s
+
+このURLの有効期限は%d分で、1回だけ使えます。
+お心当たりのない場合は、このメールを破棄してください。パスワードは変更されません。
+`, accountURL("/reset-password", token), int(resetPasswordTokenTTL/time.Minute))
+
+	return mailer.Send(MailMessage{
+		To:      email,
+		Subject: "【ISUTRAIN】パスワードの再設定",
+		Body:    body,
+	})
+}
+
+func verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		メールアドレスの確認
+		POST /api/auth/verify
+	*/
+
+	defer r.Body.Close()
+	buf, _ := ioutil.ReadAll(r.Body)
+
+	req := VerifyEmailRequest{}
+	json.Unmarshal(buf, &req)
+	if req.Token == "" {
+		errorResponse(w, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	tx := dbx.MustBegin()
+	userID, err := consumeUserToken(tx, req.Token, userTokenVerifyEmail)
+	if err == errInvalidUserToken {
+		tx.Rollback()
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	_, err = tx.Exec("UPDATE users SET email_verified_at=NOW() WHERE id=? AND email_verified_at IS NULL", userID)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	messageResponse(w, "email verified")
+}
+
+func resendVerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		メールアドレス確認メールの再送
+		POST /api/auth/verify/resend
+	*/
+
+	user, errCode, errMsg := authenticate(r, scopeReserve)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	if user.EmailVerifiedAt != nil {
+		errorResponse(w, http.StatusBadRequest, "email already verified")
+		return
+	}
+
+	if ok, retryAfter := loginLimit.allow(clientIP(r), strings.ToLower(user.Email)); !ok {
+		tooManyRequestsResponse(w, retryAfter)
+		return
+	}
+
+	tx := dbx.MustBegin()
+	token, err := issueUserToken(tx, user.ID, userTokenVerifyEmail, verifyEmailTokenTTL)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	if err := sendVerifyEmailMail(user.Email, token); err != nil {
+		errorResponse(w, http.StatusInternalServerError, "failed to send mail")
+		log.Println(err.Error())
+		return
+	}
+
+	messageResponse(w, "verification mail sent")
+}
+
+func passwordResetRequestHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		パスワード再設定の受付
+		POST /api/auth/password/reset
+		登録の有無が分からないように、ユーザーが存在しなくても同じレスポンスを返す
+	*/
+
+	defer r.Body.Close()
+	buf, _ := ioutil.ReadAll(r.Body)
+
+	req := PasswordResetRequest{}
+	json.Unmarshal(buf, &req)
+	if req.Email == "" {
+		errorResponse(w, http.StatusBadRequest, "email is required")
+		return
+	}
+
+	if ok, retryAfter := loginLimit.allow(clientIP(r), strings.ToLower(req.Email)); !ok {
+		tooManyRequestsResponse(w, retryAfter)
+		return
+	}
+
+	user := User{}
+	err := dbx.Get(&user, "SELECT * FROM users WHERE email=?", req.Email)
+	if err == sql.ErrNoRows {
+		messageResponse(w, "password reset mail sent")
+		return
+	}
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	tx := dbx.MustBegin()
+	token, err := issueUserToken(tx, user.ID, userTokenResetPassword, resetPasswordTokenTTL)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	if err := sendPasswordResetMail(user.Email, token); err != nil {
+		errorResponse(w, http.StatusInternalServerError, "failed to send mail")
+		log.Println(err.Error())
+		return
+	}
+
+	messageResponse(w, "password reset mail sent")
+}
+
+func passwordResetConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		パスワードの再設定
+		POST /api/auth/password/reset/confirm
+	*/
+
+	defer r.Body.Close()
+	buf, _ := ioutil.ReadAll(r.Body)
+
+	req := PasswordResetConfirmRequest{}
+	json.Unmarshal(buf, &req)
+	if req.Token == "" || req.Password == "" {
+		errorResponse(w, http.StatusBadRequest, "token and password are required")
+		return
+	}
+
+	hashedPassword, err := hashPassword(req.Password)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "password hash error")
+		log.Println(err.Error())
+		return
+	}
+
+	tx := dbx.MustBegin()
+	userID, err := consumeUserToken(tx, req.Token, userTokenResetPassword)
+	if err == errInvalidUserToken {
+		tx.Rollback()
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	user := User{}
+	err = tx.Get(&user, "SELECT * FROM users WHERE id=? FOR UPDATE", userID)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	// メールを受け取れたので、メールアドレスも確認済みにする
+	_, err = tx.Exec(
+		"UPDATE users SET salt='', super_secure_password=?, email_verified_at=COALESCE(email_verified_at, NOW()) WHERE id=?",
+		hashedPassword, userID,
+	)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	// パスワードを間違え続けてロックアウトされていても、再設定後はログインできるようにする
+	loginLimit.recordSuccess(clientIP(r), strings.ToLower(user.Email))
+
+	messageResponse(w, "password updated")
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"goji.io/pat"
+)
+
+// APIトークン (パーソナルアクセストークン)
+//
+//   - キオスク端末やバッチ処理など、ブラウザ以外のクライアント向け
+//   - Authorization: Bearer <token> で送る。DBにはトークンのハッシュだけを保存する
+//   - スコープは search ⊂ reserve。トークンでトークンを発行する場合は、そのトークンのスコープまで
+//   - Cookie のセッションでは search と reserve が使える
+const (
+	scopeSearch  = "search"  // 予約の参照
+	scopeReserve = "reserve" // 予約・支払い・キャンセル
+
+	apiTokenPrefix     = "isutrain_pat_"
+	apiTokenBytes      = 32
+	apiTokenMaxPerUser = 20
+	apiTokenMaxDays    = 365
+)
+
+// スコープの強さ。強いスコープは弱いスコープを含む
+var scopeLevels = map[string]int{
+	scopeSearch:  1,
+	scopeReserve: 2,
+}
+
+type APIToken struct {
+	ID         int64      `json:"id" db:"id"`
+	UserID     int64      `json:"-" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	Hint       string     `json:"hint" db:"token_hint"`
+	Scope      string     `json:"scope" db:"scope"`
+	ExpiresAt  *time.Time `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+type APITokenCreateRequest struct {
+	Name          string `json:"name"`
+	Scope         string `json:"scope"`
+	ExpiresInDays int    `json:"expires_in_days"`
+}
+
+type APITokenCreateResponse struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+func scopeAllows(granted, required string) bool {
+	return scopeLevels[granted] > 0 && scopeLevels[granted] >= scopeLevels[required]
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return "", false
+	}
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return "", true
+	}
+	return strings.TrimSpace(auth[7:]), true
+}
+
+// authenticate は Bearer トークンか Cookie のセッションでユーザーを認証し、scope の権限があるかを確認する
+func authenticate(r *http.Request, scope string) (user User, errCode int, errMsg string) {
+	user, _, errCode, errMsg = authenticateScope(r, scope)
+	return user, errCode, errMsg
+}
+
+// authenticateScope は authenticate に加えて、認証に使ったトークン (Cookie なら reserve) のスコープを返す
+func authenticateScope(r *http.Request, scope string) (user User, granted string, errCode int, errMsg string) {
+	token, ok := bearerToken(r)
+	if !ok {
+		user, errCode, errMsg = getUser(r)
+		if errCode != http.StatusOK {
+			return user, "", errCode, errMsg
+		}
+		if !scopeAllows(scopeReserve, scope) {
+			return user, "", http.StatusForbidden, "insufficient scope"
+		}
+		return user, scopeReserve, http.StatusOK, ""
+	}
+
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return user, "", http.StatusUnauthorized, "invalid token"
+	}
+
+	apiToken := APIToken{}
+	query := "SELECT * FROM api_tokens WHERE token_hash=? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())"
+	err := dbx.Get(&apiToken, query, hashUserToken(token))
+	if err == sql.ErrNoRows {
+		return user, "", http.StatusUnauthorized, "invalid token"
+	}
+	if err != nil {
+		log.Print(err)
+		return user, "", http.StatusInternalServerError, "db error"
+	}
+
+	err = dbx.Get(&user, "SELECT * FROM `users` WHERE `id` = ?", apiToken.UserID)
+	if err == sql.ErrNoRows {
+		return user, "", http.StatusUnauthorized, "user not found"
+	}
+	if err != nil {
+		log.Print(err)
+		return user, "", http.StatusInternalServerError, "db error"
+	}
+
+	if !scopeAllows(apiToken.Scope, scope) {
+		return user, "", http.StatusForbidden, "insufficient scope"
+	}
+
+	// 最終利用日時は分単位で十分なので、頻繁には更新しない
+	if apiToken.LastUsedAt == nil || time.Since(*apiToken.LastUsedAt) > time.Minute {
+		_, err = dbx.Exec("UPDATE api_tokens SET last_used_at=NOW() WHERE id=?", apiToken.ID)
+		if err != nil {
+			log.Print(err)
+		}
+	}
+
+	return user, apiToken.Scope, http.StatusOK, ""
+}
+
+func apiTokenCreateHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		APIトークンの発行
+		POST /api/user/tokens
+		トークンはこのレスポンスでしか返さない。トークンで発行する場合は、そのトークンより強いスコープは発行できない
+	*/
+	user, granted, errCode, errMsg := authenticateScope(r, scopeReserve)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	defer r.Body.Close()
+	buf, _ := ioutil.ReadAll(r.Body)
+
+	req := APITokenCreateRequest{}
+	err := json.Unmarshal(buf, &req)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, "JSON parseに失敗しました")
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		errorResponse(w, http.StatusBadRequest, "トークンの名前が不正です")
+		return
+	}
+	if _, ok := scopeLevels[req.Scope]; !ok {
+		errorResponse(w, http.StatusBadRequest, "スコープが不正です")
+		return
+	}
+	if !scopeAllows(granted, req.Scope) {
+		errorResponse(w, http.StatusForbidden, "insufficient scope")
+		return
+	}
+	if req.ExpiresInDays < 0 || apiTokenMaxDays < req.ExpiresInDays {
+		errorResponse(w, http.StatusBadRequest, fmt.Sprintf("有効期限は%d日以内で指定してください", apiTokenMaxDays))
+		return
+	}
+
+	tx := dbx.MustBegin()
+
+	var count int
+	err = tx.Get(&count, "SELECT COUNT(*) FROM api_tokens WHERE user_id=? AND revoked_at IS NULL FOR UPDATE", user.ID)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	if count >= apiTokenMaxPerUser {
+		tx.Rollback()
+		errorResponse(w, http.StatusBadRequest, fmt.Sprintf("有効なトークンは%d個までです", apiTokenMaxPerUser))
+		return
+	}
+
+	random := secureRandomStr(apiTokenBytes)
+	token := apiTokenPrefix + random
+	// expires_in_days が0なら無期限
+	result, err := tx.Exec(
+		"INSERT INTO api_tokens (user_id, name, token_hash, token_hint, scope, expires_at, created_at) VALUES (?, ?, ?, ?, ?, IF(? > 0, DATE_ADD(NOW(), INTERVAL ? DAY), NULL), NOW())",
+		user.ID, req.Name, hashUserToken(token), random[:8], req.Scope, req.ExpiresInDays, req.ExpiresInDays,
+	)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	resp := APITokenCreateResponse{Token: token}
+	err = tx.Get(&resp.APIToken, "SELECT * FROM api_tokens WHERE id=?", id)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func apiTokensHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		APIトークンの一覧
+		GET /api/user/tokens
+	*/
+	user, errCode, errMsg := authenticate(r, scopeSearch)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	tokenList := []APIToken{}
+	err := dbx.Select(&tokenList, "SELECT * FROM api_tokens WHERE user_id=? ORDER BY id DESC", user.ID)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	json.NewEncoder(w).Encode(tokenList)
+}
+
+func apiTokenRevokeHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		APIトークンの失効
+		POST /api/user/tokens/:token_id/revoke
+	*/
+	user, errCode, errMsg := authenticate(r, scopeReserve)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	tokenID, err := strconv.ParseInt(pat.Param(r, "token_id"), 10, 64)
+	if err != nil || tokenID <= 0 {
+		errorResponse(w, http.StatusBadRequest, "incorrect token id")
+		return
+	}
+
+	result, err := dbx.Exec(
+		"UPDATE api_tokens SET revoked_at=NOW() WHERE id=? AND user_id=? AND revoked_at IS NULL",
+		tokenID, user.ID,
+	)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	if rowsAffected == 0 {
+		errorResponse(w, http.StatusNotFound, "token not found")
+		return
+	}
+
+	messageResponse(w, "revoked")
+}
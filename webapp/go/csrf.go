@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/gorilla/sessions"
+)
+
+// CSRF対策 (synchronizer token + Origin チェック)
+//
+//   - ログイン時にセッションにトークンを発行し、X-CSRF-Token レスポンスヘッダで返す
+//     (GET /api/auth でも取り直せる)
+//   - 状態を変更するリクエストでは、同じ値を X-CSRF-Token リクエストヘッダで送ってもらう
+//   - Origin (無ければ Referer) が付いている場合は、自分自身か CSRF_TRUSTED_ORIGINS に
+//     含まれるオリジンでなければ拒否する
+const (
+	csrfHeaderName     = "X-CSRF-Token"
+	csrfSessionKey     = "csrf_token"
+	csrfTokenBytes     = 32
+	csrfTrustedOrigins = "CSRF_TRUSTED_ORIGINS"
+)
+
+// セッションを使わない、またはセッション確立前に呼ばれるエンドポイント
+var csrfExemptPaths = map[string]bool{
+	"/initialize":                      true,
+	"/api/auth/signup":                 true,
+	"/api/auth/login":                  true,
+	"/api/auth/verify":                 true,
+	"/api/auth/password/reset":         true,
+	"/api/auth/password/reset/confirm": true,
+	"/api/ticket/verify":               true,
+	"/api/ticket/checkin":              true,
+	"/api/payment/webhook":             true,
+}
+
+func csrfSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// issueCSRFToken はセッションに新しいトークンを発行する (保存は呼び出し側で行う)
+func issueCSRFToken(session *sessions.Session) string {
+	token := secureRandomStr(csrfTokenBytes)
+	session.Values[csrfSessionKey] = token
+	return token
+}
+
+func sessionCSRFToken(session *sessions.Session) string {
+	token, _ := session.Values[csrfSessionKey].(string)
+	return token
+}
+
+func csrfTrustedOriginList() []string {
+	origins := []string{}
+	for _, origin := range strings.Split(os.Getenv(csrfTrustedOrigins), ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// csrfOriginAllowed は Origin / Referer が自分自身か信頼済みのオリジンかを返す
+// どちらも無い場合 (ブラウザ以外のクライアント) は許可し、トークンの検証に任せる
+func csrfOriginAllowed(r *http.Request, trusted []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Header.Get("Referer")
+		if origin == "" {
+			return true
+		}
+	}
+	if origin == "null" {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, t := range trusted {
+		if strings.EqualFold(u.Scheme+"://"+u.Host, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func sessionLoggedIn(session *sessions.Session) bool {
+	// ログアウト時は user_id に 0 を入れるので、0 以外のときだけログイン中とみなす
+	switch userID := session.Values["user_id"].(type) {
+	case int64:
+		return userID != 0
+	case int:
+		return userID != 0
+	}
+	return false
+}
+
+func csrfMiddleware(next http.Handler) http.Handler {
+	trusted := csrfTrustedOriginList()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if csrfSafeMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Bearer トークンはブラウザが自動で送ることはないので対象外
+		if _, ok := bearerToken(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !csrfOriginAllowed(r, trusted) {
+			log.Printf("csrf: origin rejected: %s %s origin=%q referer=%q", r.Method, r.URL.Path, r.Header.Get("Origin"), r.Header.Get("Referer"))
+			errorResponse(w, http.StatusForbidden, "invalid origin")
+			return
+		}
+
+		if csrfExemptPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// トークンはログイン時に発行されるので、無ければ未ログイン。認証エラーはハンドラに任せる
+		// ログイン中なのにトークンが無いセッション (トークン導入前のログインなど) は拒否し、ログインし直してもらう
+		session := getSession(r)
+		expected := sessionCSRFToken(session)
+		if expected == "" {
+			if sessionLoggedIn(session) {
+				errorResponse(w, http.StatusForbidden, "invalid csrf token")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		actual := r.Header.Get(csrfHeaderName)
+		if subtle.ConstantTimeCompare([]byte(actual), []byte(expected)) != 1 {
+			errorResponse(w, http.StatusForbidden, "invalid csrf token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func csrfSessionCookie(t *testing.T) (*http.Cookie, string) {
+	// ログイン済みセッションのCookieと発行されたトークンを作る
+	return newCSRFSessionCookie(t, true)
+}
+
+func newCSRFSessionCookie(t *testing.T, withToken bool) (*http.Cookie, string) {
+	return newSessionCookie(t, int64(1), withToken)
+}
+
+func newSessionCookie(t *testing.T, userID interface{}, withToken bool) (*http.Cookie, string) {
+	r := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+	w := httptest.NewRecorder()
+	session := getSession(r)
+	session.Values["user_id"] = userID
+	token := ""
+	if withToken {
+		token = issueCSRFToken(session)
+	}
+	if err := session.Save(r, w); err != nil {
+		t.Fatalf("failed test %#v", err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("failed test %#v", cookies)
+	}
+	return cookies[0], token
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	cookie, token := csrfSessionCookie(t)
+
+	h := csrfMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method string
+		path   string
+		cookie bool
+		token  string
+		origin string
+		want   int
+	}{
+		// 参照系はトークン不要
+		{http.MethodGet, "/api/user/reservations", true, "", "", http.StatusOK},
+		// ログイン済みの更新系はトークン必須
+		{http.MethodPost, "/api/train/reserve", true, token, "", http.StatusOK},
+		{http.MethodPost, "/api/train/reserve", true, "", "", http.StatusForbidden},
+		{http.MethodPost, "/api/train/reserve", true, "invalid", "", http.StatusForbidden},
+		{http.MethodPost, "/api/train/reservation/commit", true, "", "", http.StatusForbidden},
+		{http.MethodPost, "/api/user/reservations/1/cancel", true, "", "", http.StatusForbidden},
+		{http.MethodPost, "/api/auth/logout", true, "", "", http.StatusForbidden},
+		// 未ログインならハンドラに任せる
+		{http.MethodPost, "/api/train/reserve", false, "", "", http.StatusOK},
+		// ログインはトークン不要
+		{http.MethodPost, "/api/auth/login", true, "", "", http.StatusOK},
+		// 自分自身以外のオリジンからは拒否
+		{http.MethodPost, "/api/train/reserve", true, token, "http://example.com", http.StatusOK},
+		{http.MethodPost, "/api/train/reserve", true, token, "https://evil.example.org", http.StatusForbidden},
+		{http.MethodPost, "/api/auth/login", false, "", "https://evil.example.org", http.StatusForbidden},
+		{http.MethodPost, "/api/auth/login", false, "", "null", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "http://example.com"+tt.path, nil)
+		if tt.cookie {
+			r.AddCookie(cookie)
+		}
+		if tt.token != "" {
+			r.Header.Set(csrfHeaderName, tt.token)
+		}
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Fatalf("failed test %#v: got=%d", tt, w.Code)
+		}
+	}
+
+	// ログイン中なのにトークンの無いセッションは拒否
+	noTokenCookie, _ := newCSRFSessionCookie(t, false)
+	r := httptest.NewRequest(http.MethodPost, "http://example.com/api/train/reserve", nil)
+	r.AddCookie(noTokenCookie)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("failed test: session without csrf token got=%d", w.Code)
+	}
+
+	// ログアウト後のセッション (user_id=0, トークン無し) はハンドラに任せて 401 を返してもらう
+	loggedOutCookie, _ := newSessionCookie(t, 0, false)
+	r = httptest.NewRequest(http.MethodPost, "http://example.com/api/train/reserve", nil)
+	r.AddCookie(loggedOutCookie)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed test: logged out session got=%d", w.Code)
+	}
+}
+
+func TestCSRFOriginAllowed(t *testing.T) {
+	trusted := []string{"http://localhost:8080"}
+
+	tests := []struct {
+		origin  string
+		referer string
+		want    bool
+	}{
+		{"", "", true},
+		{"http://example.com", "", true},
+		{"https://example.com", "", true},
+		{"http://localhost:8080", "", true},
+		{"https://localhost:8080", "", false},
+		{"http://example.com.evil.example.org", "", false},
+		{"", "http://example.com/reservation", true},
+		{"", "https://evil.example.org/", false},
+		{"null", "", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com/api/train/reserve", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if tt.referer != "" {
+			r.Header.Set("Referer", tt.referer)
+		}
+		if got := csrfOriginAllowed(r, trusted); got != tt.want {
+			t.Fatalf("failed test %#v", tt)
+		}
+	}
+}
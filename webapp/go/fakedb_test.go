@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// テスト用の database/sql ドライバ
+// クエリに含まれる文字列ごとに返す行を登録しておき、実行した更新系のクエリを記録する
+type fakeDB struct {
+	t       *testing.T
+	mu      sync.Mutex
+	rules   []fakeRule
+	execs   []fakeExec
+	queries []string
+}
+
+type fakeRule struct {
+	match string
+	fn    func(args []driver.Value) fakeResult
+}
+
+type fakeResult struct {
+	columns      []string
+	rows         [][]driver.Value
+	rowsAffected int64
+	lastInsertId int64
+	err          error
+}
+
+type fakeExec struct {
+	query string
+	args  []driver.Value
+}
+
+// withFakeDB は dbx を fakeDB に差し替える。戻り値の関数で元に戻す
+func withFakeDB(t *testing.T) (*fakeDB, func()) {
+	f := &fakeDB{t: t}
+	orig := dbx
+	dbx = sqlx.NewDb(sql.OpenDB(f), "mysql")
+	return f, func() {
+		dbx.Close()
+		dbx = orig
+	}
+}
+
+// on は match を含むクエリに fn の結果を返す。先に登録したものが優先
+func (f *fakeDB) on(match string, fn func(args []driver.Value) fakeResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, fakeRule{match, fn})
+}
+
+// rows は match を含むクエリに常に同じ行を返す
+func (f *fakeDB) rows(match string, columns []string, rows ...[]driver.Value) {
+	f.on(match, func([]driver.Value) fakeResult {
+		return fakeResult{columns: columns, rows: rows}
+	})
+}
+
+// executed は match を含む更新系のクエリの引数を実行した順に返す
+func (f *fakeDB) executed(match string) [][]driver.Value {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ret := [][]driver.Value{}
+	for _, e := range f.execs {
+		if strings.Contains(e.query, match) {
+			ret = append(ret, e.args)
+		}
+	}
+	return ret
+}
+
+// lastQuery は最後に実行したクエリを返す
+func (f *fakeDB) lastQuery() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.queries) == 0 {
+		return ""
+	}
+	return f.queries[len(f.queries)-1]
+}
+
+func (f *fakeDB) result(query string, args []driver.Value) fakeResult {
+	f.mu.Lock()
+	f.queries = append(f.queries, query)
+	rules := append([]fakeRule{}, f.rules...)
+	f.mu.Unlock()
+	for _, rule := range rules {
+		if strings.Contains(query, rule.match) {
+			return rule.fn(args)
+		}
+	}
+	f.t.Errorf("unexpected query: %s %v", query, args)
+	return fakeResult{err: fmt.Errorf("unexpected query")}
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, fmt.Errorf("not supported") }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, fmt.Errorf("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res := c.db.result(query, namedValues(args))
+	if res.err != nil {
+		return nil, res.err
+	}
+	return &fakeRows{columns: res.columns, rows: res.rows}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	values := namedValues(args)
+	c.db.mu.Lock()
+	c.db.execs = append(c.db.execs, fakeExec{query, values})
+	c.db.mu.Unlock()
+	res := c.db.result(query, values)
+	if res.err != nil {
+		return nil, res.err
+	}
+	return fakeExecResult(res), nil
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeExecResult fakeResult
+
+func (r fakeExecResult) LastInsertId() (int64, error) { return r.lastInsertId, nil }
+func (r fakeExecResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	i       int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.i])
+	r.i++
+	return nil
+}
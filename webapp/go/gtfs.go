@@ -0,0 +1,333 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// 駅の擬似座標の基準 (東京駅) と路線の向き (東京から大阪方面)
+const (
+	gtfsOriginLat  = 35.681236
+	gtfsOriginLon  = 139.767125
+	gtfsBearingLat = -0.270
+	gtfsBearingLon = -0.963
+	gtfsKmPerDeg   = 111.0
+)
+
+// GTFS の route_type (2 = Rail)
+const gtfsRouteTypeRail = 2
+
+type gtfsTimetable struct {
+	Date       time.Time `db:"date"`
+	TrainClass string    `db:"train_class"`
+	TrainName  string    `db:"train_name"`
+	Station    string    `db:"station"`
+	Departure  string    `db:"departure"`
+	Arrival    string    `db:"arrival"`
+}
+
+type gtfsStopTime struct {
+	StopID        int
+	StopSequence  int
+	ArrivalTime   string
+	DepartureTime string
+}
+
+func gtfsStationCoordinate(distance float64) (float64, float64) {
+	// distance(km) だけ基準点から路線の向きにずらした座標
+	lat := gtfsOriginLat + gtfsBearingLat*distance/gtfsKmPerDeg
+	lon := gtfsOriginLon + gtfsBearingLon*distance/(gtfsKmPerDeg*math.Cos(gtfsOriginLat*math.Pi/180))
+	return lat, lon
+}
+
+func gtfsRouteID(trainClass string) string {
+	// 列車クラス(最速など)を route_id (express など) にする
+	for key, name := range TrainClassMap {
+		if name == trainClass {
+			return key
+		}
+	}
+	return trainClass
+}
+
+func gtfsTripID(date time.Time, trainClass, trainName string) string {
+	return fmt.Sprintf("%s_%s_%s", date.Format("20060102"), gtfsRouteID(trainClass), trainName)
+}
+
+func gtfsParseClock(s string) (int, error) {
+	var h, m, sec int
+	_, err := fmt.Sscanf(s, "%d:%d:%d", &h, &m, &sec)
+	if err != nil {
+		return 0, err
+	}
+	return h*3600 + m*60 + sec, nil
+}
+
+func gtfsFormatClock(sec int) string {
+	// GTFS では運行日の0時からの経過時間なので24時を超えることがある
+	return fmt.Sprintf("%02d:%02d:%02d", sec/3600, sec/60%60, sec%60)
+}
+
+func gtfsBuildStopTimes(train Train, timetableList []gtfsTimetable, stationMap map[string]Station) ([]gtfsStopTime, error) {
+	// 列車の進行方向に駅を並べ、始発駅から終着駅までの停車駅を返す
+	start, ok := stationMap[train.StartStation]
+	if !ok {
+		return nil, fmt.Errorf("unknown station: %s", train.StartStation)
+	}
+	last, ok := stationMap[train.LastStation]
+	if !ok {
+		return nil, fmt.Errorf("unknown station: %s", train.LastStation)
+	}
+	lo, hi := start.ID, last.ID
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+
+	stops := []gtfsTimetable{}
+	for _, t := range timetableList {
+		station, ok := stationMap[t.Station]
+		if !ok {
+			return nil, fmt.Errorf("unknown station: %s", t.Station)
+		}
+		if station.ID < lo || hi < station.ID {
+			continue
+		}
+		stops = append(stops, t)
+	}
+	sort.Slice(stops, func(i, j int) bool {
+		if train.IsNobori {
+			return stationMap[stops[i].Station].ID > stationMap[stops[j].Station].ID
+		}
+		return stationMap[stops[i].Station].ID < stationMap[stops[j].Station].ID
+	})
+
+	stopTimes := []gtfsStopTime{}
+	prev, offset := 0, 0
+	for i, t := range stops {
+		arrival, err := gtfsParseClock(t.Arrival)
+		if err != nil {
+			return nil, err
+		}
+		departure, err := gtfsParseClock(t.Departure)
+		if err != nil {
+			return nil, err
+		}
+
+		// 始発駅は到着時刻=発車時刻とする
+		if i == 0 {
+			arrival = departure
+		}
+
+		// 時刻が戻ったら日付をまたいだとみなす
+		if arrival+offset < prev {
+			offset += 24 * 3600
+		}
+		arrival += offset
+		if departure+offset < arrival {
+			departure += 24 * 3600
+		}
+		departure += offset
+
+		// 終着駅は発車時刻=到着時刻とする
+		if i == len(stops)-1 {
+			departure = arrival
+		}
+
+		stopTimes = append(stopTimes, gtfsStopTime{
+			StopID:        stationMap[t.Station].ID,
+			StopSequence:  i + 1,
+			ArrivalTime:   gtfsFormatClock(arrival),
+			DepartureTime: gtfsFormatClock(departure),
+		})
+		prev = departure
+	}
+	return stopTimes, nil
+}
+
+func gtfsWriteCSV(zw *zip.Writer, name string, header []string, records [][]string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	cw.Write(header)
+	cw.WriteAll(records)
+	return cw.Error()
+}
+
+func gtfsHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		GTFS (静的) フィードの出力
+		GET /api/gtfs.zip
+	*/
+
+	stationList := []Station{}
+	err := dbx.Select(&stationList, "SELECT * FROM station_master ORDER BY id")
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "駅データの取得に失敗しました")
+		log.Println(err.Error())
+		return
+	}
+	stationMap := map[string]Station{}
+	for _, station := range stationList {
+		stationMap[station.Name] = station
+	}
+
+	trainList := []Train{}
+	err = dbx.Select(&trainList, "SELECT * FROM train_master ORDER BY date, train_class, train_name")
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "列車データの取得に失敗しました")
+		log.Println(err.Error())
+		return
+	}
+	trainMap := map[string]Train{}
+	for _, train := range trainList {
+		trainMap[gtfsTripID(train.Date, train.TrainClass, train.TrainName)] = train
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"isutrain_gtfs.zip\"")
+
+	// ここから先はレスポンスを書き始めているのでエラーはログに出して打ち切る
+	zw := zip.NewWriter(w)
+	defer zw.Close()
+
+	err = gtfsWriteCSV(zw, "agency.txt",
+		[]string{"agency_id", "agency_name", "agency_url", "agency_timezone", "agency_lang"},
+		[][]string{{"isutrain", "ISUTRAIN", "https://isutrain.example.com/", "Asia/Tokyo", "ja"}},
+	)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	records := [][]string{}
+	for _, station := range stationList {
+		lat, lon := gtfsStationCoordinate(station.Distance)
+		records = append(records, []string{
+			fmt.Sprint(station.ID), station.Name,
+			fmt.Sprintf("%.6f", lat), fmt.Sprintf("%.6f", lon),
+		})
+	}
+	err = gtfsWriteCSV(zw, "stops.txt", []string{"stop_id", "stop_name", "stop_lat", "stop_lon"}, records)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	records = [][]string{}
+	for _, key := range []string{"express", "semi_express", "local"} {
+		records = append(records, []string{key, "isutrain", TrainClassMap[key], "", fmt.Sprint(gtfsRouteTypeRail)})
+	}
+	err = gtfsWriteCSV(zw, "routes.txt", []string{"route_id", "agency_id", "route_short_name", "route_long_name", "route_type"}, records)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	records = [][]string{}
+	dateRecords := [][]string{}
+	dateSeen := map[string]bool{}
+	for _, train := range trainList {
+		serviceID := train.Date.Format("20060102")
+		directionID := "0"
+		if train.IsNobori {
+			directionID = "1"
+		}
+		records = append(records, []string{
+			gtfsRouteID(train.TrainClass), serviceID,
+			gtfsTripID(train.Date, train.TrainClass, train.TrainName),
+			train.LastStation, train.TrainName, directionID,
+		})
+		if !dateSeen[serviceID] {
+			dateSeen[serviceID] = true
+			dateRecords = append(dateRecords, []string{serviceID, serviceID, "1"})
+		}
+	}
+	err = gtfsWriteCSV(zw, "trips.txt", []string{"route_id", "service_id", "trip_id", "trip_headsign", "trip_short_name", "direction_id"}, records)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	err = gtfsWriteCSV(zw, "calendar_dates.txt", []string{"service_id", "date", "exception_type"}, dateRecords)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	// stop_times は件数が多いので列車ごとに読みながら書き出す
+	f, err := zw.Create("stop_times.txt")
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	cw := csv.NewWriter(f)
+	cw.Write([]string{"trip_id", "arrival_time", "departure_time", "stop_id", "stop_sequence"})
+
+	rows, err := dbx.Queryx("SELECT date, train_class, train_name, station, departure, arrival FROM train_timetable_master ORDER BY date, train_class, train_name")
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	defer rows.Close()
+
+	flush := func(tripID string, timetableList []gtfsTimetable) error {
+		train, ok := trainMap[tripID]
+		if !ok {
+			// train_master に無い列車は出力しない
+			return nil
+		}
+		stopTimes, err := gtfsBuildStopTimes(train, timetableList, stationMap)
+		if err != nil {
+			return err
+		}
+		for _, st := range stopTimes {
+			cw.Write([]string{tripID, st.ArrivalTime, st.DepartureTime, fmt.Sprint(st.StopID), fmt.Sprint(st.StopSequence)})
+		}
+		return nil
+	}
+
+	currentTripID := ""
+	timetableList := []gtfsTimetable{}
+	for rows.Next() {
+		t := gtfsTimetable{}
+		err = rows.StructScan(&t)
+		if err != nil {
+			log.Println(err.Error())
+			return
+		}
+		tripID := gtfsTripID(t.Date, t.TrainClass, t.TrainName)
+		if tripID != currentTripID && len(timetableList) > 0 {
+			err = flush(currentTripID, timetableList)
+			if err != nil {
+				log.Println(err.Error())
+				return
+			}
+			timetableList = timetableList[:0]
+		}
+		currentTripID = tripID
+		timetableList = append(timetableList, t)
+	}
+	if err = rows.Err(); err != nil {
+		log.Println(err.Error())
+		return
+	}
+	if len(timetableList) > 0 {
+		err = flush(currentTripID, timetableList)
+		if err != nil {
+			log.Println(err.Error())
+			return
+		}
+	}
+
+	cw.Flush()
+	if err = cw.Error(); err != nil {
+		log.Println(err.Error())
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGtfsBuildStopTimes(t *testing.T) {
+
+	stationMap := map[string]Station{
+		"東京":  {ID: 1, Name: "東京"},
+		"古岡":  {ID: 2, Name: "古岡"},
+		"油交":  {ID: 3, Name: "油交"},
+		"名古屋": {ID: 4, Name: "名古屋"},
+	}
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	timetableList := []gtfsTimetable{
+		{Date: date, Station: "東京", Arrival: "23:40:00", Departure: "23:45:00"},
+		{Date: date, Station: "古岡", Arrival: "23:55:00", Departure: "23:58:00"},
+		{Date: date, Station: "油交", Arrival: "00:10:00", Departure: "00:12:00"},
+		{Date: date, Station: "名古屋", Arrival: "00:30:00", Departure: "00:32:00"},
+	}
+
+	// 下り: 油交止まり
+	train := Train{StartStation: "東京", LastStation: "油交"}
+	stopTimes, err := gtfsBuildStopTimes(train, timetableList, stationMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stopTimes) != 3 {
+		t.Fatalf("failed test %#v", stopTimes)
+	}
+	if stopTimes[0].ArrivalTime != "23:45:00" || stopTimes[0].StopSequence != 1 {
+		t.Fatalf("failed test %#v", stopTimes[0])
+	}
+	// 日付をまたいだ後は24時以降で表す
+	if stopTimes[2].ArrivalTime != "24:10:00" || stopTimes[2].DepartureTime != "24:10:00" || stopTimes[2].StopID != 3 {
+		t.Fatalf("failed test %#v", stopTimes[2])
+	}
+
+	// 上り: 駅IDの大きい方から並ぶ
+	train = Train{StartStation: "名古屋", LastStation: "古岡", IsNobori: true}
+	stopTimes, err = gtfsBuildStopTimes(train, timetableList, stationMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stopTimes) != 3 || stopTimes[0].StopID != 4 || stopTimes[2].StopID != 2 {
+		t.Fatalf("failed test %#v", stopTimes)
+	}
+
+	_, err = gtfsBuildStopTimes(Train{StartStation: "大阪", LastStation: "東京"}, timetableList, stationMap)
+	if err == nil {
+		t.Fatalf("failed test: unknown station accepted")
+	}
+}
+
+func TestGtfsStationCoordinate(t *testing.T) {
+
+	lat, lon := gtfsStationCoordinate(0)
+	if lat != gtfsOriginLat || lon != gtfsOriginLon {
+		t.Fatalf("failed test %f %f", lat, lon)
+	}
+
+	// 遠い駅ほど西にある
+	_, lon1 := gtfsStationCoordinate(100)
+	_, lon2 := gtfsStationCoordinate(400)
+	if !(lon2 < lon1 && lon1 < gtfsOriginLon) {
+		t.Fatalf("failed test %f %f", lon1, lon2)
+	}
+}
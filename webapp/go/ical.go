@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// iCalendar の1行の最大長 (オクテット)
+const icalLineLimit = 75
+
+const icalTimezone = "BEGIN:VTIMEZONE\r\n" +
+	"TZID:Asia/Tokyo\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:19700101T000000\r\n" +
+	"TZOFFSETFROM:+0900\r\n" +
+	"TZOFFSETTO:+0900\r\n" +
+	"TZNAME:JST\r\n" +
+	"END:STANDARD\r\n" +
+	"END:VTIMEZONE\r\n"
+
+func icalEscape(s string) string {
+	// RFC 5545 3.3.11 TEXT
+	r := strings.NewReplacer(
+		"\\", "\\\\",
+		";", "\\;",
+		",", "\\,",
+		"\r\n", "\\n",
+		"\n", "\\n",
+	)
+	return r.Replace(s)
+}
+
+func icalFold(line string) string {
+	// 75オクテットを超える行は CRLF + 空白 で折り返す (UTF-8の途中では切らない)
+	var b strings.Builder
+	n := 0
+	for _, c := range line {
+		size := len(string(c))
+		if n+size > icalLineLimit {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(c)
+		n += size
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+func icalEventTimes(date, departureTime, arrivalTime string) (time.Time, time.Time, error) {
+	// 時刻表の時刻はJST。到着が出発より前なら日をまたいでいる
+	jst := time.FixedZone("Asia/Tokyo", 9*60*60)
+	dep, err := time.ParseInLocation("2006/01/02 15:04:05", date+" "+departureTime, jst)
+	if err != nil {
+		return dep, dep, err
+	}
+	arr, err := time.ParseInLocation("2006/01/02 15:04:05", date+" "+arrivalTime, jst)
+	if err != nil {
+		return dep, arr, err
+	}
+	if arr.Before(dep) {
+		arr = arr.AddDate(0, 0, 1)
+	}
+	return dep, arr, nil
+}
+
+func renderReservationsICS(reservationList []Reservation, reservationResponseList []ReservationResponse, now time.Time) (string, error) {
+	// reservationResponseList は reservationList と同じ順序であること
+	// 出発時刻を過ぎた予約は出力しない
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\n")
+	b.WriteString("VERSION:2.0\r\n")
+	b.WriteString("PRODID:-//ISUTRAIN//Reservations//JA\r\n")
+	b.WriteString("CALSCALE:GREGORIAN\r\n")
+	b.WriteString("METHOD:PUBLISH\r\n")
+	b.WriteString("X-WR-CALNAME:ISUTRAIN\r\n")
+	b.WriteString("X-WR-TIMEZONE:Asia/Tokyo\r\n")
+	b.WriteString(icalTimezone)
+
+	dtstamp := now.UTC().Format("20060102T150405Z")
+	for i, res := range reservationResponseList {
+		dep, arr, err := icalEventTimes(res.Date, res.DepartureTime, res.ArrivalTime)
+		if err != nil {
+			return "", err
+		}
+		if dep.Before(now) {
+			continue
+		}
+
+		lines := []string{
+			fmt.Sprintf("列車: %s %s号", res.TrainClass, res.TrainName),
+			fmt.Sprintf("区間: %s %s → %s %s", res.Departure, res.DepartureTime, res.Arrival, res.ArrivalTime),
+		}
+		if res.CarNumber == 0 {
+			lines = append(lines, "号車: 自由席")
+		} else {
+			seats := []string{}
+			for _, seat := range res.Seats {
+				seats = append(seats, fmt.Sprintf("%d%s", seat.SeatRow, seat.SeatColumn))
+			}
+			lines = append(lines, fmt.Sprintf("号車: %d号車", res.CarNumber))
+			lines = append(lines, "座席: "+strings.Join(seats, " "))
+		}
+		lines = append(lines, fmt.Sprintf("人数: 大人%d 小人%d", res.Adult, res.Child))
+		lines = append(lines, fmt.Sprintf("予約番号: %d", res.ReservationId))
+		description := strings.Join(lines, "\n")
+
+		status := "TENTATIVE"
+		if reservationList[i].Status == "done" {
+			status = "CONFIRMED"
+		}
+
+		b.WriteString("BEGIN:VEVENT\r\n")
+		b.WriteString(fmt.Sprintf("UID:reservation-%d@isutrain\r\n", res.ReservationId))
+		b.WriteString("DTSTAMP:" + dtstamp + "\r\n")
+		b.WriteString("DTSTART;TZID=Asia/Tokyo:" + dep.Format("20060102T150405") + "\r\n")
+		b.WriteString("DTEND;TZID=Asia/Tokyo:" + arr.Format("20060102T150405") + "\r\n")
+		b.WriteString(icalFold("SUMMARY:" + icalEscape(fmt.Sprintf("%s %s号 %s → %s", res.TrainClass, res.TrainName, res.Departure, res.Arrival))))
+		b.WriteString(icalFold("LOCATION:" + icalEscape(res.Departure)))
+		b.WriteString(icalFold("DESCRIPTION:" + icalEscape(description)))
+		b.WriteString("STATUS:" + status + "\r\n")
+		b.WriteString("END:VEVENT\r\n")
+	}
+
+	b.WriteString("END:VCALENDAR\r\n")
+	return b.String(), nil
+}
+
+func userReservationsICSHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		予約一覧のiCalendar出力
+		GET /api/user/reservations.ics
+		これから乗車する仮予約・支払い済みの予約を1件1VEVENTとして返す
+	*/
+	user, errCode, errMsg := authenticate(r, scopeSearch)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	// 前日以前の予約は出発済みなので読まない (当日の出発済みの予約は renderReservationsICS で除く)
+	now := time.Now()
+	today := now.In(time.FixedZone("Asia/Tokyo", 9*60*60)).Format("2006-01-02")
+	reservationList := []Reservation{}
+	query := "SELECT * FROM reservations WHERE user_id=? AND status IN ('requesting', 'done') AND date >= ? ORDER BY date, reservation_id"
+	err := dbx.Select(&reservationList, query, user.ID, today)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reservationResponseList, err := makeReservationResponses(reservationList)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		log.Println("makeReservationResponses()", err)
+		return
+	}
+
+	ics, err := renderReservationsICS(reservationList, reservationResponseList, now)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "iCalendarの生成に失敗しました")
+		log.Println(err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar;charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"isutrain.ics\"")
+	w.Write([]byte(ics))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderReservationsICS(t *testing.T) {
+
+	reservationList := []Reservation{{ReservationId: 1, Status: "done"}, {ReservationId: 2, Status: "requesting"}}
+	reservationResponseList := []ReservationResponse{
+		{
+			ReservationId: 1, Date: "2020/01/01", TrainClass: "最速", TrainName: "1",
+			CarNumber: 4, Departure: "東京", Arrival: "大阪",
+			DepartureTime: "06:00:00", ArrivalTime: "08:30:00",
+			Adult: 1, Seats: []SeatReservation{{SeatRow: 1, SeatColumn: "A"}, {SeatRow: 1, SeatColumn: "B"}},
+		},
+		{
+			ReservationId: 2, Date: "2020/01/01", TrainClass: "遅いやつ", TrainName: "99",
+			Departure: "東京", Arrival: "大阪",
+			DepartureTime: "23:30:00", ArrivalTime: "01:10:00",
+			Adult: 1,
+		},
+	}
+
+	ics, err := renderReservationsICS(reservationList, reservationResponseList, time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"BEGIN:VTIMEZONE\r\nTZID:Asia/Tokyo\r\n",
+		"UID:reservation-1@isutrain\r\n",
+		"DTSTART;TZID=Asia/Tokyo:20200101T060000\r\n",
+		"DTEND;TZID=Asia/Tokyo:20200101T083000\r\n",
+		"STATUS:CONFIRMED\r\n",
+		"UID:reservation-2@isutrain\r\n",
+		// 日をまたぐ列車
+		"DTEND;TZID=Asia/Tokyo:20200102T011000\r\n",
+		"STATUS:TENTATIVE\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Fatalf("failed test: %q not found in\n%s", want, ics)
+		}
+	}
+
+	// 折り返しを戻すと座席が含まれている
+	unfolded := strings.Replace(ics, "\r\n ", "", -1)
+	if !strings.Contains(unfolded, "座席: 1A 1B") {
+		t.Fatalf("failed test %s", unfolded)
+	}
+
+	for _, line := range strings.Split(ics, "\r\n") {
+		if len(line) > icalLineLimit {
+			t.Fatalf("failed test: line too long %q", line)
+		}
+	}
+}
+
+func TestIcalEscape(t *testing.T) {
+
+	got := icalEscape("a,b;c\\d\ne")
+	if got != `a\,b\;c\\d\ne` {
+		t.Fatalf("failed test %s", got)
+	}
+}
+
+func TestRenderReservationsICSUpcoming(t *testing.T) {
+
+	reservationList := []Reservation{{ReservationId: 1, Status: "done"}, {ReservationId: 2, Status: "done"}}
+	reservationResponseList := []ReservationResponse{
+		{
+			ReservationId: 1, Date: "2020/01/01", TrainClass: "最速", TrainName: "1",
+			Departure: "東京", Arrival: "大阪",
+			DepartureTime: "06:00:00", ArrivalTime: "08:30:00",
+			Adult: 1,
+		},
+		{
+			ReservationId: 2, Date: "2020/01/01", TrainClass: "最速", TrainName: "2",
+			Departure: "東京", Arrival: "大阪",
+			DepartureTime: "12:00:00", ArrivalTime: "14:30:00",
+			Adult: 1,
+		},
+	}
+
+	// 2020/01/01 09:00 JST: 1件目は出発済み、2件目はこれから
+	ics, err := renderReservationsICS(reservationList, reservationResponseList, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(ics, "UID:reservation-1@isutrain") {
+		t.Fatalf("failed test: departed reservation exported\n%s", ics)
+	}
+	if !strings.Contains(ics, "UID:reservation-2@isutrain") {
+		t.Fatalf("failed test: upcoming reservation not exported\n%s", ics)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/quotedprintable"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const defaultMailFrom = "ISUTRAIN <no-reply@isutrain.example.com>"
+
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer はメールの送信手段
+type Mailer interface {
+	Send(msg MailMessage) error
+}
+
+// FileMailer は送信する代わりに Dir に .eml ファイルとして書き出す (開発・テスト用)
+type FileMailer struct {
+	Dir  string
+	From string
+	now  func() time.Time
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{Dir: dir, From: from, now: time.Now}
+}
+
+var mailFileNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+func (m *FileMailer) Send(msg MailMessage) error {
+	if err := os.MkdirAll(m.Dir, 0755); err != nil {
+		return err
+	}
+
+	now := m.now()
+	b, err := renderMail(m.From, msg, now)
+	if err != nil {
+		return err
+	}
+
+	// 時刻順に並ぶように、ファイル名の先頭はナノ秒単位の時刻にする
+	name := fmt.Sprintf("%d_%s.eml", now.UnixNano(), mailFileNameUnsafe.ReplaceAllString(msg.To, "_"))
+	return ioutil.WriteFile(filepath.Join(m.Dir, name), b, 0644)
+}
+
+// discardMailer はメールを送らない
+type discardMailer struct{}
+
+func (discardMailer) Send(msg MailMessage) error {
+	return nil
+}
+
+func newMailerFromEnv() Mailer {
+	// MAIL_DIR が無ければメールは捨てる (ベンチマーク中に大量のファイルを作らないため)
+	dir := os.Getenv("MAIL_DIR")
+	if dir == "" {
+		return discardMailer{}
+	}
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = defaultMailFrom
+	}
+	return NewFileMailer(dir, from)
+}
+
+func renderMail(from string, msg MailMessage, now time.Time) ([]byte, error) {
+	// ヘッダインジェクション対策
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(from, "\r\n") {
+		return nil, fmt.Errorf("invalid mail address: %q", msg.To)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@isutrain>\r\n", secureRandomStr(16))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	b.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&b)
+	if _, err := qp.Write(bytes.Replace([]byte(msg.Body), []byte("\n"), []byte("\r\n"), -1)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "isutrain_mail")
+	if err != nil {
+		t.Fatalf("failed test %#v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := NewFileMailer(filepath.Join(dir, "inbox"), defaultMailFrom)
+	m.now = func() time.Time { return time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC) }
+
+	token := "0123456789abcdef"
+	os.Setenv("APP_BASE_URL", "https://isutrain.example.com/")
+	defer os.Unsetenv("APP_BASE_URL")
+	mailer = m
+	defer func() { mailer = newMailerFromEnv() }()
+
+	err = sendPasswordResetMail("user+1@example.com", token)
+	if err != nil {
+		t.Fatalf("failed test %#v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "inbox", "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("failed test %#v %#v", files, err)
+	}
+	if !strings.HasSuffix(files[0], "_user_1_example.com.eml") {
+		t.Fatalf("failed test %#v", files[0])
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatalf("failed test %#v", err)
+	}
+	defer f.Close()
+	msg, err := mail.ReadMessage(f)
+	if err != nil {
+		t.Fatalf("failed test %#v", err)
+	}
+
+	if msg.Header.Get("To") != "user+1@example.com" {
+		t.Fatalf("failed test %#v", msg.Header)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "【ISUTRAIN】パスワードの再設定" {
+		t.Fatalf("failed test %#v %#v", subject, err)
+	}
+
+	raw, err := ioutil.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatalf("failed test %#v", err)
+	}
+	body := string(raw)
+	if !strings.Contains(body, "https://isutrain.example.com/reset-password?token="+token) {
+		t.Fatalf("failed test %s", body)
+	}
+}
+
+func TestRenderMailHeaderInjection(t *testing.T) {
+	_, err := renderMail(defaultMailFrom, MailMessage{
+		To:      "user@example.com\r\nBcc: victim@example.com",
+		Subject: "test",
+		Body:    "test",
+	}, time.Now())
+	if err == nil {
+		t.Fatalf("failed test")
+	}
+}
+
+func TestHashUserToken(t *testing.T) {
+	h := hashUserToken("token")
+	if len(h) != 64 || h == "token" || h != hashUserToken("token") || h == hashUserToken("token2") {
+		t.Fatalf("failed test %#v", h)
+	}
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"bytes"
 	crand "crypto/rand"
-	"crypto/sha256"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -13,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -20,7 +20,6 @@ import (
 	"github.com/jmoiron/sqlx"
 	goji "goji.io"
 	"goji.io/pat"
-	"golang.org/x/crypto/pbkdf2"
 	// "sync"
 )
 
@@ -54,6 +53,44 @@ type Fare struct {
 	FareMultiplier float64   `json:"fare_multiplier" db:"fare_multiplier"`
 }
 
+type DistanceFareBand struct {
+	MinDistance float64  `json:"min_distance"`
+	MaxDistance *float64 `json:"max_distance"`
+	Fare        int      `json:"base_fare"`
+}
+
+type FareBreakdown struct {
+	Distance             float64          `json:"distance"`
+	DistanceFare         DistanceFareBand `json:"distance_fare"`
+	TrainClass           string           `json:"train_class"`
+	SeatClass            string           `json:"seat_class"`
+	FareMultiplier       float64          `json:"fare_multiplier"`
+	SeasonStartDate      string           `json:"season_start_date"`
+	SeasonEndDate        string           `json:"season_end_date,omitempty"`
+	TrainClassMultiplier float64          `json:"train_class_multiplier"`
+	SeatClassMultiplier  float64          `json:"seat_class_multiplier"`
+	SeasonMultiplier     float64          `json:"season_multiplier"`
+	UnitFare             int              `json:"unit_fare"`
+}
+
+type FareQuoteResponse struct {
+	Date              string        `json:"date"`
+	Departure         string        `json:"departure"`
+	Arrival           string        `json:"arrival"`
+	Breakdown         FareBreakdown `json:"breakdown"`
+	Adult             int           `json:"adult"`
+	Child             int           `json:"child"`
+	AdultFare         int           `json:"adult_fare"`
+	ChildFare         int           `json:"child_fare"`
+	ChildDiscountRate float64       `json:"child_discount_rate"`
+	Total             int           `json:"total"`
+	// 動的運賃が有効で train_name を指定した場合のみ
+	PriceAdjustment  *PriceAdjustment `json:"price_adjustment,omitempty"`
+	AdjustedUnitFare int              `json:"adjusted_unit_fare,omitempty"`
+	QuoteToken       string           `json:"quote_token,omitempty"`
+	QuoteExpiresAt   *time.Time       `json:"quote_expires_at,omitempty"`
+}
+
 type Train struct {
 	Date         time.Time `json:"date" db:"date"`
 	DepartureAt  string    `json:"departure_at" db:"departure_at"`
@@ -71,22 +108,34 @@ type Seat struct {
 	SeatRow       int    `json:"seat_row" db:"seat_row"`
 	SeatClass     string `json:"seat_class" db:"seat_class"`
 	IsSmokingSeat bool   `json:"is_smoking_seat" db:"is_smoking_seat"`
+	IsAccessible  bool   `json:"is_accessible" db:"is_accessible"`
 }
 
 type Reservation struct {
-	ReservationId int        `json:"reservation_id" db:"reservation_id"`
-	UserId        *int       `json:"user_id" db:"user_id"`
-	Date          *time.Time `json:"date" db:"date"`
-	TrainClass    string     `json:"train_class" db:"train_class"`
-	TrainName     string     `json:"train_name" db:"train_name"`
-	Departure     string     `json:"departure" db:"departure"`
-	Arrival       string     `json:"arrival" db:"arrival"`
-	PaymentStatus string     `json:"payment_status" db:"payment_status"`
-	Status        string     `json:"status" db:"status"`
-	PaymentId     string     `json:"payment_id,omitempty" db:"payment_id"`
-	Adult         int        `json:"adult" db:"adult"`
-	Child         int        `json:"child" db:"child"`
-	Amount        int        `json:"amount" db:"amount"`
+	ReservationId    int        `json:"reservation_id" db:"reservation_id"`
+	UserId           *int       `json:"user_id" db:"user_id"`
+	Date             *time.Time `json:"date" db:"date"`
+	TrainClass       string     `json:"train_class" db:"train_class"`
+	TrainName        string     `json:"train_name" db:"train_name"`
+	Departure        string     `json:"departure" db:"departure"`
+	Arrival          string     `json:"arrival" db:"arrival"`
+	PaymentStatus    string     `json:"payment_status" db:"payment_status"`
+	Status           string     `json:"status" db:"status"`
+	PaymentId        string     `json:"payment_id,omitempty" db:"payment_id"`
+	Adult            int        `json:"adult" db:"adult"`
+	Child            int        `json:"child" db:"child"`
+	Amount           int        `json:"amount" db:"amount"`
+	CheckedInAt      *time.Time `json:"checked_in_at" db:"checked_in_at"`
+	CheckedInStation *string    `json:"checked_in_station" db:"checked_in_station"`
+	BookedBy         *int64     `json:"booked_by" db:"booked_by"`
+	BillingOrgId     *int64     `json:"billing_organization_id" db:"billing_organization_id"`
+	NeedsAssistance  bool       `json:"needs_assistance" db:"needs_assistance"`
+	// 動的運賃の調整内容 (無効な場合は NULL)
+	PricingBaseUnitFare     *int       `json:"pricing_base_unit_fare" db:"pricing_base_unit_fare"`
+	PricingLoadFactor       *float64   `json:"pricing_load_factor" db:"pricing_load_factor"`
+	PricingHoursToDeparture *float64   `json:"pricing_hours_to_departure" db:"pricing_hours_to_departure"`
+	PricingMultiplier       *float64   `json:"pricing_multiplier" db:"pricing_multiplier"`
+	PricingQuotedAt         *time.Time `json:"pricing_quoted_at" db:"pricing_quoted_at"`
 }
 
 type SeatReservation struct {
@@ -105,6 +154,8 @@ type CarInformation struct {
 	CarNumber           int                    `json:"car_number"`
 	SeatInformationList []SeatInformation      `json:"seats"`
 	Cars                []SimpleCarInformation `json:"cars"`
+	// 車いすスペースを介助が必要なお客様向けに確保している期限。確保期間を過ぎていれば null
+	AccessibleSeatHeldUntil *time.Time `json:"accessible_seat_held_until"`
 }
 
 type SimpleCarInformation struct {
@@ -118,6 +169,7 @@ type SeatInformation struct {
 	Class         string `json:"class"`
 	IsSmokingSeat bool   `json:"is_smoking_seat"`
 	IsOccupied    bool   `json:"is_occupied"`
+	IsAccessible  bool   `json:"is_accessible"`
 }
 
 type SeatInformationByCarNumber struct {
@@ -139,11 +191,12 @@ type TrainSearchResponse struct {
 }
 
 type User struct {
-	ID             int64
-	Email          string `json:"email"`
-	Password       string `json:"password"`
-	Salt           []byte `db:"salt"`
-	HashedPassword []byte `db:"super_secure_password"`
+	ID              int64
+	Email           string     `json:"email"`
+	Password        string     `json:"password"`
+	Salt            []byte     `db:"salt"`
+	HashedPassword  []byte     `db:"super_secure_password"`
+	EmailVerifiedAt *time.Time `json:"-" db:"email_verified_at"`
 }
 
 type TrainReservationRequest struct {
@@ -159,6 +212,13 @@ type TrainReservationRequest struct {
 	Adult         int           `json:"adult"`
 	Column        string        `json:"Column"`
 	Seats         []RequestSeat `json:"seats"`
+	OnBehalfOf    int64         `json:"on_behalf_of"`
+	// 車いすスペースを利用する、介助が必要なお客様の予約
+	NeedsAssistance bool `json:"needs_assistance"`
+	// 動的運賃の見積もりトークン (GET /api/fare/quote)
+	QuoteToken string `json:"quote_token"`
+	// 指定すると仮予約の時点で与信を確保する (payment.go)
+	CardToken string `json:"card_token"`
 }
 
 type RequestSeat struct {
@@ -173,8 +233,9 @@ type TrainReservationResponse struct {
 }
 
 type ReservationPaymentRequest struct {
-	CardToken     string `json:"card_token"`
-	ReservationId int    `json:"reservation_id"`
+	CardToken        string `json:"card_token"`
+	ReservationId    int    `json:"reservation_id"`
+	UseCorporateCard bool   `json:"use_corporate_card"`
 }
 
 type ReservationPaymentResponse struct {
@@ -185,6 +246,7 @@ type PaymentInformationRequest struct {
 	CardToken     string `json:"card_token"`
 	ReservationId int    `json:"reservation_id"`
 	Amount        int    `json:"amount"`
+	Currency      string `json:"currency"`
 }
 
 type PaymentInformation struct {
@@ -211,6 +273,8 @@ type ReservationResponse struct {
 	DepartureTime string            `json:"departure_time"`
 	ArrivalTime   string            `json:"arrival_time"`
 	Seats         []SeatReservation `json:"seats"`
+	// 介助が必要なお客様の予約か (乗務員・駅係員向け)
+	NeedsAssistance bool `json:"needs_assistance"`
 }
 
 type CancelPaymentInformationRequest struct {
@@ -223,6 +287,8 @@ type CancelPaymentInformationResponse struct {
 
 type Settings struct {
 	PaymentAPI string `json:"payment_api"`
+	// カードの登録にだけ使える payment-API の公開用キー(ブラウザから X-Merchant-Key ヘッダで送る)
+	PaymentPublishableKey string `json:"payment_publishable_key,omitempty"`
 }
 
 type InitializeResponse struct {
@@ -231,7 +297,9 @@ type InitializeResponse struct {
 }
 
 type AuthResponse struct {
-	Email string `json:"email"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	CSRFToken     string `json:"csrf_token"`
 }
 
 const (
@@ -301,109 +369,295 @@ func secureRandomStr(b int) string {
 }
 
 func distanceFareHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		距離運賃の一覧
+		GET /api/fare/distance
+	*/
 
 	distanceFareList := []DistanceFare{}
 
-	query := "SELECT * FROM distance_fare_master"
+	query := "SELECT * FROM distance_fare_master ORDER BY distance"
 	err := dbx.Select(&distanceFareList, query)
 	if err != nil {
 		errorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	for _, distanceFare := range distanceFareList {
-		fmt.Fprintf(w, "%#v, %#v\n", distanceFare.Distance, distanceFare.Fare)
-	}
-
 	w.Header().Set("Content-Type", "application/json;charset=utf-8")
 	json.NewEncoder(w).Encode(distanceFareList)
 }
 
-func getDistanceFare(origToDestDistance float64) (int, error) {
+func getDistanceFareBand(origToDestDistance float64) (DistanceFareBand, error) {
+
+	band := DistanceFareBand{}
 
 	distanceFareList := []DistanceFare{}
 
 	query := "SELECT distance,fare FROM distance_fare_master ORDER BY distance"
 	err := dbx.Select(&distanceFareList, query)
 	if err != nil {
-		return 0, err
+		return band, err
 	}
 
 	lastDistance := 0.0
 	lastFare := 0
 	for _, distanceFare := range distanceFareList {
 
-		fmt.Println(origToDestDistance, distanceFare.Distance, distanceFare.Fare)
 		if float64(lastDistance) < origToDestDistance && origToDestDistance < float64(distanceFare.Distance) {
+			maxDistance := distanceFare.Distance
+			band.MaxDistance = &maxDistance
 			break
 		}
 		lastDistance = distanceFare.Distance
 		lastFare = distanceFare.Fare
 	}
 
-	return lastFare, nil
+	band.MinDistance = lastDistance
+	band.Fare = lastFare
+	return band, nil
+}
+
+func getDistanceFare(origToDestDistance float64) (int, error) {
+	band, err := getDistanceFareBand(origToDestDistance)
+	if err != nil {
+		return 0, err
+	}
+	return band.Fare, nil
 }
 
 func fareCalc(date time.Time, depStation int, destStation int, trainClass, seatClass string) (int, error) {
+	breakdown, err := fareCalcBreakdown(date, depStation, destStation, trainClass, seatClass)
+	if err != nil {
+		return 0, err
+	}
+	return breakdown.UnitFare, nil
+}
+
+func fareCalcBreakdown(date time.Time, depStation int, destStation int, trainClass, seatClass string) (FareBreakdown, error) {
 	//
 	// 料金計算メモ
 	// 距離運賃(円) * 期間倍率(繁忙期なら2倍等) * 車両クラス倍率(急行・各停等) * 座席クラス倍率(プレミアム・指定席・自由席)
 	//
+	// fare_master の倍率は 期間 * 車両クラス * 座席クラス をかけ合わせたものなので、
+	// 内訳は splitFareMultiplier で倍率ごとに分ける
+	//
 	var err error
 	var fromStation, toStation Station
+	breakdown := FareBreakdown{}
 
 	query := "SELECT * FROM station_master WHERE id=?"
 
 	// From
 	err = dbx.Get(&fromStation, query, depStation)
 	if err == sql.ErrNoRows {
-		return 0, err
+		return breakdown, err
 	}
 	if err != nil {
-		return 0, err
+		return breakdown, err
 	}
 
 	// To
 	err = dbx.Get(&toStation, query, destStation)
 	if err == sql.ErrNoRows {
-		return 0, err
+		return breakdown, err
 	}
 	if err != nil {
 		log.Print(err)
-		return 0, err
+		return breakdown, err
 	}
 
-	fmt.Println("distance", math.Abs(toStation.Distance-fromStation.Distance))
-	distFare, err := getDistanceFare(math.Abs(toStation.Distance - fromStation.Distance))
+	breakdown.Distance = math.Abs(toStation.Distance - fromStation.Distance)
+	breakdown.DistanceFare, err = getDistanceFareBand(breakdown.Distance)
 	if err != nil {
-		return 0, err
+		return breakdown, err
 	}
-	fmt.Println("distFare", distFare)
 
 	// 期間・車両・座席クラス倍率
 	fareList := []Fare{}
-	query = "SELECT * FROM fare_master WHERE train_class=? AND seat_class=? ORDER BY start_date"
+	query = "SELECT * FROM fare_master WHERE train_class=? AND seat_class IN (?, 'non-reserved') ORDER BY start_date"
 	err = dbx.Select(&fareList, query, trainClass, seatClass)
 	if err != nil {
-		return 0, err
+		return breakdown, err
 	}
 
-	if len(fareList) == 0 {
-		return 0, fmt.Errorf("fare_master does not exists")
-	}
-
-	selectedFare := fareList[0]
+	// 日付が最初の期間より前の場合は最初の期間の倍率を使う
+	// 自由席の倍率が一番低い期間 (通常期) を期間倍率 1.0 とみなし、その倍率を列車クラス倍率とする
+	var selectedFare, nonReservedFare, baseNonReservedFare *Fare
+	var nextStartDate *time.Time
 	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
-	for _, fare := range fareList {
-		if !date.Before(fare.StartDate) {
-			fmt.Println(fare.StartDate, fare.FareMultiplier)
-			selectedFare = fare
+	for i, fare := range fareList {
+		switch {
+		case fare.SeatClass == seatClass && (selectedFare == nil || !date.Before(fare.StartDate)):
+			selectedFare = &fareList[i]
+		case fare.SeatClass == seatClass && nextStartDate == nil:
+			nextStartDate = &fareList[i].StartDate
+		}
+		if fare.SeatClass == "non-reserved" && (nonReservedFare == nil || !date.Before(fare.StartDate)) {
+			nonReservedFare = &fareList[i]
+		}
+		if fare.SeatClass == "non-reserved" && (baseNonReservedFare == nil || fare.FareMultiplier < baseNonReservedFare.FareMultiplier) {
+			baseNonReservedFare = &fareList[i]
 		}
 	}
 
-	fmt.Println("%%%%%%%%%%%%%%%%%%%")
+	if selectedFare == nil {
+		return breakdown, fmt.Errorf("fare_master does not exists")
+	}
+	if nonReservedFare == nil || baseNonReservedFare.FareMultiplier <= 0 {
+		return breakdown, fmt.Errorf("fare_master does not exists for non-reserved")
+	}
+
+	breakdown.TrainClass = trainClass
+	breakdown.SeatClass = seatClass
+	breakdown.FareMultiplier = selectedFare.FareMultiplier
+	breakdown.SeasonStartDate = selectedFare.StartDate.Format("2006/01/02")
+	if nextStartDate != nil {
+		breakdown.SeasonEndDate = nextStartDate.AddDate(0, 0, -1).Format("2006/01/02")
+	}
+	breakdown.TrainClassMultiplier, breakdown.SeatClassMultiplier, breakdown.SeasonMultiplier = splitFareMultiplier(selectedFare.FareMultiplier, nonReservedFare.FareMultiplier, baseNonReservedFare.FareMultiplier)
+	breakdown.UnitFare = int(float64(breakdown.DistanceFare.Fare) * selectedFare.FareMultiplier)
+
+	return breakdown, nil
+}
+
+func splitFareMultiplier(fareMultiplier, nonReservedMultiplier, baseNonReservedMultiplier float64) (trainClassMultiplier, seatClassMultiplier, seasonMultiplier float64) {
+	// 車両クラス倍率は通常期の自由席の倍率、
+	// 座席クラス倍率は同じ期間・車両クラスの自由席の倍率との比、
+	// 期間倍率は自由席の倍率を車両クラス倍率で割ったもの
+	// (3つをかけ合わせると fare_master の倍率になる)
+	trainClassMultiplier = baseNonReservedMultiplier
+	seatClassMultiplier = fareMultiplier / nonReservedMultiplier
+	seasonMultiplier = nonReservedMultiplier / trainClassMultiplier
+	return trainClassMultiplier, seatClassMultiplier, seasonMultiplier
+}
 
-	return int(float64(distFare) * selectedFare.FareMultiplier), nil
+func calcTotalFare(unitFare, adult, child int) int {
+	// 小人は半額 (予約時の料金計算と同じ)
+	return (adult * unitFare) + (child*unitFare)/2
+}
+
+func fareQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		料金の見積もり (内訳付き)
+		GET /api/fare/quote?date=2020-01-01T00:00:00.000Z&from=東京&to=大阪&train_class=最速&seat_class=reserved&adult=1&child=0
+	*/
+
+	jst := time.FixedZone("Asia/Tokyo", 9*60*60)
+	date, err := time.Parse(time.RFC3339, r.URL.Query().Get("date"))
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	date = date.In(jst)
+
+	trainClass := r.URL.Query().Get("train_class")
+	seatClass := r.URL.Query().Get("seat_class")
+	fromName := r.URL.Query().Get("from")
+	toName := r.URL.Query().Get("to")
+	adult, adultErr := strconv.Atoi(r.URL.Query().Get("adult"))
+	child, childErr := strconv.Atoi(r.URL.Query().Get("child"))
+
+	switch seatClass {
+	case "premium", "reserved", "non-reserved":
+	default:
+		errorResponse(w, http.StatusBadRequest, "座席クラスが不正です")
+		return
+	}
+	if adultErr != nil || childErr != nil || adult < 0 || child < 0 || adult+child == 0 {
+		errorResponse(w, http.StatusBadRequest, "人数が不正です")
+		return
+	}
+
+	var fromStation, toStation Station
+	query := "SELECT * FROM station_master WHERE name=?"
+
+	// From
+	err = dbx.Get(&fromStation, query, fromName)
+	if err == sql.ErrNoRows {
+		errorResponse(w, http.StatusBadRequest, "乗車駅が存在しません")
+		return
+	}
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// To
+	err = dbx.Get(&toStation, query, toName)
+	if err == sql.ErrNoRows {
+		errorResponse(w, http.StatusBadRequest, "降車駅が存在しません")
+		return
+	}
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	breakdown, err := fareCalcBreakdown(date, fromStation.ID, toStation.ID, trainClass, seatClass)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		log.Println("fareCalcBreakdown " + err.Error())
+		return
+	}
+
+	unitFare := breakdown.UnitFare
+	resp := FareQuoteResponse{}
+
+	// 動的運賃は列車ごとに決まるので、train_name を指定した場合のみ調整する
+	if trainName := r.URL.Query().Get("train_name"); pricingConfig.Enabled && trainName != "" {
+		var train Train
+		query = "SELECT * FROM train_master WHERE date=? AND train_class=? AND train_name=?"
+		err = dbx.Get(&train, query, date.Format("2006/01/02"), trainClass, trainName)
+		if err == sql.ErrNoRows {
+			errorResponse(w, http.StatusNotFound, "列車が存在しません")
+			return
+		}
+		if err != nil {
+			errorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		departureAt, err := trainDepartureAt(dbx, date, trainClass, trainName, fromStation.Name)
+		if err == sql.ErrNoRows {
+			errorResponse(w, http.StatusBadRequest, "列車が乗車駅に停車しません")
+			return
+		}
+		if err != nil {
+			errorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		now := time.Now()
+		adj, err := pricingConfig.priceAdjustmentFor(train, fromStation, toStation, seatClass, departureAt, now)
+		if err != nil {
+			errorResponse(w, http.StatusInternalServerError, err.Error())
+			log.Println("priceAdjustmentFor " + err.Error())
+			return
+		}
+		claims := newFareQuoteClaims(date, trainClass, trainName, seatClass, fromStation.Name, toStation.Name, breakdown.UnitFare, adj, now)
+		resp.QuoteToken, err = pricingConfig.signQuote(claims)
+		if err != nil {
+			errorResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		expiresAt := time.Unix(claims.ExpiresAt, 0).In(jst)
+		resp.QuoteExpiresAt = &expiresAt
+		resp.PriceAdjustment = &adj
+		resp.AdjustedUnitFare = claims.UnitFare
+		unitFare = claims.UnitFare
+	}
+
+	resp.Date = date.Format("2006/01/02")
+	resp.Departure = fromStation.Name
+	resp.Arrival = toStation.Name
+	resp.Breakdown = breakdown
+	resp.Adult = adult
+	resp.Child = child
+	resp.AdultFare = adult * unitFare
+	resp.ChildFare = calcTotalFare(unitFare, 0, child)
+	resp.ChildDiscountRate = 0.5
+	resp.Total = calcTotalFare(unitFare, adult, child)
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	json.NewEncoder(w).Encode(resp)
 }
 
 func getStationsHandler(w http.ResponseWriter, r *http.Request) {
@@ -458,6 +712,7 @@ func trainSearchHandler(w http.ResponseWriter, r *http.Request) {
 
 	adult, _ := strconv.Atoi(r.URL.Query().Get("adult"))
 	child, _ := strconv.Atoi(r.URL.Query().Get("child"))
+	needsAssistance, _ := strconv.ParseBool(r.URL.Query().Get("needs_assistance"))
 
 	var fromStation, toStation Station
 	query := "SELECT * FROM station_master WHERE name=?"
@@ -603,23 +858,26 @@ func trainSearchHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			premium_avail_seats, err := train.getAvailableSeats(fromStation, toStation, "premium", false)
+			// 車いすスペースの確保期間中か
+			accessibleSeatHeld := time.Now().Before(accessibleSeatHoldUntil(departureDate))
+
+			premium_avail_seats, err := train.getAvailableSeats(fromStation, toStation, "premium", false, needsAssistance, accessibleSeatHeld)
 			if err != nil {
 				errorResponse(w, http.StatusBadRequest, err.Error())
 				return
 			}
-			premium_smoke_avail_seats, err := train.getAvailableSeats(fromStation, toStation, "premium", true)
+			premium_smoke_avail_seats, err := train.getAvailableSeats(fromStation, toStation, "premium", true, needsAssistance, accessibleSeatHeld)
 			if err != nil {
 				errorResponse(w, http.StatusBadRequest, err.Error())
 				return
 			}
 
-			reserved_avail_seats, err := train.getAvailableSeats(fromStation, toStation, "reserved", false)
+			reserved_avail_seats, err := train.getAvailableSeats(fromStation, toStation, "reserved", false, needsAssistance, accessibleSeatHeld)
 			if err != nil {
 				errorResponse(w, http.StatusBadRequest, err.Error())
 				return
 			}
-			reserved_smoke_avail_seats, err := train.getAvailableSeats(fromStation, toStation, "reserved", true)
+			reserved_smoke_avail_seats, err := train.getAvailableSeats(fromStation, toStation, "reserved", true, needsAssistance, accessibleSeatHeld)
 			if err != nil {
 				errorResponse(w, http.StatusBadRequest, err.Error())
 				return
@@ -668,20 +926,35 @@ func trainSearchHandler(w http.ResponseWriter, r *http.Request) {
 				errorResponse(w, http.StatusBadRequest, err.Error())
 				return
 			}
-			premiumFare = premiumFare*adult + premiumFare/2*child
-
 			reservedFare, err := fareCalc(date, fromStation.ID, toStation.ID, train.TrainClass, "reserved")
 			if err != nil {
 				errorResponse(w, http.StatusBadRequest, err.Error())
 				return
 			}
-			reservedFare = reservedFare*adult + reservedFare/2*child
-
 			nonReservedFare, err := fareCalc(date, fromStation.ID, toStation.ID, train.TrainClass, "non-reserved")
 			if err != nil {
 				errorResponse(w, http.StatusBadRequest, err.Error())
 				return
 			}
+
+			// 動的運賃 (有効な場合のみ)
+			if pricingConfig.Enabled {
+				now := time.Now()
+				for _, f := range []struct {
+					seatClass string
+					fare      *int
+				}{{"premium", &premiumFare}, {"reserved", &reservedFare}, {"non-reserved", &nonReservedFare}} {
+					adj, err := pricingConfig.priceAdjustmentFor(train, fromStation, toStation, f.seatClass, departureDate, now)
+					if err != nil {
+						errorResponse(w, http.StatusInternalServerError, err.Error())
+						return
+					}
+					*f.fare = adj.apply(*f.fare)
+				}
+			}
+
+			premiumFare = premiumFare*adult + premiumFare/2*child
+			reservedFare = reservedFare*adult + reservedFare/2*child
 			nonReservedFare = nonReservedFare*adult + nonReservedFare/2*child
 
 			fareInformation := map[string]int{
@@ -803,7 +1076,7 @@ func trainSeatsHandler(w http.ResponseWriter, r *http.Request) {
 
 	for _, seat := range seatList {
 
-		s := SeatInformation{seat.SeatRow, seat.SeatColumn, seat.SeatClass, seat.IsSmokingSeat, false}
+		s := SeatInformation{seat.SeatRow, seat.SeatColumn, seat.SeatClass, seat.IsSmokingSeat, false, seat.IsAccessible}
 
 		seatReservationList := []SeatReservation{}
 
@@ -837,6 +1110,10 @@ WHERE
 			if err != nil {
 				panic(err)
 			}
+			if reservation.Status == "no_show" {
+				// 乗車しなかった予約の座席は解放済み
+				continue
+			}
 
 			var departureStation, arrivalStation Station
 			query = "SELECT * FROM station_master WHERE name=?"
@@ -893,7 +1170,15 @@ WHERE
 		i = i + 1
 	}
 
-	c := CarInformation{date.Format("2006/01/02"), trainClass, trainName, carNumber, seatInformationList, simpleCarInformationList}
+	c := CarInformation{date.Format("2006/01/02"), trainClass, trainName, carNumber, seatInformationList, simpleCarInformationList, nil}
+	departureAt, err := trainDepartureAt(dbx, date, trainClass, trainName, fromStation.Name)
+	if err != nil && err != sql.ErrNoRows {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if holdUntil := accessibleSeatHoldUntil(departureAt); err == nil && time.Now().Before(holdUntil) {
+		c.AccessibleSeatHeldUntil = &holdUntil
+	}
 	resp, err := json.Marshal(c)
 	if err != nil {
 		errorResponse(w, http.StatusBadRequest, err.Error())
@@ -1098,6 +1383,16 @@ func trainReservationHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// 車いすスペースの確保期間中か
+	departureAt, err := trainDepartureAt(tx, date, req.TrainClass, req.TrainName, req.Departure)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "時刻表の取得に失敗しました")
+		log.Println(err.Error())
+		return
+	}
+	accessibleSeatHeld := time.Now().Before(accessibleSeatHoldUntil(departureAt))
+
 	/*
 		あいまい座席検索
 		seatsが空白の時に発動する
@@ -1148,7 +1443,7 @@ func trainReservationHandler(w http.ResponseWriter, r *http.Request) {
 
 			var seatInformationList []SeatInformation
 			for _, seat := range seatList {
-				s := SeatInformation{seat.SeatRow, seat.SeatColumn, seat.SeatClass, seat.IsSmokingSeat, false}
+				s := SeatInformation{seat.SeatRow, seat.SeatColumn, seat.SeatClass, seat.IsSmokingSeat, false, seat.IsAccessible}
 				seatReservationList := []SeatReservation{}
 				query = "SELECT s.* FROM seat_reservations s, reservations r WHERE r.date=? AND r.train_class=? AND r.train_name=? AND car_number=? AND seat_row=? AND seat_column=? FOR UPDATE"
 				err = dbx.Select(
@@ -1173,6 +1468,10 @@ func trainReservationHandler(w http.ResponseWriter, r *http.Request) {
 					if err != nil {
 						panic(err)
 					}
+					if reservation.Status == "no_show" {
+						// 乗車しなかった予約の座席は解放済み
+						continue
+					}
 
 					var departureStation, arrivalStation Station
 					query = "SELECT * FROM station_master WHERE name=?"
@@ -1212,6 +1511,16 @@ func trainReservationHandler(w http.ResponseWriter, r *http.Request) {
 				seatInformationList = append(seatInformationList, s)
 			}
 
+			// 介助が必要な予約は車いすスペースのある号車から選ぶ
+			seatInformationList, ok := accessibleSeatsForRequest(seatInformationList, req.NeedsAssistance, accessibleSeatHeld)
+			if !ok {
+				if carnum == 16 {
+					req.Seats = []RequestSeat{}
+					break
+				}
+				continue
+			}
+
 			// 曖昧予約席とその他の候補席を選出
 			var seatnum int           // 予約する座席の合計数
 			var reserved bool         // あいまい指定席確保済フラグ
@@ -1296,13 +1605,19 @@ func trainReservationHandler(w http.ResponseWriter, r *http.Request) {
 				log.Println(err.Error())
 				return
 			}
+			if seatList.IsAccessible && accessibleSeatHeld && !req.NeedsAssistance {
+				tx.Rollback()
+				errorResponse(w, http.StatusBadRequest, fmt.Sprintf("車いすスペースは発車の%s前まで介助が必要なお客様のみ予約できます", accessibleSeatRelease))
+				return
+			}
 		}
 		break
 	}
 
 	// 当該列車・列車名の予約一覧取得
+	// 乗車しなかった(no_show)予約の座席は解放済みとして扱う
 	reservations := []Reservation{}
-	query = "SELECT * FROM reservations WHERE date=? AND train_class=? AND train_name=? FOR UPDATE"
+	query = "SELECT * FROM reservations WHERE date=? AND train_class=? AND train_name=? AND status<>'no_show' FOR UPDATE"
 	err = tx.Select(
 		&reservations, query,
 		date.Format("2006/01/02"),
@@ -1472,11 +1787,30 @@ func trainReservationHandler(w http.ResponseWriter, r *http.Request) {
 		errorResponse(w, http.StatusBadRequest, "リクエストされた座席クラスが不明です")
 		return
 	}
-	sumFare := (req.Adult * fare) + (req.Child*fare)/2
+	// 動的運賃。見積もりトークンがあれば見積もった運賃で確定する
+	var quote *FareQuoteClaims
+	if pricingConfig.Enabled {
+		claims, err := pricingConfig.reservationQuote(*req, date, tmas, fromStation, toStation, fare, departureAt, time.Now())
+		if isQuoteTokenError(err) {
+			tx.Rollback()
+			errorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			tx.Rollback()
+			errorResponse(w, http.StatusInternalServerError, "運賃の計算に失敗しました")
+			log.Println(err.Error())
+			return
+		}
+		fare = claims.UnitFare
+		quote = &claims
+	}
+
+	sumFare := calcTotalFare(fare, req.Adult, req.Child)
 	fmt.Println("SUMFARE")
 
 	// userID取得。ログインしてないと怒られる。
-	user, errCode, errMsg := getUser(r)
+	user, errCode, errMsg := authenticate(r, scopeReserve)
 	if errCode != http.StatusOK {
 		tx.Rollback()
 		errorResponse(w, errCode, errMsg)
@@ -1484,11 +1818,43 @@ func trainReservationHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 代理予約。同じ組織のメンバーの予約だけ手配できる
+	travellerID := user.ID
+	var bookedBy *int64
+	if req.OnBehalfOf != 0 && req.OnBehalfOf != user.ID {
+		orgID, _, err := sharedOrganizationRole(tx, user.ID, req.OnBehalfOf)
+		if err != nil {
+			tx.Rollback()
+			errorResponse(w, http.StatusInternalServerError, "組織情報の取得に失敗しました")
+			log.Println(err.Error())
+			return
+		}
+		if orgID == 0 {
+			tx.Rollback()
+			errorResponse(w, http.StatusForbidden, "同じ組織のメンバーの予約のみ代理で行えます")
+			return
+		}
+		travellerID = req.OnBehalfOf
+		bookedBy = &user.ID
+	}
+
+	var pricingBaseUnitFare *int
+	var pricingLoadFactor, pricingHoursToDeparture, pricingMultiplier *float64
+	var pricingQuotedAt *time.Time
+	if quote != nil {
+		quotedAt := time.Unix(quote.QuotedAt, 0)
+		pricingBaseUnitFare = &quote.BaseUnitFare
+		pricingLoadFactor = &quote.Adjustment.LoadFactor
+		pricingHoursToDeparture = &quote.Adjustment.HoursToDeparture
+		pricingMultiplier = &quote.Adjustment.Multiplier
+		pricingQuotedAt = &quotedAt
+	}
+
 	//予約ID発行と予約情報登録
-	query = "INSERT INTO `reservations` (`user_id`, `date`, `train_class`, `train_name`, `departure`, `arrival`, `status`, `payment_id`, `adult`, `child`, `amount`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	query = "INSERT INTO `reservations` (`user_id`, `date`, `train_class`, `train_name`, `departure`, `arrival`, `status`, `payment_id`, `adult`, `child`, `amount`, `booked_by`, `needs_assistance`, `pricing_base_unit_fare`, `pricing_load_factor`, `pricing_hours_to_departure`, `pricing_multiplier`, `pricing_quoted_at`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
 	result, err := tx.Exec(
 		query,
-		user.ID,
+		travellerID,
 		date.Format("2006/01/02"),
 		req.TrainClass,
 		req.TrainName,
@@ -1499,6 +1865,13 @@ func trainReservationHandler(w http.ResponseWriter, r *http.Request) {
 		req.Adult,
 		req.Child,
 		sumFare,
+		bookedBy,
+		req.NeedsAssistance,
+		pricingBaseUnitFare,
+		pricingLoadFactor,
+		pricingHoursToDeparture,
+		pricingMultiplier,
+		pricingQuotedAt,
 	)
 	if err != nil {
 		tx.Rollback()
@@ -1534,6 +1907,40 @@ func trainReservationHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "予約の保存に失敗しました")
+		log.Println(err.Error())
+		return
+	}
+
+	// 座席を確保したら与信を確保する
+	// payment-API の応答を待つ間に同じ列車の予約を止めないよう、コミットしてから行う
+	if req.CardToken != "" {
+		authorization, err := authorizePayment(req.CardToken, id, sumFare)
+		if err != nil {
+			discardReservation(id)
+			errorResponse(w, http.StatusBadRequest, "与信の確保に失敗しました。カードトークンが間違っている可能性があります")
+			log.Println(err.Error())
+			return
+		}
+		_, err = dbx.Exec(
+			"UPDATE reservations SET payment_id=?, payment_status=? WHERE reservation_id=?",
+			authorization.PaymentId,
+			paymentStatusAuthorized,
+			id,
+		)
+		if err != nil {
+			if err := voidAuthorization(authorization.PaymentId); err != nil {
+				log.Println(err.Error())
+			}
+			discardReservation(id)
+			errorResponse(w, http.StatusInternalServerError, "予約情報の更新に失敗しました")
+			log.Println(err.Error())
+			return
+		}
+	}
+
 	rr := TrainReservationResponse{
 		ReservationId: id,
 		Amount:        sumFare,
@@ -1541,15 +1948,48 @@ func trainReservationHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	response, err := json.Marshal(rr)
 	if err != nil {
-		tx.Rollback()
 		errorResponse(w, http.StatusInternalServerError, "レスポンスの生成に失敗しました")
 		log.Println(err.Error())
 		return
 	}
-	tx.Commit()
+
+	// 座席の購読者に通知
+	seatReservationList := []SeatReservation{}
+	for _, v := range req.Seats {
+		seatReservationList = append(seatReservationList, SeatReservation{int(id), req.CarNumber, v.Row, v.Column})
+	}
+	publishSeatEvent(SeatEventReserved, Reservation{
+		ReservationId: int(id),
+		Date:          &date,
+		TrainClass:    req.TrainClass,
+		TrainName:     req.TrainName,
+		Departure:     req.Departure,
+		Arrival:       req.Arrival,
+	}, seatReservationList)
+
 	w.Write(response)
 }
 
+// discardReservation は与信を確保できなかった仮予約を座席ごと削除する
+func discardReservation(reservationID int64) {
+	tx := dbx.MustBegin()
+	_, err := tx.Exec("DELETE FROM seat_reservations WHERE reservation_id=?", reservationID)
+	if err != nil {
+		tx.Rollback()
+		log.Println(err.Error())
+		return
+	}
+	_, err = tx.Exec("DELETE FROM reservations WHERE reservation_id=?", reservationID)
+	if err != nil {
+		tx.Rollback()
+		log.Println(err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println(err.Error())
+	}
+}
+
 func reservationPaymentHandler(w http.ResponseWriter, r *http.Request) {
 	/*
 		支払い及び予約確定API
@@ -1595,19 +2035,26 @@ func reservationPaymentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 支払い前のユーザチェック。本人以外のユーザの予約を支払ったりキャンセルできてはいけない。
-	user, errCode, errMsg := getUser(r)
+	user, errCode, errMsg := authenticate(r, scopeReserve)
 	if errCode != http.StatusOK {
 		tx.Rollback()
 		errorResponse(w, errCode, errMsg)
 		log.Printf("%s", errMsg)
 		return
 	}
-	if int64(*reservation.UserId) != user.ID {
+	// 同じ組織のメンバーが手配した予約や、組織の管理者はメンバーの予約も支払える
+	ok, err := reservationAccessible(tx, user, reservation)
+	if err != nil {
 		tx.Rollback()
-		errorResponse(w, http.StatusForbidden, "他のユーザIDの支払いはできません")
+		errorResponse(w, http.StatusInternalServerError, "組織情報の取得に失敗しました")
 		log.Println(err.Error())
 		return
 	}
+	if !ok {
+		tx.Rollback()
+		errorResponse(w, http.StatusForbidden, "他のユーザIDの支払いはできません")
+		return
+	}
 
 	// 予約情報の支払いステータス確認
 	switch reservation.Status {
@@ -1619,60 +2066,139 @@ func reservationPaymentHandler(w http.ResponseWriter, r *http.Request) {
 		break
 	}
 
-	// 決済する
-	payInfo := PaymentInformationRequest{req.CardToken, req.ReservationId, reservation.Amount}
-	j, err := json.Marshal(PaymentInformation{PayInfo: payInfo})
-	if err != nil {
-		tx.Rollback()
-		errorResponse(w, http.StatusInternalServerError, "JSON Marshalに失敗しました")
-		log.Println(err.Error())
-		return
+	// 法人カードで支払う場合は、乗車者の組織に登録されたカードを使う
+	cardToken := req.CardToken
+	var billingOrgID *int64
+	if req.UseCorporateCard {
+		orgID, _, err := sharedOrganizationRole(tx, user.ID, int64(*reservation.UserId))
+		if err != nil {
+			tx.Rollback()
+			errorResponse(w, http.StatusInternalServerError, "組織情報の取得に失敗しました")
+			log.Println(err.Error())
+			return
+		}
+		if orgID == 0 {
+			tx.Rollback()
+			errorResponse(w, http.StatusForbidden, "法人カードは同じ組織のメンバーの予約にのみ使えます")
+			return
+		}
+		org := Organization{}
+		err = tx.Get(&org, "SELECT * FROM organizations WHERE id=?", orgID)
+		if err != nil {
+			tx.Rollback()
+			errorResponse(w, http.StatusInternalServerError, "組織情報の取得に失敗しました")
+			log.Println(err.Error())
+			return
+		}
+		if org.CardToken == nil {
+			tx.Rollback()
+			errorResponse(w, http.StatusBadRequest, "法人カードが登録されていません")
+			return
+		}
+		cardToken = *org.CardToken
+		billingOrgID = &orgID
 	}
 
-	payment_api := os.Getenv("PAYMENT_API")
-	if payment_api == "" {
-		payment_api = "http://payment:5000"
+	// 仮予約で与信を確保していれば、その与信で売上を確定する
+	// 法人カードで支払う場合や与信の有効期限が切れている場合は、改めて決済する
+	captured := false
+	if reservation.PaymentStatus == paymentStatusAuthorized && !req.UseCorporateCard {
+		err = capturePayment(reservation.PaymentId)
+		if err == nil {
+			captured = true
+		} else {
+			log.Println(err.Error())
+			// タイムアウトなどで失敗しても売上が確定していることがあるので、二重に決済しないよう状態を確認する
+			payment, err := getPayment(reservation.PaymentId)
+			switch {
+			case err != nil:
+				tx.Rollback()
+				errorResponse(w, http.StatusBadGateway, "与信による決済の結果を確認できませんでした。時間をおいて再度お試しください")
+				log.Println(err.Error())
+				return
+			case payment.PaymentInformation.State == paymentStateCaptured && !payment.PaymentInformation.IsCanceled:
+				captured = true
+			case !paymentRechargeable(payment):
+				tx.Rollback()
+				errorResponse(w, http.StatusBadGateway, "与信による決済に失敗しました。時間をおいて再度お試しください")
+				return
+			case req.CardToken == "":
+				tx.Rollback()
+				errorResponse(w, http.StatusBadRequest, "与信による決済に失敗しました。与信の有効期限が切れている可能性があります")
+				return
+			}
+		}
 	}
 
-	resp, err := http.Post(payment_api+"/payment", "application/json", bytes.NewBuffer(j))
-	if err != nil {
-		tx.Rollback()
-		errorResponse(w, resp.StatusCode, "HTTP POSTに失敗しました")
-		log.Println(err.Error())
-		return
-	}
+	paymentID := reservation.PaymentId
+	if !captured {
+		// 決済する
+		payInfo := PaymentInformationRequest{cardToken, req.ReservationId, reservation.Amount, paymentCurrency}
+		j, err := json.Marshal(PaymentInformation{PayInfo: payInfo})
+		if err != nil {
+			tx.Rollback()
+			errorResponse(w, http.StatusInternalServerError, "JSON Marshalに失敗しました")
+			log.Println(err.Error())
+			return
+		}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		tx.Rollback()
-		errorResponse(w, http.StatusInternalServerError, "レスポンスの読み込みに失敗しました")
-		log.Println(err.Error())
-		return
-	}
+		payment_api := os.Getenv("PAYMENT_API")
+		if payment_api == "" {
+			payment_api = "http://payment:5000"
+		}
 
-	// リクエスト失敗
-	if resp.StatusCode != http.StatusOK {
-		tx.Rollback()
-		errorResponse(w, http.StatusInternalServerError, "決済に失敗しました。カードトークンや支払いIDが間違っている可能性があります")
-		log.Println(resp.StatusCode)
-		return
-	}
+		paymentReq, err := http.NewRequest("POST", payment_api+"/payment", bytes.NewBuffer(j))
+		if err != nil {
+			tx.Rollback()
+			errorResponse(w, http.StatusInternalServerError, "HTTPリクエストの作成に失敗しました")
+			log.Println(err.Error())
+			return
+		}
+		paymentReq.Header.Set("Content-Type", "application/json")
+		setPaymentMerchantKey(paymentReq)
+		resp, err := http.DefaultClient.Do(paymentReq)
+		if err != nil {
+			tx.Rollback()
+			errorResponse(w, resp.StatusCode, "HTTP POSTに失敗しました")
+			log.Println(err.Error())
+			return
+		}
 
-	// リクエスト取り出し
-	output := PaymentResponse{}
-	err = json.Unmarshal(body, &output)
-	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, "JSON parseに失敗しました")
-		log.Println(err.Error())
-		return
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			tx.Rollback()
+			errorResponse(w, http.StatusInternalServerError, "レスポンスの読み込みに失敗しました")
+			log.Println(err.Error())
+			return
+		}
+
+		// リクエスト失敗
+		if resp.StatusCode != http.StatusOK {
+			tx.Rollback()
+			errorResponse(w, http.StatusInternalServerError, "決済に失敗しました。カードトークンや支払いIDが間違っている可能性があります")
+			log.Println(resp.StatusCode)
+			return
+		}
+
+		// リクエスト取り出し
+		output := PaymentResponse{}
+		err = json.Unmarshal(body, &output)
+		if err != nil {
+			errorResponse(w, http.StatusInternalServerError, "JSON parseに失敗しました")
+			log.Println(err.Error())
+			return
+		}
+		paymentID = output.PaymentId
 	}
 
 	// 予約情報の更新
-	query = "UPDATE reservations SET status=?, payment_id=? WHERE reservation_id=?"
+	query = "UPDATE reservations SET status=?, payment_id=?, payment_status=?, billing_organization_id=? WHERE reservation_id=?"
 	_, err = tx.Exec(
 		query,
 		"done",
-		output.PaymentId,
+		paymentID,
+		paymentStatusCaptured,
+		billingOrgID,
 		req.ReservationId,
 	)
 	if err != nil {
@@ -1693,20 +2219,58 @@ func reservationPaymentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	tx.Commit()
+
+	// 使わなかった与信はキャンセルしておく
+	// 取り消し (void) は売上確定済みだと失敗するので、どの状態でも取り消せるキャンセルを使う
+	if reservation.PaymentStatus == paymentStatusAuthorized && !captured {
+		if err := cancelPayment(reservation.PaymentId); err != nil {
+			log.Println(err.Error())
+		}
+	}
+
+	// 座席の購読者に通知
+	seatReservationList := []SeatReservation{}
+	err = dbx.Select(&seatReservationList, "SELECT * FROM seat_reservations WHERE reservation_id=?", reservation.ReservationId)
+	if err != nil {
+		log.Println(err.Error())
+	}
+	publishSeatEvent(SeatEventCommitted, reservation, seatReservationList)
+
 	w.Write(response)
 }
 
 func getAuthHandler(w http.ResponseWriter, r *http.Request) {
 
 	// userID取得
-	user, errCode, errMsg := getUser(r)
+	user, errCode, errMsg := authenticate(r, scopeSearch)
 	if errCode != http.StatusOK {
 		errorResponse(w, errCode, errMsg)
 		log.Printf("%s", errMsg)
 		return
 	}
 
-	resp := AuthResponse{user.Email}
+	// Bearer トークンは CSRF 対策の対象外なので、トークンを配布しない
+	if _, ok := bearerToken(r); ok {
+		resp := AuthResponse{user.Email, user.EmailVerifiedAt != nil, ""}
+		w.Header().Set("Content-Type", "application/json;charset=utf-8")
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	// CSRFトークンの配布 (ログイン時に発行済みのはずだが、無ければここで発行する)
+	session := getSession(r)
+	csrfToken := sessionCSRFToken(session)
+	if csrfToken == "" {
+		csrfToken = issueCSRFToken(session)
+		if err := session.Save(r, w); err != nil {
+			log.Print(err)
+			errorResponse(w, http.StatusInternalServerError, "session error")
+			return
+		}
+	}
+
+	resp := AuthResponse{user.Email, user.EmailVerifiedAt != nil, csrfToken}
+	w.Header().Set(csrfHeaderName, csrfToken)
 	w.Header().Set("Content-Type", "application/json;charset=utf-8")
 	json.NewEncoder(w).Encode(resp)
 }
@@ -1723,26 +2287,58 @@ func signUpHandler(w http.ResponseWriter, r *http.Request) {
 	user := User{}
 	json.Unmarshal(buf, &user)
 
+	if ok, retryAfter := loginLimit.allow(clientIP(r), ""); !ok {
+		tooManyRequestsResponse(w, retryAfter)
+		return
+	}
+
 	// TODO: validation
 
-	salt := make([]byte, 1024)
-	_, err := crand.Read(salt)
+	superSecurePassword, err := hashPassword(user.Password)
 	if err != nil {
-		errorResponse(w, http.StatusInternalServerError, "salt generator error")
+		errorResponse(w, http.StatusInternalServerError, "password hash error")
+		log.Println(err.Error())
 		return
 	}
-	superSecurePassword := pbkdf2.Key([]byte(user.Password), salt, 100, 256, sha256.New)
 
-	_, err = dbx.Exec(
-		"INSERT INTO `users` (`email`, `salt`, `super_secure_password`) VALUES (?, ?, ?)",
+	tx := dbx.MustBegin()
+	result, err := tx.Exec(
+		"INSERT INTO `users` (`email`, `salt`, `super_secure_password`) VALUES (?, '', ?)",
 		user.Email,
-		salt,
 		superSecurePassword,
 	)
 	if err != nil {
+		tx.Rollback()
 		errorResponse(w, http.StatusBadRequest, "user registration failed")
 		return
 	}
+	userID, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "user registration failed")
+		log.Println(err.Error())
+		return
+	}
+
+	// メールアドレス確認用のトークン
+	token, err := issueUserToken(tx, userID, userTokenVerifyEmail, verifyEmailTokenTTL)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "user registration failed")
+		log.Println(err.Error())
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "user registration failed")
+		log.Println(err.Error())
+		return
+	}
+
+	// 送れなくても登録は完了している (確認メールは再送できる)
+	if err := sendVerifyEmailMail(user.Email, token); err != nil {
+		log.Println(err.Error())
+	}
 
 	messageResponse(w, "registration complete")
 }
@@ -1759,10 +2355,19 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 	postUser := User{}
 	json.Unmarshal(buf, &postUser)
 
+	// 試行回数制限 (パスワードのハッシュ計算より前に判定する)
+	ip := clientIP(r)
+	account := strings.ToLower(postUser.Email)
+	if ok, retryAfter := loginLimit.allow(ip, account); !ok {
+		tooManyRequestsResponse(w, retryAfter)
+		return
+	}
+
 	user := User{}
 	query := "SELECT * FROM users WHERE email=?"
 	err := dbx.Get(&user, query, postUser.Email)
 	if err == sql.ErrNoRows {
+		loginLimit.recordFailure(ip, account)
 		errorResponse(w, http.StatusForbidden, "authentication failed")
 		return
 	}
@@ -1771,21 +2376,35 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	challengePassword := pbkdf2.Key([]byte(postUser.Password), user.Salt, 100, 256, sha256.New)
-
-	if !bytes.Equal(user.HashedPassword, challengePassword) {
+	ok, needsRehash, err := verifyPassword(user, postUser.Password)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "password hash error")
+		log.Println(err.Error())
+		return
+	}
+	if !ok {
+		loginLimit.recordFailure(ip, account)
 		errorResponse(w, http.StatusForbidden, "authentication failed")
 		return
 	}
+	loginLimit.recordSuccess(ip, account)
+
+	// 旧形式や古いパラメータのハッシュは作り直す (失敗してもログインは続ける)
+	if needsRehash {
+		rehashPassword(user, postUser.Password)
+	}
 
 	session := getSession(r)
 
 	session.Values["user_id"] = user.ID
+	// ログインのたびにCSRFトークンを発行し直す
+	csrfToken := issueCSRFToken(session)
 	if err = session.Save(r, w); err != nil {
 		log.Print(err)
 		errorResponse(w, http.StatusInternalServerError, "session error")
 		return
 	}
+	w.Header().Set(csrfHeaderName, csrfToken)
 	messageResponse(w, "autheticated")
 }
 
@@ -1798,6 +2417,7 @@ func logoutHandler(w http.ResponseWriter, r *http.Request) {
 	session := getSession(r)
 
 	session.Values["user_id"] = 0
+	delete(session.Values, csrfSessionKey)
 	if err := session.Save(r, w); err != nil {
 		log.Print(err)
 		errorResponse(w, http.StatusInternalServerError, "session error")
@@ -1807,103 +2427,213 @@ func logoutHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func makeReservationResponse(reservation Reservation) (ReservationResponse, error) {
+	reservationResponseList, err := makeReservationResponses([]Reservation{reservation})
+	if err != nil {
+		return ReservationResponse{}, err
+	}
+	return reservationResponseList[0], nil
+}
 
-	reservationResponse := ReservationResponse{}
+type timetableKey struct {
+	Date       string
+	TrainClass string
+	TrainName  string
+	Station    string
+}
 
-	var departure, arrival string
-	err := dbx.Get(
-		&departure,
-		"SELECT departure FROM train_timetable_master WHERE date=? AND train_class=? AND train_name=? AND station=?",
-		reservation.Date.Format("2006/01/02"), reservation.TrainClass, reservation.TrainName, reservation.Departure,
-	)
+type seatKey struct {
+	TrainClass string
+	CarNumber  int
+	SeatColumn string
+	SeatRow    int
+}
+
+func makeReservationResponses(reservationList []Reservation) ([]ReservationResponse, error) {
+	// 予約一覧からレスポンスを組み立てる
+	// 時刻表・座席予約・座席マスタはまとめて引く
+
+	reservationResponseList := []ReservationResponse{}
+	if len(reservationList) == 0 {
+		return reservationResponseList, nil
+	}
+
+	reservationIDs := []int{}
+	timetableArgs := []interface{}{}
+	timetablePlaceholders := []string{}
+	trainSeen := map[string]bool{}
+	for _, reservation := range reservationList {
+		reservationIDs = append(reservationIDs, reservation.ReservationId)
+
+		date := reservation.Date.Format("2006/01/02")
+		key := fmt.Sprintf("%s_%s_%s", date, reservation.TrainClass, reservation.TrainName)
+		if trainSeen[key] {
+			continue
+		}
+		trainSeen[key] = true
+		timetablePlaceholders = append(timetablePlaceholders, "(?, ?, ?)")
+		timetableArgs = append(timetableArgs, date, reservation.TrainClass, reservation.TrainName)
+	}
+
+	// 時刻表
+	timetableList := []struct {
+		Date       time.Time `db:"date"`
+		TrainClass string    `db:"train_class"`
+		TrainName  string    `db:"train_name"`
+		Station    string    `db:"station"`
+		Departure  string    `db:"departure"`
+		Arrival    string    `db:"arrival"`
+	}{}
+	query := "SELECT date, train_class, train_name, station, departure, arrival FROM train_timetable_master WHERE (date, train_class, train_name) IN (" + strings.Join(timetablePlaceholders, ", ") + ")"
+	err := dbx.Select(&timetableList, query, timetableArgs...)
 	if err != nil {
-		return reservationResponse, err
+		return nil, err
 	}
-	err = dbx.Get(
-		&arrival,
-		"SELECT arrival FROM train_timetable_master WHERE date=? AND train_class=? AND train_name=? AND station=?",
-		reservation.Date.Format("2006/01/02"), reservation.TrainClass, reservation.TrainName, reservation.Arrival,
-	)
+	departureMap := map[timetableKey]string{}
+	arrivalMap := map[timetableKey]string{}
+	for _, t := range timetableList {
+		key := timetableKey{t.Date.Format("2006/01/02"), t.TrainClass, t.TrainName, t.Station}
+		departureMap[key] = t.Departure
+		arrivalMap[key] = t.Arrival
+	}
+
+	// 座席予約
+	seatReservationList := []SeatReservation{}
+	query, args, err := sqlx.In("SELECT * FROM seat_reservations WHERE reservation_id IN (?)", reservationIDs)
+	if err != nil {
+		return nil, err
+	}
+	err = dbx.Select(&seatReservationList, query, args...)
 	if err != nil {
-		return reservationResponse, err
+		return nil, err
+	}
+	seatReservationMap := map[int][]SeatReservation{}
+	for _, seatReservation := range seatReservationList {
+		seatReservationMap[seatReservation.ReservationId] = append(seatReservationMap[seatReservation.ReservationId], seatReservation)
+	}
+
+	// 座席種別 (1つの予約内で車両番号は全席同じなので先頭の席で引く)
+	seatArgs := []interface{}{}
+	seatPlaceholders := []string{}
+	seatSeen := map[seatKey]bool{}
+	for _, reservation := range reservationList {
+		seats := seatReservationMap[reservation.ReservationId]
+		if len(seats) == 0 || seats[0].CarNumber == 0 {
+			continue
+		}
+		key := seatKey{reservation.TrainClass, seats[0].CarNumber, seats[0].SeatColumn, seats[0].SeatRow}
+		if seatSeen[key] {
+			continue
+		}
+		seatSeen[key] = true
+		seatPlaceholders = append(seatPlaceholders, "(?, ?, ?, ?)")
+		seatArgs = append(seatArgs, key.TrainClass, key.CarNumber, key.SeatColumn, key.SeatRow)
+	}
+	seatClassMap := map[seatKey]string{}
+	if len(seatPlaceholders) > 0 {
+		seatList := []Seat{}
+		query = "SELECT * FROM seat_master WHERE (train_class, car_number, seat_column, seat_row) IN (" + strings.Join(seatPlaceholders, ", ") + ")"
+		err = dbx.Select(&seatList, query, seatArgs...)
+		if err != nil {
+			return nil, err
+		}
+		for _, seat := range seatList {
+			seatClassMap[seatKey{seat.TrainClass, seat.CarNumber, seat.SeatColumn, seat.SeatRow}] = seat.SeatClass
+		}
 	}
 
-	reservationResponse.ReservationId = reservation.ReservationId
-	reservationResponse.Date = reservation.Date.Format("2006/01/02")
-	reservationResponse.Amount = reservation.Amount
-	reservationResponse.Adult = reservation.Adult
-	reservationResponse.Child = reservation.Child
-	reservationResponse.Departure = reservation.Departure
-	reservationResponse.Arrival = reservation.Arrival
-	reservationResponse.TrainClass = reservation.TrainClass
-	reservationResponse.TrainName = reservation.TrainName
-	reservationResponse.DepartureTime = departure
-	reservationResponse.ArrivalTime = arrival
+	for _, reservation := range reservationList {
+		reservationResponse := ReservationResponse{}
 
-	query := "SELECT * FROM seat_reservations WHERE reservation_id=?"
-	err = dbx.Select(&reservationResponse.Seats, query, reservation.ReservationId)
+		date := reservation.Date.Format("2006/01/02")
+		departure, ok := departureMap[timetableKey{date, reservation.TrainClass, reservation.TrainName, reservation.Departure}]
+		if !ok {
+			return nil, sql.ErrNoRows
+		}
+		arrival, ok := arrivalMap[timetableKey{date, reservation.TrainClass, reservation.TrainName, reservation.Arrival}]
+		if !ok {
+			return nil, sql.ErrNoRows
+		}
 
-	// 1つの予約内で車両番号は全席同じ
-	reservationResponse.CarNumber = reservationResponse.Seats[0].CarNumber
+		reservationResponse.ReservationId = reservation.ReservationId
+		reservationResponse.Date = date
+		reservationResponse.Amount = reservation.Amount
+		reservationResponse.Adult = reservation.Adult
+		reservationResponse.Child = reservation.Child
+		reservationResponse.Departure = reservation.Departure
+		reservationResponse.Arrival = reservation.Arrival
+		reservationResponse.TrainClass = reservation.TrainClass
+		reservationResponse.TrainName = reservation.TrainName
+		reservationResponse.DepartureTime = departure
+		reservationResponse.ArrivalTime = arrival
+		reservationResponse.NeedsAssistance = reservation.NeedsAssistance
+
+		seats := seatReservationMap[reservation.ReservationId]
+		if len(seats) == 0 {
+			return nil, fmt.Errorf("seat reservations not found: reservation_id=%d", reservation.ReservationId)
+		}
 
-	if reservationResponse.Seats[0].CarNumber == 0 {
-		reservationResponse.SeatClass = "non-reserved"
-	} else {
-		// 座席種別を取得
-		seat := Seat{}
-		query = "SELECT * FROM seat_master WHERE train_class=? AND car_number=? AND seat_column=? AND seat_row=?"
-		err = dbx.Get(
-			&seat, query,
-			reservation.TrainClass, reservationResponse.CarNumber,
-			reservationResponse.Seats[0].SeatColumn, reservationResponse.Seats[0].SeatRow,
-		)
-		if err == sql.ErrNoRows {
-			return reservationResponse, err
+		// 1つの予約内で車両番号は全席同じ
+		reservationResponse.CarNumber = seats[0].CarNumber
+
+		if reservationResponse.CarNumber == 0 {
+			reservationResponse.SeatClass = "non-reserved"
+		} else {
+			seatClass, ok := seatClassMap[seatKey{reservation.TrainClass, seats[0].CarNumber, seats[0].SeatColumn, seats[0].SeatRow}]
+			if !ok {
+				return nil, sql.ErrNoRows
+			}
+			reservationResponse.SeatClass = seatClass
 		}
-		if err != nil {
-			return reservationResponse, err
+
+		for _, v := range seats {
+			// omit
+			v.ReservationId = 0
+			v.CarNumber = 0
+			reservationResponse.Seats = append(reservationResponse.Seats, v)
 		}
-		reservationResponse.SeatClass = seat.SeatClass
-	}
 
-	for i, v := range reservationResponse.Seats {
-		// omit
-		v.ReservationId = 0
-		v.CarNumber = 0
-		reservationResponse.Seats[i] = v
+		reservationResponseList = append(reservationResponseList, reservationResponse)
 	}
-	return reservationResponse, nil
+	return reservationResponseList, nil
 }
 
 func userReservationsHandler(w http.ResponseWriter, r *http.Request) {
 	/*
-		ログイン
-		POST /auth/login
+		予約一覧
+		GET /api/user/reservations
+		?limit=&cursor=&from_date=&to_date=&status=&train_class=&sort=&order=
 	*/
-	user, errCode, errMsg := getUser(r)
+	user, errCode, errMsg := authenticate(r, scopeSearch)
 	if errCode != http.StatusOK {
 		errorResponse(w, errCode, errMsg)
 		return
 	}
-	reservationList := []Reservation{}
 
-	query := "SELECT * FROM reservations WHERE user_id=?"
-	err := dbx.Select(&reservationList, query, user.ID)
+	listQuery, err := parseReservationListQuery(r.URL.Query())
 	if err != nil {
 		errorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	reservationResponseList := []ReservationResponse{}
+	query, args := listQuery.buildQuery(user.ID)
+	reservationList := []Reservation{}
+	err = dbx.Select(&reservationList, query, args...)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	for _, r := range reservationList {
-		res, err := makeReservationResponse(r)
-		if err != nil {
-			errorResponse(w, http.StatusBadRequest, err.Error())
-			log.Println("makeReservationResponse()", err)
-			return
-		}
-		reservationResponseList = append(reservationResponseList, res)
+	// limit+1件目が取れていれば次のページがある
+	if listQuery.Limit > 0 && len(reservationList) > listQuery.Limit {
+		reservationList = reservationList[:listQuery.Limit]
+		w.Header().Set("X-Next-Cursor", listQuery.nextCursor(reservationList[len(reservationList)-1]))
+	}
+
+	reservationResponseList, err := makeReservationResponses(reservationList)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		log.Println("makeReservationResponses()", err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json;charset=utf-8")
@@ -1915,7 +2645,7 @@ func userReservationResponseHandler(w http.ResponseWriter, r *http.Request) {
 		ログイン
 		POST /auth/login
 	*/
-	user, errCode, errMsg := getUser(r)
+	user, errCode, errMsg := authenticate(r, scopeSearch)
 	if errCode != http.StatusOK {
 		errorResponse(w, errCode, errMsg)
 		return
@@ -1928,8 +2658,8 @@ func userReservationResponseHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	reservation := Reservation{}
-	query := "SELECT * FROM reservations WHERE reservation_id=? AND user_id=?"
-	err = dbx.Get(&reservation, query, itemID, user.ID)
+	query := "SELECT * FROM reservations WHERE reservation_id=?"
+	err = dbx.Get(&reservation, query, itemID)
 	if err == sql.ErrNoRows {
 		errorResponse(w, http.StatusNotFound, "Reservation not found")
 		return
@@ -1938,6 +2668,14 @@ func userReservationResponseHandler(w http.ResponseWriter, r *http.Request) {
 		errorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	// 権限のない予約は存在しないものとして扱う
+	if ok, err := reservationAccessible(dbx, user, reservation); err != nil || !ok {
+		if err != nil {
+			log.Println(err.Error())
+		}
+		errorResponse(w, http.StatusNotFound, "Reservation not found")
+		return
+	}
 
 	reservationResponse, err := makeReservationResponse(reservation)
 
@@ -1952,7 +2690,7 @@ func userReservationResponseHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func userReservationCancelHandler(w http.ResponseWriter, r *http.Request) {
-	user, errCode, errMsg := getUser(r)
+	user, errCode, errMsg := authenticate(r, scopeReserve)
 	if errCode != http.StatusOK {
 		errorResponse(w, errCode, errMsg)
 		return
@@ -1967,17 +2705,37 @@ func userReservationCancelHandler(w http.ResponseWriter, r *http.Request) {
 	tx := dbx.MustBegin()
 
 	reservation := Reservation{}
-	query := "SELECT * FROM reservations WHERE reservation_id=? AND user_id=?"
-	err = tx.Get(&reservation, query, itemID, user.ID)
+	query := "SELECT * FROM reservations WHERE reservation_id=? FOR UPDATE"
+	err = tx.Get(&reservation, query, itemID)
 	fmt.Println("CANCEL", reservation, itemID, user.ID)
 	if err == sql.ErrNoRows {
 		tx.Rollback()
-		errorResponse(w, http.StatusBadRequest, "reservations naiyo")
+		errorResponse(w, http.StatusNotFound, "Reservation not found")
 		return
 	}
 	if err != nil {
 		tx.Rollback()
 		errorResponse(w, http.StatusInternalServerError, "予約情報の検索に失敗しました")
+		return
+	}
+	ok, err := reservationAccessible(tx, user, reservation)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "組織情報の取得に失敗しました")
+		log.Println(err.Error())
+		return
+	}
+	// 権限のない予約は存在しないものとして扱う
+	if !ok {
+		tx.Rollback()
+		errorResponse(w, http.StatusNotFound, "Reservation not found")
+		return
+	}
+
+	if reservation.CheckedInAt != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusBadRequest, "乗車済みの予約はキャンセルできません")
+		return
 	}
 
 	switch reservation.Status {
@@ -1985,6 +2743,10 @@ func userReservationCancelHandler(w http.ResponseWriter, r *http.Request) {
 		tx.Rollback()
 		errorResponse(w, http.StatusInternalServerError, "何らかの理由により予約はRejected状態です")
 		return
+	case "no_show":
+		tx.Rollback()
+		errorResponse(w, http.StatusBadRequest, "乗車しなかった予約はキャンセルできません")
+		return
 	case "done":
 		// 支払いをキャンセルする
 		payInfo := CancelPaymentInformationRequest{reservation.PaymentId}
@@ -2009,6 +2771,7 @@ func userReservationCancelHandler(w http.ResponseWriter, r *http.Request) {
 			log.Println(err.Error())
 			return
 		}
+		setPaymentMerchantKey(req)
 		resp, err := client.Do(req)
 		if err != nil {
 			tx.Rollback()
@@ -2045,15 +2808,37 @@ func userReservationCancelHandler(w http.ResponseWriter, r *http.Request) {
 		fmt.Println(output)
 	default:
 		// pass(requesting状態のものはpayment_id無いので叩かない)
+		// 仮予約で与信を確保していれば取り消す。失敗しても与信は有効期限で取り消されるのでキャンセルは続ける
+		if reservation.PaymentStatus == paymentStatusAuthorized {
+			if err := voidAuthorization(reservation.PaymentId); err != nil {
+				log.Println(err.Error())
+			}
+		}
 	}
 
+	// 解放される座席 (通知用)
+	seatReservationList := []SeatReservation{}
+	query = "SELECT * FROM seat_reservations WHERE reservation_id=?"
+	err = tx.Select(&seatReservationList, query, itemID)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// 本人または同じ組織のメンバーの予約であることを確認した、予約者の予約だけを消す
 	query = "DELETE FROM reservations WHERE reservation_id=? AND user_id=?"
-	_, err = tx.Exec(query, itemID, user.ID)
+	result, err := tx.Exec(query, itemID, *reservation.UserId)
 	if err != nil {
 		tx.Rollback()
 		errorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		tx.Rollback()
+		errorResponse(w, http.StatusNotFound, "Reservation not found")
+		return
+	}
 
 	query = "DELETE FROM seat_reservations WHERE reservation_id=?"
 	_, err = tx.Exec(query, itemID)
@@ -2070,6 +2855,10 @@ func userReservationCancelHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	tx.Commit()
+
+	// 座席の購読者に通知
+	publishSeatEvent(SeatEventReleased, reservation, seatReservationList)
+
 	messageResponse(w, "cancell complete")
 }
 
@@ -2081,6 +2870,15 @@ func initializeHandler(w http.ResponseWriter, r *http.Request) {
 	dbx.Exec("TRUNCATE seat_reservations")
 	dbx.Exec("TRUNCATE reservations")
 	dbx.Exec("TRUNCATE users")
+	dbx.Exec("TRUNCATE user_tokens")
+	dbx.Exec("TRUNCATE api_tokens")
+	dbx.Exec("TRUNCATE organizations")
+	dbx.Exec("TRUNCATE organization_members")
+	dbx.Exec("TRUNCATE organization_invitations")
+	dbx.Exec("TRUNCATE payment_webhook_events")
+
+	loginLimit.reset()
+	registerPaymentWebhook()
 
 	resp := InitializeResponse{
 		availableDays,
@@ -2097,7 +2895,8 @@ func settingsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	settings := Settings{
-		PaymentAPI: payment_api,
+		PaymentAPI:            payment_api,
+		PaymentPublishableKey: os.Getenv("PAYMENT_PUBLISHABLE_KEY"),
 	}
 
 	w.Header().Set("Content-Type", "application/json;charset=utf-8")
@@ -2152,10 +2951,19 @@ func main() {
 	}
 	defer dbx.Close()
 
+	// 乗車券の署名鍵
+	initTicketKey()
+
+	// 乗車しなかった予約の定期処理
+	startNoShowSweeper()
+
 	// HTTP
 
 	mux := goji.NewMux()
 
+	// 状態を変更するリクエストのCSRF対策
+	mux.Use(csrfMiddleware)
+
 	mux.HandleFunc(pat.Post("/initialize"), initializeHandler)
 	mux.HandleFunc(pat.Get("/api/settings"), settingsHandler)
 
@@ -2163,17 +2971,48 @@ func main() {
 	mux.HandleFunc(pat.Get("/api/stations"), getStationsHandler)
 	mux.HandleFunc(pat.Get("/api/train/search"), trainSearchHandler)
 	mux.HandleFunc(pat.Get("/api/train/seats"), trainSeatsHandler)
+	mux.HandleFunc(pat.Get("/api/train/seats/stream"), trainSeatsStreamHandler)
 	mux.HandleFunc(pat.Post("/api/train/reserve"), trainReservationHandler)
 	mux.HandleFunc(pat.Post("/api/train/reservation/commit"), reservationPaymentHandler)
+	mux.HandleFunc(pat.Get("/api/gtfs.zip"), gtfsHandler)
+	mux.HandleFunc(pat.Get("/api/fare/quote"), fareQuoteHandler)
+	mux.HandleFunc(pat.Get("/api/fare/distance"), distanceFareHandler)
 
 	// 認証関連
 	mux.HandleFunc(pat.Get("/api/auth"), getAuthHandler)
 	mux.HandleFunc(pat.Post("/api/auth/signup"), signUpHandler)
 	mux.HandleFunc(pat.Post("/api/auth/login"), loginHandler)
 	mux.HandleFunc(pat.Post("/api/auth/logout"), logoutHandler)
+	mux.HandleFunc(pat.Post("/api/auth/verify"), verifyEmailHandler)
+	mux.HandleFunc(pat.Post("/api/auth/verify/resend"), resendVerifyEmailHandler)
+	mux.HandleFunc(pat.Post("/api/auth/password/reset"), passwordResetRequestHandler)
+	mux.HandleFunc(pat.Post("/api/auth/password/reset/confirm"), passwordResetConfirmHandler)
 	mux.HandleFunc(pat.Get("/api/user/reservations"), userReservationsHandler)
+	mux.HandleFunc(pat.Get("/api/user/reservations.ics"), userReservationsICSHandler)
 	mux.HandleFunc(pat.Get("/api/user/reservations/:item_id"), userReservationResponseHandler)
 	mux.HandleFunc(pat.Post("/api/user/reservations/:item_id/cancel"), userReservationCancelHandler)
+	mux.HandleFunc(pat.Get("/api/user/reservations/:item_id/ticket"), userReservationTicketHandler)
+	mux.HandleFunc(pat.Get("/api/user/tokens"), apiTokensHandler)
+	mux.HandleFunc(pat.Post("/api/user/tokens"), apiTokenCreateHandler)
+	mux.HandleFunc(pat.Post("/api/user/tokens/:token_id/revoke"), apiTokenRevokeHandler)
+	mux.HandleFunc(pat.Post("/api/organizations"), organizationCreateHandler)
+	mux.HandleFunc(pat.Get("/api/organization"), organizationHandler)
+	mux.HandleFunc(pat.Post("/api/organization/members"), organizationMemberInviteHandler)
+	mux.HandleFunc(pat.Post("/api/organization/members/:user_id/remove"), organizationMemberRemoveHandler)
+	mux.HandleFunc(pat.Post("/api/organization/card"), organizationCardHandler)
+	mux.HandleFunc(pat.Get("/api/organization/invitations"), organizationInvitationsHandler)
+	mux.HandleFunc(pat.Post("/api/organization/invitations/:invitation_id/accept"), organizationInvitationAcceptHandler)
+	mux.HandleFunc(pat.Post("/api/organization/invitations/:invitation_id/decline"), organizationInvitationDeclineHandler)
+	mux.HandleFunc(pat.Get("/api/organization/reservations"), organizationReservationsHandler)
+	mux.HandleFunc(pat.Get("/api/organization/statement.csv"), organizationStatementHandler)
+
+	// 乗車券
+	mux.HandleFunc(pat.Post("/api/ticket/verify"), ticketVerifyHandler)
+	mux.HandleFunc(pat.Post("/api/ticket/checkin"), ticketCheckinHandler)
+	mux.HandleFunc(pat.Get("/api/ticket/public_key"), ticketPublicKeyHandler)
+
+	// payment-API からの Webhook
+	mux.HandleFunc(pat.Post("/api/payment/webhook"), paymentWebhookHandler)
 
 	fmt.Println(banner)
 	err = http.ListenAndServe(":8000", mux)
@@ -0,0 +1,893 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"goji.io/pat"
+)
+
+// 法人アカウント
+//
+//   - ユーザーは1つの組織にだけ所属できる (organization_members.user_id は UNIQUE)
+//   - メンバーは管理者が招待し、招待されたユーザーが承諾して初めて組織に加わる (organization_invitations)
+//   - 同じ組織のメンバーは互いの予約を代理で行える (reservations.booked_by に手配した人を記録する)
+//   - 組織の管理者は、メンバーが手配したメンバーの予約と法人カードで支払った予約を参照・キャンセルでき、月次の請求明細を出力できる
+//     メンバーが自分で手配した個人の予約は管理者からも見えない
+//   - 法人カードで支払った予約は reservations.billing_organization_id に組織を記録する
+const (
+	orgRoleAdmin  = "admin"
+	orgRoleMember = "member"
+)
+
+type Organization struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	CardToken *string   `json:"-" db:"card_token"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type OrganizationMember struct {
+	UserID    int64     `json:"user_id" db:"user_id"`
+	Email     string    `json:"email" db:"email"`
+	Role      string    `json:"role" db:"role"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type OrganizationInvitation struct {
+	ID               int64     `json:"id" db:"id"`
+	OrganizationID   int64     `json:"organization_id" db:"organization_id"`
+	OrganizationName string    `json:"organization_name" db:"organization_name"`
+	Email            string    `json:"email" db:"email"`
+	Role             string    `json:"role" db:"role"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+}
+
+type OrganizationResponse struct {
+	Organization
+	Role             string               `json:"role"`
+	HasCorporateCard bool                 `json:"has_corporate_card"`
+	Members          []OrganizationMember `json:"members"`
+	// 承諾待ちの招待 (管理者のみ)
+	Invitations []OrganizationInvitation `json:"invitations,omitempty"`
+}
+
+type OrganizationCreateRequest struct {
+	Name string `json:"name"`
+}
+
+type OrganizationMemberInviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type OrganizationCardRequest struct {
+	CardToken string `json:"card_token"`
+}
+
+type OrganizationReservationResponse struct {
+	ReservationResponse
+	UserID           int64   `json:"user_id"`
+	UserEmail        string  `json:"user_email"`
+	BookedByEmail    *string `json:"booked_by_email"`
+	Status           string  `json:"status"`
+	BilledToCorpCard bool    `json:"billed_to_corporate_card"`
+}
+
+// reservationOrgAccess は操作するユーザーの組織と、予約の乗車者・手配者がその組織のメンバーかどうか
+type reservationOrgAccess struct {
+	OrganizationID int64
+	Role           string // 操作するユーザーの役割 (組織に所属していなければ空)
+	RiderIsMember  bool
+	BookerIsMember bool
+}
+
+// canManageReservation は予約を参照・支払い・キャンセルできるかを判定する
+func canManageReservation(userID int64, reservation Reservation, access reservationOrgAccess) bool {
+	if reservation.UserId != nil && int64(*reservation.UserId) == userID {
+		return true
+	}
+	switch access.Role {
+	case orgRoleAdmin:
+		// 管理者は組織で手配した予約と法人カードで支払った予約だけ。メンバーが自分で手配した個人の予約は見えない
+		if reservation.BillingOrgId != nil && *reservation.BillingOrgId == access.OrganizationID {
+			return true
+		}
+		return reservation.BookedBy != nil && access.RiderIsMember && access.BookerIsMember
+	case orgRoleMember:
+		// 一般メンバーは自分が手配した予約だけ
+		return access.RiderIsMember && reservation.BookedBy != nil && *reservation.BookedBy == userID
+	}
+	return false
+}
+
+// sharedOrganizationRole は userID と otherID が同じ組織に所属していれば、組織IDと userID の役割を返す
+func sharedOrganizationRole(q sqlx.Queryer, userID, otherID int64) (int64, string, error) {
+	var membership struct {
+		OrganizationID int64  `db:"organization_id"`
+		Role           string `db:"role"`
+	}
+	query := "SELECT m1.organization_id, m1.role FROM organization_members m1 JOIN organization_members m2 ON m1.organization_id = m2.organization_id WHERE m1.user_id=? AND m2.user_id=?"
+	err := sqlx.Get(q, &membership, query, userID, otherID)
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	return membership.OrganizationID, membership.Role, nil
+}
+
+// reservationAccessible は user が予約を操作できるかを判定する
+func reservationAccessible(q sqlx.Queryer, user User, reservation Reservation) (bool, error) {
+	if reservation.UserId == nil {
+		return false, nil
+	}
+	// 本人の予約なら組織を引くまでもない
+	if int64(*reservation.UserId) == user.ID {
+		return true, nil
+	}
+	org, role, err := userOrganization(q, user.ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	access := reservationOrgAccess{OrganizationID: org.ID, Role: role}
+	access.RiderIsMember, err = isOrganizationMember(q, org.ID, int64(*reservation.UserId))
+	if err != nil {
+		return false, err
+	}
+	if reservation.BookedBy != nil {
+		access.BookerIsMember, err = isOrganizationMember(q, org.ID, *reservation.BookedBy)
+		if err != nil {
+			return false, err
+		}
+	}
+	return canManageReservation(user.ID, reservation, access), nil
+}
+
+// isOrganizationMember は userID が組織のメンバーかどうかを返す
+func isOrganizationMember(q sqlx.Queryer, orgID, userID int64) (bool, error) {
+	var count int
+	err := sqlx.Get(q, &count, "SELECT COUNT(*) FROM organization_members WHERE organization_id=? AND user_id=?", orgID, userID)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// userOrganization はユーザーが所属する組織と役割を返す。所属していなければ sql.ErrNoRows
+func userOrganization(q sqlx.Queryer, userID int64) (Organization, string, error) {
+	var row struct {
+		Organization
+		Role string `db:"role"`
+	}
+	query := "SELECT o.*, m.role FROM organizations o JOIN organization_members m ON o.id = m.organization_id WHERE m.user_id=?"
+	err := sqlx.Get(q, &row, query, userID)
+	return row.Organization, row.Role, err
+}
+
+func organizationStatementMonth(s string) (time.Time, time.Time, error) {
+	// month は 2020/01 の形式
+	from, err := time.Parse("2006/01", s)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return from, from.AddDate(0, 1, 0), nil
+}
+
+func isDuplicateEntry(err error) bool {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	return ok && mysqlErr.Number == 1062
+}
+
+func organizationCreateHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		組織の作成
+		POST /api/organizations
+		作成したユーザーが組織の管理者になる
+	*/
+	user, errCode, errMsg := authenticate(r, scopeReserve)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	defer r.Body.Close()
+	buf, _ := ioutil.ReadAll(r.Body)
+
+	req := OrganizationCreateRequest{}
+	err := json.Unmarshal(buf, &req)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, "JSON parseに失敗しました")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		errorResponse(w, http.StatusBadRequest, "組織名が不正です")
+		return
+	}
+
+	tx := dbx.MustBegin()
+
+	result, err := tx.Exec("INSERT INTO organizations (name, created_at) VALUES (?, NOW())", req.Name)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	orgID, err := result.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO organization_members (organization_id, user_id, role, created_at) VALUES (?, ?, ?, NOW())",
+		orgID, user.ID, orgRoleAdmin,
+	)
+	if isDuplicateEntry(err) {
+		tx.Rollback()
+		errorResponse(w, http.StatusConflict, "既に組織に所属しています")
+		return
+	}
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	org := Organization{}
+	err = tx.Get(&org, "SELECT * FROM organizations WHERE id=?", orgID)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(org)
+}
+
+func organizationHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		所属している組織とメンバーの一覧
+		GET /api/organization
+		代理予約の on_behalf_of にはメンバーの user_id を使う
+	*/
+	user, errCode, errMsg := authenticate(r, scopeSearch)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	org, role, err := userOrganization(dbx, user.ID)
+	if err == sql.ErrNoRows {
+		errorResponse(w, http.StatusNotFound, "組織に所属していません")
+		return
+	}
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	members := []OrganizationMember{}
+	query := "SELECT m.user_id, u.email, m.role, m.created_at FROM organization_members m JOIN users u ON m.user_id = u.id WHERE m.organization_id=? ORDER BY m.created_at, m.user_id"
+	err = dbx.Select(&members, query, org.ID)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	resp := OrganizationResponse{
+		Organization:     org,
+		Role:             role,
+		HasCorporateCard: org.CardToken != nil,
+		Members:          members,
+	}
+	if role == orgRoleAdmin {
+		resp.Invitations = []OrganizationInvitation{}
+		query := organizationInvitationQuery + " WHERE i.organization_id=? ORDER BY i.created_at, i.id"
+		err = dbx.Select(&resp.Invitations, query, org.ID)
+		if err != nil {
+			errorResponse(w, http.StatusInternalServerError, "db error")
+			log.Println(err.Error())
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func organizationMemberInviteHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		メンバーの招待 (組織の管理者のみ)
+		POST /api/organization/members
+		{"email": "...", "role": "member"}
+		招待されたユーザーが承諾するまで組織には加わらない。同じユーザーを招待し直すと役割を上書きする
+	*/
+	user, errCode, errMsg := authenticate(r, scopeReserve)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	defer r.Body.Close()
+	buf, _ := ioutil.ReadAll(r.Body)
+
+	req := OrganizationMemberInviteRequest{}
+	err := json.Unmarshal(buf, &req)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, "JSON parseに失敗しました")
+		return
+	}
+	if req.Role == "" {
+		req.Role = orgRoleMember
+	}
+	if req.Role != orgRoleAdmin && req.Role != orgRoleMember {
+		errorResponse(w, http.StatusBadRequest, "役割が不正です")
+		return
+	}
+
+	org, role, err := userOrganization(dbx, user.ID)
+	if err == sql.ErrNoRows || (err == nil && role != orgRoleAdmin) {
+		errorResponse(w, http.StatusForbidden, "組織の管理者のみ操作できます")
+		return
+	}
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	member := User{}
+	err = dbx.Get(&member, "SELECT * FROM users WHERE email=?", req.Email)
+	if err == sql.ErrNoRows {
+		errorResponse(w, http.StatusNotFound, "user not found")
+		return
+	}
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	ok, err := isOrganizationMember(dbx, org.ID, member.ID)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	if ok {
+		errorResponse(w, http.StatusConflict, "既に組織のメンバーです")
+		return
+	}
+
+	_, err = dbx.Exec(
+		"INSERT INTO organization_invitations (organization_id, user_id, role, invited_by, created_at) VALUES (?, ?, ?, ?, NOW()) ON DUPLICATE KEY UPDATE role=VALUES(role), invited_by=VALUES(invited_by), created_at=VALUES(created_at)",
+		org.ID, member.ID, req.Role, user.ID,
+	)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	// 招待は記録できているので、メールが送れなくてもエラーにはしない
+	if err := sendOrganizationInvitationMail(member.Email, org.Name); err != nil {
+		log.Println(err.Error())
+	}
+
+	messageResponse(w, "member invited")
+}
+
+const organizationInvitationQuery = "SELECT i.id, i.organization_id, o.name AS organization_name, u.email, i.role, i.created_at FROM organization_invitations i JOIN organizations o ON i.organization_id = o.id JOIN users u ON i.user_id = u.id"
+
+func sendOrganizationInvitationMail(email, orgName string) error {
+	body := fmt.Sprintf(`「%s」の法人アカウントに招待されました。
+
+ログインして次のURLを開き、招待を承諾してください。
+%s
+
+お心当たりのない場合は、招待を辞退するか、このメールを破棄してください。
+`, orgName, appBaseURL()+"/organization/invitations")
+
+	return mailer.Send(MailMessage{
+		To:      email,
+		Subject: "【ISUTRAIN】法人アカウントへの招待",
+		Body:    body,
+	})
+}
+
+func organizationInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		自分宛ての招待の一覧
+		GET /api/organization/invitations
+	*/
+	user, errCode, errMsg := authenticate(r, scopeSearch)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	invitations := []OrganizationInvitation{}
+	query := organizationInvitationQuery + " WHERE i.user_id=? ORDER BY i.created_at, i.id"
+	err := dbx.Select(&invitations, query, user.ID)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+func organizationInvitationAcceptHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		招待の承諾
+		POST /api/organization/invitations/:invitation_id/accept
+		承諾すると招待された役割で組織に加わり、自分宛ての他の招待は取り消される
+	*/
+	user, errCode, errMsg := authenticate(r, scopeReserve)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	invitationID, err := strconv.ParseInt(pat.Param(r, "invitation_id"), 10, 64)
+	if err != nil || invitationID <= 0 {
+		errorResponse(w, http.StatusBadRequest, "incorrect invitation id")
+		return
+	}
+
+	tx := dbx.MustBegin()
+
+	var invitation struct {
+		OrganizationID int64  `db:"organization_id"`
+		Role           string `db:"role"`
+	}
+	// 他のユーザー宛ての招待は存在しないものとして扱う
+	err = tx.Get(&invitation, "SELECT organization_id, role FROM organization_invitations WHERE id=? AND user_id=? FOR UPDATE", invitationID, user.ID)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		errorResponse(w, http.StatusNotFound, "invitation not found")
+		return
+	}
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO organization_members (organization_id, user_id, role, created_at) VALUES (?, ?, ?, NOW())",
+		invitation.OrganizationID, user.ID, invitation.Role,
+	)
+	if isDuplicateEntry(err) {
+		tx.Rollback()
+		errorResponse(w, http.StatusConflict, "既に組織に所属しています")
+		return
+	}
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	// 所属できる組織は1つだけなので、他の組織からの招待も片付ける
+	_, err = tx.Exec("DELETE FROM organization_invitations WHERE user_id=?", user.ID)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	messageResponse(w, "invitation accepted")
+}
+
+func organizationInvitationDeclineHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		招待の辞退
+		POST /api/organization/invitations/:invitation_id/decline
+	*/
+	user, errCode, errMsg := authenticate(r, scopeReserve)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	invitationID, err := strconv.ParseInt(pat.Param(r, "invitation_id"), 10, 64)
+	if err != nil || invitationID <= 0 {
+		errorResponse(w, http.StatusBadRequest, "incorrect invitation id")
+		return
+	}
+
+	result, err := dbx.Exec("DELETE FROM organization_invitations WHERE id=? AND user_id=?", invitationID, user.ID)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		errorResponse(w, http.StatusNotFound, "invitation not found")
+		return
+	}
+
+	messageResponse(w, "invitation declined")
+}
+
+func organizationMemberRemoveHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		メンバーの削除 (組織の管理者のみ。自分自身は管理者でなくても脱退できる)
+		POST /api/organization/members/:user_id/remove
+		管理者が1人もいなくなる削除はできない
+	*/
+	user, errCode, errMsg := authenticate(r, scopeReserve)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	memberID, err := strconv.ParseInt(pat.Param(r, "user_id"), 10, 64)
+	if err != nil || memberID <= 0 {
+		errorResponse(w, http.StatusBadRequest, "incorrect user id")
+		return
+	}
+
+	tx := dbx.MustBegin()
+
+	org, role, err := userOrganization(tx, user.ID)
+	if err == sql.ErrNoRows || (err == nil && role != orgRoleAdmin && memberID != user.ID) {
+		tx.Rollback()
+		errorResponse(w, http.StatusForbidden, "組織の管理者のみ操作できます")
+		return
+	}
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	members := []OrganizationMember{}
+	query := "SELECT m.user_id, u.email, m.role, m.created_at FROM organization_members m JOIN users u ON m.user_id = u.id WHERE m.organization_id=? FOR UPDATE"
+	err = tx.Select(&members, query, org.ID)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	found := false
+	admins := 0
+	for _, m := range members {
+		if m.Role == orgRoleAdmin && m.UserID != memberID {
+			admins++
+		}
+		if m.UserID == memberID {
+			found = true
+		}
+	}
+	if !found {
+		tx.Rollback()
+		errorResponse(w, http.StatusNotFound, "member not found")
+		return
+	}
+	if admins == 0 {
+		tx.Rollback()
+		errorResponse(w, http.StatusBadRequest, "組織には管理者が1人以上必要です")
+		return
+	}
+
+	_, err = tx.Exec("DELETE FROM organization_members WHERE organization_id=? AND user_id=?", org.ID, memberID)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	messageResponse(w, "member removed")
+}
+
+func organizationCardHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		法人カードの登録 (組織の管理者のみ)
+		POST /api/organization/card
+		card_token は payment-API で発行したもの。空文字なら登録を解除する
+	*/
+	user, errCode, errMsg := authenticate(r, scopeReserve)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	defer r.Body.Close()
+	buf, _ := ioutil.ReadAll(r.Body)
+
+	req := OrganizationCardRequest{}
+	err := json.Unmarshal(buf, &req)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, "JSON parseに失敗しました")
+		return
+	}
+	if len(req.CardToken) > 100 {
+		errorResponse(w, http.StatusBadRequest, "card_token が不正です")
+		return
+	}
+
+	org, role, err := userOrganization(dbx, user.ID)
+	if err == sql.ErrNoRows || (err == nil && role != orgRoleAdmin) {
+		errorResponse(w, http.StatusForbidden, "組織の管理者のみ操作できます")
+		return
+	}
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	var cardToken *string
+	if req.CardToken != "" {
+		cardToken = &req.CardToken
+	}
+	_, err = dbx.Exec("UPDATE organizations SET card_token=? WHERE id=?", cardToken, org.ID)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	messageResponse(w, "card updated")
+}
+
+func organizationReservationsHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		組織の予約一覧 (組織の管理者のみ)
+		GET /api/organization/reservations?month=2020/01
+		メンバーがメンバーのために手配した予約と、法人カードで支払った予約を返す。メンバーが自分で手配した個人の予約は含めない
+		month を省略した場合はすべての予約を返す。法人カードで支払った予約は、後から組織を抜けたメンバーの分も含める
+	*/
+	user, errCode, errMsg := authenticate(r, scopeSearch)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	org, role, err := userOrganization(dbx, user.ID)
+	if err == sql.ErrNoRows || (err == nil && role != orgRoleAdmin) {
+		errorResponse(w, http.StatusForbidden, "組織の管理者のみ参照できます")
+		return
+	}
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	query := "SELECT * FROM reservations WHERE ((user_id IN (SELECT user_id FROM organization_members WHERE organization_id=?) AND booked_by IN (SELECT user_id FROM organization_members WHERE organization_id=?)) OR billing_organization_id=?)"
+	args := []interface{}{org.ID, org.ID, org.ID}
+	if month := r.URL.Query().Get("month"); month != "" {
+		from, to, err := organizationStatementMonth(month)
+		if err != nil {
+			errorResponse(w, http.StatusBadRequest, "month は 2020/01 の形式で指定してください")
+			return
+		}
+		query += " AND date >= ? AND date < ?"
+		args = append(args, from.Format("2006/01/02"), to.Format("2006/01/02"))
+	}
+	query += " ORDER BY date, reservation_id"
+
+	reservationList := []Reservation{}
+	err = dbx.Select(&reservationList, query, args...)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	reservationResponseList, err := makeReservationResponses(reservationList)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		log.Println("makeReservationResponses()", err)
+		return
+	}
+
+	emails, err := organizationUserEmails(reservationList)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	resp := []OrganizationReservationResponse{}
+	for i, reservation := range reservationList {
+		item := OrganizationReservationResponse{
+			ReservationResponse: reservationResponseList[i],
+			UserID:              int64(*reservation.UserId),
+			UserEmail:           emails[int64(*reservation.UserId)],
+			Status:              reservation.Status,
+			BilledToCorpCard:    reservation.BillingOrgId != nil && *reservation.BillingOrgId == org.ID,
+		}
+		if reservation.BookedBy != nil {
+			email := emails[*reservation.BookedBy]
+			item.BookedByEmail = &email
+		}
+		resp = append(resp, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func organizationStatementHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		月次の請求明細 (組織の管理者のみ)
+		GET /api/organization/statement.csv?month=2020/01
+		法人カードで支払いが完了した予約を乗車日で集計する。最終行は合計金額
+	*/
+	user, errCode, errMsg := authenticate(r, scopeSearch)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	month := r.URL.Query().Get("month")
+	from, to, err := organizationStatementMonth(month)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, "month は 2020/01 の形式で指定してください")
+		return
+	}
+
+	org, role, err := userOrganization(dbx, user.ID)
+	if err == sql.ErrNoRows || (err == nil && role != orgRoleAdmin) {
+		errorResponse(w, http.StatusForbidden, "組織の管理者のみ参照できます")
+		return
+	}
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	reservationList := []Reservation{}
+	query := "SELECT * FROM reservations WHERE billing_organization_id=? AND status='done' AND date >= ? AND date < ? ORDER BY date, reservation_id"
+	err = dbx.Select(&reservationList, query, org.ID, from.Format("2006/01/02"), to.Format("2006/01/02"))
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	emails, err := organizationUserEmails(reservationList)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "db error")
+		log.Println(err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"statement-%s.csv\"", from.Format("200601")))
+	writeOrganizationStatement(w, reservationList, emails)
+}
+
+// writeOrganizationStatement は請求明細をCSVで書き出す
+func writeOrganizationStatement(w http.ResponseWriter, reservationList []Reservation, emails map[int64]string) {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"reservation_id", "date", "train_class", "train_name", "departure", "arrival", "user_email", "booked_by_email", "adult", "child", "amount"})
+
+	total := 0
+	for _, reservation := range reservationList {
+		bookedBy := ""
+		if reservation.BookedBy != nil {
+			bookedBy = emails[*reservation.BookedBy]
+		}
+		cw.Write([]string{
+			strconv.Itoa(reservation.ReservationId),
+			reservation.Date.Format("2006/01/02"),
+			reservation.TrainClass,
+			reservation.TrainName,
+			reservation.Departure,
+			reservation.Arrival,
+			emails[int64(*reservation.UserId)],
+			bookedBy,
+			strconv.Itoa(reservation.Adult),
+			strconv.Itoa(reservation.Child),
+			strconv.Itoa(reservation.Amount),
+		})
+		total += reservation.Amount
+	}
+	cw.Write([]string{"total", "", "", "", "", "", "", "", "", "", strconv.Itoa(total)})
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// organizationUserEmails は予約の乗車者と手配者のメールアドレスをまとめて引く
+func organizationUserEmails(reservationList []Reservation) (map[int64]string, error) {
+	emails := map[int64]string{}
+	ids := []int64{}
+	seen := map[int64]bool{}
+	for _, reservation := range reservationList {
+		for _, id := range []*int64{userIDPtr(reservation.UserId), reservation.BookedBy} {
+			if id != nil && !seen[*id] {
+				seen[*id] = true
+				ids = append(ids, *id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return emails, nil
+	}
+
+	query, args, err := sqlx.In("SELECT id, email FROM users WHERE id IN (?)", ids)
+	if err != nil {
+		return nil, err
+	}
+	users := []struct {
+		ID    int64  `db:"id"`
+		Email string `db:"email"`
+	}{}
+	err = dbx.Select(&users, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		emails[u.ID] = u.Email
+	}
+	return emails, nil
+}
+
+func userIDPtr(id *int) *int64 {
+	if id == nil {
+		return nil
+	}
+	v := int64(*id)
+	return &v
+}
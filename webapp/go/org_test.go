@@ -0,0 +1,303 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"goji.io"
+	"goji.io/pat"
+)
+
+func TestCanManageReservation(t *testing.T) {
+	owner := 1
+	booker := int64(2)
+	orgID := int64(10)
+	otherOrgID := int64(11)
+	reservation := Reservation{UserId: &owner, BookedBy: &booker}
+	selfBooked := Reservation{UserId: &owner}
+	billed := Reservation{UserId: &owner, BillingOrgId: &orgID}
+	billedToOther := Reservation{UserId: &owner, BillingOrgId: &otherOrgID}
+
+	member := reservationOrgAccess{OrganizationID: orgID, Role: orgRoleMember, RiderIsMember: true, BookerIsMember: true}
+	admin := reservationOrgAccess{OrganizationID: orgID, Role: orgRoleAdmin, RiderIsMember: true, BookerIsMember: true}
+	// 手配者が組織を抜けた
+	adminBookerLeft := reservationOrgAccess{OrganizationID: orgID, Role: orgRoleAdmin, RiderIsMember: true}
+	// 乗車者が組織を抜けた
+	memberRiderLeft := reservationOrgAccess{OrganizationID: orgID, Role: orgRoleMember, BookerIsMember: true}
+	adminRiderLeft := reservationOrgAccess{OrganizationID: orgID, Role: orgRoleAdmin, BookerIsMember: true}
+
+	tests := []struct {
+		userID      int64
+		reservation Reservation
+		access      reservationOrgAccess
+		want        bool
+	}{
+		// 本人
+		{1, reservation, reservationOrgAccess{}, true},
+		{1, selfBooked, member, true},
+		// 手配したメンバー
+		{2, reservation, member, true},
+		{2, reservation, admin, true},
+		// 組織を抜けた手配者
+		{2, reservation, reservationOrgAccess{}, false},
+		{2, reservation, memberRiderLeft, false},
+		// 手配していないメンバー
+		{3, reservation, member, false},
+		{3, selfBooked, member, false},
+		{3, billed, member, false},
+		// 組織の管理者は組織で手配した予約と法人カードで支払った予約だけ
+		{3, reservation, admin, true},
+		{3, billed, admin, true},
+		{3, billed, adminRiderLeft, true},
+		{3, selfBooked, admin, false},
+		{3, billedToOther, admin, false},
+		{3, reservation, adminBookerLeft, false},
+		{3, reservation, adminRiderLeft, false},
+		// 組織外
+		{3, selfBooked, reservationOrgAccess{}, false},
+		{3, Reservation{}, member, false},
+	}
+
+	for _, tt := range tests {
+		if got := canManageReservation(tt.userID, tt.reservation, tt.access); got != tt.want {
+			t.Fatalf("failed test %#v", tt)
+		}
+	}
+}
+
+// fakeOrganization は fakeDB に組織 10 とメンバーを登録する。admins 以外は一般メンバー
+func fakeOrganization(db *fakeDB, members []int64, admins ...int64) {
+	role := func(id int64) string {
+		for _, a := range admins {
+			if a == id {
+				return orgRoleAdmin
+			}
+		}
+		return orgRoleMember
+	}
+	isMember := func(id int64) bool {
+		for _, m := range members {
+			if m == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	db.on("SELECT * FROM `users` WHERE `id` = ?", func(args []driver.Value) fakeResult {
+		return fakeResult{
+			columns: []string{"id", "email"},
+			rows:    [][]driver.Value{{args[0], fmt.Sprintf("user%d@example.com", args[0])}},
+		}
+	})
+	db.on("SELECT o.*, m.role FROM organizations o JOIN organization_members m", func(args []driver.Value) fakeResult {
+		res := fakeResult{columns: []string{"id", "name", "card_token", "created_at", "role"}}
+		if id := args[0].(int64); isMember(id) {
+			res.rows = [][]driver.Value{{int64(10), "ISUCON株式会社", nil, time.Now(), role(id)}}
+		}
+		return res
+	})
+	db.on("SELECT COUNT(*) FROM organization_members WHERE organization_id=? AND user_id=?", func(args []driver.Value) fakeResult {
+		count := int64(0)
+		if args[0] == int64(10) && isMember(args[1].(int64)) {
+			count = 1
+		}
+		return fakeResult{columns: []string{"COUNT(*)"}, rows: [][]driver.Value{{count}}}
+	})
+}
+
+func TestOrganizationMemberInviteHandler(t *testing.T) {
+	db, restore := withFakeDB(t)
+	defer restore()
+	fakeOrganization(db, []int64{1}, 1)
+	db.rows("SELECT * FROM users WHERE email=?", []string{"id", "email"}, []driver.Value{int64(2), "user2@example.com"})
+	db.on("INSERT INTO organization_invitations", func([]driver.Value) fakeResult {
+		return fakeResult{rowsAffected: 1}
+	})
+
+	cookie, _ := newSessionCookie(t, int64(1), false)
+	r := httptest.NewRequest(http.MethodPost, "/api/organization/members", strings.NewReader(`{"email":"user2@example.com"}`))
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	organizationMemberInviteHandler(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed test %d %s", w.Code, w.Body.String())
+	}
+	// 招待するだけで、承諾されるまではメンバーにしない
+	if got := db.executed("INSERT INTO organization_members"); len(got) != 0 {
+		t.Fatalf("failed test %#v", got)
+	}
+	got := db.executed("INSERT INTO organization_invitations")
+	if len(got) != 1 || got[0][0] != int64(10) || got[0][1] != int64(2) || got[0][2] != orgRoleMember || got[0][3] != int64(1) {
+		t.Fatalf("failed test %#v", got)
+	}
+}
+
+func TestOrganizationInvitationAcceptHandler(t *testing.T) {
+	tests := []struct {
+		userID int64
+		want   int
+	}{
+		// 招待されたユーザー
+		{2, http.StatusOK},
+		// 他のユーザー宛ての招待は承諾できない
+		{3, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		db, restore := withFakeDB(t)
+		fakeOrganization(db, []int64{1}, 1)
+		// 招待 3 はユーザー 2 宛て
+		db.on("FROM organization_invitations WHERE id=? AND user_id=?", func(args []driver.Value) fakeResult {
+			res := fakeResult{columns: []string{"organization_id", "role"}}
+			if args[0] == int64(3) && args[1] == int64(2) {
+				res.rows = [][]driver.Value{{int64(10), orgRoleMember}}
+			}
+			return res
+		})
+		db.on("INSERT INTO organization_members", func([]driver.Value) fakeResult {
+			return fakeResult{rowsAffected: 1}
+		})
+		db.on("DELETE FROM organization_invitations WHERE user_id=?", func([]driver.Value) fakeResult {
+			return fakeResult{rowsAffected: 1}
+		})
+
+		mux := goji.NewMux()
+		mux.HandleFunc(pat.Post("/api/organization/invitations/:invitation_id/accept"), organizationInvitationAcceptHandler)
+		cookie, _ := newSessionCookie(t, tt.userID, false)
+		r := httptest.NewRequest(http.MethodPost, "/api/organization/invitations/3/accept", nil)
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if w.Code != tt.want {
+			t.Fatalf("failed test %#v: got=%d %s", tt, w.Code, w.Body.String())
+		}
+		inserted := db.executed("INSERT INTO organization_members")
+		if tt.want != http.StatusOK && len(inserted) != 0 {
+			t.Fatalf("failed test %#v: inserted %#v", tt, inserted)
+		}
+		if tt.want == http.StatusOK && (len(inserted) != 1 || inserted[0][0] != int64(10) || inserted[0][1] != tt.userID || inserted[0][2] != orgRoleMember) {
+			t.Fatalf("failed test %#v: inserted %#v", tt, inserted)
+		}
+		restore()
+	}
+}
+
+func TestReservationAccessible(t *testing.T) {
+	db, restore := withFakeDB(t)
+	defer restore()
+	// 1 が管理者、2 と 3 が一般メンバー、9 は組織外
+	fakeOrganization(db, []int64{1, 2, 3}, 1)
+
+	rider := 2
+	outsider := 9
+	member := int64(3)
+	nonMember := int64(9)
+	orgID := int64(10)
+	otherOrgID := int64(11)
+
+	tests := []struct {
+		userID      int64
+		reservation Reservation
+		want        bool
+	}{
+		// メンバーが代理で手配した予約
+		{1, Reservation{UserId: &rider, BookedBy: &member}, true},
+		{3, Reservation{UserId: &rider, BookedBy: &member}, true},
+		// 法人カードで支払った予約は、乗車者が組織を抜けていても管理者は見える
+		{1, Reservation{UserId: &outsider, BillingOrgId: &orgID}, true},
+		// メンバーが自分で手配した個人の予約
+		{1, Reservation{UserId: &rider}, false},
+		{1, Reservation{UserId: &rider, BillingOrgId: &otherOrgID}, false},
+		// 組織外のユーザーが手配した予約
+		{1, Reservation{UserId: &rider, BookedBy: &nonMember}, false},
+		// 組織外のユーザー
+		{9, Reservation{UserId: &rider, BookedBy: &member}, false},
+	}
+
+	for _, tt := range tests {
+		got, err := reservationAccessible(dbx, User{ID: tt.userID}, tt.reservation)
+		if err != nil || got != tt.want {
+			t.Fatalf("failed test %#v: got=%v err=%v", tt, got, err)
+		}
+	}
+}
+
+func TestUserReservationResponseHandlerHidesPersonalTrip(t *testing.T) {
+	db, restore := withFakeDB(t)
+	defer restore()
+	fakeOrganization(db, []int64{1, 2}, 1)
+	// メンバー 2 が自分で手配した予約
+	db.rows("SELECT * FROM reservations WHERE reservation_id=?",
+		[]string{"reservation_id", "user_id", "status", "booked_by", "billing_organization_id"},
+		[]driver.Value{int64(5), int64(2), "done", nil, nil},
+	)
+
+	mux := goji.NewMux()
+	mux.HandleFunc(pat.Get("/api/user/reservations/:item_id"), userReservationResponseHandler)
+	cookie, _ := newSessionCookie(t, int64(1), false)
+	r := httptest.NewRequest(http.MethodGet, "/api/user/reservations/5", nil)
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	// 管理者にも存在しないものとして扱う
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("failed test %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOrganizationReservationsHandler(t *testing.T) {
+	db, restore := withFakeDB(t)
+	defer restore()
+	fakeOrganization(db, []int64{1, 2}, 1)
+
+	var query string
+	var args []driver.Value
+	db.on("SELECT * FROM reservations WHERE", func(a []driver.Value) fakeResult {
+		query = db.lastQuery()
+		args = a
+		return fakeResult{columns: []string{"reservation_id"}}
+	})
+
+	cookie, _ := newSessionCookie(t, int64(1), false)
+	r := httptest.NewRequest(http.MethodGet, "/api/organization/reservations", nil)
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	organizationReservationsHandler(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed test %d %s", w.Code, w.Body.String())
+	}
+	// メンバーが自分で手配した個人の予約 (booked_by が NULL) は含めない
+	want := "((user_id IN (SELECT user_id FROM organization_members WHERE organization_id=?) AND booked_by IN (SELECT user_id FROM organization_members WHERE organization_id=?)) OR billing_organization_id=?)"
+	if !strings.Contains(query, want) {
+		t.Fatalf("failed test %s", query)
+	}
+	if len(args) != 3 || args[0] != int64(10) || args[1] != int64(10) || args[2] != int64(10) {
+		t.Fatalf("failed test %#v", args)
+	}
+}
+
+func TestOrganizationStatementMonth(t *testing.T) {
+	from, to, err := organizationStatementMonth("2020/12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from.Format("2006/01/02") != "2020/12/01" || to.Format("2006/01/02") != "2021/01/01" {
+		t.Fatalf("failed test %#v %#v", from, to)
+	}
+
+	for _, s := range []string{"", "2020-12", "2020/13", "2020/12/01"} {
+		if _, _, err := organizationStatementMonth(s); err == nil {
+			t.Fatalf("failed test %#v", s)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// パスワードのハッシュ
+//
+//   - users.super_secure_password に PHC 文字列 ($argon2id$... / $2b$...) で保存する
+//     ソルトも PHC 文字列に含まれるので users.salt は空にする
+//   - '$' で始まらないものは旧形式 (users.salt を使った pbkdf2-sha256 の生バイト列)
+//   - 旧形式や、設定と異なるパラメータのハッシュはログイン成功時に作り直す
+const (
+	passwordAlgorithmArgon2id = "argon2id"
+	passwordAlgorithmBcrypt   = "bcrypt"
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var errMalformedPasswordHash = errors.New("malformed password hash")
+
+type passwordHashConfig struct {
+	Algorithm     string
+	Argon2Memory  uint32 // KiB
+	Argon2Time    uint32
+	Argon2Threads uint8
+	BcryptCost    int
+}
+
+// 本番向けのデフォルト (OWASP の推奨値)。ベンチマーク時は環境変数で軽くする
+var defaultPasswordHashConfig = passwordHashConfig{
+	Algorithm:     passwordAlgorithmArgon2id,
+	Argon2Memory:  19 * 1024,
+	Argon2Time:    2,
+	Argon2Threads: 1,
+	BcryptCost:    bcrypt.DefaultCost,
+}
+
+var passwordConfig = newPasswordHashConfigFromEnv()
+
+func newPasswordHashConfigFromEnv() passwordHashConfig {
+	config := defaultPasswordHashConfig
+
+	uintEnv := func(key string, bitSize int, min uint64) uint64 {
+		v, err := strconv.ParseUint(os.Getenv(key), 10, bitSize)
+		if err != nil || v < min {
+			log.Fatalf("invalid %s: %s.", key, os.Getenv(key))
+		}
+		return v
+	}
+
+	if s := os.Getenv("PASSWORD_HASH_ALGORITHM"); s != "" {
+		if s != passwordAlgorithmArgon2id && s != passwordAlgorithmBcrypt {
+			log.Fatalf("invalid PASSWORD_HASH_ALGORITHM: %s.", s)
+		}
+		config.Algorithm = s
+	}
+	if os.Getenv("PASSWORD_HASH_ARGON2_MEMORY") != "" {
+		config.Argon2Memory = uint32(uintEnv("PASSWORD_HASH_ARGON2_MEMORY", 32, 8))
+	}
+	if os.Getenv("PASSWORD_HASH_ARGON2_TIME") != "" {
+		config.Argon2Time = uint32(uintEnv("PASSWORD_HASH_ARGON2_TIME", 32, 1))
+	}
+	if os.Getenv("PASSWORD_HASH_ARGON2_THREADS") != "" {
+		config.Argon2Threads = uint8(uintEnv("PASSWORD_HASH_ARGON2_THREADS", 8, 1))
+	}
+	if os.Getenv("PASSWORD_HASH_BCRYPT_COST") != "" {
+		config.BcryptCost = int(uintEnv("PASSWORD_HASH_BCRYPT_COST", 8, uint64(bcrypt.MinCost)))
+		if config.BcryptCost > bcrypt.MaxCost {
+			log.Fatalf("invalid PASSWORD_HASH_BCRYPT_COST: %d.", config.BcryptCost)
+		}
+	}
+
+	return config
+}
+
+// hashPassword はパスワードを設定に従ってハッシュし、PHC 文字列を返す
+func hashPassword(password string) ([]byte, error) {
+	return passwordConfig.hash(password)
+}
+
+func (c passwordHashConfig) hash(password string) ([]byte, error) {
+	switch c.Algorithm {
+	case passwordAlgorithmBcrypt:
+		return bcrypt.GenerateFromPassword([]byte(password), c.BcryptCost)
+	default:
+		salt := make([]byte, argon2SaltLength)
+		if _, err := crand.Read(salt); err != nil {
+			return nil, err
+		}
+		key := argon2.IDKey([]byte(password), salt, c.Argon2Time, c.Argon2Memory, c.Argon2Threads, argon2KeyLength)
+		phc := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, c.Argon2Memory, c.Argon2Time, c.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key),
+		)
+		return []byte(phc), nil
+	}
+}
+
+// verifyPassword はパスワードが一致するかと、ハッシュを作り直すべきかを返す
+func verifyPassword(user User, password string) (ok bool, needsRehash bool, err error) {
+	return passwordConfig.verify(user.Salt, user.HashedPassword, password)
+}
+
+func (c passwordHashConfig) verify(salt, hashed []byte, password string) (bool, bool, error) {
+	switch {
+	case bytes.HasPrefix(hashed, []byte("$argon2id$")):
+		return c.verifyArgon2id(string(hashed), password)
+	case bytes.HasPrefix(hashed, []byte("$2a$")), bytes.HasPrefix(hashed, []byte("$2b$")), bytes.HasPrefix(hashed, []byte("$2y$")):
+		err := bcrypt.CompareHashAndPassword(hashed, []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+		cost, err := bcrypt.Cost(hashed)
+		if err != nil {
+			return false, false, err
+		}
+		return true, c.Algorithm != passwordAlgorithmBcrypt || cost != c.BcryptCost, nil
+	case bytes.HasPrefix(hashed, []byte("$")):
+		return false, false, errMalformedPasswordHash
+	default:
+		// 旧形式
+		challenge := pbkdf2.Key([]byte(password), salt, 100, 256, sha256.New)
+		return subtle.ConstantTimeCompare(hashed, challenge) == 1, true, nil
+	}
+}
+
+// rehashPassword はログインに成功したユーザーのハッシュを今の設定で作り直す
+func rehashPassword(user User, password string) {
+	hashed, err := hashPassword(password)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+	// 同時にパスワードが再設定されていたら上書きしない
+	_, err = dbx.Exec(
+		"UPDATE users SET salt='', super_secure_password=? WHERE id=? AND super_secure_password=?",
+		hashed, user.ID, user.HashedPassword,
+	)
+	if err != nil {
+		log.Println(err.Error())
+	}
+}
+
+func (c passwordHashConfig) verifyArgon2id(phc, password string) (bool, bool, error) {
+	// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+	parts := strings.Split(phc, "$")
+	if len(parts) != 6 {
+		return false, false, errMalformedPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, errMalformedPasswordHash
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, false, errMalformedPasswordHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, errMalformedPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false, errMalformedPasswordHash
+	}
+
+	challenge := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, challenge) != 1 {
+		return false, false, nil
+	}
+
+	needsRehash := c.Algorithm != passwordAlgorithmArgon2id ||
+		memory != c.Argon2Memory || time != c.Argon2Time || threads != c.Argon2Threads ||
+		len(salt) != argon2SaltLength || len(key) != argon2KeyLength
+	return true, needsRehash, nil
+}
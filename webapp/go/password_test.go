@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// テストでは軽いパラメータを使う
+var testPasswordHashConfig = passwordHashConfig{
+	Algorithm:     passwordAlgorithmArgon2id,
+	Argon2Memory:  64,
+	Argon2Time:    1,
+	Argon2Threads: 1,
+	BcryptCost:    bcrypt.MinCost,
+}
+
+func TestPasswordHashArgon2id(t *testing.T) {
+	c := testPasswordHashConfig
+
+	hashed, err := c.hash("password")
+	if err != nil {
+		t.Fatalf("failed test %#v", err)
+	}
+	if !strings.HasPrefix(string(hashed), "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("failed test %s", hashed)
+	}
+
+	ok, needsRehash, err := c.verify([]byte{}, hashed, "password")
+	if !ok || needsRehash || err != nil {
+		t.Fatalf("failed test %#v %#v %#v", ok, needsRehash, err)
+	}
+	ok, _, err = c.verify([]byte{}, hashed, "wrong password")
+	if ok || err != nil {
+		t.Fatalf("failed test %#v %#v", ok, err)
+	}
+
+	// 同じパスワードでもソルトが違うので別のハッシュになる
+	hashed2, _ := c.hash("password")
+	if string(hashed) == string(hashed2) {
+		t.Fatalf("failed test %s", hashed2)
+	}
+
+	// パラメータを変えたら作り直す
+	c.Argon2Time = 2
+	ok, needsRehash, err = c.verify([]byte{}, hashed, "password")
+	if !ok || !needsRehash || err != nil {
+		t.Fatalf("failed test %#v %#v %#v", ok, needsRehash, err)
+	}
+}
+
+func TestPasswordHashBcrypt(t *testing.T) {
+	c := testPasswordHashConfig
+	c.Algorithm = passwordAlgorithmBcrypt
+
+	hashed, err := c.hash("password")
+	if err != nil {
+		t.Fatalf("failed test %#v", err)
+	}
+	ok, needsRehash, err := c.verify([]byte{}, hashed, "password")
+	if !ok || needsRehash || err != nil {
+		t.Fatalf("failed test %#v %#v %#v", ok, needsRehash, err)
+	}
+	ok, _, err = c.verify([]byte{}, hashed, "wrong password")
+	if ok || err != nil {
+		t.Fatalf("failed test %#v %#v", ok, err)
+	}
+
+	// argon2id に切り替えたら作り直す
+	c.Algorithm = passwordAlgorithmArgon2id
+	ok, needsRehash, err = c.verify([]byte{}, hashed, "password")
+	if !ok || !needsRehash || err != nil {
+		t.Fatalf("failed test %#v %#v %#v", ok, needsRehash, err)
+	}
+}
+
+func TestPasswordHashLegacy(t *testing.T) {
+	c := testPasswordHashConfig
+
+	// signUpHandler が以前保存していた形式
+	salt := []byte(strings.Repeat("s", 1024))
+	hashed := pbkdf2.Key([]byte("password"), salt, 100, 256, sha256.New)
+
+	ok, needsRehash, err := c.verify(salt, hashed, "password")
+	if !ok || !needsRehash || err != nil {
+		t.Fatalf("failed test %#v %#v %#v", ok, needsRehash, err)
+	}
+	ok, _, err = c.verify(salt, hashed, "wrong password")
+	if ok || err != nil {
+		t.Fatalf("failed test %#v %#v", ok, err)
+	}
+}
+
+func TestPasswordHashMalformed(t *testing.T) {
+	c := testPasswordHashConfig
+
+	for _, hashed := range []string{
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=18$m=64,t=1,p=1$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=64$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=64,t=1,p=1$!!!$aGFzaA",
+		"$scrypt$ln=16,r=8,p=1$c2FsdA$aGFzaA",
+	} {
+		ok, _, err := c.verify([]byte{}, []byte(hashed), "password")
+		if ok || err != errMalformedPasswordHash {
+			t.Fatalf("failed test %s %#v %#v", hashed, ok, err)
+		}
+	}
+}
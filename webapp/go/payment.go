@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+// 二段階決済 (payment-API の与信確保・売上確定・与信取消)
+//
+//   - 仮予約 (POST /api/train/reserve) で card_token が渡されたら与信を確保し、
+//     reservations.payment_id に決済IDを、payment_status に authorized を記録する
+//   - 支払い (POST /api/train/reservation/commit) では確保した与信で売上を確定する
+//   - 仮予約のキャンセル時は与信を取り消す。取り消せなくても与信は有効期限で自動的に取り消される
+//   - 与信の確保は座席を確保したトランザクションをコミットしてから行う (列車の予約のロックを持ったまま待たない)
+const (
+	paymentStatusAuthorized = "authorized"
+	paymentStatusCaptured   = "captured"
+)
+
+// payment-API の決済の状態 (GET /payment/:payment_id の state)
+const (
+	paymentStateCaptured = "CAPTURED"
+	paymentStateVoided   = "VOIDED"
+)
+
+// 運賃は円なので、payment-API には通貨 JPY (最小単位は円) で渡す
+const paymentCurrency = "JPY"
+
+type AuthorizePaymentResponse struct {
+	PaymentId string    `json:"payment_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	IsOk      bool      `json:"is_ok"`
+}
+
+type GetPaymentResponse struct {
+	PaymentInformation struct {
+		State      string `json:"state"`
+		IsCanceled bool   `json:"is_canceled"`
+	} `json:"payment_information"`
+	IsOk bool `json:"is_ok"`
+}
+
+func paymentAPI() string {
+	payment_api := os.Getenv("PAYMENT_API")
+	if payment_api == "" {
+		payment_api = "http://payment:5000"
+	}
+	return payment_api
+}
+
+// setPaymentMerchantKey は payment-API に加盟店のAPIキー(PAYMENT_MERCHANT_KEY)を渡す
+// 未設定ならヘッダを付けない(payment-API のデフォルトの加盟店になる)
+func setPaymentMerchantKey(req *http.Request) {
+	if key := os.Getenv("PAYMENT_MERCHANT_KEY"); key != "" {
+		req.Header.Set("X-Merchant-Key", key)
+	}
+}
+
+func postPaymentAPI(path string, body interface{}, out interface{}) error {
+	return requestPaymentAPI("POST", path, body, out)
+}
+
+func requestPaymentAPI(method, path string, body interface{}, out interface{}) error {
+	j, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: time.Duration(10) * time.Second}
+	req, err := http.NewRequest(method, paymentAPI()+path, bytes.NewBuffer(j))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setPaymentMerchantKey(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("payment-API %s: status %d: %s", path, resp.StatusCode, string(b))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(b, out)
+}
+
+// authorizePayment は予約の運賃分の与信を確保し、決済IDを返す
+func authorizePayment(cardToken string, reservationID int64, amount int) (AuthorizePaymentResponse, error) {
+	output := AuthorizePaymentResponse{}
+	payInfo := PaymentInformationRequest{cardToken, int(reservationID), amount, paymentCurrency}
+	err := postPaymentAPI("/authorize", PaymentInformation{PayInfo: payInfo}, &output)
+	return output, err
+}
+
+// capturePayment は確保した与信で売上を確定する
+func capturePayment(paymentID string) error {
+	return postPaymentAPI("/payment/"+paymentID+"/capture", struct{}{}, nil)
+}
+
+// voidAuthorization は確保した与信を取り消す
+func voidAuthorization(paymentID string) error {
+	return postPaymentAPI("/payment/"+paymentID+"/void", struct{}{}, nil)
+}
+
+// cancelPayment は決済をキャンセルする。売上確定済みでも与信のままでも使える
+func cancelPayment(paymentID string) error {
+	return requestPaymentAPI("DELETE", "/payment/"+paymentID, CancelPaymentInformationRequest{paymentID}, nil)
+}
+
+// getPayment は決済の状態を取得する
+func getPayment(paymentID string) (GetPaymentResponse, error) {
+	output := GetPaymentResponse{}
+	err := requestPaymentAPI("GET", "/payment/"+paymentID, struct{}{}, &output)
+	return output, err
+}
+
+// paymentRechargeable は売上確定に失敗した与信を、別の決済で払い直してよいかを返す
+// タイムアウトなどで失敗しても売上が確定していることがあるので、与信が取り消された (期限切れを含む) か
+// キャンセルされていて、もう売上が確定しないことが分かった場合だけ払い直す
+func paymentRechargeable(payment GetPaymentResponse) bool {
+	return payment.PaymentInformation.State == paymentStateVoided || payment.PaymentInformation.IsCanceled
+}
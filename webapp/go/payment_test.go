@@ -0,0 +1,148 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// テスト用の payment-API。売上確定はいつも失敗させ、決済の状態は state で返す
+type fakePaymentAPI struct {
+	mu       sync.Mutex
+	state    string
+	getFails bool
+	calls    []string
+}
+
+func (p *fakePaymentAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, r.Method+" "+r.URL.Path)
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/capture"):
+		// タイムアウトなど、売上が確定したかどうか分からない失敗
+		w.WriteHeader(http.StatusGatewayTimeout)
+	case r.Method == http.MethodGet && r.URL.Path == "/payment/pay_1":
+		if p.getFails {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"payment_information":{"state":%q,"is_canceled":false},"is_ok":true}`, p.state)
+	case r.Method == http.MethodPost && r.URL.Path == "/payment":
+		fmt.Fprint(w, `{"payment_id":"pay_2","is_ok":true}`)
+	case r.Method == http.MethodDelete && r.URL.Path == "/payment/pay_1":
+		fmt.Fprint(w, `{"is_ok":true}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (p *fakePaymentAPI) called(call string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for _, c := range p.calls {
+		if c == call {
+			n++
+		}
+	}
+	return n
+}
+
+func TestReservationPaymentCaptureFailure(t *testing.T) {
+	cookie, _ := newSessionCookie(t, int64(1), false)
+
+	tests := []struct {
+		state       string
+		getFails    bool
+		want        int
+		wantPayment string
+		charged     int
+		canceled    int
+	}{
+		// 失敗しても売上が確定していれば、そのまま予約を確定する
+		{paymentStateCaptured, false, http.StatusOK, "pay_1", 0, 0},
+		// 与信が取り消されていれば払い直し、元の決済はキャンセルする
+		{paymentStateVoided, false, http.StatusOK, "pay_2", 1, 1},
+		// 与信が残っていたり状態が分からなければ、二重に決済しない
+		{"AUTHORIZED", false, http.StatusBadGateway, "", 0, 0},
+		{"", true, http.StatusBadGateway, "", 0, 0},
+	}
+
+	for _, tt := range tests {
+		api := &fakePaymentAPI{state: tt.state, getFails: tt.getFails}
+		ts := httptest.NewServer(api)
+		origAPI := os.Getenv("PAYMENT_API")
+		os.Setenv("PAYMENT_API", ts.URL)
+
+		db, restore := withFakeDB(t)
+		db.rows("SELECT * FROM reservations WHERE reservation_id=?",
+			[]string{"reservation_id", "user_id", "status", "payment_id", "payment_status", "amount"},
+			[]driver.Value{int64(1), int64(1), "requesting", "pay_1", paymentStatusAuthorized, int64(3000)},
+		)
+		db.rows("SELECT * FROM `users` WHERE `id` = ?", []string{"id", "email"}, []driver.Value{int64(1), "isutrain@example.com"})
+		db.on("UPDATE reservations SET status=?", func([]driver.Value) fakeResult {
+			return fakeResult{rowsAffected: 1}
+		})
+		db.rows("SELECT * FROM seat_reservations WHERE reservation_id=?", []string{"reservation_id", "car_number", "seat_row", "seat_column"})
+
+		body := `{"reservation_id":1,"card_token":"card_1"}`
+		r := httptest.NewRequest(http.MethodPost, "/api/train/reservation/commit", strings.NewReader(body))
+		r.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		reservationPaymentHandler(w, r)
+
+		if w.Code != tt.want {
+			t.Fatalf("failed test %#v: got=%d %s", tt, w.Code, w.Body.String())
+		}
+		updates := db.executed("UPDATE reservations SET status=?")
+		if tt.wantPayment == "" && len(updates) != 0 {
+			t.Fatalf("failed test %#v: updated %#v", tt, updates)
+		}
+		if tt.wantPayment != "" && (len(updates) != 1 || updates[0][1] != tt.wantPayment) {
+			t.Fatalf("failed test %#v: updated %#v", tt, updates)
+		}
+		if got := api.called("POST /payment"); got != tt.charged {
+			t.Fatalf("failed test %#v: charged %d times", tt, got)
+		}
+		if got := api.called("DELETE /payment/pay_1"); got != tt.canceled {
+			t.Fatalf("failed test %#v: canceled %d times", tt, got)
+		}
+		if got := api.called("POST /payment/pay_1/void"); got != 0 {
+			t.Fatalf("failed test %#v: voided %d times", tt, got)
+		}
+
+		restore()
+		os.Setenv("PAYMENT_API", origAPI)
+		ts.Close()
+	}
+}
+
+func TestPaymentRechargeable(t *testing.T) {
+	tests := []struct {
+		state      string
+		isCanceled bool
+		want       bool
+	}{
+		{paymentStateVoided, false, true},
+		{paymentStateCaptured, true, true},
+		{paymentStateCaptured, false, false},
+		{"AUTHORIZED", false, false},
+	}
+	for _, tt := range tests {
+		payment := GetPaymentResponse{}
+		payment.PaymentInformation.State = tt.state
+		payment.PaymentInformation.IsCanceled = tt.isCanceled
+		if got := paymentRechargeable(payment); got != tt.want {
+			b, _ := json.Marshal(payment)
+			t.Fatalf("failed test %s", b)
+		}
+	}
+}
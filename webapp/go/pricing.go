@@ -0,0 +1,315 @@
+package main
+
+import (
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 動的運賃
+//
+//   - DYNAMIC_PRICING=1 のときだけ有効。無効なら fare_master の運賃のまま
+//   - 列車・区間の乗車率 (その日のその列車の予約済み座席の割合) と発車までの時間で倍率を決め、下限・上限で丸める
+//   - 見積もり (GET /api/fare/quote) で署名付きの見積もりトークンを返し、
+//     予約時にトークンを渡すと見積もった運賃で予約できる (有効期限内のみ)
+//   - 予約には調整に使った乗車率・発車までの時間・倍率を記録する
+const (
+	quoteTokenVersion = "ISUQUOTE1"
+	quoteTokenTTL     = 10 * time.Minute
+)
+
+var (
+	errInvalidQuoteToken  = errors.New("見積もりトークンが不正です")
+	errExpiredQuoteToken  = errors.New("見積もりの有効期限が切れています。再度見積もりを行ってください")
+	errMismatchQuoteToken = errors.New("見積もりと予約の内容が一致しません")
+)
+
+// pricingStep は閾値以上のときに適用する倍率
+type pricingStep struct {
+	Threshold  float64
+	Multiplier float64
+}
+
+// 乗車率 (0〜1) ごとの倍率。閾値の昇順
+var loadFactorSteps = []pricingStep{
+	{0.0, 0.9},
+	{0.5, 1.0},
+	{0.7, 1.1},
+	{0.85, 1.25},
+	{0.95, 1.4},
+}
+
+// 発車までの時間 (時間) ごとの倍率。閾値の昇順
+var leadTimeSteps = []pricingStep{
+	{0, 1.2},
+	{24, 1.1},
+	{24 * 7, 1.0},
+	{24 * 30, 0.9},
+}
+
+type dynamicPricingConfig struct {
+	Enabled bool
+	Floor   float64
+	Cap     float64
+	Key     []byte
+}
+
+var pricingConfig = newDynamicPricingConfigFromEnv()
+
+func newDynamicPricingConfigFromEnv() dynamicPricingConfig {
+	config := dynamicPricingConfig{
+		Enabled: os.Getenv("DYNAMIC_PRICING") == "1",
+		Floor:   0.8,
+		Cap:     1.5,
+	}
+
+	floatEnv := func(key string, value *float64) {
+		s := os.Getenv(key)
+		if s == "" {
+			return
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v <= 0 {
+			log.Fatalf("invalid %s: %s.", key, s)
+		}
+		*value = v
+	}
+	floatEnv("DYNAMIC_PRICING_FLOOR", &config.Floor)
+	floatEnv("DYNAMIC_PRICING_CAP", &config.Cap)
+	if config.Floor > config.Cap {
+		log.Fatalf("invalid DYNAMIC_PRICING_FLOOR/DYNAMIC_PRICING_CAP: %v > %v.", config.Floor, config.Cap)
+	}
+
+	// 未指定の場合は起動ごとに鍵を生成するため、再起動前の見積もりトークンは使えなくなる
+	if s := os.Getenv("DYNAMIC_PRICING_QUOTE_KEY"); s != "" {
+		config.Key = []byte(s)
+	} else {
+		config.Key = make([]byte, 32)
+		if _, err := crand.Read(config.Key); err != nil {
+			log.Fatalf("failed to generate quote key: %s.", err.Error())
+		}
+	}
+
+	return config
+}
+
+// PriceAdjustment は動的運賃の調整内容
+type PriceAdjustment struct {
+	LoadFactor         float64 `json:"load_factor"`
+	HoursToDeparture   float64 `json:"hours_to_departure"`
+	LoadMultiplier     float64 `json:"load_multiplier"`
+	LeadTimeMultiplier float64 `json:"lead_time_multiplier"`
+	Multiplier         float64 `json:"multiplier"`
+}
+
+// 見積もりトークンに埋め込む内容
+type FareQuoteClaims struct {
+	Date         string          `json:"date"`
+	TrainClass   string          `json:"train_class"`
+	TrainName    string          `json:"train_name"`
+	SeatClass    string          `json:"seat_class"`
+	Departure    string          `json:"departure"`
+	Arrival      string          `json:"arrival"`
+	BaseUnitFare int             `json:"base_unit_fare"`
+	UnitFare     int             `json:"unit_fare"`
+	Adjustment   PriceAdjustment `json:"adjustment"`
+	QuotedAt     int64           `json:"quoted_at"`
+	ExpiresAt    int64           `json:"expires_at"`
+}
+
+func stepMultiplier(steps []pricingStep, v float64) float64 {
+	multiplier := steps[0].Multiplier
+	for _, step := range steps {
+		if v >= step.Threshold {
+			multiplier = step.Multiplier
+		}
+	}
+	return multiplier
+}
+
+// adjust は乗車率と発車までの時間から倍率を求める
+// 自由席は座席数が決まっていないので、乗車率は使わない
+func (c dynamicPricingConfig) adjust(seatClass string, loadFactor float64, untilDeparture time.Duration) PriceAdjustment {
+	adj := PriceAdjustment{
+		LoadFactor:       loadFactor,
+		HoursToDeparture: untilDeparture.Hours(),
+		LoadMultiplier:   1.0,
+	}
+	if seatClass != "non-reserved" {
+		adj.LoadMultiplier = stepMultiplier(loadFactorSteps, loadFactor)
+	}
+	adj.LeadTimeMultiplier = stepMultiplier(leadTimeSteps, adj.HoursToDeparture)
+
+	adj.Multiplier = adj.LoadMultiplier * adj.LeadTimeMultiplier
+	if adj.Multiplier < c.Floor {
+		adj.Multiplier = c.Floor
+	}
+	if adj.Multiplier > c.Cap {
+		adj.Multiplier = c.Cap
+	}
+	return adj
+}
+
+func (adj PriceAdjustment) apply(unitFare int) int {
+	// 倍率の浮動小数点誤差で1円ずれないように四捨五入する
+	return int(math.Round(float64(unitFare) * adj.Multiplier))
+}
+
+// trainLoadFactor は列車・区間・座席クラスの乗車率を返す
+func trainLoadFactor(train Train, fromStation, toStation Station, seatClass string) (float64, error) {
+	if seatClass == "non-reserved" {
+		return 0, nil
+	}
+
+	var total int
+	err := dbx.Get(&total, "SELECT COUNT(*) FROM seat_master WHERE train_class=? AND seat_class=?", train.TrainClass, seatClass)
+	if err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 0, nil
+	}
+
+	// 区間が重なる、同じ日・同じ列車の予約済みの座席を数える
+	query := `
+	SELECT COUNT(*)
+	FROM seat_reservations sr, reservations r, seat_master s, station_master std, station_master sta
+	WHERE
+		r.date=? AND
+		r.train_class=? AND
+		r.train_name=? AND
+		r.reservation_id=sr.reservation_id AND
+		r.status<>'no_show' AND
+		s.train_class=r.train_class AND
+		s.car_number=sr.car_number AND
+		s.seat_column=sr.seat_column AND
+		s.seat_row=sr.seat_row AND
+		s.seat_class=? AND
+		std.name=r.departure AND
+		sta.name=r.arrival
+	` + sectionOverlapCondition(train.IsNobori)
+
+	var reserved int
+	err = dbx.Get(
+		&reserved, query,
+		train.Date.Format("2006/01/02"), train.TrainClass, train.TrainName, seatClass,
+		fromStation.ID, fromStation.ID, toStation.ID, toStation.ID, fromStation.ID, toStation.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return float64(reserved) / float64(total), nil
+}
+
+// priceAdjustmentFor は現在の乗車率で調整内容を求める
+func (c dynamicPricingConfig) priceAdjustmentFor(train Train, fromStation, toStation Station, seatClass string, departureAt, now time.Time) (PriceAdjustment, error) {
+	loadFactor, err := trainLoadFactor(train, fromStation, toStation, seatClass)
+	if err != nil {
+		return PriceAdjustment{}, err
+	}
+	return c.adjust(seatClass, loadFactor, departureAt.Sub(now)), nil
+}
+
+func (c dynamicPricingConfig) signQuote(claims FareQuoteClaims) (string, error) {
+	// ISUQUOTE1.<base64url(JSON)>.<base64url(HMAC-SHA256)>
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := quoteTokenVersion + "." + base64.RawURLEncoding.EncodeToString(b)
+	mac := hmac.New(sha256.New, c.Key)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (c dynamicPricingConfig) verifyQuote(token string, now time.Time) (FareQuoteClaims, error) {
+	claims := FareQuoteClaims{}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != quoteTokenVersion {
+		return claims, errInvalidQuoteToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, errInvalidQuoteToken
+	}
+	mac := hmac.New(sha256.New, c.Key)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return claims, errInvalidQuoteToken
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims, errInvalidQuoteToken
+	}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return claims, errInvalidQuoteToken
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return claims, errExpiredQuoteToken
+	}
+	return claims, nil
+}
+
+// matches は見積もりが予約の内容と一致するかを返す
+// 季節運賃 (fare_master) が見積もり後に変わった場合も一致しないものとする
+func (claims FareQuoteClaims) matches(req TrainReservationRequest, date time.Time, baseUnitFare int) bool {
+	return claims.Date == date.Format("2006/01/02") &&
+		claims.TrainClass == req.TrainClass &&
+		claims.TrainName == req.TrainName &&
+		claims.SeatClass == req.SeatClass &&
+		claims.Departure == req.Departure &&
+		claims.Arrival == req.Arrival &&
+		claims.BaseUnitFare == baseUnitFare
+}
+
+func newFareQuoteClaims(date time.Time, trainClass, trainName, seatClass, departure, arrival string, baseUnitFare int, adj PriceAdjustment, now time.Time) FareQuoteClaims {
+	return FareQuoteClaims{
+		Date:         date.Format("2006/01/02"),
+		TrainClass:   trainClass,
+		TrainName:    trainName,
+		SeatClass:    seatClass,
+		Departure:    departure,
+		Arrival:      arrival,
+		BaseUnitFare: baseUnitFare,
+		UnitFare:     adj.apply(baseUnitFare),
+		Adjustment:   adj,
+		QuotedAt:     now.Unix(),
+		ExpiresAt:    now.Add(quoteTokenTTL).Unix(),
+	}
+}
+
+// reservationQuote は予約に使う運賃を決める
+// 見積もりトークンがあれば見積もった運賃、なければその時点の乗車率で調整した運賃
+func (c dynamicPricingConfig) reservationQuote(req TrainReservationRequest, date time.Time, train Train, fromStation, toStation Station, baseUnitFare int, departureAt, now time.Time) (FareQuoteClaims, error) {
+	if req.QuoteToken != "" {
+		claims, err := c.verifyQuote(req.QuoteToken, now)
+		if err != nil {
+			return claims, err
+		}
+		if !claims.matches(req, date, baseUnitFare) {
+			return claims, errMismatchQuoteToken
+		}
+		return claims, nil
+	}
+
+	adj, err := c.priceAdjustmentFor(train, fromStation, toStation, req.SeatClass, departureAt, now)
+	if err != nil {
+		return FareQuoteClaims{}, err
+	}
+	return newFareQuoteClaims(date, req.TrainClass, req.TrainName, req.SeatClass, req.Departure, req.Arrival, baseUnitFare, adj, now), nil
+}
+
+func isQuoteTokenError(err error) bool {
+	return err == errInvalidQuoteToken || err == errExpiredQuoteToken || err == errMismatchQuoteToken
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDynamicPricingAdjust(t *testing.T) {
+	config := dynamicPricingConfig{Enabled: true, Floor: 0.8, Cap: 1.5}
+
+	tests := []struct {
+		seatClass      string
+		loadFactor     float64
+		untilDeparture time.Duration
+		want           float64
+	}{
+		// 空いていて1ヶ月以上先 → 0.9 * 0.9 = 0.81
+		{"reserved", 0.1, 40 * 24 * time.Hour, 0.81},
+		// 乗車率50%・1週間以上先 → 1.0 * 1.0
+		{"reserved", 0.5, 10 * 24 * time.Hour, 1.0},
+		// 乗車率85%・当日 → 1.25 * 1.2 = 1.5
+		{"premium", 0.85, 3 * time.Hour, 1.5},
+		// ほぼ満席・当日 → 1.4 * 1.2 は上限で 1.5
+		{"premium", 0.99, time.Hour, 1.5},
+		// 自由席は乗車率を使わない
+		{"non-reserved", 0.99, 10 * 24 * time.Hour, 1.0},
+		// 発車後は当日と同じ
+		{"reserved", 0.5, -time.Hour, 1.2},
+	}
+	for _, tt := range tests {
+		adj := config.adjust(tt.seatClass, tt.loadFactor, tt.untilDeparture)
+		if diff := adj.Multiplier - tt.want; diff > 1e-9 || diff < -1e-9 {
+			t.Fatalf("failed test %#v: got=%#v", tt, adj)
+		}
+	}
+
+	// 下限
+	config.Floor = 0.95
+	if adj := config.adjust("reserved", 0.1, 40*24*time.Hour); adj.Multiplier != 0.95 {
+		t.Fatalf("failed test floor: got=%#v", adj)
+	}
+}
+
+func TestPriceAdjustmentApply(t *testing.T) {
+	adj := PriceAdjustment{Multiplier: 1.25}
+	if got := adj.apply(1001); got != 1251 {
+		t.Fatalf("failed test %#v", got)
+	}
+}
+
+func TestQuoteToken(t *testing.T) {
+	config := dynamicPricingConfig{Enabled: true, Floor: 0.8, Cap: 1.5, Key: []byte("test key")}
+	now := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	jst := time.FixedZone("Asia/Tokyo", 9*60*60)
+	date := time.Date(2020, 1, 6, 0, 0, 0, 0, jst)
+
+	// 乗車率70%・5日前 → 1.1 * 1.1
+	adj := config.adjust("reserved", 0.7, 5*24*time.Hour)
+	claims := newFareQuoteClaims(date, "最速", "1", "reserved", "東京", "大阪", 10000, adj, now)
+	if claims.UnitFare != 12100 {
+		t.Fatalf("failed test %#v", claims)
+	}
+
+	token, err := config.signQuote(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := config.verifyQuote(token, now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != claims {
+		t.Fatalf("failed test %#v", got)
+	}
+
+	req := TrainReservationRequest{TrainClass: "最速", TrainName: "1", SeatClass: "reserved", Departure: "東京", Arrival: "大阪"}
+	if !got.matches(req, date, 10000) {
+		t.Fatalf("failed test %#v", req)
+	}
+	if got.matches(req, date, 12000) {
+		t.Fatal("failed test: base fare changed")
+	}
+	req.SeatClass = "premium"
+	if got.matches(req, date, 10000) {
+		t.Fatalf("failed test %#v", req)
+	}
+
+	if _, err := config.verifyQuote(token, now.Add(quoteTokenTTL)); err != errExpiredQuoteToken {
+		t.Fatalf("failed test expired: %#v", err)
+	}
+	if _, err := config.verifyQuote(token[:len(token)-2]+"AA", now); err != errInvalidQuoteToken {
+		t.Fatalf("failed test tampered: %#v", err)
+	}
+	other := dynamicPricingConfig{Key: []byte("other key")}
+	if _, err := other.verifyQuote(token, now); err != errInvalidQuoteToken {
+		t.Fatalf("failed test other key: %#v", err)
+	}
+	if _, err := config.verifyQuote("ISUQUOTE1.abc", now); err != errInvalidQuoteToken {
+		t.Fatalf("failed test malformed: %#v", err)
+	}
+}
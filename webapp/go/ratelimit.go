@@ -0,0 +1,294 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ログイン・ユーザー登録の試行回数制限
+//
+//   - IPごと・アカウントごとのトークンバケットで試行の頻度を制限する
+//   - 連続して認証に失敗すると、IP・アカウントごとに段階的に長くなるロックアウトをかける
+//     (失敗回数がしきい値に達するとロックアウト基準時間、以降1回ごとに倍、上限あり)
+//   - 認証に成功すると、アカウントの失敗回数は消え、IPの失敗回数は1つ減る
+//   - 状態はプロセスのメモリに持つ。POST /initialize で初期化される
+//
+// ベンチマーカーは1つのIPから大量にログインするので、デフォルト値は緩めにしてある
+var loginLimit = newLoginLimiterFromEnv()
+
+type loginLimiterConfig struct {
+	IPRate           float64 // IPごとの試行回数/秒 (0なら無制限)
+	IPBurst          float64
+	AccountRate      float64 // アカウントごとの試行回数/秒 (0なら無制限)
+	AccountBurst     float64
+	AccountThreshold int // アカウントの連続失敗回数のしきい値 (0ならロックアウトしない)
+	IPThreshold      int // IPの連続失敗回数のしきい値 (0ならロックアウトしない)
+	LockoutBase      time.Duration
+	LockoutMax       time.Duration
+}
+
+var defaultLoginLimiterConfig = loginLimiterConfig{
+	IPRate:           100,
+	IPBurst:          200,
+	AccountRate:      30,
+	AccountBurst:     60,
+	AccountThreshold: 5,
+	IPThreshold:      20,
+	LockoutBase:      time.Second,
+	LockoutMax:       15 * time.Minute,
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type failureState struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+type loginLimiter struct {
+	mu        sync.Mutex
+	config    loginLimiterConfig
+	now       func() time.Time
+	buckets   map[string]*tokenBucket
+	failures  map[string]*failureState
+	lastPrune time.Time
+}
+
+func newLoginLimiter(config loginLimiterConfig) *loginLimiter {
+	return &loginLimiter{
+		config:   config,
+		now:      time.Now,
+		buckets:  map[string]*tokenBucket{},
+		failures: map[string]*failureState{},
+	}
+}
+
+func newLoginLimiterFromEnv() *loginLimiter {
+	config := defaultLoginLimiterConfig
+
+	floatEnv := func(key string, dst *float64) {
+		if s := os.Getenv(key); s != "" {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil || v < 0 {
+				log.Fatalf("invalid %s: %s.", key, s)
+			}
+			*dst = v
+		}
+	}
+	intEnv := func(key string, dst *int) {
+		if s := os.Getenv(key); s != "" {
+			v, err := strconv.Atoi(s)
+			if err != nil || v < 0 {
+				log.Fatalf("invalid %s: %s.", key, s)
+			}
+			*dst = v
+		}
+	}
+	durationEnv := func(key string, dst *time.Duration) {
+		if s := os.Getenv(key); s != "" {
+			v, err := time.ParseDuration(s)
+			if err != nil || v <= 0 {
+				log.Fatalf("invalid %s: %s.", key, s)
+			}
+			*dst = v
+		}
+	}
+
+	floatEnv("LOGIN_LIMIT_IP_RATE", &config.IPRate)
+	floatEnv("LOGIN_LIMIT_IP_BURST", &config.IPBurst)
+	floatEnv("LOGIN_LIMIT_ACCOUNT_RATE", &config.AccountRate)
+	floatEnv("LOGIN_LIMIT_ACCOUNT_BURST", &config.AccountBurst)
+	intEnv("LOGIN_LOCKOUT_ACCOUNT_THRESHOLD", &config.AccountThreshold)
+	intEnv("LOGIN_LOCKOUT_IP_THRESHOLD", &config.IPThreshold)
+	durationEnv("LOGIN_LOCKOUT_BASE", &config.LockoutBase)
+	durationEnv("LOGIN_LOCKOUT_MAX", &config.LockoutMax)
+
+	return newLoginLimiter(config)
+}
+
+func (l *loginLimiter) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.buckets = map[string]*tokenBucket{}
+	l.failures = map[string]*failureState{}
+}
+
+// allow は試行してよいかを返す。だめな場合は再試行までの待ち時間も返す
+// account が空の場合はIPのみで判定する
+func (l *loginLimiter) allow(ip, account string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	keys := []string{"ip:" + ip}
+	rates := []float64{l.config.IPRate}
+	bursts := []float64{l.config.IPBurst}
+	if account != "" {
+		keys = append(keys, "account:"+account)
+		rates = append(rates, l.config.AccountRate)
+		bursts = append(bursts, l.config.AccountBurst)
+	}
+
+	// ロックアウト中か
+	var wait time.Duration
+	for _, key := range keys {
+		if f, ok := l.failures[key]; ok && now.Before(f.lockedUntil) {
+			if d := f.lockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	if wait > 0 {
+		return false, wait
+	}
+
+	// トークンが足りるか (全部足りる場合のみ消費する)
+	for i, key := range keys {
+		if rates[i] <= 0 {
+			continue
+		}
+		b := l.bucket(key, bursts[i], now)
+		b.tokens = math.Min(bursts[i], b.tokens+now.Sub(b.last).Seconds()*rates[i])
+		b.last = now
+		if b.tokens < 1 {
+			d := time.Duration((1 - b.tokens) / rates[i] * float64(time.Second))
+			if d > wait {
+				wait = d
+			}
+		}
+	}
+	if wait > 0 {
+		return false, wait
+	}
+	for i, key := range keys {
+		if rates[i] <= 0 {
+			continue
+		}
+		l.buckets[key].tokens--
+	}
+	return true, 0
+}
+
+func (l *loginLimiter) bucket(key string, burst float64, now time.Time) *tokenBucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// recordFailure は認証失敗を記録し、しきい値を超えていればロックアウトする
+func (l *loginLimiter) recordFailure(ip, account string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.addFailure("ip:"+ip, l.config.IPThreshold, now)
+	if account != "" {
+		l.addFailure("account:"+account, l.config.AccountThreshold, now)
+	}
+}
+
+func (l *loginLimiter) addFailure(key string, threshold int, now time.Time) {
+	f, ok := l.failures[key]
+	if !ok || now.Sub(f.last) > l.config.LockoutMax {
+		// 最後の失敗から十分時間が経っていれば数え直す
+		f = &failureState{}
+		l.failures[key] = f
+	}
+	f.count++
+	f.last = now
+
+	if threshold <= 0 || f.count < threshold {
+		return
+	}
+	lockout := l.config.LockoutBase
+	for i := threshold; i < f.count && lockout < l.config.LockoutMax; i++ {
+		lockout *= 2
+	}
+	if lockout > l.config.LockoutMax {
+		lockout = l.config.LockoutMax
+	}
+	f.lockedUntil = now.Add(lockout)
+}
+
+// recordSuccess はアカウントの連続失敗回数を消し、IPの失敗回数を1つ減らす
+// IPの失敗回数は、リスト型攻撃でたまに成功しても消えないように、成功1回につき1つだけ減らす
+// (同じIPからの正規の利用者はほとんど成功するので、失敗回数はしきい値まで貯まらない)
+func (l *loginLimiter) recordSuccess(ip, account string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.failures, "account:"+account)
+	if f, ok := l.failures["ip:"+ip]; ok {
+		f.count--
+		if f.count <= 0 && !l.now().Before(f.lockedUntil) {
+			delete(l.failures, "ip:"+ip)
+		}
+	}
+}
+
+func (l *loginLimiter) prune(now time.Time) {
+	// 1分ごとに、しばらく使われていないバケットと期限切れの失敗記録を捨てる
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > time.Hour {
+			delete(l.buckets, key)
+		}
+	}
+	for key, f := range l.failures {
+		if now.Sub(f.last) > l.config.LockoutMax && now.After(f.lockedUntil) {
+			delete(l.failures, key)
+		}
+	}
+}
+
+func clientIP(r *http.Request) string {
+	// リバースプロキシ(nginx)経由の場合は X-Real-IP を使う
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && (ip.IsLoopback() || isPrivateIP(ip)) {
+		if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+			return realIP
+		}
+	}
+	return host
+}
+
+func isPrivateIP(ip net.IP) bool {
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"} {
+		_, n, _ := net.ParseCIDR(cidr)
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func tooManyRequestsResponse(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(retryAfter.Seconds()))))
+	errorResponse(w, http.StatusTooManyRequests, "too many requests")
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newTestLoginLimiter(config loginLimiterConfig) (*loginLimiter, *fakeClock) {
+	clock := &fakeClock{time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	l := newLoginLimiter(config)
+	l.now = clock.now
+	return l, clock
+}
+
+func TestLoginLimiterCredentialStuffing(t *testing.T) {
+
+	// 1つのIPから、漏洩したリストのアカウントを1回ずつ試す
+	l, clock := newTestLoginLimiter(defaultLoginLimiterConfig)
+	attacker := "203.0.113.10"
+
+	blocked := -1
+	for i := 0; i < 1000; i++ {
+		account := fmt.Sprintf("user%d@example.com", i)
+		if ok, _ := l.allow(attacker, account); !ok {
+			blocked = i
+			break
+		}
+		l.recordFailure(attacker, account)
+		clock.advance(100 * time.Millisecond)
+	}
+	if blocked != defaultLoginLimiterConfig.IPThreshold {
+		t.Fatalf("failed test: blocked after %d attempts", blocked)
+	}
+
+	// ロックアウトは失敗が続くほど長くなる
+	_, wait1 := l.allow(attacker, "next@example.com")
+	clock.advance(wait1)
+	l.recordFailure(attacker, "next@example.com")
+	_, wait2 := l.allow(attacker, "next2@example.com")
+	if wait1 > defaultLoginLimiterConfig.LockoutBase || wait2 != 2*defaultLoginLimiterConfig.LockoutBase {
+		t.Fatalf("failed test: lockout %s -> %s", wait1, wait2)
+	}
+
+	// 途中で1件成功しても、IPの失敗回数は1つ減るだけで消えない
+	clock.advance(wait2)
+	l.recordSuccess(attacker, "found@example.com")
+	l.recordFailure(attacker, "next3@example.com")
+	if ok, _ := l.allow(attacker, "next4@example.com"); ok {
+		t.Fatalf("failed test: success reset the ip lockout")
+	}
+
+	// 他のIPの利用者には影響しない
+	if ok, _ := l.allow("198.51.100.20", "user1@example.com"); !ok {
+		t.Fatalf("failed test: another ip blocked")
+	}
+
+	// 上限より長くロックされることはない
+	for i := 0; i < 100; i++ {
+		l.recordFailure(attacker, "x@example.com")
+	}
+	if _, wait := l.allow(attacker, "y@example.com"); wait > defaultLoginLimiterConfig.LockoutMax {
+		t.Fatalf("failed test: lockout %s", wait)
+	}
+}
+
+func TestLoginLimiterDistributedBruteForce(t *testing.T) {
+
+	// 多数のIPから1つのアカウントのパスワードを総当たりする
+	l, clock := newTestLoginLimiter(defaultLoginLimiterConfig)
+	victim := "victim@example.com"
+
+	for i := 0; i < defaultLoginLimiterConfig.AccountThreshold; i++ {
+		ip := fmt.Sprintf("192.0.2.%d", i)
+		if ok, _ := l.allow(ip, victim); !ok {
+			t.Fatalf("failed test: blocked at %d", i)
+		}
+		l.recordFailure(ip, victim)
+	}
+	ok, wait := l.allow("192.0.2.200", victim)
+	if ok || wait != defaultLoginLimiterConfig.LockoutBase {
+		t.Fatalf("failed test %v %s", ok, wait)
+	}
+
+	// ロックアウトが明けて正しいパスワードで成功すれば、失敗回数は消える
+	clock.advance(wait)
+	if ok, _ := l.allow("192.0.2.200", victim); !ok {
+		t.Fatalf("failed test: still locked")
+	}
+	l.recordSuccess("192.0.2.200", victim)
+	l.recordFailure("192.0.2.201", victim)
+	if ok, _ := l.allow("192.0.2.201", victim); !ok {
+		t.Fatalf("failed test: failures not reset")
+	}
+}
+
+func TestLoginLimiterSharedIP(t *testing.T) {
+
+	// 1つのIP (ベンチマーカー・NAT配下) から多数の利用者がログインし、たまにパスワードを間違える
+	l, clock := newTestLoginLimiter(defaultLoginLimiterConfig)
+	ip := "203.0.113.10"
+
+	for i := 0; i < 1000; i++ {
+		account := fmt.Sprintf("user%d@example.com", i)
+		if ok, _ := l.allow(ip, account); !ok {
+			t.Fatalf("failed test: blocked at %d", i)
+		}
+		if i%3 == 0 {
+			l.recordFailure(ip, account)
+		} else {
+			l.recordSuccess(ip, account)
+		}
+		clock.advance(100 * time.Millisecond)
+	}
+}
+
+func TestLoginLimiterTokenBucket(t *testing.T) {
+
+	l, clock := newTestLoginLimiter(loginLimiterConfig{IPRate: 1, IPBurst: 3, AccountRate: 10, AccountBurst: 10})
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("203.0.113.10", ""); !ok {
+			t.Fatalf("failed test: burst %d", i)
+		}
+	}
+	ok, wait := l.allow("203.0.113.10", "")
+	if ok || wait != time.Second {
+		t.Fatalf("failed test %v %s", ok, wait)
+	}
+	clock.advance(wait)
+	if ok, _ := l.allow("203.0.113.10", ""); !ok {
+		t.Fatalf("failed test: not refilled")
+	}
+
+	// 制限なし
+	l, _ = newTestLoginLimiter(loginLimiterConfig{})
+	for i := 0; i < 1000; i++ {
+		l.recordFailure("203.0.113.10", "a@example.com")
+		if ok, _ := l.allow("203.0.113.10", "a@example.com"); !ok {
+			t.Fatalf("failed test: limited without config")
+		}
+	}
+}
+
+func TestTooManyRequestsResponse(t *testing.T) {
+
+	w := httptest.NewRecorder()
+	tooManyRequestsResponse(w, 1500*time.Millisecond)
+	if w.Code != 429 || w.Header().Get("Retry-After") != "2" {
+		t.Fatalf("failed test %d %s", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestClientIP(t *testing.T) {
+
+	r := httptest.NewRequest("POST", "/api/auth/login", nil)
+	r.RemoteAddr = "172.18.0.5:40000"
+	r.Header.Set("X-Real-IP", "203.0.113.10")
+	if ip := clientIP(r); ip != "203.0.113.10" {
+		t.Fatalf("failed test %s", ip)
+	}
+
+	// プロキシ以外からの X-Real-IP は信用しない
+	r.RemoteAddr = "198.51.100.1:40000"
+	if ip := clientIP(r); ip != "198.51.100.1" {
+		t.Fatalf("failed test %s", ip)
+	}
+}
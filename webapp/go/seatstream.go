@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// 購読者ごとの未送信イベントの上限。溢れた購読者は切断する
+	seatStreamBufferSize = 64
+	seatStreamPingPeriod = 30 * time.Second
+	seatStreamWriteWait  = 10 * time.Second
+)
+
+// 座席の変化の種類
+const (
+	SeatEventReserved  = "reserved"  // 仮予約で座席が確保された
+	SeatEventCommitted = "committed" // 支払いが完了した
+	SeatEventReleased  = "released"  // キャンセルや no_show で座席が解放された
+)
+
+var (
+	seatStream = newSeatHub()
+
+	seatStreamUpgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+)
+
+// 購読の単位 (列車・日付・号車)
+type seatStreamKey struct {
+	Date       string
+	TrainClass string
+	TrainName  string
+	CarNumber  int
+}
+
+type SeatEvent struct {
+	Type          string        `json:"type"`
+	ReservationId int           `json:"reservation_id"`
+	Date          string        `json:"date"`
+	TrainClass    string        `json:"train_class"`
+	TrainName     string        `json:"train_name"`
+	CarNumber     int           `json:"car_number"`
+	Departure     string        `json:"departure"`
+	Arrival       string        `json:"arrival"`
+	Seats         []RequestSeat `json:"seats"`
+}
+
+type seatSubscriber struct {
+	send chan []byte
+}
+
+type seatHub struct {
+	mu          sync.Mutex
+	subscribers map[seatStreamKey]map[*seatSubscriber]bool
+}
+
+func newSeatHub() *seatHub {
+	return &seatHub{
+		subscribers: map[seatStreamKey]map[*seatSubscriber]bool{},
+	}
+}
+
+func (h *seatHub) subscribe(key seatStreamKey) *seatSubscriber {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sub := &seatSubscriber{send: make(chan []byte, seatStreamBufferSize)}
+	if h.subscribers[key] == nil {
+		h.subscribers[key] = map[*seatSubscriber]bool{}
+	}
+	h.subscribers[key][sub] = true
+	return sub
+}
+
+func (h *seatHub) unsubscribe(key seatStreamKey, sub *seatSubscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[key][sub]; !ok {
+		return
+	}
+	delete(h.subscribers[key], sub)
+	if len(h.subscribers[key]) == 0 {
+		delete(h.subscribers, key)
+	}
+	close(sub.send)
+}
+
+func (h *seatHub) publish(event SeatEvent) {
+	key := seatStreamKey{event.Date, event.TrainClass, event.TrainName, event.CarNumber}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers[key] {
+		select {
+		case sub.send <- b:
+		default:
+			// 受信が追いつかない購読者は切断し、再接続時に座席一覧を取り直してもらう
+			delete(h.subscribers[key], sub)
+			close(sub.send)
+		}
+	}
+	if len(h.subscribers[key]) == 0 {
+		delete(h.subscribers, key)
+	}
+}
+
+func publishSeatEvent(eventType string, reservation Reservation, seatReservationList []SeatReservation) {
+	if len(seatReservationList) == 0 {
+		return
+	}
+
+	event := SeatEvent{
+		Type:          eventType,
+		ReservationId: reservation.ReservationId,
+		Date:          reservation.Date.Format("2006/01/02"),
+		TrainClass:    reservation.TrainClass,
+		TrainName:     reservation.TrainName,
+		CarNumber:     seatReservationList[0].CarNumber,
+		Departure:     reservation.Departure,
+		Arrival:       reservation.Arrival,
+		Seats:         []RequestSeat{},
+	}
+	for _, s := range seatReservationList {
+		event.Seats = append(event.Seats, RequestSeat{s.SeatRow, s.SeatColumn})
+	}
+	seatStream.publish(event)
+}
+
+func trainSeatsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		座席の変化の購読
+		GET /api/train/seats/stream?date=2020-03-01T00:00:00.000Z&train_class=最速&train_name=1&car_number=2
+		WebSocketで、指定した号車の座席が予約・確定・解放されるたびに SeatEvent を送る
+	*/
+
+	jst := time.FixedZone("Asia/Tokyo", 9*60*60)
+	date, err := time.Parse(time.RFC3339, r.URL.Query().Get("date"))
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	date = date.In(jst)
+
+	carNumber, err := strconv.Atoi(r.URL.Query().Get("car_number"))
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, "号車が不正です")
+		return
+	}
+
+	key := seatStreamKey{
+		Date:       date.Format("2006/01/02"),
+		TrainClass: r.URL.Query().Get("train_class"),
+		TrainName:  r.URL.Query().Get("train_name"),
+		CarNumber:  carNumber,
+	}
+
+	conn, err := seatStreamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade がエラーレスポンスを返している
+		log.Println(err.Error())
+		return
+	}
+	defer conn.Close()
+
+	sub := seatStream.subscribe(key)
+	defer seatStream.unsubscribe(key, sub)
+
+	// クライアントからのメッセージは読み捨て、切断を検知する
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(seatStreamPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case b, ok := <-sub.send:
+			conn.SetWriteDeadline(time.Now().Add(seatStreamWriteWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"))
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, b); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(seatStreamWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSeatHubPublish(t *testing.T) {
+
+	hub := newSeatHub()
+	key := seatStreamKey{"2020/01/01", "最速", "1", 4}
+	sub := hub.subscribe(key)
+	other := hub.subscribe(seatStreamKey{"2020/01/01", "最速", "1", 5})
+
+	hub.publish(SeatEvent{Type: SeatEventReserved, Date: "2020/01/01", TrainClass: "最速", TrainName: "1", CarNumber: 4})
+
+	select {
+	case b := <-sub.send:
+		event := SeatEvent{}
+		json.Unmarshal(b, &event)
+		if event.Type != SeatEventReserved || event.CarNumber != 4 {
+			t.Fatalf("failed test %s", b)
+		}
+	default:
+		t.Fatalf("failed test: event not delivered")
+	}
+	if len(other.send) != 0 {
+		t.Fatalf("failed test: event delivered to another car")
+	}
+
+	// 受信しない購読者はバッファが溢れた時点で切断される
+	for i := 0; i <= seatStreamBufferSize; i++ {
+		hub.publish(SeatEvent{Type: SeatEventReleased, Date: "2020/01/01", TrainClass: "最速", TrainName: "1", CarNumber: 4})
+	}
+	for range sub.send {
+	}
+	if _, ok := hub.subscribers[key]; ok {
+		t.Fatalf("failed test: slow subscriber not removed")
+	}
+
+	// 切断済みの購読者の解除は何もしない
+	hub.unsubscribe(key, sub)
+	hub.unsubscribe(seatStreamKey{"2020/01/01", "最速", "1", 5}, other)
+	if len(hub.subscribers) != 0 {
+		t.Fatalf("failed test %#v", hub.subscribers)
+	}
+}
+
+func TestTrainSeatsStreamHandler(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(trainSeatsStreamHandler))
+	defer ts.Close()
+
+	u := "ws" + strings.TrimPrefix(ts.URL, "http") + "/?date=2020-01-01T00:00:00%2B09:00&train_class=%E6%9C%80%E9%80%9F&train_name=1&car_number=4"
+	conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 購読が登録されるまで待つ
+	key := seatStreamKey{"2020/01/01", "最速", "1", 4}
+	for i := 0; ; i++ {
+		seatStream.mu.Lock()
+		n := len(seatStream.subscribers[key])
+		seatStream.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if i > 100 {
+			t.Fatalf("failed test: not subscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.FixedZone("Asia/Tokyo", 9*60*60))
+	publishSeatEvent(SeatEventReserved, Reservation{
+		ReservationId: 10, Date: &date, TrainClass: "最速", TrainName: "1", Departure: "東京", Arrival: "大阪",
+	}, []SeatReservation{{10, 4, 1, "A"}, {10, 4, 1, "B"}})
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	event := SeatEvent{}
+	err = conn.ReadJSON(&event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.ReservationId != 10 || event.Departure != "東京" || len(event.Seats) != 2 || event.Seats[1].Column != "B" {
+		t.Fatalf("failed test %#v", event)
+	}
+}
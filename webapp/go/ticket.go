@@ -0,0 +1,483 @@
+package main
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	qrcode "github.com/skip2/go-qrcode"
+	"goji.io/pat"
+	"golang.org/x/crypto/ed25519"
+)
+
+// 乗車券の先頭に付くバージョン
+const ticketVersion = "ISUTRAIN1"
+
+var (
+	ticketPrivateKey ed25519.PrivateKey
+	ticketPublicKey  ed25519.PublicKey
+
+	errInvalidTicket = errors.New("乗車券の形式が不正です")
+)
+
+// 乗車券のQRコードに埋め込む内容
+type TicketPayload struct {
+	ReservationId int               `json:"reservation_id"`
+	Date          string            `json:"date"`
+	TrainClass    string            `json:"train_class"`
+	TrainName     string            `json:"train_name"`
+	Departure     string            `json:"departure"`
+	Arrival       string            `json:"arrival"`
+	DepartureTime string            `json:"departure_time"`
+	ArrivalTime   string            `json:"arrival_time"`
+	CarNumber     int               `json:"car_number"`
+	SeatClass     string            `json:"seat_class"`
+	Adult         int               `json:"adult"`
+	Child         int               `json:"child"`
+	Seats         []SeatReservation `json:"seats"`
+	IssuedAt      int64             `json:"issued_at"`
+}
+
+type TicketVerifyRequest struct {
+	Ticket string `json:"ticket"`
+}
+
+type TicketVerifyResponse struct {
+	IsValid bool          `json:"is_valid"`
+	Ticket  TicketPayload `json:"ticket"`
+}
+
+type TicketCheckinRequest struct {
+	Ticket     string `json:"ticket"`
+	Station    string `json:"station"`
+	Date       string `json:"date"`
+	TrainClass string `json:"train_class"`
+	TrainName  string `json:"train_name"`
+}
+
+type TicketCheckinResponse struct {
+	IsOk   bool          `json:"is_ok"`
+	Ticket TicketPayload `json:"ticket"`
+}
+
+type TicketPublicKeyResponse struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+}
+
+func initTicketKey() {
+	// TICKET_SIGNING_KEY には ed25519 の seed (32byte) を base64 で指定する
+	// 未指定の場合は起動ごとに鍵を生成するため、再起動前に発行した乗車券は検証できなくなる
+	seedStr := os.Getenv("TICKET_SIGNING_KEY")
+	if seedStr == "" {
+		pub, priv, err := ed25519.GenerateKey(crand.Reader)
+		if err != nil {
+			log.Fatalf("failed to generate ticket key: %s.", err.Error())
+		}
+		ticketPrivateKey, ticketPublicKey = priv, pub
+		log.Println("TICKET_SIGNING_KEY is not set. generated a temporary key.")
+		return
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(seedStr)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		log.Fatalf("invalid TICKET_SIGNING_KEY: must be %d bytes base64.", ed25519.SeedSize)
+	}
+	ticketPrivateKey = ed25519.NewKeyFromSeed(seed)
+	ticketPublicKey = ticketPrivateKey.Public().(ed25519.PublicKey)
+}
+
+func signTicket(payload TicketPayload, key ed25519.PrivateKey) (string, error) {
+	// ISUTRAIN1.<base64url(JSON)>.<base64url(署名)>
+	// 署名対象はバージョンとペイロードをつないだ文字列
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	signed := ticketVersion + "." + base64.RawURLEncoding.EncodeToString(b)
+	sig := ed25519.Sign(key, []byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func verifyTicket(ticket string, key ed25519.PublicKey) (TicketPayload, error) {
+	payload := TicketPayload{}
+
+	parts := strings.Split(ticket, ".")
+	if len(parts) != 3 || parts[0] != ticketVersion {
+		return payload, errInvalidTicket
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return payload, errInvalidTicket
+	}
+	if !ed25519.Verify(key, []byte(parts[0]+"."+parts[1]), sig) {
+		return payload, errors.New("乗車券の署名が不正です")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return payload, errInvalidTicket
+	}
+	err = json.Unmarshal(b, &payload)
+	if err != nil {
+		return payload, errInvalidTicket
+	}
+	return payload, nil
+}
+
+func userReservationTicketHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		乗車券(QRコード)の発行
+		GET /api/user/reservations/:item_id/ticket
+	*/
+	user, errCode, errMsg := authenticate(r, scopeSearch)
+	if errCode != http.StatusOK {
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+	itemIDStr := pat.Param(r, "item_id")
+	itemID, err := strconv.ParseInt(itemIDStr, 10, 64)
+	if err != nil || itemID <= 0 {
+		errorResponse(w, http.StatusBadRequest, "incorrect item id")
+		return
+	}
+
+	reservation := Reservation{}
+	query := "SELECT * FROM reservations WHERE reservation_id=?"
+	err = dbx.Get(&reservation, query, itemID)
+	if err == sql.ErrNoRows {
+		errorResponse(w, http.StatusNotFound, "Reservation not found")
+		return
+	}
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if ok, err := reservationAccessible(dbx, user, reservation); err != nil || !ok {
+		if err != nil {
+			log.Println(err.Error())
+		}
+		errorResponse(w, http.StatusNotFound, "Reservation not found")
+		return
+	}
+
+	if reservation.Status != "done" {
+		errorResponse(w, http.StatusForbidden, "支払いが完了した予約のみ乗車券を発行できます")
+		return
+	}
+
+	reservationResponse, err := makeReservationResponse(reservation)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		log.Println("makeReservationResponse() ", err)
+		return
+	}
+
+	payload := TicketPayload{
+		ReservationId: reservationResponse.ReservationId,
+		Date:          reservationResponse.Date,
+		TrainClass:    reservationResponse.TrainClass,
+		TrainName:     reservationResponse.TrainName,
+		Departure:     reservationResponse.Departure,
+		Arrival:       reservationResponse.Arrival,
+		DepartureTime: reservationResponse.DepartureTime,
+		ArrivalTime:   reservationResponse.ArrivalTime,
+		CarNumber:     reservationResponse.CarNumber,
+		SeatClass:     reservationResponse.SeatClass,
+		Adult:         reservationResponse.Adult,
+		Child:         reservationResponse.Child,
+		Seats:         reservationResponse.Seats,
+		IssuedAt:      time.Now().Unix(),
+	}
+	ticket, err := signTicket(payload, ticketPrivateKey)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "乗車券の署名に失敗しました")
+		log.Println(err.Error())
+		return
+	}
+
+	png, err := qrcode.Encode(ticket, qrcode.Medium, 512)
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "QRコードの生成に失敗しました")
+		log.Println(err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"ticket-%d.png\"", reservation.ReservationId))
+	w.Write(png)
+}
+
+func ticketVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		乗車券の検証 (車掌向け)
+		POST /api/ticket/verify
+		署名が正しく、予約が取り消されていなければ有効
+	*/
+	defer r.Body.Close()
+	buf, _ := ioutil.ReadAll(r.Body)
+
+	req := TicketVerifyRequest{}
+	err := json.Unmarshal(bytes.TrimSpace(buf), &req)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, "JSON parseに失敗しました")
+		log.Println(err.Error())
+		return
+	}
+
+	payload, err := verifyTicket(req.Ticket, ticketPublicKey)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reservation := Reservation{}
+	query := "SELECT * FROM reservations WHERE reservation_id=?"
+	err = dbx.Get(&reservation, query, payload.ReservationId)
+	if err == sql.ErrNoRows {
+		errorResponse(w, http.StatusNotFound, "予約が見つかりません。キャンセルされた可能性があります")
+		return
+	}
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "予約情報の取得に失敗しました")
+		log.Println(err.Error())
+		return
+	}
+
+	resp := TicketVerifyResponse{
+		IsValid: reservation.Status == "done",
+		Ticket:  payload,
+	}
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func ticketCheckinHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		改札での乗車処理
+		POST /api/ticket/checkin
+		改札のある駅・日付・列車と乗車券の内容が一致すれば乗車済みにする
+	*/
+	defer r.Body.Close()
+	buf, _ := ioutil.ReadAll(r.Body)
+
+	req := TicketCheckinRequest{}
+	err := json.Unmarshal(buf, &req)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, "JSON parseに失敗しました")
+		log.Println(err.Error())
+		return
+	}
+
+	payload, err := verifyTicket(req.Ticket, ticketPublicKey)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = ticketMatchesGate(payload, req)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tx := dbx.MustBegin()
+
+	reservation := Reservation{}
+	query := "SELECT * FROM reservations WHERE reservation_id=? FOR UPDATE"
+	err = tx.Get(&reservation, query, payload.ReservationId)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		errorResponse(w, http.StatusNotFound, "予約が見つかりません。キャンセルされた可能性があります")
+		return
+	}
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "予約情報の取得に失敗しました")
+		log.Println(err.Error())
+		return
+	}
+
+	if errCode, errMsg := checkinAllowed(reservation); errCode != http.StatusOK {
+		tx.Rollback()
+		errorResponse(w, errCode, errMsg)
+		return
+	}
+
+	query = "UPDATE reservations SET checked_in_at=?, checked_in_station=? WHERE reservation_id=?"
+	_, err = tx.Exec(query, time.Now(), req.Station, reservation.ReservationId)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "乗車情報の更新に失敗しました")
+		log.Println(err.Error())
+		return
+	}
+	tx.Commit()
+
+	resp := TicketCheckinResponse{
+		IsOk:   true,
+		Ticket: payload,
+	}
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ticketMatchesGate は乗車券が改札のある駅・日付・列車で使えるかを確認する
+func ticketMatchesGate(payload TicketPayload, req TicketCheckinRequest) error {
+	if payload.Date != req.Date {
+		return errors.New("乗車日が違います")
+	}
+	if payload.TrainClass != req.TrainClass || payload.TrainName != req.TrainName {
+		return errors.New("列車が違います")
+	}
+	if payload.Departure != req.Station {
+		return errors.New("乗車駅が違います")
+	}
+	return nil
+}
+
+// checkinAllowed は予約が改札を通れる状態かを確認する
+func checkinAllowed(reservation Reservation) (errCode int, errMsg string) {
+	if reservation.CheckedInAt != nil {
+		return http.StatusConflict, "この乗車券は既に使用されています"
+	}
+	switch reservation.Status {
+	case "done":
+		return http.StatusOK, ""
+	case "no_show":
+		return http.StatusBadRequest, "発車時刻を過ぎたため乗車券は無効になっています"
+	default:
+		return http.StatusBadRequest, "支払いが完了していない予約です"
+	}
+}
+
+func startNoShowSweeper() {
+	// NOSHOW_SWEEP_INTERVAL (例: 1m) を指定した場合のみ定期的に no_show の判定を行う
+	intervalStr := os.Getenv("NOSHOW_SWEEP_INTERVAL")
+	if intervalStr == "" {
+		return
+	}
+	interval, err := time.ParseDuration(intervalStr)
+	if err != nil || interval <= 0 {
+		log.Fatalf("invalid NOSHOW_SWEEP_INTERVAL: %s.", intervalStr)
+	}
+
+	go func() {
+		for range time.Tick(interval) {
+			reservationIDs, err := sweepNoShows(time.Now())
+			if err != nil {
+				log.Println("sweepNoShows()", err)
+				continue
+			}
+			if len(reservationIDs) > 0 {
+				log.Printf("no_show: %v", reservationIDs)
+			}
+		}
+	}()
+}
+
+func sweepNoShows(now time.Time) ([]int, error) {
+	// 乗車駅を発車した後も改札を通っていない支払い済みの予約を no_show にする
+	// 発車済みなので、no_show の座席を区間重複の判定から外せば以降の区間の座席が解放される
+	tx := dbx.MustBegin()
+
+	reservationList := []Reservation{}
+	query := `
+	SELECT r.*
+	FROM reservations r, train_timetable_master t
+	WHERE
+		t.date=DATE(r.date) AND
+		t.train_class=r.train_class AND
+		t.train_name=r.train_name AND
+		t.station=r.departure AND
+		r.status='done' AND
+		r.checked_in_at IS NULL AND
+		TIMESTAMP(t.date, t.departure) < ?
+	FOR UPDATE
+	`
+	err := tx.Select(&reservationList, query, noShowCutoff(now))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	reservationIDs := []int{}
+	if len(reservationList) == 0 {
+		tx.Rollback()
+		return reservationIDs, nil
+	}
+	for _, reservation := range reservationList {
+		reservationIDs = append(reservationIDs, reservation.ReservationId)
+	}
+
+	// 解放される座席 (通知用)
+	seatReservationList := []SeatReservation{}
+	query, args, err := sqlx.In("SELECT * FROM seat_reservations WHERE reservation_id IN (?)", reservationIDs)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	err = tx.Select(&seatReservationList, query, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	query, args, err = sqlx.In("UPDATE reservations SET status='no_show' WHERE reservation_id IN (?)", reservationIDs)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	_, err = tx.Exec(query, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	// 座席の購読者に通知
+	seatReservationMap := map[int][]SeatReservation{}
+	for _, seatReservation := range seatReservationList {
+		seatReservationMap[seatReservation.ReservationId] = append(seatReservationMap[seatReservation.ReservationId], seatReservation)
+	}
+	for _, reservation := range reservationList {
+		publishSeatEvent(SeatEventReleased, reservation, seatReservationMap[reservation.ReservationId])
+	}
+
+	return reservationIDs, nil
+}
+
+// noShowCutoff は乗車駅の発車時刻がこれより前なら no_show とする時刻 (時刻表と同じ日本時間) を返す
+func noShowCutoff(now time.Time) string {
+	jst := time.FixedZone("Asia/Tokyo", 9*60*60)
+	return now.In(jst).Format("2006-01-02 15:04:05")
+}
+
+func ticketPublicKeyHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		乗車券検証用の公開鍵
+		GET /api/ticket/public_key
+		オフラインで検証する端末はこの鍵で署名を確認する
+	*/
+	resp := TicketPublicKeyResponse{
+		Algorithm: "ed25519",
+		PublicKey: base64.StdEncoding.EncodeToString(ticketPublicKey),
+	}
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestSignAndVerifyTicket(t *testing.T) {
+
+	pub, priv, err := ed25519.GenerateKey(crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := TicketPayload{
+		ReservationId: 1,
+		Date:          "2020/01/01",
+		TrainClass:    "最速",
+		TrainName:     "1",
+		Departure:     "東京",
+		Arrival:       "大阪",
+		CarNumber:     4,
+		Seats:         []SeatReservation{{SeatRow: 1, SeatColumn: "A"}},
+	}
+	ticket, err := signTicket(payload, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := verifyTicket(ticket, pub)
+	if err != nil {
+		t.Fatalf("failed test %v", err)
+	}
+	if got.ReservationId != 1 || got.Arrival != "大阪" || len(got.Seats) != 1 || got.Seats[0].SeatColumn != "A" {
+		t.Fatalf("failed test %#v", got)
+	}
+
+	// ペイロードを書き換えると検証に失敗する
+	parts := strings.Split(ticket, ".")
+	forged, _ := signTicket(TicketPayload{ReservationId: 2}, priv)
+	if _, err := verifyTicket(parts[0]+"."+strings.Split(forged, ".")[1]+"."+parts[2], pub); err == nil {
+		t.Fatalf("failed test: forged ticket accepted")
+	}
+
+	// 別の鍵では検証できない
+	otherPub, _, _ := ed25519.GenerateKey(crand.Reader)
+	if _, err := verifyTicket(ticket, otherPub); err == nil {
+		t.Fatalf("failed test: ticket verified with another key")
+	}
+
+	if _, err := verifyTicket("ISUTRAIN1.abc", pub); err == nil {
+		t.Fatalf("failed test: malformed ticket accepted")
+	}
+}
+
+func TestTicketMatchesGate(t *testing.T) {
+	payload := TicketPayload{Date: "2020/01/01", TrainClass: "最速", TrainName: "1", Departure: "東京", Arrival: "大阪"}
+
+	tests := []struct {
+		req  TicketCheckinRequest
+		want string
+	}{
+		{TicketCheckinRequest{Station: "東京", Date: "2020/01/01", TrainClass: "最速", TrainName: "1"}, ""},
+		// 降車駅の改札では乗車できない
+		{TicketCheckinRequest{Station: "大阪", Date: "2020/01/01", TrainClass: "最速", TrainName: "1"}, "乗車駅が違います"},
+		{TicketCheckinRequest{Station: "東京", Date: "2020/01/02", TrainClass: "最速", TrainName: "1"}, "乗車日が違います"},
+		{TicketCheckinRequest{Station: "東京", Date: "2020/01/01", TrainClass: "最速", TrainName: "3"}, "列車が違います"},
+		{TicketCheckinRequest{Station: "東京", Date: "2020/01/01", TrainClass: "中間", TrainName: "1"}, "列車が違います"},
+	}
+	for _, tt := range tests {
+		err := ticketMatchesGate(payload, tt.req)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Fatalf("failed test %#v: got=%q", tt.req, got)
+		}
+	}
+}
+
+func TestCheckinAllowed(t *testing.T) {
+	checkedInAt := time.Date(2020, 1, 1, 6, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		reservation Reservation
+		want        int
+	}{
+		{Reservation{Status: "done"}, http.StatusOK},
+		// 二重の乗車はできない
+		{Reservation{Status: "done", CheckedInAt: &checkedInAt}, http.StatusConflict},
+		{Reservation{Status: "no_show"}, http.StatusBadRequest},
+		{Reservation{Status: "requesting"}, http.StatusBadRequest},
+		{Reservation{Status: "rejected"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if got, _ := checkinAllowed(tt.reservation); got != tt.want {
+			t.Fatalf("failed test %#v: got=%d", tt.reservation, got)
+		}
+	}
+}
+
+func TestTicketCheckinHandler(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	origPriv, origPub := ticketPrivateKey, ticketPublicKey
+	ticketPrivateKey, ticketPublicKey = priv, pub
+	defer func() { ticketPrivateKey, ticketPublicKey = origPriv, origPub }()
+
+	ticket, err := signTicket(TicketPayload{ReservationId: 1, Date: "2020/01/01", TrainClass: "最速", TrainName: "1", Departure: "東京", Arrival: "大阪"}, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, restore := withFakeDB(t)
+	defer restore()
+	var checkedInAt interface{}
+	db.on("SELECT * FROM reservations WHERE reservation_id=?", func(args []driver.Value) fakeResult {
+		return fakeResult{
+			columns: []string{"reservation_id", "status", "checked_in_at"},
+			rows:    [][]driver.Value{{int64(1), "done", checkedInAt}},
+		}
+	})
+	db.on("UPDATE reservations SET checked_in_at", func(args []driver.Value) fakeResult {
+		checkedInAt = args[0]
+		return fakeResult{rowsAffected: 1}
+	})
+
+	checkin := func(station, date string) int {
+		body := fmt.Sprintf(`{"ticket":%q,"station":%q,"date":%q,"train_class":"最速","train_name":"1"}`, ticket, station, date)
+		r := httptest.NewRequest(http.MethodPost, "/api/ticket/checkin", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		ticketCheckinHandler(w, r)
+		return w.Code
+	}
+
+	// 駅・日付が違う場合は予約を見ずに拒否する
+	if got := checkin("大阪", "2020/01/01"); got != http.StatusBadRequest {
+		t.Fatalf("failed test: wrong station got=%d", got)
+	}
+	if got := checkin("東京", "2020/01/02"); got != http.StatusBadRequest {
+		t.Fatalf("failed test: wrong date got=%d", got)
+	}
+	if n := len(db.executed("UPDATE reservations")); n != 0 {
+		t.Fatalf("failed test: updated %d times", n)
+	}
+
+	if got := checkin("東京", "2020/01/01"); got != http.StatusOK {
+		t.Fatalf("failed test: checkin got=%d", got)
+	}
+	updates := db.executed("UPDATE reservations SET checked_in_at")
+	if len(updates) != 1 || updates[0][1] != "東京" || updates[0][2] != int64(1) {
+		t.Fatalf("failed test %#v", updates)
+	}
+
+	// 二重の乗車
+	if got := checkin("東京", "2020/01/01"); got != http.StatusConflict {
+		t.Fatalf("failed test: double checkin got=%d", got)
+	}
+	if n := len(db.executed("UPDATE reservations")); n != 1 {
+		t.Fatalf("failed test: updated %d times", n)
+	}
+}
+
+func TestSweepNoShows(t *testing.T) {
+	db, restore := withFakeDB(t)
+	defer restore()
+
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var cutoff driver.Value
+	db.on("FROM reservations r, train_timetable_master t", func(args []driver.Value) fakeResult {
+		cutoff = args[0]
+		return fakeResult{
+			columns: []string{"reservation_id", "date", "train_class", "train_name", "departure", "arrival", "status"},
+			rows: [][]driver.Value{
+				{int64(1), date, "最速", "1", "東京", "大阪", "done"},
+				{int64(2), date, "最速", "1", "東京", "名古屋", "done"},
+			},
+		}
+	})
+	db.rows("SELECT * FROM seat_reservations WHERE reservation_id IN",
+		[]string{"reservation_id", "car_number", "seat_row", "seat_column"},
+		[]driver.Value{int64(1), int64(4), int64(1), "A"},
+		[]driver.Value{int64(2), int64(4), int64(2), "B"},
+	)
+	db.on("UPDATE reservations SET status='no_show'", func([]driver.Value) fakeResult {
+		return fakeResult{rowsAffected: 2}
+	})
+
+	sub := seatStream.subscribe(seatStreamKey{"2020/01/01", "最速", "1", 4})
+	defer seatStream.unsubscribe(seatStreamKey{"2020/01/01", "最速", "1", 4}, sub)
+
+	// 時刻表は日本時間なので、締め切りも日本時間で比べる
+	now := time.Date(2020, 1, 1, 0, 30, 0, 0, time.UTC)
+	ids, err := sweepNoShows(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(ids) != "[1 2]" {
+		t.Fatalf("failed test %#v", ids)
+	}
+	if cutoff != "2020-01-01 09:30:00" {
+		t.Fatalf("failed test: cutoff=%#v", cutoff)
+	}
+	updates := db.executed("UPDATE reservations SET status='no_show'")
+	if len(updates) != 1 || fmt.Sprint(updates[0]) != "[1 2]" {
+		t.Fatalf("failed test %#v", updates)
+	}
+
+	// no_show にした予約の座席は解放を通知する
+	for _, want := range []string{"1A", "2B"} {
+		select {
+		case b := <-sub.send:
+			event := SeatEvent{}
+			json.Unmarshal(b, &event)
+			if event.Type != SeatEventReleased || len(event.Seats) != 1 || fmt.Sprintf("%d%s", event.Seats[0].Row, event.Seats[0].Column) != want {
+				t.Fatalf("failed test %s", b)
+			}
+		default:
+			t.Fatalf("failed test: released event not delivered")
+		}
+	}
+}
+
+func TestNoShowCutoff(t *testing.T) {
+	jst := time.FixedZone("Asia/Tokyo", 9*60*60)
+	tests := []struct {
+		now  time.Time
+		want string
+	}{
+		{time.Date(2020, 1, 1, 0, 30, 0, 0, time.UTC), "2020-01-01 09:30:00"},
+		{time.Date(2019, 12, 31, 15, 0, 0, 0, time.UTC), "2020-01-01 00:00:00"},
+		{time.Date(2020, 1, 1, 6, 0, 0, 0, jst), "2020-01-01 06:00:00"},
+	}
+	for _, tt := range tests {
+		if got := noShowCutoff(tt.now); got != tt.want {
+			t.Fatalf("failed test %v: got=%s", tt.now, got)
+		}
+	}
+}
+
+func TestGetAvailableSeatsReleasesNoShow(t *testing.T) {
+	db, restore := withFakeDB(t)
+	defer restore()
+
+	db.rows("SELECT * FROM seat_master",
+		[]string{"train_class", "car_number", "seat_column", "seat_row", "seat_class"},
+		[]driver.Value{"最速", int64(4), "A", int64(1), "reserved"},
+		[]driver.Value{"最速", int64(4), "B", int64(1), "reserved"},
+	)
+	// 1A は乗車しなかった (no_show) 予約、1B は支払い済みの予約の座席
+	db.on("FROM seat_reservations sr, reservations r", func([]driver.Value) fakeResult {
+		res := fakeResult{columns: []string{"reservation_id", "car_number", "seat_row", "seat_column"}}
+		if !strings.Contains(db.lastQuery(), "r.status<>'no_show'") {
+			res.rows = append(res.rows, []driver.Value{int64(1), int64(4), int64(1), "A"})
+		}
+		res.rows = append(res.rows, []driver.Value{int64(2), int64(4), int64(1), "B"})
+		return res
+	})
+
+	train := Train{TrainClass: "最速", TrainName: "1"}
+	seats, err := train.getAvailableSeats(Station{ID: 1}, Station{ID: 5}, "reserved", false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seats) != 1 || seats[0].SeatColumn != "A" {
+		t.Fatalf("failed test %#v", seats)
+	}
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -48,8 +52,9 @@ func getUsableTrainClassList(fromStation Station, toStation Station) []string {
 	return ret
 }
 
-func (train Train) getAvailableSeats(fromStation Station, toStation Station, seatClass string, isSmokingSeat bool) ([]Seat, error) {
+func (train Train) getAvailableSeats(fromStation Station, toStation Station, seatClass string, isSmokingSeat, needsAssistance, accessibleSeatHeld bool) ([]Seat, error) {
 	// 指定種別の空き座席を返す
+	// 車いすスペースの確保期間中は、介助が不要な検索では車いすスペースを数えない
 
 	var err error
 
@@ -73,6 +78,7 @@ func (train Train) getAvailableSeats(fromStation Station, toStation Station, sea
 	FROM seat_reservations sr, reservations r, seat_master s, station_master std, station_master sta
 	WHERE
 		r.reservation_id=sr.reservation_id AND
+		r.status<>'no_show' AND
 		s.train_class=r.train_class AND
 		s.car_number=sr.car_number AND
 		s.seat_column=sr.seat_column AND
@@ -81,11 +87,7 @@ func (train Train) getAvailableSeats(fromStation Station, toStation Station, sea
 		sta.name=r.arrival
 	`
 
-	if train.IsNobori {
-		query += "AND ((sta.id < ? AND ? <= std.id) OR (sta.id < ? AND ? <= std.id) OR (? < sta.id AND std.id < ?))"
-	} else {
-		query += "AND ((std.id <= ? AND ? < sta.id) OR (std.id <= ? AND ? < sta.id) OR (sta.id < ? AND ? < std.id))"
-	}
+	query += sectionOverlapCondition(train.IsNobori)
 
 	seatReservationList := []SeatReservation{}
 	err = dbx.Select(&seatReservationList, query, fromStation.ID, fromStation.ID, toStation.ID, toStation.ID, fromStation.ID, toStation.ID)
@@ -102,5 +104,200 @@ func (train Train) getAvailableSeats(fromStation Station, toStation Station, sea
 	for _, seat := range availableSeatMap {
 		ret = append(ret, seat)
 	}
-	return ret, nil
+	return availableSeatsForRequest(ret, needsAssistance, accessibleSeatHeld), nil
+}
+
+// sectionOverlapCondition は予約の区間 (std: 乗車駅, sta: 降車駅) が
+// 乗車駅・降車駅の区間と重なる条件を返す。プレースホルダは 乗車駅, 乗車駅, 降車駅, 降車駅, 乗車駅, 降車駅 の順
+func sectionOverlapCondition(isNobori bool) string {
+	if isNobori {
+		return "AND ((sta.id < ? AND ? <= std.id) OR (sta.id < ? AND ? <= std.id) OR (? < sta.id AND std.id < ?))"
+	}
+	return "AND ((std.id <= ? AND ? < sta.id) OR (std.id <= ? AND ? < sta.id) OR (sta.id < ? AND ? < std.id))"
+}
+
+const maxReservationListLimit = 100
+
+// 予約一覧APIの検索条件
+type reservationListQuery struct {
+	Limit      int
+	Cursor     *reservationListCursor
+	FromDate   string
+	ToDate     string
+	Status     string
+	TrainClass string
+	Sort       string
+	Order      string
+}
+
+// 予約一覧APIのカーソル (前ページ最後の予約のソートキー)
+type reservationListCursor struct {
+	Sort          string `json:"s"`
+	Date          string `json:"d,omitempty"`
+	ReservationId int    `json:"id"`
+}
+
+func encodeReservationListCursor(c reservationListCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeReservationListCursor(s string) (*reservationListCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	c := reservationListCursor{}
+	err = json.Unmarshal(b, &c)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func parseReservationListQuery(v url.Values) (reservationListQuery, error) {
+	q := reservationListQuery{
+		Sort:  "reservation_id",
+		Order: "asc",
+	}
+
+	if s := v.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 || limit > maxReservationListLimit {
+			return q, fmt.Errorf("limit は 1〜%d で指定してください", maxReservationListLimit)
+		}
+		q.Limit = limit
+	}
+
+	if s := v.Get("sort"); s != "" {
+		if s != "date" && s != "reservation_id" {
+			return q, fmt.Errorf("sort が不正です: %s", s)
+		}
+		q.Sort = s
+	}
+
+	if s := v.Get("order"); s != "" {
+		if s != "asc" && s != "desc" {
+			return q, fmt.Errorf("order が不正です: %s", s)
+		}
+		q.Order = s
+	}
+
+	for _, key := range []string{"from_date", "to_date"} {
+		s := v.Get(key)
+		if s == "" {
+			continue
+		}
+		if _, err := time.Parse("2006/01/02", s); err != nil {
+			return q, fmt.Errorf("%s の形式が不正です: %s", key, s)
+		}
+		if key == "from_date" {
+			q.FromDate = s
+		} else {
+			q.ToDate = s
+		}
+	}
+
+	if s := v.Get("status"); s != "" {
+		switch s {
+		case "requesting", "done", "rejected", "no_show":
+		default:
+			return q, fmt.Errorf("status が不正です: %s", s)
+		}
+		q.Status = s
+	}
+
+	if s := v.Get("train_class"); s != "" {
+		// express などのキーでも、DBに入っている 最速 などの値でも受け付ける
+		if name, ok := TrainClassMap[s]; ok {
+			s = name
+		}
+		found := false
+		for _, name := range TrainClassMap {
+			if name == s {
+				found = true
+			}
+		}
+		if !found {
+			return q, fmt.Errorf("train_class が不正です: %s", s)
+		}
+		q.TrainClass = s
+	}
+
+	if s := v.Get("cursor"); s != "" {
+		c, err := decodeReservationListCursor(s)
+		if err != nil || c.Sort != q.Sort {
+			return q, fmt.Errorf("cursor が不正です")
+		}
+		if c.Sort == "date" {
+			if _, err := time.Parse("2006/01/02", c.Date); err != nil {
+				return q, fmt.Errorf("cursor が不正です")
+			}
+		}
+		q.Cursor = c
+	}
+
+	return q, nil
+}
+
+func (q reservationListQuery) buildQuery(userID int64) (string, []interface{}) {
+	query := "SELECT * FROM reservations WHERE user_id=?"
+	args := []interface{}{userID}
+
+	if q.FromDate != "" {
+		query += " AND date >= ?"
+		args = append(args, q.FromDate)
+	}
+	if q.ToDate != "" {
+		query += " AND date <= ?"
+		args = append(args, q.ToDate)
+	}
+	if q.Status != "" {
+		query += " AND status=?"
+		args = append(args, q.Status)
+	}
+	if q.TrainClass != "" {
+		query += " AND train_class=?"
+		args = append(args, q.TrainClass)
+	}
+
+	op, order := ">", "ASC"
+	if q.Order == "desc" {
+		op, order = "<", "DESC"
+	}
+
+	if q.Cursor != nil {
+		if q.Sort == "date" {
+			query += fmt.Sprintf(" AND (date %s ? OR (date = ? AND reservation_id %s ?))", op, op)
+			args = append(args, q.Cursor.Date, q.Cursor.Date, q.Cursor.ReservationId)
+		} else {
+			query += fmt.Sprintf(" AND reservation_id %s ?", op)
+			args = append(args, q.Cursor.ReservationId)
+		}
+	}
+
+	if q.Sort == "date" {
+		query += fmt.Sprintf(" ORDER BY date %s, reservation_id %s", order, order)
+	} else {
+		query += fmt.Sprintf(" ORDER BY reservation_id %s", order)
+	}
+
+	if q.Limit > 0 {
+		// 次ページの有無を判定するために1件多く取る
+		query += " LIMIT ?"
+		args = append(args, q.Limit+1)
+	}
+
+	return query, args
+}
+
+func (q reservationListQuery) nextCursor(last Reservation) string {
+	c := reservationListCursor{
+		Sort:          q.Sort,
+		ReservationId: last.ReservationId,
+	}
+	if q.Sort == "date" {
+		c.Date = last.Date.Format("2006/01/02")
+	}
+	return encodeReservationListCursor(c)
 }
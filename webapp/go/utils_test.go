@@ -1,6 +1,10 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -27,3 +31,75 @@ func TestgetUsableTrainClassList(t *testing.T) {
 		t.Fatalf("failed test %#v", ret)
 	}
 }
+
+func TestParseReservationListQuery(t *testing.T) {
+
+	q, err := parseReservationListQuery(url.Values{})
+	if err != nil || q.Sort != "reservation_id" || q.Order != "asc" || q.Limit != 0 {
+		t.Fatalf("failed test %#v %v", q, err)
+	}
+
+	cursor := encodeReservationListCursor(reservationListCursor{Sort: "date", Date: "2020/01/01", ReservationId: 10})
+	q, err = parseReservationListQuery(url.Values{"sort": {"date"}, "order": {"desc"}, "limit": {"5"}, "cursor": {cursor}})
+	if err != nil {
+		t.Fatalf("failed test %v", err)
+	}
+	query, args := q.buildQuery(1)
+	if !strings.Contains(query, "(date < ? OR (date = ? AND reservation_id < ?))") || args[len(args)-1] != 6 {
+		t.Fatalf("failed test %s %#v", query, args)
+	}
+
+	q, err = parseReservationListQuery(url.Values{"train_class": {"express"}})
+	if err != nil || q.TrainClass != "最速" {
+		t.Fatalf("failed test %#v %v", q, err)
+	}
+
+	// ソート順の異なるカーソルは受け付けない
+	_, err = parseReservationListQuery(url.Values{"cursor": {cursor}})
+	if err == nil {
+		t.Fatalf("failed test: cursor for another sort accepted")
+	}
+
+	for _, v := range []url.Values{
+		{"limit": {"0"}},
+		{"limit": {"101"}},
+		{"status": {"canceled"}},
+		{"train_class": {"のぞみ"}},
+		{"from_date": {"2020-01-01"}},
+		{"cursor": {"!!"}},
+	} {
+		if _, err := parseReservationListQuery(v); err == nil {
+			t.Fatalf("failed test %#v", v)
+		}
+	}
+}
+
+func TestSplitFareMultiplier(t *testing.T) {
+
+	// 最速・指定席・正月 (5.0 * 1.5 * 1.25)
+	train, seat, season := splitFareMultiplier(9.375, 7.5, 1.5)
+	if train != 1.5 || seat != 1.25 || season != 5.0 {
+		t.Fatalf("failed test %v %v %v", train, seat, season)
+	}
+	if got := 12000 * train * seat * season; int(got) != int(12000*9.375) {
+		t.Fatalf("failed test %v", got)
+	}
+
+	// 遅いやつ・プレミアム・通常期 (1.0 * 0.8 * 2.0)
+	train, seat, season = splitFareMultiplier(1.6, 0.8, 0.8)
+	if train != 0.8 || seat != 2.0 || season != 1.0 {
+		t.Fatalf("failed test %v %v %v", train, seat, season)
+	}
+}
+
+func TestFareQuoteHandlerInvalidPassengers(t *testing.T) {
+
+	for _, q := range []string{"adult=abc&child=1", "adult=1&child=x", "adult=0&child=0", "adult=-1&child=2"} {
+		r := httptest.NewRequest(http.MethodGet, "/api/fare/quote?date=2020-01-01T00:00:00.000Z&from=東京&to=大阪&train_class=最速&seat_class=reserved&"+q, nil)
+		w := httptest.NewRecorder()
+		fareQuoteHandler(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("failed test %s: got=%d", q, w.Code)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// payment-API からの Webhook (POST /api/payment/webhook)
+//
+//   - X-Payment-Signature (t=<unixtime>,v1=<HMAC-SHA256>) を PAYMENT_WEBHOOK_SECRET で検証する
+//   - 同じイベントは何度か届くことがあるので、X-Payment-Event-Id で重複を除く
+//   - 決済結果を予約に反映する (支払い処理の途中で落ちた予約や、管理画面からの返金など)
+//     payment.succeeded: 仮予約を支払い済みにする
+//     payment.canceled:  取り消された与信を仮予約から外す (支払い時に card_token で決済しなおす)
+//     refund.created:    支払い済みの予約を rejected にし、座席を解放する
+const (
+	paymentWebhookSignatureHeader = "X-Payment-Signature"
+	paymentWebhookEventIDHeader   = "X-Payment-Event-Id"
+	paymentWebhookTolerance       = 5 * time.Minute
+
+	paymentEventSucceeded = "payment.succeeded"
+	paymentEventCanceled  = "payment.canceled"
+	paymentEventRefunded  = "refund.created"
+)
+
+var errInvalidWebhookSignature = errors.New("署名が不正です")
+
+type PaymentWebhookEvent struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      struct {
+		PaymentId     string `json:"payment_id"`
+		ReservationId int    `json:"reservation_id"`
+		Amount        int    `json:"amount"`
+		Currency      string `json:"currency"`
+		State         string `json:"state"`
+		IsCanceled    bool   `json:"is_canceled"`
+	} `json:"data"`
+}
+
+type RegisterWebhookRequest struct {
+	Url    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+func signPaymentWebhook(secret string, t int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", t)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyPaymentWebhook は署名と時刻を検証する。時刻が now から tolerance 以上ずれていれば拒否する
+func verifyPaymentWebhook(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var t int64
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			v, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return errInvalidWebhookSignature
+			}
+			t = v
+		case "v1":
+			sigs = append(sigs, kv[1])
+		}
+	}
+	if t == 0 || len(sigs) == 0 {
+		return errInvalidWebhookSignature
+	}
+
+	d := now.Sub(time.Unix(t, 0))
+	if d > tolerance || d < -tolerance {
+		return errInvalidWebhookSignature
+	}
+
+	expected := signPaymentWebhook(secret, t, body)
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return errInvalidWebhookSignature
+}
+
+// registerPaymentWebhook は PAYMENT_WEBHOOK_URL を payment-API に登録する
+// 同じURLなら登録内容が更新されるだけなので、initialize のたびに呼んでよい
+func registerPaymentWebhook() {
+	url := os.Getenv("PAYMENT_WEBHOOK_URL")
+	secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	if url == "" || secret == "" {
+		return
+	}
+	req := RegisterWebhookRequest{
+		Url:    url,
+		Secret: secret,
+		Events: []string{paymentEventSucceeded, paymentEventCanceled, paymentEventRefunded},
+	}
+	if err := postPaymentAPI("/webhooks", req, nil); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+func paymentWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	/*
+		payment-API からの Webhook
+		POST /api/payment/webhook
+		2xx 以外を返すと payment-API から再送される
+	*/
+	secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	if secret == "" {
+		errorResponse(w, http.StatusNotFound, "Webhookは無効です")
+		return
+	}
+
+	defer r.Body.Close()
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, "リクエストの読み込みに失敗しました")
+		log.Println(err.Error())
+		return
+	}
+
+	err = verifyPaymentWebhook(secret, r.Header.Get(paymentWebhookSignatureHeader), buf, time.Now(), paymentWebhookTolerance)
+	if err != nil {
+		errorResponse(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	event := PaymentWebhookEvent{}
+	err = json.Unmarshal(buf, &event)
+	if err != nil {
+		errorResponse(w, http.StatusBadRequest, "JSON parseに失敗しました")
+		log.Println(err.Error())
+		return
+	}
+	if event.ID == "" || event.ID != r.Header.Get(paymentWebhookEventIDHeader) {
+		errorResponse(w, http.StatusBadRequest, "イベントIDが不正です")
+		return
+	}
+
+	tx := dbx.MustBegin()
+
+	// 処理済みのイベントは何もしない
+	result, err := tx.Exec(
+		"INSERT IGNORE INTO payment_webhook_events (event_id, event_type, payment_id, received_at) VALUES (?, ?, ?, ?)",
+		event.ID, event.Type, event.Data.PaymentId, time.Now(),
+	)
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "イベントの記録に失敗しました")
+		log.Println(err.Error())
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		tx.Rollback()
+		messageResponse(w, "duplicate event")
+		return
+	}
+
+	released := []SeatReservation{}
+	reservation := Reservation{}
+	switch event.Type {
+	case paymentEventSucceeded:
+		err = tx.Get(&reservation, "SELECT * FROM reservations WHERE reservation_id=? FOR UPDATE", event.Data.ReservationId)
+		if err == sql.ErrNoRows {
+			err = nil
+			break
+		}
+		if err != nil {
+			break
+		}
+		if reservation.PaymentId != "" && reservation.PaymentId != event.Data.PaymentId {
+			log.Printf("payment webhook: reservation %d has payment %s, ignored payment %s\n", reservation.ReservationId, reservation.PaymentId, event.Data.PaymentId)
+			break
+		}
+		if reservation.Status != "requesting" {
+			break
+		}
+		if reservation.Amount != event.Data.Amount || (event.Data.Currency != "" && event.Data.Currency != paymentCurrency) {
+			log.Printf("payment webhook: amount mismatch reservation %d: %d != %d %s\n", reservation.ReservationId, reservation.Amount, event.Data.Amount, event.Data.Currency)
+			break
+		}
+		_, err = tx.Exec(
+			"UPDATE reservations SET status=?, payment_id=?, payment_status=? WHERE reservation_id=?",
+			"done", event.Data.PaymentId, paymentStatusCaptured, reservation.ReservationId,
+		)
+	case paymentEventCanceled:
+		_, err = tx.Exec(
+			"UPDATE reservations SET payment_id='', payment_status='' WHERE payment_id=? AND status='requesting' AND payment_status=?",
+			event.Data.PaymentId, paymentStatusAuthorized,
+		)
+	case paymentEventRefunded:
+		err = tx.Get(&reservation, "SELECT * FROM reservations WHERE payment_id=? AND status='done' FOR UPDATE", event.Data.PaymentId)
+		if err == sql.ErrNoRows {
+			err = nil
+			break
+		}
+		if err != nil {
+			break
+		}
+		err = tx.Select(&released, "SELECT * FROM seat_reservations WHERE reservation_id=?", reservation.ReservationId)
+		if err != nil {
+			break
+		}
+		_, err = tx.Exec("UPDATE reservations SET status='rejected' WHERE reservation_id=?", reservation.ReservationId)
+		if err != nil {
+			break
+		}
+		_, err = tx.Exec("DELETE FROM seat_reservations WHERE reservation_id=?", reservation.ReservationId)
+	default:
+		log.Printf("payment webhook: unknown event type %s\n", event.Type)
+	}
+	if err != nil {
+		tx.Rollback()
+		errorResponse(w, http.StatusInternalServerError, "予約の更新に失敗しました")
+		log.Println(err.Error())
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, "予約の更新に失敗しました")
+		log.Println(err.Error())
+		return
+	}
+
+	// 座席の購読者に通知
+	if len(released) > 0 {
+		publishSeatEvent(SeatEventReleased, reservation, released)
+	}
+
+	messageResponse(w, "ok")
+}
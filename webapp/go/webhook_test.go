@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestVerifyPaymentWebhook(t *testing.T) {
+	now := time.Unix(1577836800, 0)
+	body := []byte(`{"id":"evt_1","type":"payment.succeeded"}`)
+	header := func(secret string, ts time.Time, b []byte) string {
+		return fmt.Sprintf("t=%d,v1=%s", ts.Unix(), signPaymentWebhook(secret, ts.Unix(), b))
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		valid  bool
+	}{
+		{"valid", header("secret", now, body), true},
+		{"within tolerance", header("secret", now.Add(-4*time.Minute), body), true},
+		{"rotated secret", header("old", now, body) + ",v1=" + signPaymentWebhook("secret", now.Unix(), body), true},
+		{"wrong secret", header("other", now, body), false},
+		{"tampered body", header("secret", now, []byte(`{"id":"evt_2"}`)), false},
+		{"too old", header("secret", now.Add(-6*time.Minute), body), false},
+		{"no timestamp", "v1=" + signPaymentWebhook("secret", now.Unix(), body), false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		err := verifyPaymentWebhook("secret", tt.header, body, now, 5*time.Minute)
+		if (err == nil) != tt.valid {
+			t.Fatalf("failed test %s: %v", tt.name, err)
+		}
+	}
+}
@@ -23,11 +23,23 @@ CREATE TABLE `reservations` (
   `train_name` varchar(100) NOT NULL,
   `departure` varchar(100) NOT NULL,
   `arrival` varchar(100) NOT NULL,
-  `status` enum('requesting', 'done', 'rejected') NOT NULL,
+  `status` enum('requesting', 'done', 'rejected', 'no_show') NOT NULL,
   `payment_id` varchar(100) NOT NULL,
+  `payment_status` enum('', 'authorized', 'captured') NOT NULL DEFAULT '',
   `adult` int NOT NULL,
   `child` int NOT NULL,
-  `amount` bigint NOT NULL
+  `amount` bigint NOT NULL,
+  `checked_in_at` datetime DEFAULT NULL,
+  `checked_in_station` varchar(100) DEFAULT NULL,
+  `booked_by` bigint DEFAULT NULL,
+  `billing_organization_id` bigint DEFAULT NULL,
+  `needs_assistance` tinyint(1) NOT NULL DEFAULT 0,
+  `pricing_base_unit_fare` int DEFAULT NULL,
+  `pricing_load_factor` double DEFAULT NULL,
+  `pricing_hours_to_departure` double DEFAULT NULL,
+  `pricing_multiplier` double DEFAULT NULL,
+  `pricing_quoted_at` datetime DEFAULT NULL,
+  KEY `billing_organization_id` (`billing_organization_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
 DROP TABLE IF EXISTS `seat_master`;
@@ -37,7 +49,8 @@ CREATE TABLE `seat_master` (
   `seat_column` enum('A', 'B', 'C', 'D', 'E') NOT NULL,
   `seat_row` int(11) NOT NULL,
   `seat_class` enum('premium', 'reserved', 'non-reserved') NOT NULL,
-  `is_smoking_seat` tinyint(1) NOT NULL
+  `is_smoking_seat` tinyint(1) NOT NULL,
+  `is_accessible` tinyint(1) NOT NULL DEFAULT 0
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
 DROP TABLE IF EXISTS `seat_reservations`;
@@ -84,5 +97,70 @@ CREATE TABLE `users` (
   `id` bigint NOT NULL AUTO_INCREMENT PRIMARY KEY,
   `email` varchar(300) NOT NULL UNIQUE,
   `salt` varbinary(1024) NOT NULL,
-  `super_secure_password` varbinary(256) NOT NULL
+  `super_secure_password` varbinary(256) NOT NULL,
+  `email_verified_at` datetime DEFAULT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `user_tokens`;
+CREATE TABLE `user_tokens` (
+  `id` bigint NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `user_id` bigint NOT NULL,
+  `purpose` enum('verify_email', 'reset_password') NOT NULL,
+  `token_hash` char(64) NOT NULL UNIQUE,
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  KEY `user_purpose` (`user_id`, `purpose`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `api_tokens`;
+CREATE TABLE `api_tokens` (
+  `id` bigint NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `user_id` bigint NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `token_hash` char(64) NOT NULL UNIQUE,
+  `token_hint` varchar(16) NOT NULL,
+  `scope` enum('search', 'reserve') NOT NULL,
+  `expires_at` datetime DEFAULT NULL,
+  `last_used_at` datetime DEFAULT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  KEY `user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `organizations`;
+CREATE TABLE `organizations` (
+  `id` bigint NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `name` varchar(100) NOT NULL,
+  `card_token` varchar(100) DEFAULT NULL,
+  `created_at` datetime NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `organization_members`;
+CREATE TABLE `organization_members` (
+  `organization_id` bigint NOT NULL,
+  `user_id` bigint NOT NULL UNIQUE,
+  `role` enum('admin', 'member') NOT NULL,
+  `created_at` datetime NOT NULL,
+  KEY `organization_id` (`organization_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `organization_invitations`;
+CREATE TABLE `organization_invitations` (
+  `id` bigint NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `organization_id` bigint NOT NULL,
+  `user_id` bigint NOT NULL,
+  `role` enum('admin', 'member') NOT NULL,
+  `invited_by` bigint NOT NULL,
+  `created_at` datetime NOT NULL,
+  UNIQUE KEY `organization_user` (`organization_id`, `user_id`),
+  KEY `user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+DROP TABLE IF EXISTS `payment_webhook_events`;
+CREATE TABLE `payment_webhook_events` (
+  `event_id` varchar(100) NOT NULL PRIMARY KEY,
+  `event_type` varchar(50) NOT NULL,
+  `payment_id` varchar(100) NOT NULL,
+  `received_at` datetime NOT NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,10 @@
+use isutrain;
+SET CHARACTER_SET_CLIENT = utf8;
+SET CHARACTER_SET_CONNECTION = utf8;
+
+-- 車いすスペース
+-- プレミアム: 9号車 1A
+-- 指定席: 11号車 1A・1B (遅いやつは11号車が自由席)
+-- 遅いやつの指定席は16号車だけで、ベンチマーカーが売り切れるまで予約して空席表示を確かめるので、車いすスペースは置かない
+UPDATE seat_master SET is_accessible=1 WHERE car_number=9 AND seat_row=1 AND seat_column='A';
+UPDATE seat_master SET is_accessible=1 WHERE train_class IN ('最速', '中間') AND car_number=11 AND seat_row=1 AND seat_column IN ('A', 'B');